@@ -2,28 +2,30 @@ package base_hook
 
 import (
 	"context"
-	"crypto/tls"
-	"fmt"
 	"github.com/gogf/gf/v2/container/garray"
-	"github.com/gogf/gf/v2/container/gmap"
 	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
-	"github.com/gogf/gf/v2/net/gclient"
 	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/net/gtrace"
 	"github.com/gogf/gf/v2/os/glog"
-	"github.com/gogf/gf/v2/util/gconv"
-	"github.com/gorilla/websocket"
+	"github.com/gogf/gf/v2/os/grpool"
+	"github.com/gogf/gf/v2/util/guid"
 	"github.com/kysion/base-library/base_model"
 	"github.com/kysion/base-library/base_model/base_enum"
+	"net/http"
 	"reflect"
-	"strings"
+	"sync"
 	"time"
 )
 
-var wsArr = gmap.Map{}
-
 type BaseHookModel struct {
 	hookArr garray.Array
+	metaArr []hookMeta // 订阅配置，与 hookArr 的下标一一对应
+	mu      sync.RWMutex
+	pool    *grpool.Pool // 异步调度使用的协程池
+
+	middlewares []Middleware // 当前Hook的中间件
 }
 
 func (s *BaseHookModel) GetHookArr() *garray.Array {
@@ -35,191 +37,306 @@ type BaseHook[T any, F any] struct {
 }
 
 type Option struct {
-	Data        any    // 需要广播的数据
-	HookTypeStr string // Hook类型，通常是HookFunc的名称
-	NetMessage  bool   // 是否是网络消息
+	Data         any                        // 需要广播的数据
+	HookTypeStr  string                     // Hook类型，通常是HookFunc的名称
+	NetMessage   bool                       // 是否是网络消息
+	DeliveryMode base_enum.HookDeliveryMode `json:"-"` // 网络消息的投递方式，默认为failover
+	DeliveryKey  string                     `json:"-"` // hash投递方式下用于选择服务的Key
+	// 网络消息的幂等Key，同一业务操作重复发布时使用相同的Key，接收方只处理一次；为空时按消息ID去重
+	IdempotencyKey string `json:"-"`
+	// 本地订阅者的调度选项，Mode 为空时同步调用；异步调用时订阅者的错误交由 OnError 处理，不在返回值中
+	Dispatch DispatchOption `json:"-"`
 }
 
+// GetBusinessType 获取业务类型：订阅函数类型已通过 base_enum.Hook.BusinessType.Register 声明主题时返回该主题，否则为订阅函数的类型名
 func (s *BaseHook[T, F]) GetBusinessType() base_enum.HookBusinessType {
 	var t F
 	return base_enum.Hook.BusinessType.New(reflect.TypeOf(t).String())
 }
 
-// InstallHook 安装Hook
-func (s *BaseHook[T, F]) InstallHook(filter T, hookFunc F) {
+// InstallHook 安装Hook，可通过 InstallOption 指定订阅者的优先级及超时时间
+func (s *BaseHook[T, F]) InstallHook(filter T, hookFunc F, option ...InstallOption) {
 	item := base_model.KeyValueT[T, F]{Key: filter, Value: hookFunc}
 
+	meta := hookMeta{}
+	if len(option) > 0 {
+		meta.InstallOption = option[0]
+	}
+	meta.record = registerSubscriber(s.GetBusinessType().Code(), filter, hookFunc, 1)
+
 	// 安装的时候就注册Hook消息
 	RegisterHookMessage(s)
-	s.hookArr.Append(item)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 按优先级从高到低排列，相同优先级按安装顺序排列
+	index := len(s.metaArr)
+	for i, m := range s.metaArr {
+		if m.Priority < meta.Priority {
+			index = i
+			break
+		}
+	}
+
+	if index >= s.hookArr.Len() {
+		s.hookArr.Append(item)
+	} else {
+		_ = s.hookArr.InsertBefore(index, item)
+	}
+	s.metaArr = append(s.metaArr[:index], append([]hookMeta{meta}, s.metaArr[index:]...)...)
 }
 
 // UnInstallHook 卸载Hook
 func (s *BaseHook[T, F]) UnInstallHook(filter T, f ...func(filter T, key T) bool) {
-	newFuncArr := garray.NewArray()
-	s.hookArr.Iterator(func(key int, value interface{}) bool {
-		item := value.(base_model.KeyValueT[T, F])
-
-		if len(f) > 0 && f[0](filter, item.Key) == false {
-			newFuncArr.Append(value)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	newFuncArr := make([]interface{}, 0)
+	newMetaArr := make([]hookMeta, 0)
+	for i, entry := range s.entries() {
+		if len(f) > 0 && f[0](filter, entry.Key) == false {
+			newFuncArr = append(newFuncArr, s.hookArr.At(i))
+			newMetaArr = append(newMetaArr, entry.hookMeta)
+		} else {
+			unregisterSubscriber(entry.record)
 		}
-
-		return true
-	})
-	s.hookArr = *newFuncArr
+	}
+	s.hookArr.SetArray(newFuncArr)
+	s.metaArr = newMetaArr
 }
 
 // ClearAllHook 清除Hook
 func (s *BaseHook[T, F]) ClearAllHook() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, meta := range s.metaArr {
+		unregisterSubscriber(meta.record)
+	}
 	s.hookArr.Clear()
+	s.metaArr = nil
+}
+
+// snapshot 获取当前所有订阅项的快照
+func (s *BaseHook[T, F]) snapshot() []hookEntry[T, F] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.entries()
+}
+
+// entries 获取所有订阅项，调用方需持有锁
+func (s *BaseHook[T, F]) entries() []hookEntry[T, F] {
+	items := s.hookArr.Slice()
+	result := make([]hookEntry[T, F], 0, len(items))
+	for i, v := range items {
+		entry := hookEntry[T, F]{KeyValueT: v.(base_model.KeyValueT[T, F])}
+		if i < len(s.metaArr) {
+			entry.hookMeta = s.metaArr[i]
+		}
+		result = append(result, entry)
+	}
+	return result
 }
 
 // Iterator 遍历Hook
 func (s *BaseHook[T, F]) Iterator(f func(key T, value F), options ...Option) {
 
-	s.hookArr.Iterator(func(key int, value interface{}) bool {
-		item := value.(base_model.KeyValueT[T, F])
+	for _, item := range s.snapshot() {
 		f(item.Key, item.Value)
-		return true
-	})
+	}
 
 	if len(options) <= 0 || options[0].Data == nil || options[0].NetMessage == false {
 		return
 	}
 
+	s.publishAsync(context.Background(), options[0])
+}
+
+var (
+	publishPoolOnce sync.Once
+	publishPool     *grpool.Pool // 异步投递网络消息使用的协程池
+)
+
+// getPublishPool 获取异步投递网络消息使用的协程池，大小按配置 service.hook.publish.workerSize
+func getPublishPool(ctx context.Context) *grpool.Pool {
+	publishPoolOnce.Do(func() {
+		publishPool = grpool.New(g.Cfg().MustGet(ctx, "service.hook.publish.workerSize", 64).Int())
+	})
+	return publishPool
+}
+
+// publishTimeout 异步投递的超时时间，按websocket传输的确认超时及最大投递次数计算，并预留一个确认超时用于建立连接
+func publishTimeout(ctx context.Context) time.Duration {
+	option := transport.getOption(ctx)
+	return option.AckTimeout * time.Duration(max(option.MaxAttempts, 1)+1)
+}
+
+// publishAsync 异步投递网络消息，避免等待对端确认阻塞调用方；投递在协程池中执行，超过 publishTimeout 后放弃，投递过程中的 panic 仅记录日志
+func (s *BaseHook[T, F]) publishAsync(ctx context.Context, option Option) {
 	var valueObj F
-	options[0].HookTypeStr = reflect.TypeOf(valueObj).String()
+	option.HookTypeStr = reflect.TypeOf(valueObj).String()
+	businessType := base_enum.Hook.BusinessType.New(option.HookTypeStr)
 
-	_ = g.Try(context.Background(), func(ctx context.Context) {
-		var businessType F
-		typeEnumStr := reflect.TypeOf(businessType).String()
-		fmt.Println(typeEnumStr)
+	// 异步投递不受调用方上下文取消的影响，但保留链路ID等上下文信息
+	ctx = context.WithoutCancel(ctx)
+	err := getPublishPool(ctx).AddWithRecover(ctx, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, publishTimeout(ctx))
+		defer cancel()
 
-		s.publish(options[0], base_enum.Hook.BusinessType.New(typeEnumStr))
+		if err := s.publish(ctx, option, businessType); err != nil {
+			g.Log().Error(ctx, err)
+		}
+	}, func(ctx context.Context, exception error) {
+		g.Log().Errorf(ctx, "Hook消息 %s 投递失败：%+v", businessType.Code(), exception)
 	})
+	if err != nil {
+		g.Log().Error(ctx, gerror.Wrapf(err, "Hook消息 %s 投递失败", businessType.Code()))
+	}
 }
 
 func (s *BaseHook[T, F]) Where(filter T, f func(filter T, key T) bool) []F {
 	result := make([]F, 0)
 
-	s.hookArr.Iterator(func(key int, value interface{}) bool {
-		item := value.(base_model.KeyValueT[T, F])
-
+	for _, item := range s.snapshot() {
 		if f(filter, item.Key) {
 			result = append(result, item.Value)
 		}
-
-		return true
-	})
+	}
 
 	return result
 }
 
 // HookDistribution 开启一个websocket服务，用于接收广播消息，需要在路由注册时候注册
 func HookDistribution(r *ghttp.Request) {
+	serveHookDistribution(r, nil)
+}
+
+// HookDistributionWith 返回使用指定处理函数接收业务消息的websocket服务，handler 为nil时交由网关广播
+func HookDistributionWith(handler InboundHandler) ghttp.HandlerFunc {
+	return func(r *ghttp.Request) {
+		serveHookDistribution(r, handler)
+	}
+}
+
+func serveHookDistribution(r *ghttp.Request, handler InboundHandler) {
+	// 校验来源白名单及握手认证信息
+	if err := verifyHandshake(r); err != nil {
+		glog.Warning(r.Context(), err)
+		r.Response.WriteStatusExit(http.StatusUnauthorized)
+	}
+
 	ws, err := r.WebSocket()
 	if err != nil {
 		glog.Error(r.Context(), err)
 		r.Exit()
 	}
+	conn := &hookConn{ws: ws}
 
-	if Gateway().HasHookMessage() {
-		for {
-			_, msg, err := ws.ReadMessage()
-			if err != nil {
-				return
-			}
+	for {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
 
-			fmt.Println(string(msg))
+		data := base_model.HookModel{}
+		// 解析订阅数据包
+		err = gjson.DecodeTo(msg, &data)
+		if err != nil {
+			glog.Error(r.Context(), err)
+			continue
+		}
+		data.Source = newHostInfo(r.RemoteAddr)
 
-			data := base_model.HookModel{}
-			// 解析订阅数据包
-			err = gjson.DecodeTo(msg, &data)
-			if err != nil {
-				fmt.Println(err)
-				continue
-			}
-			addr := strings.Split(r.RemoteAddr, ":")
-			data.Ctx = r.Context()
-			data.Source = &base_model.HookHostInfo{
-				Host: addr[0],
-				Port: gconv.Int(addr[1]),
-			}
+		// 回复确认消息，未携带消息ID的旧版本消息无需确认
+		reply, ok := receiveHookModel(r.Context(), &data, handler, conn)
+		if !ok {
+			continue
+		}
 
-			// 处理广播消息
-			Gateway().BroadcastMessage(data)
+		if err = conn.write(r.Context(), reply); err != nil {
+			glog.Error(r.Context(), err)
+			return
 		}
 	}
 }
 
 // 发送消息给对应的服务List
-func (s *BaseHook[T, F]) publish(dataInfo interface{}, businessType base_enum.HookBusinessType) {
-	/*
-			跨进程Hook订阅的方案：
-				1、获取配置的服务注册表
-				2、按照配置服务，通过ws协议连接到对应的服务 (重连检测机制)
-				3、发送消息给对应的服务
-		       	4、读取服务响应的消息 （可选）
-	*/
+func (s *BaseHook[T, F]) publish(ctx context.Context, dataInfo interface{}, businessType base_enum.HookBusinessType) error {
+	data, err := newNetHookModel(businessType.Code(), dataInfo)
+	if err != nil {
+		return err
+	}
 
-	// 1、获取配置的服务注册表
-	serviceArr := garray.NewStrArrayFrom(g.Cfg().MustGet(context.Background(), "service.hostAddressArr").Strings())
+	return deliverHookModel(ctx, data)
+}
 
+// newNetHookModel 构建发送给其他服务的消息，直接发布及经发件箱发布时使用相同的格式
+func newNetHookModel(businessType string, dataInfo interface{}) (base_model.HookModel, error) {
 	data := base_model.HookModel{
-		BusinessTypeStr: businessType.Code(),
+		BusinessTypeStr: businessType,
 		Data:            dataInfo, // 发送的数据,
 	}
 
-	// 2、按照配置服务，通过ws协议连接到对应的服务
-	serviceArr.Iterator(func(k int, v string) bool {
-		// ws链接服务
-		wsPath := g.Cfg().MustGet(context.Background(), "service.wsPath", "/ws").String()
-		urlStr := "ws://" + v + wsPath // 例如：ws://127.0.0.1:7778/ws
-		fmt.Println(urlStr)
-
-		var conn *websocket.Conn
-		var err error
-		wsConn := wsArr.Get(urlStr)
-		if wsConn == nil {
-			client := gclient.NewWebSocket()
-			client.HandshakeTimeout = time.Second
-			client.TLSClientConfig = &tls.Config{} // 设置 tls 配置
-			conn, _, err = client.Dial(urlStr, nil)
-			if err != nil {
-				// 如果连接失败，则调用重链接方法
-				return true
+	if option, ok := dataInfo.(Option); ok {
+		setDeliveryMode(&data, option)
+
+		// 声明了载荷约定的业务类型按约定编码载荷，未声明时保持原有格式，兼容旧版本的服务
+		if _, ok = GetSchema(data.BusinessTypeStr); ok {
+			if err := encodePayload(&data, option.Data); err != nil {
+				return data, err
 			}
-			// 增加连接断开等消息检测，如果连接断开则将conn对象从wsArr中删除
-			go func() {
-				for {
-					_, _, err = conn.ReadMessage()
-					if err != nil {
-						wsArr.Remove(urlStr)
-						break
-					}
-				}
-			}()
-
-			wsArr.Set(urlStr, conn)
-		} else {
-			conn = wsConn.(*websocket.Conn)
 		}
+	}
 
-		//defer conn.Close()
-		// 4、异步读取服务响应的消息 （可选）
-		//go func() {
-		//	_, msg, _ := conn.ReadMessage()
-		//	fmt.Println(string(msg))
-		//	conn.Close()
-		//}()
+	return data, nil
+}
 
-		// 3、发送消息给对应的服务
-		err = conn.WriteJSON(data)
-		if err != nil {
-			panic(err)
-		}
+// deliverHookModel 将Hook消息投递给配置的服务List，并等待对端确认，所有服务均不可达时返回错误
+func deliverHookModel(ctx context.Context, data base_model.HookModel) error {
+	/*
+			跨进程Hook订阅的方案：
+				1、获取配置的服务注册表
+				2、按照业务类型选择传输方式（默认ws协议），与对应的服务建立连接 (心跳及重连机制)
+				3、按投递方式（故障转移、广播、轮询、一致性哈希）发送消息给对应的服务
+		       	4、等待服务回复的确认消息，超时未确认则重新投递
+	*/
 
-		return false
-	})
+	if base_enum.Hook.BusinessType.IsPattern(data.BusinessTypeStr) {
+		return gerror.Newf("包含通配符的业务类型只能用于订阅：%s", data.BusinessTypeStr)
+	}
+
+	// 1、按业务类型获取传输方式及配置的服务注册表
+	t, hosts, err := routeTransport(ctx, data.BusinessTypeStr)
+	if err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		return gerror.New("未配置或未发现跨进程通信的服务地址：service.hostAddressArr、service.hook.discovery")
+	}
 
+	// 2、与所有配置的服务建立连接
+	if err = t.Connect(ctx, hosts...); err != nil {
+		return err
+	}
+
+	if data.MessageId == "" {
+		data.MessageId = guid.S()
+	}
+	if data.TraceId == "" {
+		data.TraceId = gtrace.GetTraceID(ctx)
+	}
+
+	// 3、按投递方式发送消息给对应的服务，并等待确认
+	return deliverByMode(ctx, t, hosts, data)
+}
+
+// setDeliveryMode 将Option中的投递方式及幂等Key写入Hook消息
+func setDeliveryMode(data *base_model.HookModel, option Option) {
+	if option.DeliveryMode != nil {
+		data.DeliveryModeStr = option.DeliveryMode.Code()
+	}
+	data.DeliveryKey = option.DeliveryKey
+	data.IdempotencyKey = option.IdempotencyKey
 }
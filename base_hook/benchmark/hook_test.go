@@ -0,0 +1,75 @@
+package hook_benchmark
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kysion/base-library/base_hook"
+)
+
+// 对比基于反射的 PublishHookMessage 与类型安全的 TypedHook 的调用开销
+
+var (
+	reflectHook = &base_hook.BaseHook[int, base_hook.UserHookFunc]{}
+	typedHook   = base_hook.NewTypedHook[int, *base_hook.User]("benchmark.user")
+	hookTypeStr = reflect.TypeOf(base_hook.UserHookFunc(nil)).String()
+	user        = &base_hook.User{UserId: 1, Username: "kysion"}
+)
+
+func init() {
+	reflectHook.InstallHook(1, func(ctx context.Context, info *base_hook.User) error {
+		return nil
+	})
+	typedHook.Subscribe(1, func(ctx context.Context, info *base_hook.User) error {
+		return nil
+	})
+}
+
+// BenchmarkReflectPublish 测试基于反射的Hook发布的性能
+func BenchmarkReflectPublish(b *testing.B) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = base_hook.PublishHookMessage(ctx, reflectHook, base_hook.Option{
+			Data:        user,
+			HookTypeStr: hookTypeStr,
+		})
+	}
+}
+
+// BenchmarkTypedPublish 测试类型安全的Hook发布的性能
+func BenchmarkTypedPublish(b *testing.B) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = typedHook.Publish(ctx, user)
+	}
+}
+
+// BenchmarkTypedPublishWithFilter 测试带过滤条件的类型安全Hook发布的性能
+func BenchmarkTypedPublishWithFilter(b *testing.B) {
+	ctx := context.Background()
+	option := base_hook.TypedOption[int]{
+		Filter: func(filter int) bool {
+			return filter == 1
+		},
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = typedHook.Publish(ctx, user, option)
+	}
+}
+
+// BenchmarkTypedParallelPublish 测试并发发布类型安全Hook的性能
+func BenchmarkTypedParallelPublish(b *testing.B) {
+	ctx := context.Background()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = typedHook.Publish(ctx, user)
+		}
+	})
+}
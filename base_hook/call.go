@@ -0,0 +1,343 @@
+package base_hook
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	基于Hook网关的请求/应答：
+		1、调用方通过 Call 发送 request 消息，对端确认接收后等待应答，应答与请求通过消息ID关联
+		2、应答方通过 HandleCall 注册应答函数，应答函数可多次发送部分应答（replyChunk），返回值作为最终应答（reply）
+		3、请求携带调用方上下文的截止时间，应答方据此设置处理的超时时间
+		4、对端返回的错误转换为 RemoteError，保留对端的错误码，可通过 gerror.Code 判断错误类型
+		5、应答在每个请求的缓冲区中排队，缓冲区已满（OnPartial 处理过慢）时请求以 ErrCallReplyOverflow 失败，不阻塞传输的读取协程
+*/
+
+var (
+	ErrCallNoHandler  = gerror.NewCode(gcode.CodeNotFound, "Hook请求没有对应的应答函数")
+	ErrCallFanout     = gerror.NewCode(gcode.CodeNotSupported, "Hook请求不支持fanout投递方式")
+	ErrCallConnClosed = gerror.New("Hook请求的连接已断开")
+	// ErrCallReplyOverflow 应答的缓冲区已满，部分应答的处理速度跟不上对端发送的速度
+	ErrCallReplyOverflow = gerror.NewCode(gcode.CodeOperationFailed, "Hook请求的应答缓冲区已满")
+)
+
+// RemoteError 对端返回的错误
+type RemoteError struct {
+	BusinessType string     // 业务类型
+	RemoteCode   gcode.Code // 对端的错误码
+	Message      string     // 对端的错误信息
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("Hook对端返回错误[%s]：%s", e.BusinessType, e.Message)
+}
+
+// Code 返回对端的错误码，使 gerror.Code 可以识别远程错误
+func (e *RemoteError) Code() gcode.Code {
+	return e.RemoteCode
+}
+
+// Is 对端拒绝的消息视为 ErrMessageRejected，兼容原有的判断方式
+func (e *RemoteError) Is(target error) bool {
+	return target == ErrMessageRejected
+}
+
+// builtinCodes 框架内置的错误码
+var builtinCodes = []gcode.Code{
+	gcode.CodeInternalError, gcode.CodeValidationFailed, gcode.CodeDbOperationError, gcode.CodeInvalidParameter,
+	gcode.CodeMissingParameter, gcode.CodeInvalidOperation, gcode.CodeInvalidConfiguration, gcode.CodeMissingConfiguration,
+	gcode.CodeNotImplemented, gcode.CodeNotSupported, gcode.CodeOperationFailed, gcode.CodeNotAuthorized,
+	gcode.CodeSecurityReason, gcode.CodeServerBusy, gcode.CodeUnknown, gcode.CodeNotFound, gcode.CodeInvalidRequest,
+	gcode.CodeNecessaryPackageNotImport, gcode.CodeInternalPanic, gcode.CodeBusinessValidationFailed,
+}
+
+// newRemoteError 根据对端回复的消息构建远程错误
+func newRemoteError(model base_model.HookModel) *RemoteError {
+	var code gcode.Code = gcode.CodeUnknown
+	if model.ErrorCode != 0 {
+		code = gcode.New(model.ErrorCode, "", nil)
+		// 框架内置的错误码使用本地的定义，便于直接比较
+		for _, item := range builtinCodes {
+			if item.Code() == model.ErrorCode {
+				code = item
+				break
+			}
+		}
+	}
+
+	return &RemoteError{
+		BusinessType: model.BusinessTypeStr,
+		RemoteCode:   code,
+		Message:      model.Error,
+	}
+}
+
+// setReplyError 将错误信息及错误码写入回复消息
+func setReplyError(reply *base_model.HookModel, err error) {
+	reply.Error = err.Error()
+	if code := gerror.Code(err); code != gcode.CodeNil {
+		reply.ErrorCode = code.Code()
+	}
+}
+
+// ReplyStream 发送部分应答
+type ReplyStream interface {
+	// Send 发送一条部分应答，调用方按发送顺序接收
+	Send(data interface{}) error
+}
+
+// CallHandler 应答函数，request 为请求消息，返回值作为最终应答
+type CallHandler func(ctx context.Context, request base_model.HookModel, stream ReplyStream) (reply interface{}, err error)
+
+// CallOption 请求选项
+type CallOption struct {
+	Timeout      time.Duration                 // 调用方上下文未设置截止时间时使用的超时时间，为0时读取配置 service.hook.call.timeout
+	OnPartial    func(partial *gvar.Var) error // 部分应答的回调，返回错误时结束等待
+	DeliveryMode base_enum.HookDeliveryMode    // 投递方式，不支持fanout，默认为failover
+	DeliveryKey  string                        // hash投递方式下用于选择服务的Key
+}
+
+var (
+	callHandlerMap = gmap.NewStrAnyMap(true) // key为业务类型，value为 CallHandler
+	pendingCalls   = gmap.NewStrAnyMap(true) // key为请求的消息ID，value为 *pendingCall
+	servedCalls    = gcache.New()            // 已接收的请求ID，避免重投的请求被重复处理
+)
+
+// HandleCall 注册业务类型的应答函数，重复注册时覆盖
+func HandleCall(businessType string, handler CallHandler) {
+	callHandlerMap.Set(base_enum.Hook.BusinessType.Resolve(businessType), handler)
+}
+
+// RemoveCallHandler 移除业务类型的应答函数，之后收到的请求返回 ErrCallNoHandler
+func RemoveCallHandler(businessType string) {
+	callHandlerMap.Remove(base_enum.Hook.BusinessType.Resolve(businessType))
+}
+
+// pendingCall 等待应答的请求
+type pendingCall struct {
+	replies  chan base_model.HookModel
+	done     chan struct{}
+	overflow chan struct{} // 应答的缓冲区已满时关闭
+	once     sync.Once
+}
+
+// Call 发送请求给其他服务并等待应答，返回最终应答的数据。
+// 部分应答交由 CallOption.OnPartial 处理；对端返回的错误转换为 *RemoteError。
+func Call(ctx context.Context, businessType string, payload interface{}, option ...CallOption) (*gvar.Var, error) {
+	var opt CallOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+	if opt.DeliveryMode != nil && opt.DeliveryMode.Code() == base_enum.Hook.DeliveryMode.Fanout.Code() {
+		return nil, ErrCallFanout
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := opt.Timeout
+		if timeout <= 0 {
+			timeout = g.Cfg().MustGet(ctx, "service.hook.call.timeout", "30s").Duration()
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+
+	request := base_model.HookModel{
+		MessageId:       guid.S(),
+		MessageTypeStr:  base_enum.Hook.MessageType.Request.Code(),
+		BusinessTypeStr: base_enum.Hook.BusinessType.Resolve(businessType),
+		DeliveryKey:     opt.DeliveryKey,
+		Data:            payload,
+		Deadline:        deadline.UnixMilli(),
+	}
+	if opt.DeliveryMode != nil {
+		request.DeliveryModeStr = opt.DeliveryMode.Code()
+	}
+
+	// 先登记再发送，避免应答先于确认到达
+	call := &pendingCall{
+		replies:  make(chan base_model.HookModel, 64),
+		done:     make(chan struct{}),
+		overflow: make(chan struct{}),
+	}
+	pendingCalls.Set(request.MessageId, call)
+	defer func() {
+		pendingCalls.Remove(request.MessageId)
+		close(call.done)
+	}()
+
+	if err := deliverHookModel(ctx, request); err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case reply := <-call.replies:
+			if reply.MessageType().Code() == base_enum.Hook.MessageType.ReplyChunk.Code() {
+				if opt.OnPartial != nil {
+					if err := opt.OnPartial(gvar.New(reply.Data)); err != nil {
+						return nil, err
+					}
+				}
+				continue
+			}
+
+			if reply.Error != "" {
+				return nil, newRemoteError(reply)
+			}
+			return gvar.New(reply.Data), nil
+
+		case <-call.overflow:
+			return nil, gerror.WrapCodef(gcode.CodeOperationFailed, ErrCallReplyOverflow, "请求ID：%s", request.MessageId)
+
+		case <-ctx.Done():
+			return nil, gerror.WrapCode(gcode.CodeOperationFailed, ctx.Err(), "Hook请求等待应答超时")
+		}
+	}
+}
+
+// isReplyFrame 是否为应答消息
+func isReplyFrame(model base_model.HookModel) bool {
+	code := model.MessageType().Code()
+	return code == base_enum.Hook.MessageType.Reply.Code() || code == base_enum.Hook.MessageType.ReplyChunk.Code()
+}
+
+// dispatchReply 将对端的应答交给等待中的请求，请求已结束时丢弃；由传输的读取协程调用，不能阻塞，
+// 缓冲区已满时丢弃应答并使请求以 ErrCallReplyOverflow 失败
+func dispatchReply(model base_model.HookModel) {
+	v := pendingCalls.Get(model.MessageId)
+	if v == nil {
+		return
+	}
+	call := v.(*pendingCall)
+
+	select {
+	case call.replies <- model:
+	case <-call.done:
+	default:
+		call.once.Do(func() {
+			close(call.overflow)
+		})
+	}
+}
+
+// hookConn 服务端的websocket连接，确认消息及应答可能由多个协程并发写入
+type hookConn struct {
+	ws *ghttp.WebSocket
+	mu sync.Mutex
+}
+
+// write 签名并发送消息
+func (c *hookConn) write(ctx context.Context, model base_model.HookModel) error {
+	if err := signHookModel(ctx, &model); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ws.WriteJSON(model)
+}
+
+// serveCall 处理请求消息：查找应答函数并异步调用，应答通过同一连接返回；重投的请求仅确认不重复处理
+func serveCall(request base_model.HookModel, conn replyWriter) error {
+	v := callHandlerMap.Get(request.BusinessTypeStr)
+	if v == nil {
+		return gerror.WrapCode(gcode.CodeNotFound, ErrCallNoHandler, request.BusinessTypeStr)
+	}
+	handler := v.(CallHandler)
+
+	ttl := time.Minute
+	if request.Deadline > 0 {
+		ttl += max(time.Until(time.UnixMilli(request.Deadline)), 0)
+	}
+	if ok, _ := servedCalls.SetIfNotExist(request.Ctx, request.MessageId, true, ttl); !ok {
+		return nil
+	}
+
+	go func() {
+		ctx := request.Ctx
+		if request.Deadline > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(request.Deadline))
+			defer cancel()
+		}
+
+		reply := base_model.HookModel{
+			MessageId:       request.MessageId,
+			MessageTypeStr:  base_enum.Hook.MessageType.Reply.Code(),
+			BusinessTypeStr: request.BusinessTypeStr,
+		}
+
+		data, err := invokeCallHandler(ctx, handler, request, &replyStream{ctx: ctx, conn: conn, request: request})
+		if err != nil {
+			setReplyError(&reply, err)
+		} else {
+			reply.Data = data
+		}
+
+		if err = conn.write(ctx, reply); err != nil {
+			g.Log().Error(ctx, gerror.Wrap(err, "Hook应答发送失败"))
+		}
+	}()
+
+	return nil
+}
+
+// invokeCallHandler 调用应答函数，并将panic及超时转换为错误
+func invokeCallHandler(ctx context.Context, handler CallHandler, request base_model.HookModel, stream ReplyStream) (reply interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			g.Log().Errorf(ctx, "Hook应答函数执行异常：%v\n%s", r, debug.Stack())
+			err = gerror.NewCodef(gcode.CodeInternalPanic, "Hook应答函数执行异常：%v", r)
+		}
+	}()
+
+	reply, err = handler(ctx, request, stream)
+	if ctx.Err() != nil && (err == nil || gerror.Code(err) == gcode.CodeNil) {
+		err = gerror.WrapCode(gcode.CodeOperationFailed, ctx.Err(), "Hook应答函数执行超时")
+	}
+
+	return reply, err
+}
+
+// replyStream 通过请求所在的连接发送部分应答
+type replyStream struct {
+	ctx     context.Context
+	conn    replyWriter
+	request base_model.HookModel
+}
+
+func (s *replyStream) Send(data interface{}) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	err := s.conn.write(s.ctx, base_model.HookModel{
+		MessageId:       s.request.MessageId,
+		MessageTypeStr:  base_enum.Hook.MessageType.ReplyChunk.Code(),
+		BusinessTypeStr: s.request.BusinessTypeStr,
+		Data:            data,
+	})
+	if err != nil {
+		return gerror.Wrap(ErrCallConnClosed, err.Error())
+	}
+
+	return nil
+}
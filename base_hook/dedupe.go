@@ -0,0 +1,273 @@
+package base_hook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	Hook消息的幂等消费：
+		1、每条消息携带唯一的消息ID，业务层可通过 Option.IdempotencyKey 指定幂等Key，重复发布同一业务操作时使用相同的Key
+		2、网关在调用订阅者之前向去重存储认领消息，认领写入是原子的，同一消息只有一个节点认领成功；
+		   已处理成功的消息直接确认，正在其他节点处理的消息返回 ErrDedupeProcessing，由发送方稍后重投
+		3、订阅者全部处理成功后将认领标记为处理成功，处理失败时释放认领，重投的消息将重新处理
+		4、认领在租约时长后失效，处理节点异常退出后其他节点可重新认领；同一节点并发重投时，后到的消息等待正在进行的处理完成并返回其结果
+		5、广播（fanout）的消息每个节点都应处理一次，去重Key追加本节点ID（service.hook.security.nodeId），仅在同一节点内去重
+		6、存储方式：内存LRU（MemoryDedupeStore）或数据库表（DbDedupeStore），处理成功的记录在有效期后失效
+*/
+
+// ErrDedupeProcessing 消息已被其他节点认领且正在处理
+var ErrDedupeProcessing = gerror.NewCode(gcode.CodeOperationFailed, "Hook消息正在其他节点处理")
+
+// DedupeStore 去重存储，记录正在处理及已处理成功的消息
+type DedupeStore interface {
+	// Claim 原子地认领消息，返回是否认领成功；消息已处理成功时返回false，正在处理时返回 ErrDedupeProcessing
+	Claim(ctx context.Context, key string, model base_model.HookModel) (bool, error)
+	// Record 将认领标记为处理成功
+	Record(ctx context.Context, key string, model base_model.HookModel) error
+	// Release 释放尚未处理成功的认领
+	Release(ctx context.Context, key string) error
+}
+
+// DedupeOption 去重配置
+type DedupeOption struct {
+	Enabled       bool          // 是否启用
+	Driver        string        // 存储方式：memory、db
+	Capacity      int           // memory 最多保留的记录数，超出时淘汰最久未使用的记录
+	Ttl           time.Duration // 记录的有效期，应大于发送方重投的最长时间
+	LeaseTimeout  time.Duration // 认领的租约时长，需大于订阅者的处理耗时，到期未处理成功的消息可被重新认领
+	Table         string        // db 去重表名
+	PurgeInterval time.Duration // db 清理过期记录的间隔
+}
+
+// DefaultDedupeOption 从配置 service.hook.dedupe 读取去重配置，未配置的项使用默认值
+func DefaultDedupeOption(ctx context.Context) DedupeOption {
+	return DedupeOption{
+		Enabled:       g.Cfg().MustGet(ctx, "service.hook.dedupe.enabled", false).Bool(),
+		Driver:        g.Cfg().MustGet(ctx, "service.hook.dedupe.driver", "memory").String(),
+		Capacity:      g.Cfg().MustGet(ctx, "service.hook.dedupe.capacity", 100000).Int(),
+		Ttl:           g.Cfg().MustGet(ctx, "service.hook.dedupe.ttl", "24h").Duration(),
+		LeaseTimeout:  g.Cfg().MustGet(ctx, "service.hook.dedupe.leaseTimeout", "1m").Duration(),
+		Table:         g.Cfg().MustGet(ctx, "service.hook.dedupe.table", "hook_dedupe").String(),
+		PurgeInterval: g.Cfg().MustGet(ctx, "service.hook.dedupe.purgeInterval", "10m").Duration(),
+	}
+}
+
+var (
+	dedupeStoreMu     sync.RWMutex
+	dedupeStore       DedupeStore
+	dedupeStoreInited bool
+
+	// dedupeInflight 正在处理的消息，key为去重Key，value为 *dedupeCall
+	dedupeInflight sync.Map
+)
+
+// dedupeCall 正在进行的处理
+type dedupeCall struct {
+	done chan struct{}
+	err  error
+}
+
+// SetDedupeStore 设置去重存储，设置为nil时关闭去重
+func SetDedupeStore(store DedupeStore) {
+	dedupeStoreMu.Lock()
+	defer dedupeStoreMu.Unlock()
+
+	dedupeStore = store
+	dedupeStoreInited = true
+}
+
+// GetDedupeStore 获取去重存储，未设置时按配置 service.hook.dedupe 创建，未启用时返回nil
+func GetDedupeStore(ctx context.Context) (DedupeStore, error) {
+	dedupeStoreMu.RLock()
+	store, inited := dedupeStore, dedupeStoreInited
+	dedupeStoreMu.RUnlock()
+
+	if inited {
+		return store, nil
+	}
+
+	dedupeStoreMu.Lock()
+	defer dedupeStoreMu.Unlock()
+
+	if dedupeStoreInited {
+		return dedupeStore, nil
+	}
+
+	conf := DefaultDedupeOption(ctx)
+	if conf.Enabled {
+		switch conf.Driver {
+		case "memory":
+			dedupeStore = NewMemoryDedupeStore(conf)
+		case "db":
+			store := NewDbDedupeStore(g.DB(), conf)
+			store.Start(context.WithoutCancel(ctx))
+			dedupeStore = store
+		default:
+			return nil, gerror.Newf("不支持的去重存储方式：%s", conf.Driver)
+		}
+	}
+	dedupeStoreInited = true
+
+	return dedupeStore, nil
+}
+
+// DedupeKey 消息的去重Key：优先使用幂等Key，未指定时使用消息ID，均为空时返回空字符串，表示不去重
+func DedupeKey(model base_model.HookModel) string {
+	key := model.IdempotencyKey
+	if key == "" {
+		key = model.MessageId
+	}
+	if key == "" {
+		return ""
+	}
+	return model.BusinessTypeStr + ":" + key
+}
+
+// nodeDedupeKey 本节点使用的去重Key，广播的消息追加本节点ID，多个节点共用去重存储时各自处理一次
+func nodeDedupeKey(ctx context.Context, model base_model.HookModel) (string, error) {
+	key := DedupeKey(model)
+	if key == "" || model.DeliveryMode().Code() != base_enum.Hook.DeliveryMode.Fanout.Code() {
+		return key, nil
+	}
+
+	security, err := getSecurityOption(ctx)
+	if err != nil {
+		return "", err
+	}
+	return key + "@" + security.NodeId, nil
+}
+
+// dedupeMessage 向去重存储认领消息后调用 handler，已处理成功的消息直接返回；handler 成功后标记处理成功，失败时释放认领
+func dedupeMessage(model base_model.HookModel, handler func(model base_model.HookModel) error) (err error) {
+	ctx := model.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	key, err := nodeDedupeKey(ctx, model)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return handler(model)
+	}
+
+	store, err := GetDedupeStore(ctx)
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return handler(model)
+	}
+
+	// 同一消息并发重投时，等待正在进行的处理完成并返回其结果
+	call := &dedupeCall{done: make(chan struct{})}
+	if v, loaded := dedupeInflight.LoadOrStore(key, call); loaded {
+		running := v.(*dedupeCall)
+		select {
+		case <-running.done:
+			return running.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	defer func() {
+		call.err = err
+		dedupeInflight.Delete(key)
+		close(call.done)
+	}()
+
+	claimed, err := store.Claim(ctx, key, model)
+	if err != nil {
+		return gerror.Wrapf(err, "Hook消息去重认领失败：%s", key)
+	}
+	if !claimed {
+		g.Log().Debugf(ctx, "Hook消息已处理，忽略重复投递：%s", key)
+		return nil
+	}
+
+	if err = handler(model); err != nil {
+		if releaseErr := store.Release(ctx, key); releaseErr != nil {
+			g.Log().Warning(ctx, gerror.Wrapf(releaseErr, "Hook消息去重认领释放失败：%s", key))
+		}
+		return err
+	}
+
+	// 订阅者已执行成功，记录失败时仅记录日志，认领在租约到期前仍阻止其他节点重复执行
+	if recordErr := store.Record(ctx, key, model); recordErr != nil {
+		g.Log().Warning(ctx, gerror.Wrapf(recordErr, "Hook消息去重记录写入失败：%s", key))
+	}
+
+	return nil
+}
+
+// MemoryDedupeStore 内存存储的去重记录，仅对当前进程有效，超出容量时淘汰最久未使用的记录
+type MemoryDedupeStore struct {
+	cache *gcache.Cache
+	ttl   time.Duration
+	lease time.Duration
+}
+
+// dedupeClaimed 内存存储中正在处理的认领
+type dedupeClaimed struct{}
+
+// NewMemoryDedupeStore 创建内存存储的去重记录
+func NewMemoryDedupeStore(option DedupeOption) *MemoryDedupeStore {
+	var cache *gcache.Cache
+	if option.Capacity > 0 {
+		cache = gcache.New(option.Capacity)
+	} else {
+		cache = gcache.New()
+	}
+
+	return &MemoryDedupeStore{
+		cache: cache,
+		ttl:   option.Ttl,
+		lease: option.LeaseTimeout,
+	}
+}
+
+// Claim 认领消息，消息已处理成功时返回false，正在处理时返回 ErrDedupeProcessing
+func (s *MemoryDedupeStore) Claim(ctx context.Context, key string, model base_model.HookModel) (bool, error) {
+	ok, err := s.cache.SetIfNotExist(ctx, key, dedupeClaimed{}, s.lease)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	v, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if _, processing := v.Val().(dedupeClaimed); processing {
+		return false, ErrDedupeProcessing
+	}
+
+	return false, nil
+}
+
+// Record 将认领标记为处理成功
+func (s *MemoryDedupeStore) Record(ctx context.Context, key string, model base_model.HookModel) error {
+	return s.cache.Set(ctx, key, model.MessageId, s.ttl)
+}
+
+// Release 释放尚未处理成功的认领
+func (s *MemoryDedupeStore) Release(ctx context.Context, key string) error {
+	v, err := s.cache.Get(ctx, key)
+	if err != nil || v.IsNil() {
+		return err
+	}
+	if _, processing := v.Val().(dedupeClaimed); processing {
+		_, err = s.cache.Remove(ctx, key)
+	}
+
+	return err
+}
@@ -0,0 +1,167 @@
+package base_hook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/utility/daoctl"
+)
+
+// hookDedupeColumns 去重表字段
+var hookDedupeColumns = struct {
+	DedupeKey    string
+	BusinessType string
+	MessageId    string
+	ProcessedAt  string
+	ExpireAt     string
+}{
+	DedupeKey:    "dedupe_key",
+	BusinessType: "business_type",
+	MessageId:    "message_id",
+	ProcessedAt:  "processed_at",
+	ExpireAt:     "expire_at",
+}
+
+// DbDedupeStore 数据库存储的去重记录，多个节点共享，建表语句见 manifest/sql/hook_dedupe.sql
+type DbDedupeStore struct {
+	db     gdb.DB
+	option DedupeOption
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewDbDedupeStore 创建数据库存储的去重记录
+func NewDbDedupeStore(db gdb.DB, option DedupeOption) *DbDedupeStore {
+	return &DbDedupeStore{
+		db:     db,
+		option: option,
+	}
+}
+
+// Claim 认领消息：删除该Key过期的记录后写入未处理的认领记录，写入成功即认领成功；
+// 主键冲突导致写入失败时按已有的记录判断，已处理成功时返回false，正在处理时返回 ErrDedupeProcessing
+func (s *DbDedupeStore) Claim(ctx context.Context, key string, model base_model.HookModel) (bool, error) {
+	cols := hookDedupeColumns
+	now := gtime.Now()
+	_, err := daoctl.DeleteWithError(s.db.Model(s.option.Table).Ctx(ctx).Where(cols.DedupeKey, key).WhereLTE(cols.ExpireAt, now))
+	if err != nil {
+		return false, err
+	}
+
+	_, insertErr := daoctl.InsertWithError(s.db.Model(s.option.Table).Ctx(ctx), g.Map{
+		cols.DedupeKey:    key,
+		cols.BusinessType: model.BusinessTypeStr,
+		cols.MessageId:    model.MessageId,
+		cols.ExpireAt:     now.Add(s.option.LeaseTimeout),
+	})
+	if insertErr == nil {
+		return true, nil
+	}
+
+	record, err := daoctl.ScanWithError[base_model.HookDedupe](s.db.Model(s.option.Table).Ctx(ctx).Where(cols.DedupeKey, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, insertErr
+	}
+	if err != nil {
+		return false, err
+	}
+	if record.ProcessedAt == nil {
+		return false, ErrDedupeProcessing
+	}
+
+	return false, nil
+}
+
+// Record 将认领标记为处理成功并更新有效期，认领记录已失效被清理时重新写入
+func (s *DbDedupeStore) Record(ctx context.Context, key string, model base_model.HookModel) error {
+	cols := hookDedupeColumns
+	now := gtime.Now()
+	data := g.Map{
+		cols.MessageId:   model.MessageId,
+		cols.ProcessedAt: now,
+		cols.ExpireAt:    now.Add(s.option.Ttl),
+	}
+	rows, err := daoctl.UpdateWithError(s.db.Model(s.option.Table).Ctx(ctx).Where(cols.DedupeKey, key).Data(data))
+	if err != nil || rows > 0 {
+		return err
+	}
+
+	data[cols.DedupeKey] = key
+	data[cols.BusinessType] = model.BusinessTypeStr
+	_, err = daoctl.InsertWithError(s.db.Model(s.option.Table).Ctx(ctx), data)
+
+	return err
+}
+
+// Release 删除尚未处理成功的认领记录
+func (s *DbDedupeStore) Release(ctx context.Context, key string) error {
+	cols := hookDedupeColumns
+	_, err := daoctl.DeleteWithError(s.db.Model(s.option.Table).Ctx(ctx).Where(cols.DedupeKey, key).WhereNull(cols.ProcessedAt))
+
+	return err
+}
+
+// Purge 删除过期的记录，返回删除的记录数
+func (s *DbDedupeStore) Purge(ctx context.Context) (int64, error) {
+	rows, err := daoctl.DeleteWithError(s.db.Model(s.option.Table).Ctx(ctx).WhereLTE(hookDedupeColumns.ExpireAt, gtime.Now()))
+	if err != nil {
+		return 0, gerror.Wrap(err, "Hook去重记录清理失败")
+	}
+
+	return rows, nil
+}
+
+// Start 启动定时清理过期记录的协程，重复调用无副作用
+func (s *DbDedupeStore) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil || s.option.PurgeInterval <= 0 {
+		return
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.option.PurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Purge(ctx); err != nil {
+					g.Log().Error(ctx, err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止定时清理的协程
+func (s *DbDedupeStore) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+}
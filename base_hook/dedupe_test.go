@@ -0,0 +1,150 @@
+package base_hook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kysion/base-library/base_model"
+)
+
+// useDedupeStore 测试期间使用指定的去重存储，结束后恢复
+func useDedupeStore(t *testing.T, store DedupeStore) {
+	dedupeStoreMu.RLock()
+	prevStore, prevInited := dedupeStore, dedupeStoreInited
+	dedupeStoreMu.RUnlock()
+
+	SetDedupeStore(store)
+	t.Cleanup(func() {
+		dedupeStoreMu.Lock()
+		dedupeStore, dedupeStoreInited = prevStore, prevInited
+		dedupeStoreMu.Unlock()
+	})
+}
+
+// useNodeId 以指定的节点ID运行 f，模拟多个节点共用去重存储
+func useNodeId(t *testing.T, nodeId string, f func()) {
+	securityMu.Lock()
+	prev := securityOption
+	securityOption = &SecurityOption{NodeId: nodeId}
+	securityMu.Unlock()
+
+	defer func() {
+		securityMu.Lock()
+		securityOption = prev
+		securityMu.Unlock()
+	}()
+
+	f()
+}
+
+func newTestDedupeStore() *MemoryDedupeStore {
+	return NewMemoryDedupeStore(DedupeOption{Capacity: 100, Ttl: time.Minute, LeaseTimeout: time.Minute})
+}
+
+func TestDedupeMessage_SharedStoreAcrossNodes(t *testing.T) {
+	cases := []struct {
+		name         string
+		deliveryMode string
+		want         map[string]int
+	}{
+		{name: "failover", deliveryMode: "", want: map[string]int{"node-a": 1, "node-b": 0}},
+		{name: "roundRobin", deliveryMode: "roundRobin", want: map[string]int{"node-a": 1, "node-b": 0}},
+		{name: "fanout", deliveryMode: "fanout", want: map[string]int{"node-a": 1, "node-b": 1}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useDedupeStore(t, newTestDedupeStore())
+
+			model := base_model.HookModel{
+				Ctx:             context.Background(),
+				MessageId:       "msg-" + c.name,
+				BusinessTypeStr: "order.paid",
+				DeliveryModeStr: c.deliveryMode,
+			}
+
+			got := map[string]int{}
+			for _, nodeId := range []string{"node-a", "node-b"} {
+				useNodeId(t, nodeId, func() {
+					// 每个节点收到两次，模拟发送方的重投
+					for i := 0; i < 2; i++ {
+						err := dedupeMessage(model, func(model base_model.HookModel) error {
+							got[nodeId]++
+							return nil
+						})
+						if err != nil {
+							t.Fatalf("dedupeMessage on %s: %v", nodeId, err)
+						}
+					}
+				})
+			}
+
+			for nodeId, want := range c.want {
+				if got[nodeId] != want {
+					t.Errorf("%s handled %d times, want %d", nodeId, got[nodeId], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDedupeMessage_ReleaseOnFailure(t *testing.T) {
+	useDedupeStore(t, newTestDedupeStore())
+
+	model := base_model.HookModel{
+		Ctx:             context.Background(),
+		MessageId:       "msg-fail",
+		BusinessTypeStr: "order.paid",
+	}
+	failed := errors.New("handler failed")
+
+	calls := 0
+	handler := func(model base_model.HookModel) error {
+		calls++
+		if calls == 1 {
+			return failed
+		}
+		return nil
+	}
+
+	if err := dedupeMessage(model, handler); !errors.Is(err, failed) {
+		t.Fatalf("first delivery err = %v, want %v", err, failed)
+	}
+	if err := dedupeMessage(model, handler); err != nil {
+		t.Fatalf("redelivery err = %v", err)
+	}
+	if err := dedupeMessage(model, handler); err != nil {
+		t.Fatalf("duplicate err = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
+
+func TestMemoryDedupeStore_ClaimProcessing(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDedupeStore()
+	model := base_model.HookModel{MessageId: "msg-1"}
+
+	if ok, err := store.Claim(ctx, "k", model); !ok || err != nil {
+		t.Fatalf("first Claim = %v, %v", ok, err)
+	}
+	if _, err := store.Claim(ctx, "k", model); !errors.Is(err, ErrDedupeProcessing) {
+		t.Fatalf("Claim while processing err = %v, want ErrDedupeProcessing", err)
+	}
+	if err := store.Record(ctx, "k", model); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.Claim(ctx, "k", model); ok || err != nil {
+		t.Fatalf("Claim after Record = %v, %v, want false, nil", ok, err)
+	}
+	// 已处理成功的记录不会被释放
+	if err := store.Release(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := store.Claim(ctx, "k", model); ok {
+		t.Fatal("Release removed a processed record")
+	}
+}
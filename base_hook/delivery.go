@@ -0,0 +1,151 @@
+package base_hook
+
+import (
+	"context"
+	"errors"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/container/gtype"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	跨进程Hook的投递方式：
+		failover   依次尝试，投递给第一个可达的服务（默认）
+		fanout     并发投递给所有服务，适用于缓存失效等需要所有节点处理的事件
+		roundRobin 轮询选择一个服务，不可达时依次尝试下一个，适用于任务分发
+		hash       按 DeliveryKey 一致性哈希选择一个服务，相同Key始终投递给同一个服务
+*/
+
+// hashRingReplicas 一致性哈希环上每个服务的虚拟节点数
+const hashRingReplicas = 100
+
+var (
+	roundRobinCounter = gtype.NewUint64()
+	currentHashRing   = gtype.NewInterface() // 最近使用的一致性哈希环，服务地址列表变化时重新构建，value为 *hashRing
+)
+
+// deliverByMode 按消息的投递方式将消息投递给服务List
+func deliverByMode(ctx context.Context, t ITransport, hosts []string, data base_model.HookModel) error {
+	switch data.DeliveryMode().Code() {
+	case base_enum.Hook.DeliveryMode.Fanout.Code():
+		return deliverFanout(ctx, t, hosts, data)
+
+	case base_enum.Hook.DeliveryMode.RoundRobin.Code():
+		start := int((roundRobinCounter.Add(1) - 1) % uint64(len(hosts)))
+		ordered := make([]string, 0, len(hosts))
+		ordered = append(ordered, hosts[start:]...)
+		ordered = append(ordered, hosts[:start]...)
+		return deliverFailover(ctx, t, ordered, data)
+
+	case base_enum.Hook.DeliveryMode.Hash.Code():
+		if data.DeliveryKey == "" {
+			return gerror.New("hash投递方式必须指定 DeliveryKey")
+		}
+		return deliverFailover(ctx, t, getHashRing(hosts).lookup(data.DeliveryKey), data)
+
+	default:
+		return deliverFailover(ctx, t, hosts, data)
+	}
+}
+
+// deliverFailover 依次尝试投递，直到一个服务成功
+func deliverFailover(ctx context.Context, t ITransport, hosts []string, data base_model.HookModel) error {
+	var lastErr error
+
+	for _, host := range hosts {
+		err := t.Send(ctx, host, data)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+
+	return gerror.Wrap(lastErr, "Hook消息投递失败，所有服务均不可达")
+}
+
+// deliverFanout 并发投递给所有服务，返回投递失败的服务的错误
+func deliverFanout(ctx context.Context, t ITransport, hosts []string, data base_model.HookModel) error {
+	errArr := make([]error, len(hosts))
+	wg := sync.WaitGroup{}
+
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			if err := t.Send(ctx, host, data); err != nil {
+				errArr[i] = gerror.Wrapf(err, "Hook消息投递到 %s 失败", host)
+			}
+		}(i, host)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errArr...)
+}
+
+// hashRing 一致性哈希环
+type hashRing struct {
+	key    string // 服务地址列表
+	hashes []uint32
+	nodes  map[uint32]string
+	size   int
+}
+
+// getHashRing 获取服务地址列表对应的一致性哈希环，仅缓存最近使用的哈希环，服务上下线后旧的哈希环随之释放
+func getHashRing(hosts []string) *hashRing {
+	key := strings.Join(hosts, ",")
+	if ring, ok := currentHashRing.Val().(*hashRing); ok && ring.key == key {
+		return ring
+	}
+
+	ring := &hashRing{
+		key:   key,
+		nodes: make(map[uint32]string, len(hosts)*hashRingReplicas),
+		size:  len(hosts),
+	}
+	for _, host := range hosts {
+		for i := 0; i < hashRingReplicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + "#" + host))
+			ring.hashes = append(ring.hashes, hash)
+			ring.nodes[hash] = host
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool {
+		return ring.hashes[i] < ring.hashes[j]
+	})
+
+	currentHashRing.Set(ring)
+	return ring
+}
+
+// lookup 返回Key对应的服务，及沿哈希环顺时针方向的其他服务，用于故障转移
+func (r *hashRing) lookup(key string) []string {
+	result := make([]string, 0, r.size)
+	if len(r.hashes) == 0 {
+		return result
+	}
+
+	hash := crc32.ChecksumIEEE([]byte(key))
+	start := sort.Search(len(r.hashes), func(i int) bool {
+		return r.hashes[i] >= hash
+	})
+
+	seen := make(map[string]struct{}, r.size)
+	for i := 0; i < len(r.hashes) && len(result) < r.size; i++ {
+		host := r.nodes[r.hashes[(start+i)%len(r.hashes)]]
+		if _, ok := seen[host]; ok {
+			continue
+		}
+		seen[host] = struct{}{}
+		result = append(result, host)
+	}
+
+	return result
+}
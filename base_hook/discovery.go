@@ -0,0 +1,674 @@
+package base_hook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gfsnotify"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/utility/daoctl"
+)
+
+/*
+	跨进程Hook的服务发现：
+		1、static 配置 service.hostAddressArr 及 service.hook.discovery.static 中的地址
+		2、file   服务地址列表文件，每行一个地址或JSON数组，文件修改后立即刷新
+		3、dns    SRV记录（srv:_hook._tcp.example.com）或A/AAAA记录（a:hook.example.com:7778）展开为服务地址
+		4、db     各节点定时写入带过期时间的心跳记录，读取未过期的记录，建表语句见 manifest/sql/hook_peer.sql
+		5、自动排除本节点的地址，服务加入时建立websocket连接，离开时断开 wsArr 中的连接
+		6、某个来源查询失败时沿用该来源上一次的结果，避免短暂故障导致服务被移除
+*/
+
+// IPeerSource 服务地址来源
+type IPeerSource interface {
+	// Name 来源名称，用于日志
+	Name() string
+	// Peers 查询服务地址列表
+	Peers(ctx context.Context) ([]string, error)
+}
+
+// IPeerHeartbeat 需要登记本节点的服务地址来源
+type IPeerHeartbeat interface {
+	// Heartbeat 登记本节点并刷新过期时间
+	Heartbeat(ctx context.Context) error
+	// Leave 注销本节点
+	Leave(ctx context.Context) error
+}
+
+// defaultDiscoveryInterval 未配置刷新间隔时使用的默认值
+const defaultDiscoveryInterval = 10 * time.Second
+
+// DiscoveryOption 服务发现配置
+type DiscoveryOption struct {
+	Enabled   bool          // 是否启用，未启用时使用 service.hostAddressArr
+	Interval  time.Duration // 刷新及心跳间隔
+	Advertise string        // 本节点对外的服务地址，默认为 server.address
+	Static    []string      // 额外的静态服务地址
+	File      string        // 服务地址列表文件
+	Dns       []string      // DNS记录
+	Db        DbPeerOption  // 数据库登记
+}
+
+// DbPeerOption 数据库登记配置
+type DbPeerOption struct {
+	Enabled bool          // 是否启用
+	Table   string        // 服务注册表名
+	Ttl     time.Duration // 心跳记录的有效期，应大于刷新间隔
+}
+
+// DefaultDiscoveryOption 从配置 service.hook.discovery 读取服务发现配置，未配置的项使用默认值
+func DefaultDiscoveryOption(ctx context.Context) DiscoveryOption {
+	prefix := "service.hook.discovery."
+	return DiscoveryOption{
+		Enabled:   g.Cfg().MustGet(ctx, prefix+"enabled", false).Bool(),
+		Interval:  g.Cfg().MustGet(ctx, prefix+"interval", "10s").Duration(),
+		Advertise: g.Cfg().MustGet(ctx, prefix+"advertise", g.Cfg().MustGet(ctx, "server.address").String()).String(),
+		Static:    g.Cfg().MustGet(ctx, prefix+"static").Strings(),
+		File:      g.Cfg().MustGet(ctx, prefix+"file").String(),
+		Dns:       g.Cfg().MustGet(ctx, prefix+"dns").Strings(),
+		Db: DbPeerOption{
+			Enabled: g.Cfg().MustGet(ctx, prefix+"db.enabled", false).Bool(),
+			Table:   g.Cfg().MustGet(ctx, prefix+"db.table", "hook_peer").String(),
+			Ttl:     g.Cfg().MustGet(ctx, prefix+"db.ttl", "30s").Duration(),
+		},
+	}
+}
+
+// NewPeerSources 按配置创建服务地址来源
+func NewPeerSources(ctx context.Context, option DiscoveryOption) []IPeerSource {
+	static := append(g.Cfg().MustGet(ctx, "service.hostAddressArr").Strings(), option.Static...)
+	sources := []IPeerSource{NewStaticPeerSource(static...)}
+
+	if option.File != "" {
+		sources = append(sources, NewFilePeerSource(option.File))
+	}
+	if len(option.Dns) > 0 {
+		sources = append(sources, NewDnsPeerSource(option.Dns...))
+	}
+	if option.Db.Enabled {
+		sources = append(sources, NewDbPeerSource(g.DB(), option.Db, option.Advertise))
+	}
+
+	return sources
+}
+
+// IDiscovery 服务发现
+type IDiscovery interface {
+	// Start 启动服务发现，未指定来源时按配置创建，首次刷新失败时返回错误
+	Start(ctx context.Context, sources ...IPeerSource) error
+	// Stop 停止服务发现，并注销本节点
+	Stop(ctx context.Context) error
+	// Refresh 立即从所有来源刷新服务地址
+	Refresh(ctx context.Context) error
+	// Peers 当前的服务地址，已排除本节点
+	Peers() []string
+	// OnChange 注册服务加入及离开的回调
+	OnChange(f func(joined, left []string))
+}
+
+// sDiscovery 服务发现实现
+type sDiscovery struct {
+	mu        sync.RWMutex
+	option    DiscoveryOption
+	running   bool
+	sources   []IPeerSource
+	lastGood  map[string][]string // 各来源上一次查询成功的结果，key为来源名称
+	peers     []string
+	listeners []func(joined, left []string)
+	watchers  []int // 文件监听的回调ID
+	refresh   chan struct{}
+	cancel    context.CancelFunc
+	stopped   chan struct{}
+	autoStart sync.Once
+}
+
+var discovery = sDiscovery{}
+
+// Discovery 获取服务发现
+func Discovery() IDiscovery {
+	return &discovery
+}
+
+func init() {
+	// 服务加入时建立websocket连接，离开时断开所有传输方式与该服务的连接
+	discovery.OnChange(func(joined, left []string) {
+		ctx := context.Background()
+		if len(joined) > 0 {
+			_ = Transport().Connect(ctx, joined...)
+		}
+		for _, addr := range left {
+			DisconnectTransports(ctx, addr)
+		}
+	})
+}
+
+// Start 启动服务发现，未指定来源时按配置创建，首次刷新失败时返回错误
+func (s *sDiscovery) Start(ctx context.Context, sources ...IPeerSource) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.option = DefaultDiscoveryOption(ctx)
+	if s.option.Interval <= 0 {
+		s.option.Interval = defaultDiscoveryInterval
+	}
+	if len(sources) == 0 {
+		sources = NewPeerSources(ctx, s.option)
+	}
+	s.sources = sources
+	s.lastGood = make(map[string][]string)
+	s.refresh = make(chan struct{}, 1)
+	s.stopped = make(chan struct{})
+	s.running = true
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.cancel = cancel
+
+	// 服务地址列表文件修改后立即刷新
+	for _, source := range sources {
+		if file, ok := source.(*FilePeerSource); ok {
+			if callback, err := gfsnotify.Add(file.path, func(event *gfsnotify.Event) {
+				s.trigger()
+			}); err != nil {
+				g.Log().Warning(ctx, gerror.Wrapf(err, "监听服务地址列表文件失败：%s", file.path))
+			} else {
+				s.watchers = append(s.watchers, callback.Id)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	s.heartbeat(ctx)
+	err := s.Refresh(ctx)
+
+	go s.run(runCtx)
+
+	return err
+}
+
+// Stop 停止服务发现，并注销本节点
+func (s *sDiscovery) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.running = false
+	s.cancel()
+	for _, id := range s.watchers {
+		_ = gfsnotify.RemoveCallback(id)
+	}
+	s.watchers = nil
+	sources := s.sources
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	var errArr []error
+	for _, source := range sources {
+		if hb, ok := source.(IPeerHeartbeat); ok {
+			if err := hb.Leave(ctx); err != nil {
+				errArr = append(errArr, err)
+			}
+		}
+	}
+
+	return errors.Join(errArr...)
+}
+
+// OnChange 注册服务加入及离开的回调
+func (s *sDiscovery) OnChange(f func(joined, left []string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, f)
+}
+
+// Peers 当前的服务地址，已排除本节点
+func (s *sDiscovery) Peers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]string(nil), s.peers...)
+}
+
+// isRunning 服务发现是否已启动
+func (s *sDiscovery) isRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.running
+}
+
+// trigger 通知刷新协程立即刷新
+func (s *sDiscovery) trigger() {
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// run 刷新协程：定时发送心跳及刷新服务地址
+func (s *sDiscovery) run(ctx context.Context) {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.option.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.heartbeat(ctx)
+		case <-s.refresh:
+		}
+
+		if err := s.Refresh(ctx); err != nil {
+			g.Log().Warning(ctx, err)
+		}
+	}
+}
+
+// heartbeat 登记本节点
+func (s *sDiscovery) heartbeat(ctx context.Context) {
+	s.mu.RLock()
+	sources := s.sources
+	s.mu.RUnlock()
+
+	for _, source := range sources {
+		if hb, ok := source.(IPeerHeartbeat); ok {
+			if err := hb.Heartbeat(ctx); err != nil {
+				g.Log().Warning(ctx, gerror.Wrapf(err, "Hook服务登记失败：%s", source.Name()))
+			}
+		}
+	}
+}
+
+// Refresh 立即从所有来源刷新服务地址，查询失败的来源沿用上一次的结果，所有来源均失败时返回错误
+func (s *sDiscovery) Refresh(ctx context.Context) error {
+	s.mu.RLock()
+	sources := s.sources
+	s.mu.RUnlock()
+
+	var errArr []error
+	result := make(map[string][]string, len(sources))
+	for _, source := range sources {
+		peers, err := source.Peers(ctx)
+		if err != nil {
+			errArr = append(errArr, gerror.Wrapf(err, "Hook服务发现查询失败：%s", source.Name()))
+			continue
+		}
+		result[source.Name()] = peers
+	}
+
+	s.mu.Lock()
+	for name, peers := range result {
+		s.lastGood[name] = peers
+	}
+	all := make([]string, 0)
+	for _, peers := range s.lastGood {
+		all = append(all, peers...)
+	}
+	peers := excludeLocalPeers(ctx, all)
+	joined, left := diffPeers(s.peers, peers)
+	s.peers = peers
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	if len(joined) > 0 || len(left) > 0 {
+		g.Log().Infof(ctx, "Hook服务变更，加入：%v，离开：%v", joined, left)
+		for _, f := range listeners {
+			f(joined, left)
+		}
+	}
+
+	if len(result) == 0 && len(errArr) > 0 {
+		return errors.Join(errArr...)
+	}
+	for _, err := range errArr {
+		g.Log().Warning(ctx, err)
+	}
+
+	return nil
+}
+
+// diffPeers 比较新旧服务地址，返回加入及离开的地址
+func diffPeers(oldPeers, newPeers []string) (joined, left []string) {
+	oldSet := make(map[string]struct{}, len(oldPeers))
+	for _, addr := range oldPeers {
+		oldSet[addr] = struct{}{}
+	}
+	newSet := make(map[string]struct{}, len(newPeers))
+	for _, addr := range newPeers {
+		newSet[addr] = struct{}{}
+		if _, ok := oldSet[addr]; !ok {
+			joined = append(joined, addr)
+		}
+	}
+	for _, addr := range oldPeers {
+		if _, ok := newSet[addr]; !ok {
+			left = append(left, addr)
+		}
+	}
+	return joined, left
+}
+
+// peerAddresses 未配置传输方式专用地址时使用的服务地址：启用服务发现时为发现的地址，否则为 service.hostAddressArr
+func peerAddresses(ctx context.Context) []string {
+	// 配置启用服务发现时，首次使用时自动启动
+	if g.Cfg().MustGet(ctx, "service.hook.discovery.enabled", false).Bool() {
+		discovery.autoStart.Do(func() {
+			if err := discovery.Start(ctx); err != nil {
+				g.Log().Warning(ctx, err)
+			}
+		})
+	}
+
+	if discovery.isRunning() {
+		return discovery.Peers()
+	}
+	return excludeLocalPeers(ctx, g.Cfg().MustGet(ctx, "service.hostAddressArr").Strings())
+}
+
+// excludeLocalPeers 去重、排序并排除本节点的地址
+func excludeLocalPeers(ctx context.Context, addrs []string) []string {
+	local := localAddresses(ctx)
+
+	seen := make(map[string]struct{}, len(addrs))
+	result := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		addr = strings.TrimSpace(addr)
+		if addr == "" || isLocalPeer(local, addr) {
+			continue
+		}
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
+		result = append(result, addr)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// localAddresses 本节点监听及对外的地址
+func localAddresses(ctx context.Context) []string {
+	return []string{
+		g.Cfg().MustGet(ctx, "server.address").String(),
+		g.Cfg().MustGet(ctx, "service.hook.discovery.advertise").String(),
+		g.Cfg().MustGet(ctx, "service.hook.tcp.address").String(),
+	}
+}
+
+var (
+	localIpOnce sync.Once
+	localIpSet  map[string]struct{}
+)
+
+// isLocalIp 是否为本机的IP
+func isLocalIp(host string) bool {
+	localIpOnce.Do(func() {
+		localIpSet = map[string]struct{}{"": {}, "localhost": {}, "0.0.0.0": {}, "::": {}}
+		addrs, _ := net.InterfaceAddrs()
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				localIpSet[ipNet.IP.String()] = struct{}{}
+			}
+		}
+	})
+
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsUnspecified()) {
+		return true
+	}
+	_, ok := localIpSet[host]
+	return ok
+}
+
+// isLocalPeer 地址是否指向本节点：与本节点的地址相同，或端口相同且主机为本机IP
+func isLocalPeer(local []string, addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+
+	for _, item := range local {
+		if item == "" {
+			continue
+		}
+		if item == addr {
+			return true
+		}
+		if err != nil {
+			continue
+		}
+
+		localHost, localPort, localErr := net.SplitHostPort(item)
+		if localErr != nil || localPort != port {
+			continue
+		}
+		if host == localHost || (isLocalIp(host) && isLocalIp(localHost)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StaticPeerSource 静态配置的服务地址
+type StaticPeerSource struct {
+	addrs []string
+}
+
+// NewStaticPeerSource 创建静态配置的服务地址来源
+func NewStaticPeerSource(addrs ...string) *StaticPeerSource {
+	return &StaticPeerSource{addrs: addrs}
+}
+
+func (s *StaticPeerSource) Name() string {
+	return "static"
+}
+
+func (s *StaticPeerSource) Peers(ctx context.Context) ([]string, error) {
+	return s.addrs, nil
+}
+
+// FilePeerSource 服务地址列表文件，每行一个地址（# 开头为注释），或JSON数组
+type FilePeerSource struct {
+	path string
+}
+
+// NewFilePeerSource 创建服务地址列表文件来源
+func NewFilePeerSource(path string) *FilePeerSource {
+	return &FilePeerSource{path: path}
+}
+
+func (s *FilePeerSource) Name() string {
+	return "file:" + s.path
+}
+
+func (s *FilePeerSource) Peers(ctx context.Context) ([]string, error) {
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimSpace(string(content))
+	if strings.HasPrefix(text, "[") {
+		result := make([]string, 0)
+		if err = gjson.DecodeTo(text, &result); err != nil {
+			return nil, gerror.Wrap(err, "服务地址列表文件格式错误")
+		}
+		return result, nil
+	}
+
+	result := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "- "))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result, nil
+}
+
+// DnsPeerSource DNS记录展开的服务地址
+type DnsPeerSource struct {
+	records  []string
+	resolver *net.Resolver
+}
+
+// NewDnsPeerSource 创建DNS记录来源，记录格式：srv:_hook._tcp.example.com 或 a:hook.example.com:7778
+func NewDnsPeerSource(records ...string) *DnsPeerSource {
+	return &DnsPeerSource{
+		records:  records,
+		resolver: net.DefaultResolver,
+	}
+}
+
+func (s *DnsPeerSource) Name() string {
+	return "dns"
+}
+
+func (s *DnsPeerSource) Peers(ctx context.Context) ([]string, error) {
+	result := make([]string, 0)
+
+	for _, record := range s.records {
+		kind, name, ok := strings.Cut(record, ":")
+		if !ok {
+			return nil, gerror.Newf("DNS记录格式错误：%s", record)
+		}
+
+		switch strings.ToLower(kind) {
+		case "srv":
+			_, srvArr, err := s.resolver.LookupSRV(ctx, "", "", name)
+			if err != nil {
+				return nil, err
+			}
+			for _, srv := range srvArr {
+				result = append(result, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+			}
+
+		case "a":
+			host, port, err := net.SplitHostPort(name)
+			if err != nil {
+				return nil, gerror.Wrapf(err, "DNS记录格式错误：%s", record)
+			}
+			ipArr, err := s.resolver.LookupHost(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			for _, ip := range ipArr {
+				result = append(result, net.JoinHostPort(ip, port))
+			}
+
+		default:
+			return nil, gerror.Newf("不支持的DNS记录类型：%s", record)
+		}
+	}
+
+	return result, nil
+}
+
+// hookPeerColumns 服务注册表字段
+var hookPeerColumns = struct {
+	Address     string
+	NodeId      string
+	HeartbeatAt string
+	ExpireAt    string
+}{
+	Address:     "address",
+	NodeId:      "node_id",
+	HeartbeatAt: "heartbeat_at",
+	ExpireAt:    "expire_at",
+}
+
+// DbPeerSource 数据库登记的服务地址，各节点定时写入心跳记录
+type DbPeerSource struct {
+	db      gdb.DB
+	option  DbPeerOption
+	address string
+}
+
+// NewDbPeerSource 创建数据库登记来源，address 为本节点对外的服务地址
+func NewDbPeerSource(db gdb.DB, option DbPeerOption, address string) *DbPeerSource {
+	return &DbPeerSource{
+		db:      db,
+		option:  option,
+		address: address,
+	}
+}
+
+func (s *DbPeerSource) Name() string {
+	return "db:" + s.option.Table
+}
+
+// Peers 读取未过期的心跳记录
+func (s *DbPeerSource) Peers(ctx context.Context) ([]string, error) {
+	rows, err := daoctl.ScanWithError[[]base_model.HookPeer](
+		s.db.Model(s.option.Table).Ctx(ctx).WhereGT(hookPeerColumns.ExpireAt, gtime.Now()),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(*rows))
+	for _, row := range *rows {
+		result = append(result, row.Address)
+	}
+	return result, nil
+}
+
+// Heartbeat 写入本节点的心跳记录
+func (s *DbPeerSource) Heartbeat(ctx context.Context) error {
+	if s.address == "" {
+		return gerror.New("未配置本节点对外的服务地址：service.hook.discovery.advertise")
+	}
+
+	security, err := getSecurityOption(ctx)
+	if err != nil {
+		return err
+	}
+
+	cols := hookPeerColumns
+	now := gtime.Now()
+	_, err = daoctl.SaveWithError(s.db.Model(s.option.Table).Ctx(ctx).OnConflict(cols.Address), g.Map{
+		cols.Address:     s.address,
+		cols.NodeId:      security.NodeId,
+		cols.HeartbeatAt: now,
+		cols.ExpireAt:    now.Add(s.option.Ttl),
+	})
+	return err
+}
+
+// Leave 删除本节点的心跳记录
+func (s *DbPeerSource) Leave(ctx context.Context) error {
+	if s.address == "" {
+		return nil
+	}
+
+	_, err := daoctl.DeleteWithError(s.db.Model(s.option.Table).Ctx(ctx).Where(hookPeerColumns.Address, s.address))
+	return err
+}
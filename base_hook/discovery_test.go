@@ -0,0 +1,229 @@
+package base_hook
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kysion/base-library/base_model"
+)
+
+// testPeerSource 测试使用的服务地址来源，记录心跳及注销的次数
+type testPeerSource struct {
+	mu         sync.Mutex
+	peers      []string
+	err        error
+	heartbeats int
+	leaves     int
+}
+
+func (s *testPeerSource) Name() string {
+	return "test"
+}
+
+func (s *testPeerSource) Peers(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.peers), s.err
+}
+
+func (s *testPeerSource) Heartbeat(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.heartbeats++
+	return nil
+}
+
+func (s *testPeerSource) Leave(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leaves++
+	return nil
+}
+
+// set 设置下一次查询的结果
+func (s *testPeerSource) set(peers []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peers, s.err = peers, err
+}
+
+// peerEvent 一次服务变更
+type peerEvent struct {
+	joined []string
+	left   []string
+}
+
+// watchDiscovery 记录服务发现的变更，测试结束时恢复原有的回调
+func watchDiscovery(t *testing.T) <-chan peerEvent {
+	events := make(chan peerEvent, 16)
+
+	discovery.mu.Lock()
+	saved := discovery.listeners
+	discovery.listeners = slices.Clone(saved)
+	discovery.mu.Unlock()
+
+	discovery.OnChange(func(joined, left []string) {
+		slices.Sort(joined)
+		slices.Sort(left)
+		events <- peerEvent{joined: joined, left: left}
+	})
+	t.Cleanup(func() {
+		discovery.mu.Lock()
+		discovery.listeners = saved
+		discovery.mu.Unlock()
+	})
+	return events
+}
+
+// nextEvent 获取下一次服务变更
+func nextEvent(t *testing.T, events <-chan peerEvent) peerEvent {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no discovery change")
+		return peerEvent{}
+	}
+}
+
+// wsPeer 获取服务地址对应的websocket连接
+func wsPeer(addr string) *peerConn {
+	if v := wsArr.Get("ws://" + addr + "/ws"); v != nil {
+		return v.(*peerConn)
+	}
+	return nil
+}
+
+// useTransportOption 设置websocket传输的配置，测试结束时恢复
+func useTransportOption(t *testing.T, option TransportOption) {
+	transport.mu.Lock()
+	saved := transport.option
+	transport.mu.Unlock()
+
+	transport.SetOption(option)
+	t.Cleanup(func() {
+		transport.mu.Lock()
+		transport.option = saved
+		transport.mu.Unlock()
+	})
+}
+
+func TestDiscovery_JoinLeave(t *testing.T) {
+	useConfig(t, `{"server": {"address": "127.0.0.1:7999"}}`)
+	useTransportOption(t, testTransportOption())
+	ctx := context.Background()
+	events := watchDiscovery(t)
+
+	ack := func(model base_model.HookModel, attempt int) (*base_model.HookModel, bool) {
+		return ackOf(model), false
+	}
+	var addrs []string
+	for i := 0; i < 2; i++ {
+		addrs = append(addrs, strings.TrimPrefix(newWsTestServer(t, ack).URL, "http://"))
+	}
+	slices.Sort(addrs)
+
+	// 启动时登记本节点，排除本节点的地址，服务加入时建立websocket连接
+	source := &testPeerSource{peers: append([]string{"127.0.0.1:7999", addrs[0]}, addrs...)}
+	if err := discovery.Start(ctx, source); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = discovery.Stop(ctx)
+		for _, addr := range addrs {
+			DisconnectTransports(ctx, addr)
+		}
+	})
+
+	if event := nextEvent(t, events); !slices.Equal(event.joined, addrs) || len(event.left) != 0 {
+		t.Fatalf("event = %+v, want %v joined", event, addrs)
+	}
+	if peers := discovery.Peers(); !slices.Equal(peers, addrs) {
+		t.Fatalf("Peers() = %v, want %v", peers, addrs)
+	}
+	if source.heartbeats != 1 {
+		t.Fatalf("heartbeats = %d, want 1", source.heartbeats)
+	}
+	for _, addr := range addrs {
+		if p := wsPeer(addr); p == nil || p.info().State != peerStateConnected {
+			t.Fatalf("peer %s is not connected", addr)
+		}
+	}
+
+	// 查询失败时沿用上一次的结果
+	source.set(nil, errors.New("unavailable"))
+	if err := discovery.Refresh(ctx); err == nil {
+		t.Fatal("Refresh() = nil, want the source error")
+	}
+	if peers := discovery.Peers(); !slices.Equal(peers, addrs) || len(events) != 0 {
+		t.Fatalf("Peers() after failure = %v, events %d", peers, len(events))
+	}
+
+	// 服务离开时断开连接
+	left := wsPeer(addrs[1])
+	source.set(addrs[:1], nil)
+	if err := discovery.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); len(event.joined) != 0 || !slices.Equal(event.left, addrs[1:]) {
+		t.Fatalf("event = %+v, want %s left", event, addrs[1])
+	}
+	if wsPeer(addrs[1]) != nil || wsPeer(addrs[0]) == nil {
+		t.Fatalf("connections after %s left: %v", addrs[1], Peers())
+	}
+	select {
+	case <-left.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("connection of the left peer was not closed")
+	}
+
+	// 所有服务离开后停止，停止时注销本节点
+	source.set(nil, nil)
+	if err := discovery.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); !slices.Equal(event.left, addrs[:1]) || len(discovery.Peers()) != 0 {
+		t.Fatalf("event = %+v, want %s left", event, addrs[0])
+	}
+	if err := discovery.Stop(ctx); err != nil || source.leaves != 1 {
+		t.Fatalf("Stop() = %v, leaves %d", err, source.leaves)
+	}
+}
+
+func TestDiffPeers(t *testing.T) {
+	joined, left := diffPeers([]string{"a:1", "b:1"}, []string{"b:1", "c:1"})
+	if !slices.Equal(joined, []string{"c:1"}) || !slices.Equal(left, []string{"a:1"}) {
+		t.Fatalf("diffPeers() = %v, %v", joined, left)
+	}
+}
+
+func TestIsLocalPeer(t *testing.T) {
+	local := []string{"0.0.0.0:7778", "", "hook.example.com:7779"}
+
+	cases := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "127.0.0.1:7778", want: true},
+		{addr: "localhost:7778", want: true},
+		{addr: "hook.example.com:7779", want: true},
+		{addr: "127.0.0.1:7780", want: false},
+		{addr: "10.255.255.1:7778", want: false},
+		{addr: "other.example.com:7779", want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.addr, func(t *testing.T) {
+			if got := isLocalPeer(local, c.addr); got != c.want {
+				t.Fatalf("isLocalPeer(%s) = %v, want %v", c.addr, got, c.want)
+			}
+		})
+	}
+}
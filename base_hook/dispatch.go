@@ -0,0 +1,283 @@
+package base_hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/gtrace"
+	"github.com/gogf/gf/v2/os/grpool"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	Hook订阅者的调度：
+		1、调度方式：同步调用、异步调用不等待结果、异步并发调用并等待全部完成
+		2、异步调度使用每个Hook独立的有界协程池
+		3、每个订阅者的超时时间基于调用方的上下文派生，超时后返回错误（订阅函数需自行响应ctx的取消）
+		4、按订阅者的优先级从高到低依次调度
+		5、订阅者panic时不影响其他订阅者，panic信息连同订阅者名称一起返回或上报
+*/
+
+var ErrSubscriberTimeout = gerror.New("Hook订阅者执行超时")
+
+// InstallOption 安装Hook的选项
+type InstallOption struct {
+	Priority int           // 优先级，值越大越先调用，默认为0
+	Timeout  time.Duration // 超时时间，为0时使用调度选项中的超时时间
+}
+
+// hookMeta 订阅配置
+type hookMeta struct {
+	InstallOption
+	record *subscriberRecord // 注册表中的订阅记录
+}
+
+// hookEntry 订阅项
+type hookEntry[T any, F any] struct {
+	base_model.KeyValueT[T, F]
+	hookMeta
+}
+
+// DispatchOption 调度选项
+type DispatchOption struct {
+	Mode    base_enum.HookDispatchMode // 调度方式，默认为同步调用
+	Timeout time.Duration              // 每个订阅者的超时时间，为0时不限制
+	OnError func(err error)            // 异步调用时订阅者的错误回调，为空时仅记录日志
+}
+
+// SubscriberError 订阅者执行失败的错误，包含失败的订阅者信息
+type SubscriberError struct {
+	Subscriber string // 订阅函数名称
+	Filter     any    // 订阅者的过滤条件
+	Panic      any    // panic的值，非panic时为nil
+	Stack      string // panic时的调用栈
+	Err        error  // 订阅者返回的错误
+}
+
+func (e *SubscriberError) Error() string {
+	if e.Panic != nil {
+		return fmt.Sprintf("Hook订阅者 %s 执行异常：%v", e.Subscriber, e.Panic)
+	}
+	return fmt.Sprintf("Hook订阅者 %s 执行失败：%v", e.Subscriber, e.Err)
+}
+
+func (e *SubscriberError) Unwrap() error {
+	return e.Err
+}
+
+// SetWorkerPool 设置异步调度使用的协程池大小，size 小于等于0时不限制
+func (s *BaseHook[T, F]) SetWorkerPool(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pool != nil {
+		s.pool.Close()
+	}
+	s.pool = grpool.New(size)
+}
+
+// getPool 获取协程池，未设置时按配置 service.hook.dispatch.workerSize 创建
+func (s *BaseHook[T, F]) getPool(ctx context.Context) *grpool.Pool {
+	s.mu.RLock()
+	pool := s.pool
+	s.mu.RUnlock()
+
+	if pool != nil {
+		return pool
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pool == nil {
+		s.pool = grpool.New(g.Cfg().MustGet(ctx, "service.hook.dispatch.workerSize", 16).Int())
+	}
+
+	return s.pool
+}
+
+// Dispatch 按调度选项调用所有订阅者，f 负责调用具体的订阅函数。
+// 同步及异步等待方式返回所有订阅者的错误；异步方式立即返回，错误交由 OnError 处理。
+func (s *BaseHook[T, F]) Dispatch(ctx context.Context, f func(ctx context.Context, key T, value F) error, option ...DispatchOption) error {
+	var opt DispatchOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+	if opt.Mode == nil {
+		opt.Mode = base_enum.Hook.DispatchMode.Sync
+	}
+
+	return s.dispatch(ctx, s.snapshot(), f, opt)
+}
+
+// dispatch 按调度选项调用指定的订阅者
+func (s *BaseHook[T, F]) dispatch(ctx context.Context, entries []hookEntry[T, F], f func(ctx context.Context, key T, value F) error, opt DispatchOption) error {
+	businessType := s.GetBusinessType().Code()
+
+	// 构建订阅者的调用信息及经过中间件包装的调用函数
+	calls := make([]*HookCall, len(entries))
+	handlers := make([]HandlerFunc, len(entries))
+	timeouts := make([]time.Duration, len(entries))
+	for i, entry := range entries {
+		entry := entry
+		calls[i] = &HookCall{
+			BusinessType: businessType,
+			Subscriber:   hookFuncName(entry.Value),
+			Filter:       entry.Key,
+			TraceId:      gtrace.GetTraceID(ctx),
+		}
+		handlers[i] = s.applyMiddleware(func(ctx context.Context, call *HookCall) error {
+			return f(ctx, entry.Key, entry.Value)
+		})
+		timeouts[i] = entry.Timeout
+		if timeouts[i] <= 0 {
+			timeouts[i] = opt.Timeout
+		}
+	}
+
+	// 调用订阅者，并记录调用次数、错误及耗时
+	invoke := func(ctx context.Context, i int) error {
+		start := time.Now()
+		err := invokeSubscriber(ctx, calls[i], handlers[i], timeouts[i])
+		entries[i].record.observe(time.Since(start), err)
+		return err
+	}
+
+	switch opt.Mode.Code() {
+	case base_enum.Hook.DispatchMode.Async.Code():
+		// 异步调用不受调用方上下文取消的影响
+		ctx = context.WithoutCancel(ctx)
+		pool := s.getPool(ctx)
+		for i := range entries {
+			i := i
+			err := pool.Add(ctx, func(ctx context.Context) {
+				if err := invoke(ctx, i); err != nil {
+					reportSubscriberError(ctx, opt, err)
+				}
+			})
+			if err != nil {
+				reportSubscriberError(ctx, opt, err)
+			}
+		}
+		return nil
+
+	case base_enum.Hook.DispatchMode.AsyncWait.Code():
+		pool := s.getPool(ctx)
+		errArr := make([]error, len(entries))
+		wg := sync.WaitGroup{}
+		for i := range entries {
+			i := i
+			wg.Add(1)
+			err := pool.Add(ctx, func(ctx context.Context) {
+				defer wg.Done()
+				errArr[i] = invoke(ctx, i)
+			})
+			if err != nil {
+				wg.Done()
+				errArr[i] = err
+			}
+		}
+		wg.Wait()
+		return errors.Join(errArr...)
+
+	default:
+		var errArr []error
+		for i := range entries {
+			if err := invoke(ctx, i); err != nil {
+				errArr = append(errArr, err)
+			}
+		}
+		return errors.Join(errArr...)
+	}
+}
+
+// invokeSubscriber 调用单个订阅者，处理超时及panic
+func invokeSubscriber(ctx context.Context, call *HookCall, handler HandlerFunc, timeout time.Duration) error {
+	if timeout <= 0 {
+		return callSubscriber(ctx, call, handler)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- callSubscriber(ctx, call, handler)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return &SubscriberError{
+			Subscriber: call.Subscriber,
+			Filter:     call.Filter,
+			Err:        gerror.Wrapf(ErrSubscriberTimeout, "超时时间 %s", timeout),
+		}
+	}
+}
+
+// callSubscriber 调用订阅者，并将错误及panic包装为 SubscriberError
+func callSubscriber(ctx context.Context, call *HookCall, handler HandlerFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &SubscriberError{
+				Subscriber: call.Subscriber,
+				Filter:     call.Filter,
+				Panic:      r,
+				Stack:      string(debug.Stack()),
+			}
+		}
+	}()
+
+	if err = handler(ctx, call); err != nil {
+		var subscriberErr *SubscriberError
+		if errors.As(err, &subscriberErr) {
+			return err
+		}
+		return &SubscriberError{
+			Subscriber: call.Subscriber,
+			Filter:     call.Filter,
+			Err:        err,
+		}
+	}
+
+	return nil
+}
+
+// reportSubscriberError 上报异步调用的订阅者错误
+func reportSubscriberError(ctx context.Context, opt DispatchOption, err error) {
+	if opt.OnError != nil {
+		opt.OnError(err)
+		return
+	}
+
+	var subscriberErr *SubscriberError
+	if errors.As(err, &subscriberErr) && subscriberErr.Stack != "" {
+		g.Log().Errorf(ctx, "%v\n%s", err, subscriberErr.Stack)
+		return
+	}
+	g.Log().Error(ctx, err)
+}
+
+// hookFuncName 获取订阅函数的名称
+func hookFuncName(value any) string {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Sprintf("%T", value)
+	}
+
+	if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return v.Type().String()
+}
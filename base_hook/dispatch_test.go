@@ -0,0 +1,347 @@
+package base_hook
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+// testOrderHookFunc 测试使用的订阅函数类型
+type testOrderHookFunc func(ctx context.Context, order *testOrder) error
+
+// newTestBaseHook 创建使用指定协程池大小的Hook，测试结束时卸载所有订阅及网关的订阅
+func newTestBaseHook(t *testing.T, workerSize int) *BaseHook[string, testOrderHookFunc] {
+	hook := &BaseHook[string, testOrderHookFunc]{}
+	hook.SetWorkerPool(workerSize)
+	t.Cleanup(func() {
+		hook.ClearAllHook()
+		gateway.Unsubscribe(hook.GetBusinessType().Code())
+	})
+	return hook
+}
+
+// callOrder 调用订阅函数
+func callOrder(ctx context.Context, key string, value testOrderHookFunc) error {
+	return value(ctx, &testOrder{Id: 1})
+}
+
+// subscriberErrors 将汇总的错误展开为 SubscriberError
+func subscriberErrors(t *testing.T, err error) []*SubscriberError {
+	t.Helper()
+
+	var errArr []error
+	if joinErr, ok := err.(interface{ Unwrap() []error }); ok {
+		errArr = joinErr.Unwrap()
+	} else if err != nil {
+		errArr = []error{err}
+	}
+
+	result := make([]*SubscriberError, 0, len(errArr))
+	for _, e := range errArr {
+		var subscriberErr *SubscriberError
+		if !errors.As(e, &subscriberErr) {
+			t.Fatalf("error %v is not a SubscriberError", e)
+		}
+		result = append(result, subscriberErr)
+	}
+	return result
+}
+
+func TestBaseHook_Priority(t *testing.T) {
+	hook := newTestBaseHook(t, 1)
+
+	var called []string
+	for _, item := range []struct {
+		name     string
+		priority int
+	}{{"a", 0}, {"b", 10}, {"c", 5}, {"d", 10}, {"e", -1}, {"f", 0}} {
+		name := item.name
+		hook.InstallHook(name, func(ctx context.Context, order *testOrder) error {
+			called = append(called, name)
+			return nil
+		}, InstallOption{Priority: item.priority})
+	}
+
+	// 按优先级从高到低，相同优先级按安装顺序
+	want := []string{"b", "d", "c", "a", "f", "e"}
+	if err := hook.Dispatch(context.Background(), callOrder); err != nil || !slices.Equal(called, want) {
+		t.Fatalf("Dispatch() = %v, called %v, want %v", err, called, want)
+	}
+
+	var keys []string
+	hook.Iterator(func(key string, value testOrderHookFunc) {
+		keys = append(keys, key)
+	})
+	if !slices.Equal(keys, want) {
+		t.Fatalf("Iterator() = %v, want %v", keys, want)
+	}
+
+	// 卸载后保持其余订阅者的顺序
+	hook.UnInstallHook("d", func(filter string, key string) bool { return filter == key })
+	called = nil
+	if _ = hook.Dispatch(context.Background(), callOrder); !slices.Equal(called, []string{"b", "c", "a", "f", "e"}) {
+		t.Fatalf("called after UnInstallHook = %v", called)
+	}
+}
+
+func TestBaseHook_DispatchSync(t *testing.T) {
+	hook := newTestBaseHook(t, 1)
+	errB := errors.New("b failed")
+
+	var called []string
+	hook.InstallHook("a", func(ctx context.Context, order *testOrder) error {
+		called = append(called, "a")
+		panic("a panicked")
+	})
+	hook.InstallHook("b", func(ctx context.Context, order *testOrder) error {
+		called = append(called, "b")
+		return errB
+	})
+	hook.InstallHook("c", func(ctx context.Context, order *testOrder) error {
+		called = append(called, "c")
+		return nil
+	})
+
+	err := hook.Dispatch(context.Background(), callOrder, DispatchOption{Mode: base_enum.Hook.DispatchMode.Sync})
+	if !slices.Equal(called, []string{"a", "b", "c"}) {
+		t.Fatalf("called = %v, want all subscribers despite the panic", called)
+	}
+	if !errors.Is(err, errB) {
+		t.Fatalf("Dispatch() = %v, want errB", err)
+	}
+
+	errs := subscriberErrors(t, err)
+	if len(errs) != 2 {
+		t.Fatalf("errors = %v, want 2", errs)
+	}
+	if errs[0].Filter != "a" || errs[0].Panic != "a panicked" || errs[0].Stack == "" || errs[0].Subscriber == "" {
+		t.Fatalf("panic error = %+v", errs[0])
+	}
+	if errs[1].Filter != "b" || errs[1].Panic != nil || !errors.Is(errs[1], errB) {
+		t.Fatalf("error = %+v", errs[1])
+	}
+}
+
+func TestBaseHook_DispatchAsync(t *testing.T) {
+	hook := newTestBaseHook(t, 2)
+
+	var (
+		release = make(chan struct{})
+		done    sync.WaitGroup
+		errMu   sync.Mutex
+		errArr  []error
+		ctxErr  atomic.Value
+	)
+	done.Add(2)
+	hook.InstallHook("block", func(ctx context.Context, order *testOrder) error {
+		defer done.Done()
+		<-release
+		if err := ctx.Err(); err != nil {
+			ctxErr.Store(err)
+		}
+		return errors.New("block failed")
+	})
+	hook.InstallHook("panic", func(ctx context.Context, order *testOrder) error {
+		defer done.Done()
+		<-release
+		panic("async panicked")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := hook.Dispatch(ctx, callOrder, DispatchOption{
+		Mode: base_enum.Hook.DispatchMode.Async,
+		OnError: func(err error) {
+			errMu.Lock()
+			errArr = append(errArr, err)
+			errMu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("Dispatch() = %v, want nil without waiting", err)
+	}
+
+	// 调用方的上下文取消后订阅者仍可继续执行
+	cancel()
+	close(release)
+	done.Wait()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		errMu.Lock()
+		n := len(errArr)
+		errMu.Unlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	errMu.Lock()
+	defer errMu.Unlock()
+	errs := subscriberErrors(t, errors.Join(errArr...))
+	if len(errs) != 2 {
+		t.Fatalf("OnError received %v, want 2 errors", errArr)
+	}
+	var panicked bool
+	for _, e := range errs {
+		panicked = panicked || e.Panic == "async panicked"
+	}
+	if !panicked {
+		t.Fatalf("OnError received %v, want the panic", errArr)
+	}
+	if v := ctxErr.Load(); v != nil {
+		t.Fatalf("subscriber context = %v, want not cancelled", v)
+	}
+}
+
+func TestBaseHook_DispatchAsyncWait(t *testing.T) {
+	cases := []struct {
+		name           string
+		workerSize     int
+		wantConcurrent int32
+	}{
+		{name: "concurrent", workerSize: 3, wantConcurrent: 3},
+		{name: "bounded pool", workerSize: 1, wantConcurrent: 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hook := newTestBaseHook(t, c.workerSize)
+
+			var running, maxRunning, finished atomic.Int32
+			for _, name := range []string{"a", "b", "c"} {
+				hook.InstallHook(name, func(ctx context.Context, order *testOrder) error {
+					n := running.Add(1)
+					for {
+						m := maxRunning.Load()
+						if n <= m || maxRunning.CompareAndSwap(m, n) {
+							break
+						}
+					}
+					time.Sleep(20 * time.Millisecond)
+					running.Add(-1)
+					finished.Add(1)
+					if name == "b" {
+						return errors.New("b failed")
+					}
+					return nil
+				})
+			}
+
+			err := hook.Dispatch(context.Background(), callOrder, DispatchOption{Mode: base_enum.Hook.DispatchMode.AsyncWait})
+			if finished.Load() != 3 {
+				t.Fatalf("Dispatch() returned before all subscribers finished: %d", finished.Load())
+			}
+			if errs := subscriberErrors(t, err); len(errs) != 1 || errs[0].Filter != "b" {
+				t.Fatalf("Dispatch() = %v, want b failed", err)
+			}
+			if maxRunning.Load() != c.wantConcurrent {
+				t.Fatalf("max concurrent = %d, want %d", maxRunning.Load(), c.wantConcurrent)
+			}
+		})
+	}
+}
+
+func TestBaseHook_DispatchTimeout(t *testing.T) {
+	hook := newTestBaseHook(t, 1)
+
+	type ctxKey struct{}
+	var (
+		mu        sync.Mutex
+		deadlines = map[string]time.Duration{}
+		values    = map[string]any{}
+	)
+	// 超时的订阅者在独立的协程中继续执行，记录的内容需要加锁
+	block := func(name string) testOrderHookFunc {
+		return func(ctx context.Context, order *testOrder) error {
+			deadline, _ := ctx.Deadline()
+			mu.Lock()
+			deadlines[name] = time.Until(deadline)
+			values[name] = ctx.Value(ctxKey{})
+			mu.Unlock()
+			<-ctx.Done()
+			return ctx.Err()
+		}
+	}
+	hook.InstallHook("option", block("option"))
+	hook.InstallHook("install", block("install"), InstallOption{Timeout: 10 * time.Millisecond})
+	hook.InstallHook("fast", func(ctx context.Context, order *testOrder) error { return nil })
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "caller")
+	start := time.Now()
+	err := hook.Dispatch(ctx, callOrder, DispatchOption{Timeout: 30 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Dispatch() took %s", elapsed)
+	}
+
+	errs := subscriberErrors(t, err)
+	if len(errs) != 2 || errs[0].Filter != "option" || errs[1].Filter != "install" {
+		t.Fatalf("Dispatch() = %v, want option and install timeouts", err)
+	}
+	for _, e := range errs {
+		if !errors.Is(e, ErrSubscriberTimeout) {
+			t.Fatalf("error = %v, want ErrSubscriberTimeout", e)
+		}
+	}
+
+	// 超时时间按订阅者的配置，未配置时按调度选项，并基于调用方的上下文派生
+	mu.Lock()
+	defer mu.Unlock()
+	if deadlines["install"] > 10*time.Millisecond || deadlines["option"] <= 10*time.Millisecond || deadlines["option"] > 30*time.Millisecond {
+		t.Fatalf("deadlines = %v", deadlines)
+	}
+	if values["option"] != "caller" || values["install"] != "caller" {
+		t.Fatalf("context values = %v, want the caller's", values)
+	}
+}
+
+func TestPublishHookMessage_Dispatch(t *testing.T) {
+	hook := newTestBaseHook(t, 2)
+
+	var (
+		wg       sync.WaitGroup
+		received atomic.Int64
+	)
+	hook.InstallHook("a", func(ctx context.Context, order *testOrder) error {
+		defer wg.Done()
+		received.Add(order.Id)
+		return nil
+	})
+	hook.InstallHook("b", func(ctx context.Context, order *testOrder) error {
+		defer wg.Done()
+		received.Add(order.Id)
+		return errors.New("b failed")
+	})
+
+	cases := []struct {
+		name    string
+		mode    base_enum.HookDispatchMode
+		wantErr int
+	}{
+		{name: "default", wantErr: 1},
+		{name: "async wait", mode: base_enum.Hook.DispatchMode.AsyncWait, wantErr: 1},
+		{name: "async", mode: base_enum.Hook.DispatchMode.Async, wantErr: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			received.Store(0)
+			wg.Add(2)
+			errArr := PublishHookMessage(context.Background(), hook, Option{
+				Data:        testOrder{Id: 3},
+				HookTypeStr: hook.GetBusinessType().Code(),
+				Dispatch:    DispatchOption{Mode: c.mode, OnError: func(err error) {}},
+			})
+			wg.Wait()
+			if len(errArr) != c.wantErr || received.Load() != 6 {
+				t.Fatalf("PublishHookMessage() = %v, received %d, want %d errors", errArr, received.Load(), c.wantErr)
+			}
+		})
+	}
+}
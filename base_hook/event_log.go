@@ -0,0 +1,282 @@
+package base_hook
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/kysion/base-library/base_model"
+)
+
+/*
+	Hook事件日志：
+		1、接收到的消息经去重认领后、交由订阅者处理前追加写入事件日志，并分配单调递增的序号；启用去重时重复投递的消息只写入一次
+		2、存储方式：文件分段存储（FileEventStore）或数据库表（DbEventStore）
+		3、消费者通过 Resume 指定处理函数，从上次处理成功的位置继续处理，处理成功后提交消费位置
+		4、通过 Replay 重放指定业务类型在某个时间范围内的消息，用于问题修复后的重新处理
+*/
+
+var ErrEventStoreClosed = gerror.New("Hook事件日志已关闭")
+
+// EventStore 事件日志存储
+type EventStore interface {
+	// Append 追加一条消息，返回分配的序号
+	Append(ctx context.Context, model base_model.HookModel) (seq int64, err error)
+	// Read 按序号升序读取序号大于 fromSeq 的事件，最多 limit 条
+	Read(ctx context.Context, fromSeq int64, limit int) ([]*base_model.HookEvent, error)
+	// ReadRange 按序号升序读取指定业务类型在 [start, end] 时间范围内、序号大于 fromSeq 的事件，最多 limit 条；
+	// businessType 为空时不限制业务类型，start、end 为空时不限制对应的边界
+	ReadRange(ctx context.Context, businessType string, start, end *gtime.Time, fromSeq int64, limit int) ([]*base_model.HookEvent, error)
+	// Offset 获取消费者最后处理成功的事件序号，未消费过时返回0
+	Offset(ctx context.Context, consumer string) (int64, error)
+	// CommitOffset 提交消费者最后处理成功的事件序号
+	CommitOffset(ctx context.Context, consumer string, seq int64) error
+	// Close 关闭存储
+	Close() error
+}
+
+// EventHandler 事件处理函数
+type EventHandler func(ctx context.Context, event *base_model.HookEvent, model base_model.HookModel) error
+
+// EventLogOption 事件日志配置
+type EventLogOption struct {
+	Enabled     bool   // 是否启用
+	Driver      string // 存储方式：file、db
+	Dir         string // file 存储目录
+	SegmentSize int64  // file 单个分段文件的最大字节数
+	Sync        bool   // file 每次写入后是否立即落盘
+	Table       string // db 事件日志表名
+	OffsetTable string // db 消费位置表名
+	BatchSize   int    // 续传及重放时每批读取的事件数
+}
+
+// DefaultEventLogOption 从配置 service.hook.eventLog 读取事件日志配置，未配置的项使用默认值
+func DefaultEventLogOption(ctx context.Context) EventLogOption {
+	return EventLogOption{
+		Enabled:     g.Cfg().MustGet(ctx, "service.hook.eventLog.enabled", false).Bool(),
+		Driver:      g.Cfg().MustGet(ctx, "service.hook.eventLog.driver", "file").String(),
+		Dir:         g.Cfg().MustGet(ctx, "service.hook.eventLog.dir", "temp/hook_event_log").String(),
+		SegmentSize: g.Cfg().MustGet(ctx, "service.hook.eventLog.segmentSize", 64<<20).Int64(),
+		Sync:        g.Cfg().MustGet(ctx, "service.hook.eventLog.sync", false).Bool(),
+		Table:       g.Cfg().MustGet(ctx, "service.hook.eventLog.table", "hook_event_log").String(),
+		OffsetTable: g.Cfg().MustGet(ctx, "service.hook.eventLog.offsetTable", "hook_event_offset").String(),
+		BatchSize:   g.Cfg().MustGet(ctx, "service.hook.eventLog.batchSize", 100).Int(),
+	}
+}
+
+var (
+	eventStoreMu     sync.RWMutex
+	eventStore       EventStore
+	eventStoreInited bool
+)
+
+// SetEventStore 设置事件日志存储，设置为nil时关闭事件日志
+func SetEventStore(store EventStore) {
+	eventStoreMu.Lock()
+	defer eventStoreMu.Unlock()
+
+	eventStore = store
+	eventStoreInited = true
+}
+
+// GetEventStore 获取事件日志存储，未设置时按配置 service.hook.eventLog 创建，未启用时返回nil
+func GetEventStore(ctx context.Context) (EventStore, error) {
+	eventStoreMu.RLock()
+	store, inited := eventStore, eventStoreInited
+	eventStoreMu.RUnlock()
+
+	if inited {
+		return store, nil
+	}
+
+	eventStoreMu.Lock()
+	defer eventStoreMu.Unlock()
+
+	if eventStoreInited {
+		return eventStore, nil
+	}
+
+	conf := DefaultEventLogOption(ctx)
+	if conf.Enabled {
+		var err error
+		switch conf.Driver {
+		case "db":
+			eventStore = NewDbEventStore(g.DB(), conf)
+		case "file":
+			eventStore, err = NewFileEventStore(conf)
+		default:
+			err = gerror.Newf("不支持的事件日志存储方式：%s", conf.Driver)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	eventStoreInited = true
+
+	return eventStore, nil
+}
+
+// appendEventLog 将接收到的消息追加写入事件日志，未启用事件日志时忽略；
+// 启用去重时同一消息只写入一次，订阅者处理失败后重新投递的消息不会再次写入
+func appendEventLog(ctx context.Context, model base_model.HookModel) error {
+	store, err := GetEventStore(ctx)
+	if err != nil || store == nil {
+		return err
+	}
+
+	dedupe, err := GetDedupeStore(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := nodeDedupeKey(ctx, model)
+	if err != nil {
+		return err
+	}
+	if dedupe == nil || key == "" {
+		return appendEvent(ctx, store, model)
+	}
+
+	key = "eventLog:" + key
+	claimed, err := dedupe.Claim(ctx, key, model)
+	if err != nil || !claimed {
+		return err
+	}
+	if err = appendEvent(ctx, store, model); err != nil {
+		if releaseErr := dedupe.Release(ctx, key); releaseErr != nil {
+			g.Log().Warning(ctx, gerror.Wrapf(releaseErr, "Hook事件日志去重认领释放失败：%s", key))
+		}
+		return err
+	}
+
+	// 已写入事件日志，记录失败时仅记录日志，认领在租约到期前仍阻止重复写入
+	if recordErr := dedupe.Record(ctx, key, model); recordErr != nil {
+		g.Log().Warning(ctx, gerror.Wrapf(recordErr, "Hook事件日志去重记录写入失败：%s", key))
+	}
+
+	return nil
+}
+
+// appendEvent 追加写入一条消息
+func appendEvent(ctx context.Context, store EventStore, model base_model.HookModel) error {
+	if _, err := store.Append(ctx, model); err != nil {
+		return gerror.Wrap(err, "Hook消息写入事件日志失败")
+	}
+	return nil
+}
+
+// Resume 从消费者上次处理成功的位置继续处理事件日志，直到没有新的事件；每处理成功一条即提交消费位置。
+// 消费位置仅由 Resume 提交，接收时已交由订阅者处理的消息不会更新消费位置，因此必须指定 handler，
+// 不支持重新分发给本进程的订阅者。处理失败时停止并返回错误，下次从失败的事件继续。
+func Resume(ctx context.Context, consumer string, handler EventHandler) (processed int, err error) {
+	if handler == nil {
+		return 0, gerror.NewCode(gcode.CodeMissingParameter, "Hook事件日志续传必须指定处理函数")
+	}
+
+	store, err := GetEventStore(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if store == nil {
+		return 0, gerror.New("未启用Hook事件日志：service.hook.eventLog.enabled")
+	}
+
+	offset, err := store.Offset(ctx, consumer)
+	if err != nil {
+		return 0, err
+	}
+
+	batchSize := DefaultEventLogOption(ctx).BatchSize
+	for {
+		events, err := store.Read(ctx, offset, batchSize)
+		if err != nil || len(events) == 0 {
+			return processed, err
+		}
+
+		for _, event := range events {
+			if err = handleEvent(ctx, event, handler, nil); err != nil {
+				return processed, gerror.Wrapf(err, "Hook事件 %d 处理失败", event.Seq)
+			}
+			if err = store.CommitOffset(ctx, consumer, event.Seq); err != nil {
+				return processed, err
+			}
+			offset = event.Seq
+			processed++
+		}
+	}
+}
+
+// Replay 重放指定业务类型在 [start, end] 时间范围内的事件，不影响消费位置。
+// handler 为空时交由网关重新分发给本进程的订阅者，重放不经过去重，已处理成功的消息也会重新处理。处理失败时停止并返回错误。
+func Replay(ctx context.Context, businessType string, start, end *gtime.Time, handler EventHandler) (processed int, err error) {
+	store, err := GetEventStore(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if store == nil {
+		return 0, gerror.New("未启用Hook事件日志：service.hook.eventLog.enabled")
+	}
+
+	var offset int64
+	batchSize := DefaultEventLogOption(ctx).BatchSize
+	for {
+		events, err := store.ReadRange(ctx, businessType, start, end, offset, batchSize)
+		if err != nil || len(events) == 0 {
+			return processed, err
+		}
+
+		for _, event := range events {
+			if err = handleEvent(ctx, event, handler, gateway.broadcast); err != nil {
+				return processed, gerror.Wrapf(err, "Hook事件 %d 重放失败", event.Seq)
+			}
+			offset = event.Seq
+			processed++
+		}
+	}
+}
+
+// handleEvent 解码事件中的消息并交由处理函数处理，handler 为空时交由 broadcast 分发
+func handleEvent(ctx context.Context, event *base_model.HookEvent, handler EventHandler, broadcast func(model base_model.HookModel) error) error {
+	model := base_model.HookModel{}
+	if err := gjson.DecodeTo(event.Payload, &model); err != nil {
+		return gerror.Wrap(err, "Hook事件解码失败")
+	}
+	model.Ctx = withTraceId(ctx, model.TraceId)
+
+	if handler == nil {
+		return broadcast(model)
+	}
+
+	return handler(model.Ctx, event, model)
+}
+
+// encodeHookEvent 将消息编码为事件日志记录
+func encodeHookEvent(model base_model.HookModel) (*base_model.HookEvent, error) {
+	payload, err := gjson.Encode(model)
+	if err != nil {
+		return nil, gerror.Wrap(err, "Hook消息序列化失败")
+	}
+
+	return &base_model.HookEvent{
+		BusinessType: model.BusinessTypeStr,
+		Payload:      string(payload),
+		CreatedAt:    gtime.Now(),
+	}, nil
+}
+
+// matchHookEvent 判断事件是否属于指定业务类型及时间范围
+func matchHookEvent(event *base_model.HookEvent, businessType string, start, end *gtime.Time) bool {
+	if businessType != "" && event.BusinessType != businessType {
+		return false
+	}
+	if start != nil && event.CreatedAt.Before(start) {
+		return false
+	}
+	if end != nil && event.CreatedAt.After(end) {
+		return false
+	}
+	return true
+}
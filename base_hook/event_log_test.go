@@ -0,0 +1,323 @@
+package base_hook
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/kysion/base-library/base_model"
+)
+
+// useEventStore 测试期间使用指定的事件日志存储，结束后关闭并恢复
+func useEventStore(t *testing.T, store EventStore) {
+	eventStoreMu.RLock()
+	prevStore, prevInited := eventStore, eventStoreInited
+	eventStoreMu.RUnlock()
+
+	SetEventStore(store)
+	t.Cleanup(func() {
+		_ = store.Close()
+		eventStoreMu.Lock()
+		eventStore, eventStoreInited = prevStore, prevInited
+		eventStoreMu.Unlock()
+	})
+}
+
+func newTestFileEventStore(t *testing.T, dir string, segmentSize int64) *FileEventStore {
+	store, err := NewFileEventStore(EventLogOption{Dir: dir, SegmentSize: segmentSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func eventModel(messageId, businessType string) base_model.HookModel {
+	return base_model.HookModel{MessageId: messageId, BusinessTypeStr: businessType}
+}
+
+func appendEvents(t *testing.T, store EventStore, models ...base_model.HookModel) {
+	for i, model := range models {
+		seq, err := store.Append(context.Background(), model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq <= 0 {
+			t.Fatalf("event %d seq = %d", i, seq)
+		}
+	}
+}
+
+func eventSeqs(events []*base_model.HookEvent) []int64 {
+	seqs := make([]int64, 0, len(events))
+	for _, event := range events {
+		seqs = append(seqs, event.Seq)
+	}
+	return seqs
+}
+
+func equalSeqs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFileEventStore_AppendRead(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// 分段很小，每个分段只能容纳一条事件
+	store := newTestFileEventStore(t, dir, 64)
+
+	appendEvents(t, store,
+		eventModel("m1", "order.paid"),
+		eventModel("m2", "order.closed"),
+		eventModel("m3", "order.paid"),
+		eventModel("m4", "order.paid"),
+	)
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+eventSegmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 4 {
+		t.Fatalf("segments = %d, want 4", len(segments))
+	}
+
+	cases := []struct {
+		name         string
+		businessType string
+		start, end   *gtime.Time
+		fromSeq      int64
+		limit        int
+		want         []int64
+	}{
+		{name: "all", limit: 10, want: []int64{1, 2, 3, 4}},
+		{name: "from seq", fromSeq: 2, limit: 10, want: []int64{3, 4}},
+		{name: "limit", limit: 2, want: []int64{1, 2}},
+		{name: "business type", businessType: "order.paid", limit: 10, want: []int64{1, 3, 4}},
+		{name: "business type from seq", businessType: "order.paid", fromSeq: 1, limit: 1, want: []int64{3}},
+		{name: "time range", start: gtime.Now().Add(-time.Minute), end: gtime.Now().Add(time.Minute), limit: 10, want: []int64{1, 2, 3, 4}},
+		{name: "before range", end: gtime.Now().Add(-time.Hour), limit: 10, want: []int64{}},
+		{name: "after range", start: gtime.Now().Add(time.Hour), limit: 10, want: []int64{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var events []*base_model.HookEvent
+			if c.businessType == "" && c.start == nil && c.end == nil {
+				events, err = store.Read(ctx, c.fromSeq, c.limit)
+			} else {
+				events, err = store.ReadRange(ctx, c.businessType, c.start, c.end, c.fromSeq, c.limit)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := eventSeqs(events); !equalSeqs(got, c.want) {
+				t.Fatalf("seqs = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFileEventStore_Reopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store := newTestFileEventStore(t, dir, 1<<20)
+	appendEvents(t, store, eventModel("m1", "order.paid"), eventModel("m2", "order.paid"))
+	if err := store.CommitOffset(ctx, "consumer", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Append(ctx, eventModel("m3", "order.paid")); !errors.Is(err, ErrEventStoreClosed) {
+		t.Fatalf("Append after Close = %v, want ErrEventStoreClosed", err)
+	}
+
+	// 模拟写入中断：最后一个分段末尾为未完整写入的行
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+eventSegmentExt))
+	f, err := os.OpenFile(segments[len(segments)-1], os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"seq":3,"businessType":"order.pa`)
+	_ = f.Close()
+
+	store = newTestFileEventStore(t, dir, 1<<20)
+	defer store.Close()
+
+	offset, err := store.Offset(ctx, "consumer")
+	if err != nil || offset != 1 {
+		t.Fatalf("Offset = %d, %v, want 1", offset, err)
+	}
+
+	seq, err := store.Append(ctx, eventModel("m3", "order.paid"))
+	if err != nil || seq != 3 {
+		t.Fatalf("Append after reopen = %d, %v, want 3", seq, err)
+	}
+
+	events, err := store.Read(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventSeqs(events); !equalSeqs(got, []int64{1, 2, 3}) {
+		t.Fatalf("seqs = %v, want [1 2 3]", got)
+	}
+}
+
+func TestResume(t *testing.T) {
+	useConfig(t, `{"service":{"hook":{"eventLog":{"batchSize":2}}}}`)
+	ctx := context.Background()
+
+	if _, err := Resume(ctx, "consumer", nil); gerror.Code(err) != gcode.CodeMissingParameter {
+		t.Fatalf("Resume(nil) = %v, want CodeMissingParameter", err)
+	}
+
+	store := newTestFileEventStore(t, t.TempDir(), 1<<20)
+	useEventStore(t, store)
+	appendEvents(t, store,
+		eventModel("m1", "order.paid"),
+		eventModel("m2", "order.paid"),
+		eventModel("m3", "order.paid"),
+		eventModel("m4", "order.paid"),
+		eventModel("m5", "order.paid"),
+	)
+
+	var handled []string
+	failOn := "m4"
+	handler := func(ctx context.Context, event *base_model.HookEvent, model base_model.HookModel) error {
+		if model.MessageId == failOn {
+			return errors.New("handler failed")
+		}
+		handled = append(handled, model.MessageId)
+		return nil
+	}
+
+	processed, err := Resume(ctx, "consumer", handler)
+	if err == nil || processed != 3 {
+		t.Fatalf("Resume = %d, %v, want 3 and an error", processed, err)
+	}
+	if offset, _ := store.Offset(ctx, "consumer"); offset != 3 {
+		t.Fatalf("offset = %d, want 3", offset)
+	}
+
+	// 修复后从失败的事件继续
+	failOn = ""
+	processed, err = Resume(ctx, "consumer", handler)
+	if err != nil || processed != 2 {
+		t.Fatalf("Resume = %d, %v, want 2", processed, err)
+	}
+	if want := []string{"m1", "m2", "m3", "m4", "m5"}; len(handled) != len(want) || handled[3] != "m4" || handled[4] != "m5" {
+		t.Fatalf("handled = %v, want %v", handled, want)
+	}
+
+	// 没有新的事件
+	if processed, err = Resume(ctx, "consumer", handler); err != nil || processed != 0 {
+		t.Fatalf("Resume = %d, %v, want 0", processed, err)
+	}
+	// 其他消费者从头开始
+	if processed, err = Resume(ctx, "other", handler); err != nil || processed != 5 {
+		t.Fatalf("Resume(other) = %d, %v, want 5", processed, err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	useConfig(t, `{"service":{"hook":{"eventLog":{"batchSize":2}}}}`)
+	ctx := context.Background()
+
+	store := newTestFileEventStore(t, t.TempDir(), 1<<20)
+	useEventStore(t, store)
+	appendEvents(t, store,
+		eventModel("m1", "test.replay.paid"),
+		eventModel("m2", "test.replay.closed"),
+		eventModel("m3", "test.replay.paid"),
+	)
+	if err := store.CommitOffset(ctx, "consumer", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	var replayed []string
+	processed, err := Replay(ctx, "test.replay.paid", gtime.Now().Add(-time.Minute), nil,
+		func(ctx context.Context, event *base_model.HookEvent, model base_model.HookModel) error {
+			replayed = append(replayed, model.MessageId)
+			return nil
+		})
+	if err != nil || processed != 2 || len(replayed) != 2 || replayed[0] != "m1" || replayed[1] != "m3" {
+		t.Fatalf("Replay = %d, %v, replayed %v, want [m1 m3]", processed, err, replayed)
+	}
+	// 重放不影响消费位置
+	if offset, _ := store.Offset(ctx, "consumer"); offset != 3 {
+		t.Fatalf("offset = %d, want 3", offset)
+	}
+
+	// 未指定处理函数时交由网关分发给订阅者
+	var broadcast []string
+	if err = Gateway().Subscribe("test.replay.closed", func(model base_model.HookModel) error {
+		broadcast = append(broadcast, model.MessageId)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer Gateway().Unsubscribe("test.replay.closed")
+
+	if processed, err = Replay(ctx, "", nil, nil, nil); err != nil || processed != 3 {
+		t.Fatalf("Replay = %d, %v, want 3", processed, err)
+	}
+	if len(broadcast) != 1 || broadcast[0] != "m2" {
+		t.Fatalf("broadcast = %v, want [m2]", broadcast)
+	}
+}
+
+func TestReceiveHookModel_EventLogOncePerMessage(t *testing.T) {
+	useConfig(t, `{}`)
+	ctx := context.Background()
+
+	store := newTestFileEventStore(t, t.TempDir(), 1<<20)
+	useEventStore(t, store)
+	useDedupeStore(t, newTestDedupeStore())
+
+	calls := 0
+	handler := func(model base_model.HookModel) error {
+		calls++
+		if calls == 1 {
+			return errors.New("handler failed")
+		}
+		return nil
+	}
+
+	// 首次处理失败后重新投递，处理成功后发送方未收到确认再次重投
+	for i := 0; i < 3; i++ {
+		model := eventModel("m1", "order.paid")
+		reply, ok := receiveHookModel(ctx, &model, handler, nil)
+		if !ok {
+			t.Fatal("reply expected for message with id")
+		}
+		wantNack := i == 0
+		if isNack := reply.Error != ""; isNack != wantNack {
+			t.Fatalf("delivery %d reply = %+v, nack want %v", i, reply, wantNack)
+		}
+	}
+
+	if calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+	events, err := store.Read(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("events = %d, want 1", len(events))
+	}
+}
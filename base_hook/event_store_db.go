@@ -0,0 +1,140 @@
+package base_hook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/utility/daoctl"
+)
+
+// hookEventColumns 事件日志表字段
+var hookEventColumns = struct {
+	Seq          string
+	BusinessType string
+	Payload      string
+	CreatedAt    string
+}{
+	Seq:          "seq",
+	BusinessType: "business_type",
+	Payload:      "payload",
+	CreatedAt:    "created_at",
+}
+
+// hookEventOffsetColumns 消费位置表字段
+var hookEventOffsetColumns = struct {
+	Consumer  string
+	Seq       string
+	UpdatedAt string
+}{
+	Consumer:  "consumer",
+	Seq:       "seq",
+	UpdatedAt: "updated_at",
+}
+
+// DbEventStore 数据库存储的事件日志，序号由数据库自增主键生成，建表语句见 manifest/sql/hook_event_log.sql
+type DbEventStore struct {
+	db     gdb.DB
+	option EventLogOption
+}
+
+// NewDbEventStore 创建数据库存储的事件日志
+func NewDbEventStore(db gdb.DB, option EventLogOption) *DbEventStore {
+	return &DbEventStore{
+		db:     db,
+		option: option,
+	}
+}
+
+// Append 追加一条消息，返回分配的序号
+func (s *DbEventStore) Append(ctx context.Context, model base_model.HookModel) (int64, error) {
+	event, err := encodeHookEvent(model)
+	if err != nil {
+		return 0, err
+	}
+
+	cols := hookEventColumns
+	seq, err := s.db.Model(s.option.Table).Ctx(ctx).InsertAndGetId(g.Map{
+		cols.BusinessType: event.BusinessType,
+		cols.Payload:      event.Payload,
+		cols.CreatedAt:    event.CreatedAt,
+	})
+	if err != nil {
+		return 0, gerror.Wrap(err, "Hook事件写入失败")
+	}
+
+	return seq, nil
+}
+
+// Read 按序号升序读取序号大于 fromSeq 的事件，最多 limit 条
+func (s *DbEventStore) Read(ctx context.Context, fromSeq int64, limit int) ([]*base_model.HookEvent, error) {
+	return s.ReadRange(ctx, "", nil, nil, fromSeq, limit)
+}
+
+// ReadRange 按序号升序读取指定业务类型在 [start, end] 时间范围内、序号大于 fromSeq 的事件，最多 limit 条
+func (s *DbEventStore) ReadRange(ctx context.Context, businessType string, start, end *gtime.Time, fromSeq int64, limit int) ([]*base_model.HookEvent, error) {
+	cols := hookEventColumns
+	model := s.db.Model(s.option.Table).Ctx(ctx).
+		WhereGT(cols.Seq, fromSeq).
+		OrderAsc(cols.Seq)
+
+	if businessType != "" {
+		model = model.Where(cols.BusinessType, businessType)
+	}
+	if start != nil {
+		model = model.WhereGTE(cols.CreatedAt, start)
+	}
+	if end != nil {
+		model = model.WhereLTE(cols.CreatedAt, end)
+	}
+	if limit > 0 {
+		model = model.Limit(limit)
+	}
+
+	result := make([]*base_model.HookEvent, 0)
+	if err := model.Scan(&result); err != nil {
+		return nil, gerror.Wrap(err, "Hook事件读取失败")
+	}
+
+	return result, nil
+}
+
+// Offset 获取消费者最后处理成功的事件序号
+func (s *DbEventStore) Offset(ctx context.Context, consumer string) (int64, error) {
+	offset, err := daoctl.ScanWithError[base_model.HookEventOffset](
+		s.db.Model(s.option.OffsetTable).Ctx(ctx).Where(hookEventOffsetColumns.Consumer, consumer),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, gerror.Wrap(err, "消费位置读取失败")
+	}
+
+	return offset.Seq, nil
+}
+
+// CommitOffset 提交消费者最后处理成功的事件序号
+func (s *DbEventStore) CommitOffset(ctx context.Context, consumer string, seq int64) error {
+	cols := hookEventOffsetColumns
+	_, err := daoctl.SaveWithError(s.db.Model(s.option.OffsetTable).Ctx(ctx).OnConflict(cols.Consumer), g.Map{
+		cols.Consumer:  consumer,
+		cols.Seq:       seq,
+		cols.UpdatedAt: gtime.Now(),
+	})
+	if err != nil {
+		return gerror.Wrap(err, "消费位置写入失败")
+	}
+
+	return nil
+}
+
+// Close 数据库连接由调用方管理，无需关闭
+func (s *DbEventStore) Close() error {
+	return nil
+}
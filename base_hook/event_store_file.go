@@ -0,0 +1,349 @@
+package base_hook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/kysion/base-library/base_model"
+)
+
+/*
+	文件分段存储的事件日志：
+		1、事件按行追加写入分段文件，每行为一条 HookEvent 的JSON，文件名为该分段第一条事件的序号
+		2、当前分段超过 SegmentSize 后创建新的分段
+		3、打开时截断最后一个分段中未完整写入的行，从最后一条事件的序号继续分配
+		4、消费位置保存在同目录下的 offsets.json 中
+*/
+
+const (
+	eventSegmentExt     = ".log"
+	eventOffsetFile     = "offsets.json"
+	defaultSegmentBytes = 64 << 20
+)
+
+// FileEventStore 文件分段存储的事件日志
+type FileEventStore struct {
+	mu          sync.Mutex
+	dir         string
+	segmentSize int64
+	sync        bool
+	file        *os.File // 当前写入的分段
+	fileSize    int64    // 当前分段的大小
+	lastSeq     int64    // 最后一条事件的序号
+
+	offsetMu sync.Mutex
+	offsets  map[string]int64
+}
+
+// NewFileEventStore 打开或创建文件分段存储的事件日志
+func NewFileEventStore(option EventLogOption) (*FileEventStore, error) {
+	if option.SegmentSize <= 0 {
+		option.SegmentSize = defaultSegmentBytes
+	}
+
+	if err := os.MkdirAll(option.Dir, 0o755); err != nil {
+		return nil, gerror.Wrap(err, "创建事件日志目录失败")
+	}
+
+	s := &FileEventStore{
+		dir:         option.Dir,
+		segmentSize: option.SegmentSize,
+		sync:        option.Sync,
+		offsets:     make(map[string]int64),
+	}
+
+	if err := s.loadOffsets(); err != nil {
+		return nil, err
+	}
+
+	if err := s.openLastSegment(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Append 追加一条消息，返回分配的序号
+func (s *FileEventStore) Append(ctx context.Context, model base_model.HookModel) (int64, error) {
+	event, err := encodeHookEvent(model)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return 0, ErrEventStoreClosed
+	}
+
+	event.Seq = s.lastSeq + 1
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return 0, gerror.Wrap(err, "Hook事件序列化失败")
+	}
+	line = append(line, '\n')
+
+	// 当前分段已满时创建新的分段
+	if s.fileSize > 0 && s.fileSize+int64(len(line)) > s.segmentSize {
+		if err = s.rotate(event.Seq); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err = s.file.Write(line); err != nil {
+		return 0, gerror.Wrap(err, "Hook事件写入失败")
+	}
+	if s.sync {
+		if err = s.file.Sync(); err != nil {
+			return 0, gerror.Wrap(err, "Hook事件落盘失败")
+		}
+	}
+
+	s.fileSize += int64(len(line))
+	s.lastSeq = event.Seq
+
+	return event.Seq, nil
+}
+
+// Read 按序号升序读取序号大于 fromSeq 的事件，最多 limit 条
+func (s *FileEventStore) Read(ctx context.Context, fromSeq int64, limit int) ([]*base_model.HookEvent, error) {
+	return s.scan(fromSeq, limit, func(event *base_model.HookEvent) bool {
+		return true
+	})
+}
+
+// ReadRange 按序号升序读取指定业务类型在 [start, end] 时间范围内、序号大于 fromSeq 的事件，最多 limit 条
+func (s *FileEventStore) ReadRange(ctx context.Context, businessType string, start, end *gtime.Time, fromSeq int64, limit int) ([]*base_model.HookEvent, error) {
+	return s.scan(fromSeq, limit, func(event *base_model.HookEvent) bool {
+		return matchHookEvent(event, businessType, start, end)
+	})
+}
+
+// Offset 获取消费者最后处理成功的事件序号
+func (s *FileEventStore) Offset(ctx context.Context, consumer string) (int64, error) {
+	s.offsetMu.Lock()
+	defer s.offsetMu.Unlock()
+
+	return s.offsets[consumer], nil
+}
+
+// CommitOffset 提交消费者最后处理成功的事件序号，先写临时文件再替换，避免写入中断导致文件损坏
+func (s *FileEventStore) CommitOffset(ctx context.Context, consumer string, seq int64) error {
+	s.offsetMu.Lock()
+	defer s.offsetMu.Unlock()
+
+	s.offsets[consumer] = seq
+
+	data, err := json.Marshal(s.offsets)
+	if err != nil {
+		return gerror.Wrap(err, "消费位置序列化失败")
+	}
+
+	path := filepath.Join(s.dir, eventOffsetFile)
+	if err = os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return gerror.Wrap(err, "消费位置写入失败")
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// Close 关闭当前写入的分段
+func (s *FileEventStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// loadOffsets 读取消费位置
+func (s *FileEventStore) loadOffsets() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, eventOffsetFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return gerror.Wrap(err, "消费位置读取失败")
+	}
+
+	if err = json.Unmarshal(data, &s.offsets); err != nil {
+		return gerror.Wrap(err, "消费位置解析失败")
+	}
+
+	return nil
+}
+
+// openLastSegment 打开最后一个分段用于追加写入，截断未完整写入的行，并恢复最后一条事件的序号
+func (s *FileEventStore) openLastSegment() error {
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+
+	if len(segments) == 0 {
+		return s.rotate(1)
+	}
+
+	path := s.segmentPath(segments[len(segments)-1])
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return gerror.Wrap(err, "事件日志分段读取失败")
+	}
+
+	// 截断最后一个换行符之后的内容
+	size := int64(bytes.LastIndexByte(data, '\n') + 1)
+	if size < int64(len(data)) {
+		if err = os.Truncate(path, size); err != nil {
+			return gerror.Wrap(err, "事件日志分段修复失败")
+		}
+		data = data[:size]
+	}
+
+	// 恢复最后一条事件的序号
+	s.lastSeq = segments[len(segments)-1] - 1
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		event := base_model.HookEvent{}
+		if json.Unmarshal(lines[i], &event) == nil && event.Seq > 0 {
+			s.lastSeq = event.Seq
+			break
+		}
+	}
+
+	s.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return gerror.Wrap(err, "事件日志分段打开失败")
+	}
+	s.fileSize = size
+
+	return nil
+}
+
+// rotate 关闭当前分段，创建以 firstSeq 命名的新分段
+func (s *FileEventStore) rotate(firstSeq int64) error {
+	if s.file != nil {
+		if err := s.file.Close(); err != nil {
+			return gerror.Wrap(err, "事件日志分段关闭失败")
+		}
+	}
+
+	file, err := os.OpenFile(s.segmentPath(firstSeq), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return gerror.Wrap(err, "事件日志分段创建失败")
+	}
+
+	s.file = file
+	s.fileSize = 0
+	return nil
+}
+
+// segments 返回所有分段的第一条事件序号，升序排列
+func (s *FileEventStore) segments() ([]int64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, gerror.Wrap(err, "事件日志目录读取失败")
+	}
+
+	result := make([]int64, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, eventSegmentExt) {
+			continue
+		}
+		if seq, err := strconv.ParseInt(strings.TrimSuffix(name, eventSegmentExt), 10, 64); err == nil {
+			result = append(result, seq)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+
+	return result, nil
+}
+
+// segmentPath 返回分段文件的路径
+func (s *FileEventStore) segmentPath(firstSeq int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", firstSeq, eventSegmentExt))
+}
+
+// scan 从包含 fromSeq+1 的分段开始顺序读取满足条件的事件
+func (s *FileEventStore) scan(fromSeq int64, limit int, match func(event *base_model.HookEvent) bool) ([]*base_model.HookEvent, error) {
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	// 跳过所有事件序号均不大于 fromSeq 的分段
+	start := 0
+	for i, first := range segments {
+		if first <= fromSeq+1 {
+			start = i
+		}
+	}
+
+	result := make([]*base_model.HookEvent, 0)
+	for _, first := range segments[start:] {
+		done, err := s.scanSegment(first, func(event *base_model.HookEvent) bool {
+			if event.Seq > fromSeq && match(event) {
+				result = append(result, event)
+			}
+			return limit > 0 && len(result) >= limit
+		})
+		if err != nil || done {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// scanSegment 顺序读取分段中的事件，f 返回 true 时停止读取
+func (s *FileEventStore) scanSegment(firstSeq int64, f func(event *base_model.HookEvent) bool) (bool, error) {
+	file, err := os.Open(s.segmentPath(firstSeq))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, gerror.Wrap(err, "事件日志分段打开失败")
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64<<10)
+	for {
+		line, err := reader.ReadBytes('\n')
+		// 未以换行符结尾的行为正在写入的事件，忽略
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, gerror.Wrap(err, "事件日志分段读取失败")
+		}
+		event := &base_model.HookEvent{}
+		if json.Unmarshal(line, event) != nil {
+			continue
+		}
+		if f(event) {
+			return true, nil
+		}
+	}
+}
@@ -4,30 +4,41 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/util/gconv"
 	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
 	"github.com/kysion/base-library/utility/enum"
 	"github.com/kysion/base-library/utility/kmap"
 	"reflect"
 	"strings"
+	"sync"
 )
 
 // GatewayHook 网关Hook
-type GatewayHook func(model base_model.HookModel)
+type GatewayHook func(model base_model.HookModel) error
 
 // sGateway 结构体
 type sGateway struct {
+	mu             sync.Mutex // 保证订阅时检查与注册的原子性
 	GatewayHookMap kmap.HashMap[string, GatewayHook]
+	patternHookMap *gmap.StrAnyMap // 通配符订阅，key为主题模式，value为 GatewayHook
 }
 
 // IGateway 接口
 type IGateway interface {
 	HasHookMessage() bool
-	BroadcastMessage(model base_model.HookModel)
+	BroadcastMessage(model base_model.HookModel) error
+	Subscribe(topic string, hook GatewayHook) error
+	Unsubscribe(topic string)
 }
 
-var gateway = sGateway{}
+var gateway = sGateway{
+	patternHookMap: gmap.NewStrAnyMap(true),
+}
 
 func Gateway() IGateway {
 	return &gateway
@@ -35,61 +46,137 @@ func Gateway() IGateway {
 
 // HasHookMessage 是否有Hook消息
 func (s *sGateway) HasHookMessage() bool {
-	return s.GatewayHookMap.Size() > 0
+	return s.GatewayHookMap.Size() > 0 || s.patternHookMap.Size() > 0
+}
+
+// Subscribe 订阅主题，主题包含通配符（* 匹配一个层级，# 匹配零个或多个层级）时作为通配符订阅；
+// 同一主题只能订阅一次，主题已被其他Hook订阅时返回错误，不覆盖已有的订阅
+func (s *sGateway) Subscribe(topic string, hook GatewayHook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isSubscribed(topic) {
+		return gerror.NewCodef(gcode.CodeInvalidOperation, "主题 %s 已被其他Hook订阅，同一主题只能注册一次", topic)
+	}
+
+	if base_enum.Hook.BusinessType.IsPattern(topic) {
+		s.patternHookMap.Set(topic, hook)
+		return nil
+	}
+	s.GatewayHookMap.Set(topic, hook)
+	return nil
+}
+
+// Unsubscribe 取消主题的订阅，之后可重新订阅该主题；主题未订阅时忽略
+func (s *sGateway) Unsubscribe(topic string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.GatewayHookMap.Remove(topic)
+	s.patternHookMap.Remove(topic)
+}
+
+// isSubscribed 主题是否已订阅
+func (s *sGateway) isSubscribed(topic string) bool {
+	return s.GatewayHookMap.Contains(topic) || s.patternHookMap.Contains(topic)
 }
 
-// BroadcastMessage 处理广播消息
-func (s *sGateway) BroadcastMessage(model base_model.HookModel) {
-	s.GatewayHookMap.Iterator(func(key string, hookFunc GatewayHook) bool {
-		hookFunc(model)
+// BroadcastMessage 处理广播消息，交由主题对应的网关Hook及匹配的通配符订阅处理，返回订阅者执行过程中产生的错误。
+// 旧版本按Go类型名发送的消息，按声明的兼容映射转换为主题后再路由。
+// 启用去重时，已处理成功的消息不再调用订阅者，见 DedupeStore。
+func (s *sGateway) BroadcastMessage(model base_model.HookModel) error {
+	model.BusinessTypeStr = base_enum.Hook.BusinessType.Resolve(model.BusinessTypeStr)
+
+	return dedupeMessage(model, s.broadcast)
+}
+
+// broadcast 将消息交由主题对应的网关Hook及匹配的通配符订阅处理，不经过去重
+func (s *sGateway) broadcast(model base_model.HookModel) error {
+	model.BusinessTypeStr = base_enum.Hook.BusinessType.Resolve(model.BusinessTypeStr)
+
+	hookArr := make([]GatewayHook, 0)
+	if hookFunc, ok := s.GatewayHookMap.Search(model.BusinessTypeStr); ok && hookFunc != nil {
+		hookArr = append(hookArr, hookFunc)
+	}
+	s.patternHookMap.Iterator(func(pattern string, v interface{}) bool {
+		if base_enum.Hook.BusinessType.Match(pattern, model.BusinessTypeStr) {
+			hookArr = append(hookArr, v.(GatewayHook))
+		}
 		return true
 	})
+
+	var errArr []error
+	for _, hookFunc := range hookArr {
+		errArr = append(errArr, hookFunc(model))
+	}
+
+	return errors.Join(errArr...)
 }
 
 // RegisterHookMessage 注册Hook消息,
 func RegisterHookMessage[K any, F any](hook *BaseHook[K, F]) bool {
-	if gateway.GatewayHookMap.Contains(hook.GetBusinessType().Code()) {
+	if gateway.isSubscribed(hook.GetBusinessType().Code()) {
 		return false
 	}
 
 	//【预注册】相当于是将业务层的hookFunc预先封装成一个函数，并且将其存储在gateway.GatewayHookMap中。然后在后续的有发布消息时，就可以通过这个函数来调用了。
-	var gatewayHook GatewayHook = func(model base_model.HookModel) {
-		gateway.GatewayHookMap.Iterator(func(key string, hookFunc GatewayHook) bool {
-			if model.BusinessType().Code() == key {
-				var option = Option{}
-				_ = gconv.Struct(model.Data, &option)
-				// 如果是网络消息，则不进行调用, 因这里是网络消息，所以强制改成false，防止循环调用
-				option.NetMessage = false
-				PublishHookMessage(context.Background(), hook, option)
-			}
-			return true
-		})
+	var gatewayHook GatewayHook = func(model base_model.HookModel) error {
+		ctx := model.Ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		var option = Option{}
+		if model.ContentType == "" {
+			// 未按载荷约定编码的消息，Data 为发布方的 Option
+			_ = gconv.Struct(model.Data, &option)
+		}
+		// 按载荷约定解码及校验业务数据，声明了载荷类型时解码为该类型
+		payload, err := decodeSchemaPayload(ctx, model, option.Data)
+		if err != nil {
+			return err
+		}
+		option.Data = payload
+		// 如果是网络消息，则不进行调用, 因这里是网络消息，所以强制改成false，防止循环调用
+		option.NetMessage = false
+		// 网关已按主题完成路由，使用本地的订阅函数类型，兼容通配符订阅及重命名的Go函数类型
+		var valueObj F
+		option.HookTypeStr = reflect.TypeOf(valueObj).String()
+
+		return errors.Join(PublishHookMessage(ctx, hook, option)...)
 	}
 
-	gateway.GatewayHookMap.Set(hook.GetBusinessType().Code(), gatewayHook)
-	return true
+	return gateway.Subscribe(hook.GetBusinessType().Code(), gatewayHook) == nil
 }
 
-// PublishHookMessage 发布Hook消息
+// PublishHookMessage 发布Hook消息，按 option.Dispatch 调度本地订阅者，返回同步及异步等待方式下订阅者的错误
 func PublishHookMessage[K any, F any](ctx context.Context, hook *BaseHook[K, F], option Option) []error {
-	var errArr []error
 	var dataKind = reflect.TypeOf(option.Data)
 	var srcDataArr []interface{}
 
 	// 如果option.Data是数组类型，则将数据断言为数组类型
 	if dataKind.Kind() == reflect.Array || dataKind.Kind() == reflect.Slice {
-		srcDataArr = option.Data.([]interface{})
+		srcDataArr = gconv.Interfaces(option.Data)
 	} else { // 如果option.Data不是数组类型，则强制构建一个数组，进行赋值
 		srcDataArr = []interface{}{option.Data}
 	}
 
-	// 如果是网络消息，则不进行调用
-	hook.Iterator(func(key K, value F) {
-		// 过滤掉不匹配的hook订阅
-		if option.NetMessage == false && option.HookTypeStr != reflect.TypeOf(value).String() {
-			return
+	// 过滤掉不匹配的hook订阅
+	entries := make([]hookEntry[K, F], 0)
+	for _, entry := range hook.snapshot() {
+		if option.NetMessage == false && !sameHookType(option.HookTypeStr, reflect.TypeOf(entry.Value).String()) {
+			continue
 		}
+		entries = append(entries, entry)
+	}
 
+	// 按调度选项调用订阅者，默认同步调用，调用过程经过全局及Hook的中间件
+	dispatchOption := option.Dispatch
+	if dispatchOption.Mode == nil {
+		dispatchOption.Mode = base_enum.Hook.DispatchMode.Sync
+	}
+	err := hook.dispatch(ctx, entries, func(ctx context.Context, key K, value F) error {
+		var errArr []error
 		var err error
 		err = g.Try(ctx, func(ctx context.Context) {
 			of := reflect.ValueOf(value) // 获取回调函数的反射对象
@@ -156,9 +243,8 @@ func PublishHookMessage[K any, F any](ctx context.Context, hook *BaseHook[K, F],
 
 			// 如果回调函数返回了错误，则返回错误
 			if len(retArr) > 0 {
-				ret := retArr[0].Interface()
-				if ret != nil {
-					errors.As(err, &ret)
+				if ret, ok := retArr[0].Interface().(error); ok && ret != nil {
+					errArr = append(errArr, ret)
 				}
 			}
 		})
@@ -166,10 +252,31 @@ func PublishHookMessage[K any, F any](ctx context.Context, hook *BaseHook[K, F],
 			g.Log().Error(ctx, err)
 			errArr = append(errArr, err)
 		}
-	}, Option{
-		Data:        option.Data,
-		NetMessage:  option.NetMessage,
-		HookTypeStr: option.HookTypeStr,
-	})
+		return errors.Join(errArr...)
+	}, dispatchOption)
+
+	var errArr []error
+	if joinErr, ok := err.(interface{ Unwrap() []error }); ok {
+		errArr = joinErr.Unwrap()
+	} else if err != nil {
+		errArr = append(errArr, err)
+	}
+
+	// 如果是网络消息，则投递给其他服务
+	if option.Data != nil && option.NetMessage {
+		hook.publishAsync(ctx, option)
+	}
+
 	return errArr
 }
+
+// sameHookType 两个订阅函数类型名是否相同，或按兼容映射对应同一个主题
+func sameHookType(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	topicA, okA := base_enum.Hook.BusinessType.Lookup(a)
+	topicB, okB := base_enum.Hook.BusinessType.Lookup(b)
+	return okA && okB && topicA.Code() == topicB.Code()
+}
@@ -0,0 +1,93 @@
+package base_hook
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	各传输方式共用的接收流程：
+		1、校验消息签名及时效，按兼容映射将Go类型名转换为主题，校验载荷的编码方式及版本
+		2、请求消息交给应答函数，应答通过 replyWriter 返回；不支持应答的传输方式直接拒绝
+		3、业务消息经去重认领后写入事件日志，再交给 InboundHandler 处理，默认为网关广播
+		4、返回回复给发送方的确认消息
+*/
+
+var ErrCallNotSupported = gerror.NewCode(gcode.CodeNotSupported, "该传输方式不支持Hook请求")
+
+// InboundHandler 接收到业务消息后的处理函数，为nil时交由网关广播给订阅者
+type InboundHandler func(model base_model.HookModel) error
+
+// replyWriter 向请求方回写消息的连接
+type replyWriter interface {
+	write(ctx context.Context, model base_model.HookModel) error
+}
+
+// receiveHookModel 处理接收到的消息，返回需要回复的确认消息；未携带消息ID的旧版本消息无需确认，此时 ok 为false
+func receiveHookModel(ctx context.Context, data *base_model.HookModel, handler InboundHandler, writer replyWriter) (reply base_model.HookModel, ok bool) {
+	// 延续发布方的调用链路
+	data.Ctx = withTraceId(ctx, data.TraceId)
+
+	// 校验消息签名及时效，通过后处理广播消息
+	err := verifyHookModel(ctx, data)
+	// 签名校验后，按声明的兼容映射将旧版本发送的Go类型名转换为主题
+	data.BusinessTypeStr = base_enum.Hook.BusinessType.Resolve(data.BusinessTypeStr)
+	if err == nil {
+		// 校验载荷的编码方式及版本，本服务无法处理的消息回复nack
+		err = checkEnvelope(*data)
+	}
+	if err != nil {
+		glog.Warning(ctx, err)
+	} else if data.MessageType().Code() == base_enum.Hook.MessageType.Request.Code() {
+		// 请求消息：确认接收后异步调用应答函数，应答通过同一连接返回
+		if writer == nil {
+			err = ErrCallNotSupported
+		} else {
+			err = serveCall(*data, writer)
+		}
+	} else {
+		// 去重认领成功后写入事件日志，再交由订阅者处理，重复投递的消息不会重复写入；写入失败时回复nack，由发送方重新投递
+		if handler == nil {
+			handler = gateway.broadcast
+		}
+		err = dedupeMessage(*data, func(model base_model.HookModel) error {
+			if err := appendEventLog(model.Ctx, model); err != nil {
+				return err
+			}
+			return handler(model)
+		})
+	}
+
+	if data.MessageId == "" {
+		return reply, false
+	}
+
+	reply = base_model.HookModel{
+		MessageId:       data.MessageId,
+		MessageTypeStr:  base_enum.Hook.MessageType.Ack.Code(),
+		BusinessTypeStr: data.BusinessTypeStr,
+	}
+	if err != nil {
+		reply.MessageTypeStr = base_enum.Hook.MessageType.Nack.Code()
+		setReplyError(&reply, err)
+	}
+
+	return reply, true
+}
+
+// newHostInfo 根据对端地址构建来源信息
+func newHostInfo(remoteAddr string) *base_model.HookHostInfo {
+	addr := strings.Split(remoteAddr, ":")
+	info := &base_model.HookHostInfo{Host: addr[0]}
+	if len(addr) > 1 {
+		info.Port = gconv.Int(addr[1])
+	}
+	return info
+}
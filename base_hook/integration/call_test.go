@@ -0,0 +1,174 @@
+package hook_integration
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/kysion/base-library/base_hook"
+	"github.com/kysion/base-library/base_model"
+)
+
+// TestCallRemoteError 对端返回的错误转换为 RemoteError，保留对端的错误码
+func TestCallRemoteError(t *testing.T) {
+	cases := []struct {
+		name     string
+		handler  base_hook.CallHandler
+		wantCode int
+		wantMsg  string
+	}{
+		{
+			name: "builtin code",
+			handler: func(ctx context.Context, request base_model.HookModel, stream base_hook.ReplyStream) (interface{}, error) {
+				return nil, gerror.NewCode(gcode.CodeInvalidParameter, "参数错误")
+			},
+			wantCode: gcode.CodeInvalidParameter.Code(),
+			wantMsg:  "参数错误",
+		},
+		{
+			name: "custom code",
+			handler: func(ctx context.Context, request base_model.HookModel, stream base_hook.ReplyStream) (interface{}, error) {
+				return nil, gerror.NewCode(gcode.New(10001, "", nil), "余额不足")
+			},
+			wantCode: 10001,
+			wantMsg:  "余额不足",
+		},
+		{
+			name: "no code",
+			handler: func(ctx context.Context, request base_model.HookModel, stream base_hook.ReplyStream) (interface{}, error) {
+				return nil, errors.New("处理失败")
+			},
+			wantCode: gcode.CodeUnknown.Code(),
+			wantMsg:  "处理失败",
+		},
+		{
+			name: "panic",
+			handler: func(ctx context.Context, request base_model.HookModel, stream base_hook.ReplyStream) (interface{}, error) {
+				panic("应答函数异常")
+			},
+			wantCode: gcode.CodeInternalPanic.Code(),
+			wantMsg:  "应答函数异常",
+		},
+	}
+
+	for _, transport := range []string{base_hook.TransportWebsocket, base_hook.TransportTcp, base_hook.TransportInProc} {
+		for _, c := range cases {
+			t.Run(transport+"/"+c.name, func(t *testing.T) {
+				topic := topicOf(t, transport, "callError")
+				base_hook.HandleCall(topic, c.handler)
+
+				_, err := base_hook.Call(context.Background(), topic, "ping")
+				var remoteErr *base_hook.RemoteError
+				if !errors.As(err, &remoteErr) {
+					t.Fatalf("期望 RemoteError，实际为 %v", err)
+				}
+				if remoteErr.BusinessType != topic || !strings.Contains(remoteErr.Message, c.wantMsg) {
+					t.Fatalf("RemoteError 为 %+v", remoteErr)
+				}
+				if code := gerror.Code(err); code.Code() != c.wantCode {
+					t.Fatalf("错误码为 %d，期望 %d", code.Code(), c.wantCode)
+				}
+				if !errors.Is(err, base_hook.ErrMessageRejected) {
+					t.Fatalf("RemoteError 应视为 ErrMessageRejected：%v", err)
+				}
+			})
+		}
+	}
+}
+
+// TestCallNoHandler 对端没有应答函数时返回 CodeNotFound
+func TestCallNoHandler(t *testing.T) {
+	topic := topicOf(t, base_hook.TransportInProc, "callNoHandler")
+
+	_, err := base_hook.Call(context.Background(), topic, "ping")
+	if gerror.Code(err) != gcode.CodeNotFound {
+		t.Fatalf("期望 CodeNotFound，实际为 %v", err)
+	}
+}
+
+// TestCallTimeout 超时未收到应答时返回错误，应答函数使用调用方的截止时间
+func TestCallTimeout(t *testing.T) {
+	cases := []struct {
+		name   string
+		ctx    func() (context.Context, context.CancelFunc)
+		option base_hook.CallOption
+	}{
+		{
+			name: "caller deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 100*time.Millisecond)
+			},
+		},
+		{
+			name: "option timeout",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.Background(), func() {}
+			},
+			option: base_hook.CallOption{Timeout: 100 * time.Millisecond},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			topic := topicOf(t, base_hook.TransportInProc, "callTimeout")
+			deadlines := make(chan time.Time, 1)
+			release := make(chan struct{})
+			base_hook.HandleCall(topic, func(ctx context.Context, request base_model.HookModel, stream base_hook.ReplyStream) (interface{}, error) {
+				deadline, _ := ctx.Deadline()
+				deadlines <- deadline
+				// 调用方返回后再结束，避免应答函数的超时错误先于调用方超时到达
+				<-release
+				return nil, ctx.Err()
+			})
+
+			ctx, cancel := c.ctx()
+			defer cancel()
+			start := time.Now()
+			_, err := base_hook.Call(ctx, topic, "ping", c.option)
+			close(release)
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("等待应答 %s，期望按请求的超时时间返回", elapsed)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) || gerror.Code(err) != gcode.CodeOperationFailed {
+				t.Fatalf("期望等待应答超时，实际为 %v", err)
+			}
+
+			// 应答函数的截止时间与调用方一致（消息中的截止时间精确到毫秒）
+			deadline := <-deadlines
+			if diff := deadline.Sub(start.Add(100 * time.Millisecond)); diff < -5*time.Millisecond || diff > 50*time.Millisecond {
+				t.Fatalf("应答函数的截止时间相差 %s", diff)
+			}
+		})
+	}
+}
+
+// TestCallReplyOverflow 部分应答的处理速度跟不上对端发送的速度时，请求以 ErrCallReplyOverflow 失败
+func TestCallReplyOverflow(t *testing.T) {
+	topic := topicOf(t, base_hook.TransportInProc, "callOverflow")
+	sent := make(chan struct{})
+	base_hook.HandleCall(topic, func(ctx context.Context, request base_model.HookModel, stream base_hook.ReplyStream) (interface{}, error) {
+		defer close(sent)
+		for i := 0; i < 100; i++ {
+			if err := stream.Send(i); err != nil {
+				return nil, err
+			}
+		}
+		return "done", nil
+	})
+
+	reply, err := base_hook.Call(context.Background(), topic, "ping", base_hook.CallOption{
+		OnPartial: func(partial *gvar.Var) error {
+			// 对端发送完所有应答后再处理，使应答的缓冲区被填满
+			<-sent
+			return nil
+		},
+	})
+	if !errors.Is(err, base_hook.ErrCallReplyOverflow) || gerror.Code(err) != gcode.CodeOperationFailed {
+		t.Fatalf("期望 ErrCallReplyOverflow，实际为 %v, %v", reply, err)
+	}
+}
@@ -0,0 +1,207 @@
+package hook_integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"github.com/kysion/base-library/base_hook"
+	"github.com/kysion/base-library/base_model"
+)
+
+// 集成测试的测试环境：在本机启动多个服务节点，每个节点同时接收 websocket、http、tcp 及进程内传输的消息，
+// 各传输方式的服务地址写入配置 service.hook.routing.hosts，并按业务类型的前缀路由到对应的传输方式
+
+const nodeCount = 3
+
+// received 节点接收到的消息
+type received struct {
+	Transport    string
+	BusinessType string
+	Data         interface{}
+}
+
+// node 服务节点
+type node struct {
+	name   string
+	server *ghttp.Server
+	tcp    *base_hook.TcpServer
+	mu     sync.Mutex
+	inbox  []received
+}
+
+// handler 返回记录指定传输方式接收到的消息的处理函数
+func (n *node) handler(transport string) base_hook.InboundHandler {
+	return func(model base_model.HookModel) error {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		n.inbox = append(n.inbox, received{
+			Transport:    transport,
+			BusinessType: model.BusinessTypeStr,
+			Data:         model.Data,
+		})
+		return nil
+	}
+}
+
+// messages 获取指定业务类型的消息
+func (n *node) messages(businessType string) []received {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	result := make([]received, 0)
+	for _, item := range n.inbox {
+		if item.BusinessType == businessType {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// reset 清空节点接收到的消息
+func (n *node) reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.inbox = nil
+}
+
+var nodes []*node
+
+func TestMain(m *testing.M) {
+	adapter, err := gcfg.NewAdapterContent(makeConfig(nil))
+	if err != nil {
+		panic(err)
+	}
+	g.Cfg().SetAdapter(adapter)
+
+	ctx := context.Background()
+	hosts := map[string][]string{}
+	for i := 0; i < nodeCount; i++ {
+		n, err := startNode(ctx, i)
+		if err != nil {
+			panic(err)
+		}
+		nodes = append(nodes, n)
+
+		port := n.server.GetListenedPort()
+		hosts[base_hook.TransportWebsocket] = append(hosts[base_hook.TransportWebsocket], fmt.Sprintf("127.0.0.1:%d", port))
+		hosts[base_hook.TransportHttp] = append(hosts[base_hook.TransportHttp], fmt.Sprintf("127.0.0.1:%d", port))
+		hosts[base_hook.TransportTcp] = append(hosts[base_hook.TransportTcp], n.tcp.Addr())
+		hosts[base_hook.TransportInProc] = append(hosts[base_hook.TransportInProc], n.name)
+	}
+	if err = adapter.SetContent(makeConfig(hosts)); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_ = base_hook.ShutdownTransports(shutdownCtx)
+	for _, n := range nodes {
+		_ = n.tcp.Close()
+		_ = n.server.Shutdown()
+	}
+
+	os.Exit(code)
+}
+
+// startNode 启动一个服务节点
+func startNode(ctx context.Context, index int) (*node, error) {
+	n := &node{name: fmt.Sprintf("node-%d", index)}
+
+	n.server = g.Server(n.name)
+	n.server.SetAddr("127.0.0.1:0")
+	n.server.SetDumpRouterMap(false)
+	n.server.SetAccessLogEnabled(false)
+	n.server.BindHandler("/ws", base_hook.HookDistributionWith(n.handler(base_hook.TransportWebsocket)))
+	n.server.BindHandler("/hook/http", base_hook.HookHttpDistributionWith(n.handler(base_hook.TransportHttp)))
+	if err := n.server.Start(); err != nil {
+		return nil, err
+	}
+
+	tcp, err := base_hook.ServeTCP(ctx, "127.0.0.1:0", n.handler(base_hook.TransportTcp))
+	if err != nil {
+		return nil, err
+	}
+	n.tcp = tcp
+
+	base_hook.DefaultBroker.Listen(n.name, n.handler(base_hook.TransportInProc))
+
+	return n, nil
+}
+
+// makeConfig 生成测试配置：业务类型按 it.<传输方式>.# 路由，消息使用hmac签名
+func makeConfig(hosts map[string][]string) string {
+	return gjson.MustEncodeString(g.Map{
+		"service": g.Map{
+			"hook": g.Map{
+				"routing": g.Map{
+					"default": base_hook.TransportWebsocket,
+					"topics": g.MapStrStr{
+						"it.http.#":   base_hook.TransportHttp,
+						"it.tcp.#":    base_hook.TransportTcp,
+						"it.inproc.#": base_hook.TransportInProc,
+					},
+					"hosts": hosts,
+				},
+				"transport": g.Map{
+					"ackTimeout": "2s",
+				},
+				"http": g.Map{
+					"batchSize":     16,
+					"batchInterval": "5ms",
+				},
+				"tcp": g.Map{
+					"ackTimeout": "2s",
+				},
+				"call": g.Map{
+					"timeout": "5s",
+				},
+				"security": g.Map{
+					"mode":   "hmac",
+					"nodeId": "integration",
+					"secret": "integration-secret",
+				},
+			},
+		},
+	})
+}
+
+// topicSeq 业务类型的序号，使 -count 多次运行时每次使用不同的业务类型
+var topicSeq atomic.Int64
+
+// topicOf 返回路由到指定传输方式的业务类型，每次调用均不同；测试结束时取消网关订阅、移除应答函数并清空节点接收到的消息
+func topicOf(t *testing.T, transport, name string) string {
+	prefix := "it." + transport
+	if transport == base_hook.TransportWebsocket {
+		prefix = "it.ws"
+	}
+	topic := fmt.Sprintf("%s.%s.%d", prefix, name, topicSeq.Add(1))
+
+	t.Cleanup(func() {
+		base_hook.Gateway().Unsubscribe(topic)
+		base_hook.RemoveCallHandler(topic)
+		for _, n := range nodes {
+			n.reset()
+		}
+	})
+	return topic
+}
+
+var transports = []string{
+	base_hook.TransportWebsocket,
+	base_hook.TransportHttp,
+	base_hook.TransportTcp,
+	base_hook.TransportInProc,
+}
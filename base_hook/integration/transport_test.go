@@ -0,0 +1,159 @@
+package hook_integration
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/kysion/base-library/base_hook"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+// TestFanoutDelivery 各传输方式以fanout方式投递，所有节点均通过对应的传输方式收到消息
+func TestFanoutDelivery(t *testing.T) {
+	for _, transport := range transports {
+		t.Run(transport, func(t *testing.T) {
+			topic := topicOf(t, transport, "fanout")
+			hook := base_hook.NewTypedHook[int, string](topic)
+
+			err := hook.Publish(context.Background(), "hello", base_hook.TypedOption[int]{
+				NetMessage:   true,
+				DeliveryMode: base_enum.Hook.DeliveryMode.Fanout,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, n := range nodes {
+				messages := n.messages(topic)
+				if len(messages) != 1 {
+					t.Fatalf("%s 收到 %d 条消息，期望 1 条", n.name, len(messages))
+				}
+				if messages[0].Transport != transport {
+					t.Fatalf("%s 通过 %s 收到消息，期望 %s", n.name, messages[0].Transport, transport)
+				}
+				if payload := decodePayload(t, messages[0].Data); payload != `"hello"` {
+					t.Fatalf("%s 收到的载荷为 %s", n.name, payload)
+				}
+			}
+		})
+	}
+}
+
+// TestRoundRobinDelivery 各传输方式以roundRobin方式并发投递，消息均匀分布到所有节点且不丢失
+func TestRoundRobinDelivery(t *testing.T) {
+	const total = nodeCount * 20
+
+	for _, transport := range transports {
+		t.Run(transport, func(t *testing.T) {
+			topic := topicOf(t, transport, "roundRobin")
+			hook := base_hook.NewTypedHook[int, int](topic)
+
+			errArr := make([]error, total)
+			wg := sync.WaitGroup{}
+			for i := 0; i < total; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errArr[i] = hook.Publish(context.Background(), i, base_hook.TypedOption[int]{
+						NetMessage:   true,
+						DeliveryMode: base_enum.Hook.DeliveryMode.RoundRobin,
+					})
+				}(i)
+			}
+			wg.Wait()
+			if err := errors.Join(errArr...); err != nil {
+				t.Fatal(err)
+			}
+
+			seen := make(map[string]bool, total)
+			for _, n := range nodes {
+				messages := n.messages(topic)
+				if len(messages) != total/nodeCount {
+					t.Fatalf("%s 收到 %d 条消息，期望 %d 条", n.name, len(messages), total/nodeCount)
+				}
+				for _, item := range messages {
+					seen[decodePayload(t, item.Data)] = true
+				}
+			}
+			if len(seen) != total {
+				t.Fatalf("收到 %d 条不同的消息，期望 %d 条", len(seen), total)
+			}
+		})
+	}
+}
+
+// TestCall 支持请求/应答的传输方式均可收到部分应答及最终应答
+func TestCall(t *testing.T) {
+	for _, transport := range []string{base_hook.TransportWebsocket, base_hook.TransportTcp, base_hook.TransportInProc} {
+		t.Run(transport, func(t *testing.T) {
+			topic := topicOf(t, transport, "call")
+			base_hook.HandleCall(topic, func(ctx context.Context, request base_model.HookModel, stream base_hook.ReplyStream) (interface{}, error) {
+				if err := stream.Send("partial"); err != nil {
+					return nil, err
+				}
+				return fmt.Sprintf("echo:%v", request.Data), nil
+			})
+
+			partials := make([]string, 0)
+			reply, err := base_hook.Call(context.Background(), topic, "ping", base_hook.CallOption{
+				OnPartial: func(partial *gvar.Var) error {
+					partials = append(partials, partial.String())
+					return nil
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reply.String() != "echo:ping" {
+				t.Fatalf("应答为 %s", reply.String())
+			}
+			if len(partials) != 1 || partials[0] != "partial" {
+				t.Fatalf("部分应答为 %v", partials)
+			}
+		})
+	}
+}
+
+// TestHttpCallNotSupported http传输不支持请求/应答，对端拒绝并返回错误码
+func TestHttpCallNotSupported(t *testing.T) {
+	topic := topicOf(t, base_hook.TransportHttp, "call")
+	base_hook.HandleCall(topic, func(ctx context.Context, request base_model.HookModel, stream base_hook.ReplyStream) (interface{}, error) {
+		return "unreachable", nil
+	})
+
+	_, err := base_hook.Call(context.Background(), topic, "ping")
+	if gerror.Code(err) != gcode.CodeNotSupported {
+		t.Fatalf("期望 CodeNotSupported，实际为 %v", err)
+	}
+}
+
+// TestUnknownPeer 进程内传输投递给未注册的服务时返回错误
+func TestUnknownPeer(t *testing.T) {
+	transport, ok := base_hook.GetTransport(base_hook.TransportInProc)
+	if !ok {
+		t.Fatal("未注册进程内传输")
+	}
+
+	err := transport.Send(context.Background(), "node-unknown", base_model.HookModel{BusinessTypeStr: topicOf(t, base_hook.TransportInProc, "unknown")})
+	if !errors.Is(err, base_hook.ErrPeerUnavailable) {
+		t.Fatalf("期望 ErrPeerUnavailable，实际为 %v", err)
+	}
+}
+
+// decodePayload 解码 TypedHook 的载荷
+func decodePayload(t *testing.T, data interface{}) string {
+	content, err := base64.StdEncoding.DecodeString(gconv.String(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
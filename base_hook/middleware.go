@@ -0,0 +1,218 @@
+package base_hook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/gtrace"
+)
+
+/*
+	Hook中间件：
+		1、中间件形如 func(next HandlerFunc) HandlerFunc，可全局注册（Use）或按Hook注册（BaseHook.Use、TypedHook.Use）
+		2、调用顺序：全局中间件在外层，Hook中间件在内层，同一层级按注册顺序由外向内
+		3、内置中间件：日志、耗时统计、失败重试、熔断、链路ID传递
+*/
+
+var ErrCircuitOpen = gerror.New("Hook订阅者熔断中")
+
+// HookCall 订阅者的调用信息
+type HookCall struct {
+	BusinessType string // 业务类型
+	Subscriber   string // 订阅函数名称
+	Filter       any    // 订阅者的过滤条件
+	Payload      any    // 消息载荷，仅 TypedHook 提供
+	TraceId      string // 链路ID
+}
+
+// HandlerFunc 订阅者调用函数
+type HandlerFunc func(ctx context.Context, call *HookCall) error
+
+// Middleware Hook中间件
+type Middleware func(next HandlerFunc) HandlerFunc
+
+var (
+	middlewareMu      sync.RWMutex
+	globalMiddlewares []Middleware
+)
+
+// Use 注册全局中间件，对所有Hook生效
+func Use(middleware ...Middleware) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+
+	globalMiddlewares = append(globalMiddlewares, middleware...)
+}
+
+// Use 注册当前Hook的中间件
+func (s *BaseHook[T, F]) Use(middleware ...Middleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.middlewares = append(s.middlewares, middleware...)
+}
+
+// applyMiddleware 使用全局及当前Hook的中间件包装调用函数
+func (s *BaseHook[T, F]) applyMiddleware(handler HandlerFunc) HandlerFunc {
+	s.mu.RLock()
+	middlewares := s.middlewares
+	s.mu.RUnlock()
+
+	return chainMiddleware(handler, middlewares)
+}
+
+// chainMiddleware 依次使用Hook中间件、全局中间件包装调用函数
+func chainMiddleware(handler HandlerFunc, hookMiddlewares []Middleware) HandlerFunc {
+	middlewareMu.RLock()
+	middlewares := append(append([]Middleware{}, globalMiddlewares...), hookMiddlewares...)
+	middlewareMu.RUnlock()
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// LoggingMiddleware 记录每次订阅者调用的业务类型、订阅者、链路ID、耗时及错误
+func LoggingMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, call *HookCall) error {
+			start := time.Now()
+			err := next(ctx, call)
+
+			if err != nil {
+				g.Log().Errorf(ctx, "hook=%s subscriber=%s traceId=%s duration=%s error=%v", call.BusinessType, call.Subscriber, call.TraceId, time.Since(start), err)
+			} else {
+				g.Log().Debugf(ctx, "hook=%s subscriber=%s traceId=%s duration=%s", call.BusinessType, call.Subscriber, call.TraceId, time.Since(start))
+			}
+
+			return err
+		}
+	}
+}
+
+// MetricsCollector 订阅者调用指标的收集器
+type MetricsCollector interface {
+	// Observe 记录一次订阅者调用的耗时及结果
+	Observe(call *HookCall, duration time.Duration, err error)
+}
+
+// MetricsMiddleware 统计每次订阅者调用的耗时，交由 collector 记录
+func MetricsMiddleware(collector MetricsCollector) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, call *HookCall) error {
+			start := time.Now()
+			err := next(ctx, call)
+			collector.Observe(call, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// RetryMiddleware 订阅者返回错误时按指数退避重试，maxAttempts 为最大调用次数（含首次调用）
+func RetryMiddleware(maxAttempts int, baseBackoff, maxBackoff time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, call *HookCall) error {
+			var err error
+			for attempt := 1; attempt <= maxAttempts; attempt++ {
+				if err = next(ctx, call); err == nil {
+					return nil
+				}
+
+				if attempt == maxAttempts {
+					break
+				}
+
+				select {
+				case <-time.After(backoffDuration(baseBackoff, maxBackoff, attempt)):
+				case <-ctx.Done():
+					return gerror.Wrap(err, ctx.Err().Error())
+				}
+			}
+			return err
+		}
+	}
+}
+
+// circuitState 单个订阅者的熔断状态
+type circuitState struct {
+	failures  int       // 连续失败次数
+	openUntil time.Time // 熔断结束时间
+	probing   bool      // 半开状态下是否已有试探调用
+}
+
+// CircuitBreakerMiddleware 订阅者连续失败 threshold 次后熔断，熔断期间直接返回 ErrCircuitOpen；
+// 经过 cooldown 后进入半开状态，允许一次试探调用，成功则恢复，失败则继续熔断。
+func CircuitBreakerMiddleware(threshold int, cooldown time.Duration) Middleware {
+	mu := sync.Mutex{}
+	states := make(map[string]*circuitState)
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, call *HookCall) error {
+			key := call.BusinessType + "#" + call.Subscriber
+
+			mu.Lock()
+			state, ok := states[key]
+			if !ok {
+				state = &circuitState{}
+				states[key] = state
+			}
+			if state.failures >= threshold {
+				if time.Now().Before(state.openUntil) || state.probing {
+					mu.Unlock()
+					return gerror.Wrap(ErrCircuitOpen, call.Subscriber)
+				}
+				state.probing = true
+			}
+			mu.Unlock()
+
+			err := next(ctx, call)
+
+			mu.Lock()
+			state.probing = false
+			if err != nil {
+				state.failures++
+				if state.failures >= threshold {
+					state.openUntil = time.Now().Add(cooldown)
+				}
+			} else {
+				state.failures = 0
+			}
+			mu.Unlock()
+
+			return err
+		}
+	}
+}
+
+// TraceMiddleware 将发布方的链路ID传递给订阅者：上下文中没有链路ID时使用调用信息中的链路ID
+func TraceMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, call *HookCall) error {
+			if gtrace.GetTraceID(ctx) == "" && call.TraceId != "" {
+				if traceCtx, err := gtrace.WithTraceID(ctx, call.TraceId); err == nil {
+					ctx = traceCtx
+				}
+			}
+			call.TraceId = gtrace.GetTraceID(ctx)
+			return next(ctx, call)
+		}
+	}
+}
+
+// withTraceId 将链路ID设置到上下文中，用于接收其他服务投递的消息
+func withTraceId(ctx context.Context, traceId string) context.Context {
+	if traceId == "" || gtrace.GetTraceID(ctx) == traceId {
+		return ctx
+	}
+
+	if traceCtx, err := gtrace.WithTraceID(ctx, traceId); err == nil {
+		return traceCtx
+	}
+
+	return ctx
+}
@@ -0,0 +1,225 @@
+package base_hook
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/net/gtrace"
+)
+
+// useGlobalMiddleware 注册测试使用的全局中间件，测试结束时恢复原有的全局中间件
+func useGlobalMiddleware(t *testing.T, middleware ...Middleware) {
+	middlewareMu.Lock()
+	saved := globalMiddlewares
+	globalMiddlewares = slices.Clone(saved)
+	middlewareMu.Unlock()
+
+	Use(middleware...)
+	t.Cleanup(func() {
+		middlewareMu.Lock()
+		globalMiddlewares = saved
+		middlewareMu.Unlock()
+	})
+}
+
+// traceMiddleware 在调用前后记录名称的中间件
+func traceMiddleware(trace *[]string, name string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, call *HookCall) error {
+			*trace = append(*trace, name+">")
+			err := next(ctx, call)
+			*trace = append(*trace, "<"+name)
+			return err
+		}
+	}
+}
+
+// countHandler 前 failures 次调用返回错误，之后返回 nil，并记录调用次数
+func countHandler(calls *int, failures int) HandlerFunc {
+	return func(ctx context.Context, call *HookCall) error {
+		*calls++
+		if *calls <= failures {
+			return errors.New("failed")
+		}
+		return nil
+	}
+}
+
+func TestMiddleware_Order(t *testing.T) {
+	var trace []string
+	useGlobalMiddleware(t, traceMiddleware(&trace, "g1"), traceMiddleware(&trace, "g2"))
+
+	hook := newTestBaseHook(t, 1)
+	hook.Use(traceMiddleware(&trace, "h1"))
+	hook.Use(traceMiddleware(&trace, "h2"))
+
+	var got *HookCall
+	hook.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, call *HookCall) error {
+			got = call
+			return next(ctx, call)
+		}
+	})
+	hook.InstallHook("a", func(ctx context.Context, order *testOrder) error {
+		trace = append(trace, "call")
+		return nil
+	})
+
+	// 全局中间件在外层，Hook中间件在内层，同一层级按注册顺序由外向内
+	if err := hook.Dispatch(context.Background(), callOrder); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"g1>", "g2>", "h1>", "h2>", "call", "<h2", "<h1", "<g2", "<g1"}
+	if !slices.Equal(trace, want) {
+		t.Fatalf("trace = %v, want %v", trace, want)
+	}
+	if got == nil || got.BusinessType != hook.GetBusinessType().Code() || got.Filter != "a" || got.Subscriber == "" {
+		t.Fatalf("call = %+v", got)
+	}
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	ctx := context.Background()
+	retry := RetryMiddleware(3, time.Millisecond, 2*time.Millisecond)
+
+	cases := []struct {
+		name      string
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "success", failures: 0, wantCalls: 1},
+		{name: "retry until success", failures: 2, wantCalls: 3},
+		{name: "max attempts", failures: 5, wantCalls: 3, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var calls int
+			err := retry(countHandler(&calls, c.failures))(ctx, &HookCall{})
+			if calls != c.wantCalls || (err != nil) != c.wantErr {
+				t.Fatalf("calls = %d, err = %v, want %d calls", calls, err, c.wantCalls)
+			}
+		})
+	}
+
+	// 退避期间上下文取消时停止重试
+	cancelCtx, cancel := context.WithCancel(ctx)
+	var calls int
+	handler := RetryMiddleware(3, time.Hour, time.Hour)(func(ctx context.Context, call *HookCall) error {
+		calls++
+		cancel()
+		return errors.New("failed")
+	})
+	if err := handler(cancelCtx, &HookCall{}); err == nil || calls != 1 {
+		t.Fatalf("cancelled = %v, calls %d, want error after 1 call", err, calls)
+	}
+}
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	ctx := context.Background()
+	cooldown := 20 * time.Millisecond
+	breaker := CircuitBreakerMiddleware(2, cooldown)
+
+	var (
+		calls int
+		fail  = true
+		call  = &HookCall{BusinessType: "test", Subscriber: "a"}
+	)
+	handler := breaker(func(ctx context.Context, call *HookCall) error {
+		calls++
+		if fail {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	// 连续失败达到阈值后熔断，不再调用订阅者
+	for i := 0; i < 2; i++ {
+		if err := handler(ctx, call); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d = %v, want the subscriber's error", i, err)
+		}
+	}
+	if err := handler(ctx, call); !errors.Is(err, ErrCircuitOpen) || calls != 2 {
+		t.Fatalf("open = %v, calls %d, want ErrCircuitOpen", err, calls)
+	}
+
+	// 按业务类型及订阅者分别熔断
+	if err := handler(ctx, &HookCall{BusinessType: "test", Subscriber: "b"}); errors.Is(err, ErrCircuitOpen) || calls != 3 {
+		t.Fatalf("other subscriber = %v, calls %d", err, calls)
+	}
+
+	// 冷却后试探调用失败时继续熔断
+	time.Sleep(cooldown)
+	if err := handler(ctx, call); errors.Is(err, ErrCircuitOpen) || calls != 4 {
+		t.Fatalf("probe = %v, calls %d, want the subscriber called", err, calls)
+	}
+	if err := handler(ctx, call); !errors.Is(err, ErrCircuitOpen) || calls != 4 {
+		t.Fatalf("after failed probe = %v, calls %d, want ErrCircuitOpen", err, calls)
+	}
+
+	// 冷却后试探调用成功时恢复
+	time.Sleep(cooldown)
+	fail = false
+	for i := 0; i < 2; i++ {
+		if err := handler(ctx, call); err != nil {
+			t.Fatalf("recovered call %d = %v", i, err)
+		}
+	}
+	if calls != 6 {
+		t.Fatalf("calls = %d, want 6", calls)
+	}
+}
+
+// testMetricsCollector 记录调用结果的指标收集器
+type testMetricsCollector struct {
+	errs []error
+}
+
+func (c *testMetricsCollector) Observe(call *HookCall, duration time.Duration, err error) {
+	c.errs = append(c.errs, err)
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	collector := &testMetricsCollector{}
+	var calls int
+	handler := MetricsMiddleware(collector)(countHandler(&calls, 1))
+
+	_ = handler(context.Background(), &HookCall{})
+	_ = handler(context.Background(), &HookCall{})
+	if len(collector.errs) != 2 || collector.errs[0] == nil || collector.errs[1] != nil {
+		t.Fatalf("observed = %v, want an error then nil", collector.errs)
+	}
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	const (
+		publisherTraceId = "0123456789abcdef0123456789abcdef"
+		callerTraceId    = "fedcba9876543210fedcba9876543210"
+	)
+
+	var got string
+	handler := TraceMiddleware()(func(ctx context.Context, call *HookCall) error {
+		got = gtrace.GetTraceID(ctx)
+		return nil
+	})
+
+	// 上下文中没有链路ID时使用发布方的链路ID
+	call := &HookCall{TraceId: publisherTraceId}
+	if err := handler(context.Background(), call); err != nil || got != publisherTraceId || call.TraceId != publisherTraceId {
+		t.Fatalf("trace id = %s, call %s, want %s", got, call.TraceId, publisherTraceId)
+	}
+
+	// 上下文中已有链路ID时保持不变
+	ctx, err := gtrace.WithTraceID(context.Background(), callerTraceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call = &HookCall{TraceId: publisherTraceId}
+	if err = handler(ctx, call); err != nil || got != callerTraceId || call.TraceId != callerTraceId {
+		t.Fatalf("trace id = %s, call %s, want %s", got, call.TraceId, callerTraceId)
+	}
+}
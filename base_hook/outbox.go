@@ -0,0 +1,412 @@
+package base_hook
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+	"github.com/kysion/base-library/utility/daoctl"
+)
+
+/*
+	事务性发件箱（Transactional Outbox）：
+		1、业务层在数据库事务内调用 PublishTx / WriteOutbox，消息与业务数据同时提交或回滚
+		2、中继协程 OutboxRelay 定时扫描待投递的消息，通过网关投递给其他服务：
+		   逐条以条件更新认领消息（将下次投递时间推迟 LeaseTimeout），认领成功的中继在事务外投递并单独更新状态，
+		   多个节点的中继不会互相阻塞；中继在认领后异常退出时，租约到期后由其他中继重新投递，接收方按消息ID去重
+		3、投递失败按指数退避重试，超过重试上限后转为死信，可通过 DeadLetters 查看、Requeue 重新投递
+		4、未设置或设置为非正数的配置项使用默认值；NewOutboxRelay 指定的配置同时作为 WriteOutbox 的默认配置，写入与投递使用同一张表
+*/
+
+// hookOutboxColumns 发件箱表字段
+var hookOutboxColumns = struct {
+	Id           string
+	BusinessType string
+	Payload      string
+	State        string
+	Attempts     string
+	LastError    string
+	NextRetryAt  string
+	DeliveredAt  string
+	CreatedAt    string
+	UpdatedAt    string
+}{
+	Id:           "id",
+	BusinessType: "business_type",
+	Payload:      "payload",
+	State:        "state",
+	Attempts:     "attempts",
+	LastError:    "last_error",
+	NextRetryAt:  "next_retry_at",
+	DeliveredAt:  "delivered_at",
+	CreatedAt:    "created_at",
+	UpdatedAt:    "updated_at",
+}
+
+// 发件箱配置未设置时使用的默认值
+const (
+	defaultOutboxTable        = "hook_outbox"
+	defaultOutboxInterval     = 2 * time.Second
+	defaultOutboxBatchSize    = 100
+	defaultOutboxMaxAttempts  = 10
+	defaultOutboxBaseBackoff  = time.Second
+	defaultOutboxMaxBackoff   = 10 * time.Minute
+	defaultOutboxLeaseTimeout = time.Minute
+)
+
+var (
+	outboxMu     sync.RWMutex
+	outboxOption *OutboxOption // 通过 SetOutboxOption 设置的发件箱配置
+)
+
+// OutboxOption 发件箱配置
+type OutboxOption struct {
+	Table        string        // 发件箱表名
+	Interval     time.Duration // 中继扫描间隔
+	BatchSize    int           // 每次扫描投递的最大消息数
+	MaxAttempts  int           // 最大投递次数，超过后转为死信
+	BaseBackoff  time.Duration // 首次重试的退避时间
+	MaxBackoff   time.Duration // 退避时间上限
+	LeaseTimeout time.Duration // 认领消息的租约时长，需大于单条消息的投递耗时，到期未更新状态的消息将被重新投递
+}
+
+// DefaultOutboxOption 从配置 service.hook.outbox 读取发件箱配置，未配置的项使用默认值
+func DefaultOutboxOption(ctx context.Context) OutboxOption {
+	return OutboxOption{
+		Table:        g.Cfg().MustGet(ctx, "service.hook.outbox.table", "hook_outbox").String(),
+		Interval:     g.Cfg().MustGet(ctx, "service.hook.outbox.interval", "2s").Duration(),
+		BatchSize:    g.Cfg().MustGet(ctx, "service.hook.outbox.batchSize", 100).Int(),
+		MaxAttempts:  g.Cfg().MustGet(ctx, "service.hook.outbox.maxAttempts", 10).Int(),
+		BaseBackoff:  g.Cfg().MustGet(ctx, "service.hook.outbox.baseBackoff", "1s").Duration(),
+		MaxBackoff:   g.Cfg().MustGet(ctx, "service.hook.outbox.maxBackoff", "10m").Duration(),
+		LeaseTimeout: g.Cfg().MustGet(ctx, "service.hook.outbox.leaseTimeout", "1m").Duration(),
+	}
+}
+
+// withDefaults 未设置或设置为非正数的项使用默认值，避免批量为0时不投递、租约为0时重复认领
+func (o OutboxOption) withDefaults() OutboxOption {
+	if o.Table == "" {
+		o.Table = defaultOutboxTable
+	}
+	if o.Interval <= 0 {
+		o.Interval = defaultOutboxInterval
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultOutboxBatchSize
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = defaultOutboxMaxAttempts
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = defaultOutboxBaseBackoff
+	}
+	if o.MaxBackoff < o.BaseBackoff {
+		o.MaxBackoff = max(defaultOutboxMaxBackoff, o.BaseBackoff)
+	}
+	if o.LeaseTimeout <= 0 {
+		o.LeaseTimeout = defaultOutboxLeaseTimeout
+	}
+	return o
+}
+
+// SetOutboxOption 设置 WriteOutbox、PublishTx 及 NewOutboxRelay 默认使用的发件箱配置，未设置的项使用默认值
+func SetOutboxOption(option OutboxOption) {
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+
+	option = option.withDefaults()
+	outboxOption = &option
+}
+
+// getOutboxOption 获取发件箱配置，未通过 SetOutboxOption 设置时读取 service.hook.outbox 配置
+func getOutboxOption(ctx context.Context) OutboxOption {
+	outboxMu.RLock()
+	option := outboxOption
+	outboxMu.RUnlock()
+
+	if option != nil {
+		return *option
+	}
+	return DefaultOutboxOption(ctx).withDefaults()
+}
+
+// PublishTx 在事务内发布跨进程Hook消息，消息写入发件箱，事务提交后由 OutboxRelay 投递给其他服务。
+// 注意：本进程内的订阅者不会被调用，需要时请在事务提交后调用 Iterator。
+func (s *BaseHook[T, F]) PublishTx(ctx context.Context, tx gdb.TX, option Option) error {
+	var valueObj F
+	option.HookTypeStr = reflect.TypeOf(valueObj).String()
+	option.NetMessage = true
+
+	data, err := newNetHookModel(s.GetBusinessType().Code(), option)
+	if err != nil {
+		return err
+	}
+
+	return writeOutbox(ctx, tx, data)
+}
+
+// WriteOutbox 在事务内将Hook消息写入发件箱；声明了载荷约定且尚未编码的载荷按约定编码，与直接发布的消息格式一致。
+// 未指定配置时使用 SetOutboxOption 或 NewOutboxRelay 指定的配置，均未指定时读取 service.hook.outbox 配置
+func WriteOutbox(ctx context.Context, tx gdb.TX, model base_model.HookModel, option ...OutboxOption) error {
+	if model.ContentType == "" {
+		if _, ok := GetSchema(model.BusinessTypeStr); ok {
+			if err := encodePayload(&model, model.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writeOutbox(ctx, tx, model, option...)
+}
+
+// writeOutbox 将已编码的消息写入发件箱
+func writeOutbox(ctx context.Context, tx gdb.TX, model base_model.HookModel, option ...OutboxOption) error {
+	conf := getOutboxOption(ctx)
+	if len(option) > 0 {
+		conf = option[0].withDefaults()
+	}
+
+	// 写入时分配消息ID，中继重复投递时保持不变，接收方据此去重
+	if model.MessageId == "" {
+		model.MessageId = guid.S()
+	}
+
+	payload, err := gjson.Encode(model)
+	if err != nil {
+		return gerror.Wrap(err, "Hook消息序列化失败")
+	}
+
+	now := gtime.Now()
+	_, err = daoctl.InsertWithError(tx.Model(conf.Table).Ctx(ctx), g.Map{
+		hookOutboxColumns.BusinessType: model.BusinessTypeStr,
+		hookOutboxColumns.Payload:      string(payload),
+		hookOutboxColumns.State:        base_enum.Hook.OutboxState.Pending.Code(),
+		hookOutboxColumns.Attempts:     0,
+		hookOutboxColumns.LastError:    "",
+		hookOutboxColumns.NextRetryAt:  now,
+		hookOutboxColumns.CreatedAt:    now,
+		hookOutboxColumns.UpdatedAt:    now,
+	})
+	if err != nil {
+		return gerror.Wrap(err, "Hook消息写入发件箱失败")
+	}
+
+	return nil
+}
+
+// OutboxRelay 发件箱中继，负责将发件箱中的消息投递给其他服务
+type OutboxRelay struct {
+	db     gdb.DB
+	option OutboxOption
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewOutboxRelay 创建发件箱中继，未指定配置时使用 SetOutboxOption 设置的配置或读取 service.hook.outbox 配置；
+// 指定的配置同时通过 SetOutboxOption 作为写入发件箱的默认配置
+func NewOutboxRelay(db gdb.DB, option ...OutboxOption) *OutboxRelay {
+	conf := getOutboxOption(context.Background())
+	if len(option) > 0 {
+		conf = option[0].withDefaults()
+		SetOutboxOption(conf)
+	}
+
+	return &OutboxRelay{
+		db:     db,
+		option: conf,
+	}
+}
+
+// Start 启动中继协程，重复调用无副作用
+func (r *OutboxRelay) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.option.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.RelayOnce(ctx); err != nil {
+					g.Log().Error(ctx, err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止中继协程，并等待当前批次投递完成
+func (r *OutboxRelay) Stop() {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+}
+
+// RelayOnce 投递一批到期的待投递消息，返回投递成功的消息数
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (delivered int, err error) {
+	cols := hookOutboxColumns
+
+	rows := make([]*base_model.HookOutbox, 0)
+	err = r.db.Model(r.option.Table).Ctx(ctx).
+		Where(cols.State, base_enum.Hook.OutboxState.Pending.Code()).
+		WhereLTE(cols.NextRetryAt, gtime.Now()).
+		OrderAsc(cols.Id).
+		Limit(r.option.BatchSize).
+		Scan(&rows)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		claimed, err := r.claim(ctx, row)
+		if err != nil {
+			return delivered, err
+		}
+		if !claimed {
+			// 已被其他节点的中继认领
+			continue
+		}
+
+		data := base_model.HookModel{}
+		deliverErr := gjson.DecodeTo(row.Payload, &data)
+		if deliverErr == nil {
+			deliverErr = deliverHookModel(ctx, data)
+		}
+
+		model := r.db.Model(r.option.Table).Ctx(ctx).Where(cols.Id, row.Id)
+		if deliverErr == nil {
+			delivered++
+			_, err = daoctl.UpdateWithError(model, g.Map{
+				cols.State:       base_enum.Hook.OutboxState.Delivered.Code(),
+				cols.Attempts:    row.Attempts + 1,
+				cols.DeliveredAt: gtime.Now(),
+				cols.UpdatedAt:   gtime.Now(),
+			})
+		} else {
+			_, err = daoctl.UpdateWithError(model, r.makeFailedData(row, deliverErr))
+		}
+
+		if err != nil {
+			// 状态更新失败的消息在租约到期后重新投递，不影响本批次已投递的其他消息
+			g.Log().Errorf(ctx, "发件箱消息 %d 的投递状态更新失败：%v", row.Id, err)
+		}
+	}
+
+	return delivered, nil
+}
+
+// claim 认领消息：仅当消息仍为待投递且已到期时将下次投递时间推迟 LeaseTimeout，返回是否认领成功
+func (r *OutboxRelay) claim(ctx context.Context, row *base_model.HookOutbox) (bool, error) {
+	cols := hookOutboxColumns
+	now := gtime.Now()
+
+	rowsAffected, err := daoctl.UpdateWithError(r.db.Model(r.option.Table).Ctx(ctx).
+		Where(cols.Id, row.Id).
+		Where(cols.State, base_enum.Hook.OutboxState.Pending.Code()).
+		WhereLTE(cols.NextRetryAt, now),
+		g.Map{
+			cols.NextRetryAt: now.Add(r.option.LeaseTimeout),
+			cols.UpdatedAt:   now,
+		},
+	)
+	if err != nil {
+		return false, gerror.Wrapf(err, "发件箱消息 %d 认领失败", row.Id)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// makeFailedData 构建投递失败后的更新数据，超过重试上限则转为死信
+func (r *OutboxRelay) makeFailedData(row *base_model.HookOutbox, deliverErr error) g.Map {
+	cols := hookOutboxColumns
+	attempts := row.Attempts + 1
+
+	data := g.Map{
+		cols.Attempts:  attempts,
+		cols.LastError: deliverErr.Error(),
+		cols.UpdatedAt: gtime.Now(),
+	}
+
+	if attempts >= r.option.MaxAttempts {
+		data[cols.State] = base_enum.Hook.OutboxState.Dead.Code()
+	} else {
+		data[cols.NextRetryAt] = gtime.Now().Add(backoffDuration(r.option.BaseBackoff, r.option.MaxBackoff, attempts))
+	}
+
+	return data
+}
+
+// DeadLetters 分页查询超过重试上限的死信消息
+func (r *OutboxRelay) DeadLetters(ctx context.Context, pagination *base_model.Pagination) (*base_model.CollectRes[*base_model.HookOutbox], error) {
+	model := r.db.Model(r.option.Table).Ctx(ctx).
+		Where(hookOutboxColumns.State, base_enum.Hook.OutboxState.Dead.Code()).
+		OrderDesc(hookOutboxColumns.Id)
+
+	return daoctl.GetAll[base_model.HookOutbox](model, pagination)
+}
+
+// Requeue 将指定的死信消息重新放回待投递队列，返回受影响的行数
+func (r *OutboxRelay) Requeue(ctx context.Context, ids ...int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	cols := hookOutboxColumns
+	model := r.db.Model(r.option.Table).Ctx(ctx).
+		WhereIn(cols.Id, ids).
+		Where(cols.State, base_enum.Hook.OutboxState.Dead.Code())
+
+	return daoctl.UpdateWithError(model, g.Map{
+		cols.State:       base_enum.Hook.OutboxState.Pending.Code(),
+		cols.Attempts:    0,
+		cols.LastError:   "",
+		cols.NextRetryAt: gtime.Now(),
+		cols.UpdatedAt:   gtime.Now(),
+	})
+}
+
+// backoffDuration 计算第 attempt 次失败后的指数退避时间：base * 2^(attempt-1)，不超过 max
+func backoffDuration(base, max time.Duration, attempt int) time.Duration {
+	if attempt <= 1 {
+		return base
+	}
+
+	d := float64(base) * math.Pow(2, float64(attempt-1))
+	if d > float64(max) {
+		return max
+	}
+
+	return time.Duration(d)
+}
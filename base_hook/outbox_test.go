@@ -0,0 +1,99 @@
+package base_hook
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// useOutboxOption 测试期间使用指定的发件箱配置，结束后恢复
+func useOutboxOption(t *testing.T, option *OutboxOption) {
+	outboxMu.Lock()
+	prevOption := outboxOption
+	outboxOption = option
+	outboxMu.Unlock()
+
+	t.Cleanup(func() {
+		outboxMu.Lock()
+		outboxOption = prevOption
+		outboxMu.Unlock()
+	})
+}
+
+func TestOutboxOption_WithDefaults(t *testing.T) {
+	option := OutboxOption{}.withDefaults()
+
+	want := OutboxOption{
+		Table:        defaultOutboxTable,
+		Interval:     defaultOutboxInterval,
+		BatchSize:    defaultOutboxBatchSize,
+		MaxAttempts:  defaultOutboxMaxAttempts,
+		BaseBackoff:  defaultOutboxBaseBackoff,
+		MaxBackoff:   defaultOutboxMaxBackoff,
+		LeaseTimeout: defaultOutboxLeaseTimeout,
+	}
+	if option != want {
+		t.Fatalf("withDefaults() = %+v, want %+v", option, want)
+	}
+
+	custom := OutboxOption{
+		Table:        "custom_outbox",
+		Interval:     time.Second,
+		BatchSize:    10,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Minute,
+		MaxBackoff:   time.Hour,
+		LeaseTimeout: time.Second,
+	}
+	if got := custom.withDefaults(); got != custom {
+		t.Fatalf("withDefaults() changed configured option: %+v", got)
+	}
+
+	// 退避时间上限小于首次退避时间时使用默认上限
+	if got := (OutboxOption{BaseBackoff: time.Hour, MaxBackoff: time.Second}).withDefaults(); got.MaxBackoff != time.Hour {
+		t.Fatalf("MaxBackoff = %s, want 1h", got.MaxBackoff)
+	}
+}
+
+func TestNewOutboxRelay_SharesOption(t *testing.T) {
+	useConfig(t, `{"service":{"hook":{"outbox":{"table":"config_outbox","batchSize":0}}}}`)
+	useOutboxOption(t, nil)
+	ctx := context.Background()
+
+	// 未设置时读取配置，配置为非正数的项使用默认值
+	if option := getOutboxOption(ctx); option.Table != "config_outbox" || option.BatchSize != defaultOutboxBatchSize {
+		t.Fatalf("getOutboxOption() = %+v, want table from config and default batch size", option)
+	}
+	if relay := NewOutboxRelay(nil); relay.option.Table != "config_outbox" {
+		t.Fatalf("relay table = %s, want config_outbox", relay.option.Table)
+	}
+
+	// 中继指定的配置同时作为写入发件箱的默认配置
+	relay := NewOutboxRelay(nil, OutboxOption{Table: "custom_outbox"})
+	if relay.option.Table != "custom_outbox" || relay.option.LeaseTimeout != defaultOutboxLeaseTimeout {
+		t.Fatalf("relay option = %+v, want custom table with defaults", relay.option)
+	}
+	if option := getOutboxOption(ctx); option != relay.option {
+		t.Fatalf("getOutboxOption() = %+v, want relay option %+v", option, relay.option)
+	}
+}
+
+func TestBackoffDuration(t *testing.T) {
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: time.Second},
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 7, want: time.Minute},
+		{attempt: 100, want: time.Minute},
+	}
+
+	for _, c := range cases {
+		if got := backoffDuration(time.Second, time.Minute, c.attempt); got != c.want {
+			t.Fatalf("backoffDuration(%d) = %s, want %s", c.attempt, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package base_hook
+
+import (
+	"encoding/json"
+
+	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+/*
+	网络消息载荷的编解码器注册表，按 ContentType 查找：
+		application/json        JSON（默认）
+		application/msgpack     MessagePack，适用于载荷较大或包含大量数字的消息
+		application/x-protobuf  protobuf字节，载荷需实现 ProtoMessage 或为 []byte
+*/
+
+// 内置的载荷编码方式
+const (
+	ContentTypeJson     = "application/json"
+	ContentTypeMsgpack  = "application/msgpack"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+var ErrUnknownContentType = gerror.NewCode(gcode.CodeNotSupported, "不支持的Hook载荷编码方式")
+
+// PayloadCodec 载荷编解码器
+type PayloadCodec interface {
+	// ContentType 编码方式
+	ContentType() string
+	// Marshal 编码载荷
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal 解码载荷，v 为指针
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// payloadCodecMap 已注册的编解码器，key为编码方式，value为 PayloadCodec
+var payloadCodecMap = gmap.NewStrAnyMap(true)
+
+func init() {
+	RegisterPayloadCodec(JsonPayloadCodec{})
+	RegisterPayloadCodec(MsgpackPayloadCodec{})
+	RegisterPayloadCodec(ProtobufPayloadCodec{})
+}
+
+// RegisterPayloadCodec 注册载荷编解码器，同一编码方式重复注册时覆盖
+func RegisterPayloadCodec(codec PayloadCodec) {
+	payloadCodecMap.Set(codec.ContentType(), codec)
+}
+
+// GetPayloadCodec 获取载荷编解码器
+func GetPayloadCodec(contentType string) (PayloadCodec, error) {
+	v := payloadCodecMap.Get(contentType)
+	if v == nil {
+		return nil, gerror.WrapCode(gcode.CodeNotSupported, ErrUnknownContentType, contentType)
+	}
+	return v.(PayloadCodec), nil
+}
+
+// JsonPayloadCodec JSON编解码器
+type JsonPayloadCodec struct{}
+
+func (JsonPayloadCodec) ContentType() string {
+	return ContentTypeJson
+}
+
+func (JsonPayloadCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JsonPayloadCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// ProtoMessage protobuf消息，gogo/protobuf、vtprotobuf 等生成的类型均实现了该接口
+type ProtoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal(data []byte) error
+}
+
+// ProtobufPayloadCodec protobuf字节编解码器，载荷需实现 ProtoMessage 或为 []byte
+type ProtobufPayloadCodec struct{}
+
+func (ProtobufPayloadCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (ProtobufPayloadCodec) Marshal(v interface{}) ([]byte, error) {
+	switch value := v.(type) {
+	case ProtoMessage:
+		return value.Marshal()
+	case []byte:
+		return value, nil
+	default:
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "protobuf载荷需实现 ProtoMessage：%T", v)
+	}
+}
+
+func (ProtobufPayloadCodec) Unmarshal(data []byte, v interface{}) error {
+	switch value := v.(type) {
+	case ProtoMessage:
+		return value.Unmarshal(data)
+	case *[]byte:
+		*value = append([]byte(nil), data...)
+	case *interface{}:
+		*value = append([]byte(nil), data...)
+	default:
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "protobuf载荷需实现 ProtoMessage：%T", v)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package base_hook
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+/*
+	MessagePack编解码器：
+		1、编码时先按JSON规则（json 标签、Marshaler）转换为通用结构，再编码为MessagePack，与JSON编码的字段保持一致
+		2、解码时将MessagePack解码为通用结构，再按JSON规则转换为目标类型
+		3、支持 nil、bool、整数（含 int64、uint64 的全部范围）、浮点数、字符串、二进制、数组及字符串为键的映射，不支持扩展类型
+		4、与JSON编码一致：[]byte 编码为Base64字符串，解码到 interface{} 时数值为 float64；解码其他服务发送的二进制格式时按Base64字符串转换
+*/
+
+// MsgpackPayloadCodec MessagePack编解码器
+type MsgpackPayloadCodec struct{}
+
+func (MsgpackPayloadCodec) ContentType() string {
+	return ContentTypeMsgpack
+}
+
+func (MsgpackPayloadCodec) Marshal(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err = decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err = msgpackEncode(buf, generic); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (MsgpackPayloadCodec) Unmarshal(data []byte, v interface{}) error {
+	d := &msgpackDecoder{data: data}
+	generic, err := d.decode()
+	if err != nil {
+		return err
+	}
+	if d.pos != len(data) {
+		return gerror.New("MessagePack数据末尾存在多余的字节")
+	}
+
+	raw, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// msgpackEncode 编码通用结构
+func msgpackEncode(buf *bytes.Buffer, v interface{}) error {
+	switch value := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if value {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			msgpackEncodeInt(buf, i)
+		} else if u, err := strconv.ParseUint(value.String(), 10, 64); err == nil {
+			// 超出 int64 范围的无符号整数
+			buf.WriteByte(0xcf)
+			_ = binary.Write(buf, binary.BigEndian, u)
+		} else if f, err := value.Float64(); err == nil {
+			buf.WriteByte(0xcb)
+			_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		} else {
+			return err
+		}
+	case string:
+		msgpackEncodeLength(buf, len(value), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buf.WriteString(value)
+	case []interface{}:
+		msgpackEncodeLength(buf, len(value), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range value {
+			if err := msgpackEncode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		msgpackEncodeLength(buf, len(value), 0x80, 15, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			_ = msgpackEncode(buf, k)
+			if err := msgpackEncode(buf, value[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return gerror.Newf("MessagePack不支持的类型：%T", v)
+	}
+	return nil
+}
+
+// msgpackEncodeInt 按数值范围选择最短的整数格式
+func msgpackEncodeInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		buf.WriteByte(byte(i))
+	case i >= -32 && i < 0:
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		_ = binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		_ = binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		_ = binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// msgpackEncodeLength 写入字符串、数组或映射的长度头，fix 为短格式的起始字节，head8 为0时表示没有8位长度格式
+func msgpackEncodeLength(buf *bytes.Buffer, n int, fix byte, fixMax int, head8, head16, head32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case head8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(head8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(head16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(head32)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+// msgpackDecoder MessagePack解码器
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+// read 读取 n 个字节
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, gerror.New("MessagePack数据不完整")
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// readUint 读取 n 字节的大端序无符号整数
+func (d *msgpackDecoder) readUint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// decode 解码一个值为通用结构
+func (d *msgpackDecoder) decode() (interface{}, error) {
+	head, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	c := head[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c >= 0xa0 && c <= 0xbf:
+		return d.decodeString(int(c & 0x1f))
+	case c >= 0x90 && c <= 0x9f:
+		return d.decodeArray(int(c & 0x0f))
+	case c >= 0x80 && c <= 0x8f:
+		return d.decodeMap(int(c & 0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.readUint(1 << (c - 0xcc))
+	case 0xd0:
+		v, err := d.readUint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := d.readUint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := d.readUint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := d.readUint(8)
+		return int64(v), err
+	case 0xca:
+		v, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.readUint(8)
+		return math.Float64frombits(v), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readUint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(n))
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readUint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(int(n))
+		return append([]byte(nil), b...), err
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n))
+	}
+
+	return nil, gerror.Newf("MessagePack不支持的格式：0x%x", c)
+}
+
+func (d *msgpackDecoder) decodeString(n int) (interface{}, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *msgpackDecoder) decodeArray(n int) (interface{}, error) {
+	// 每个元素至少一个字节，避免恶意的长度导致分配过大的内存
+	if n > len(d.data)-d.pos {
+		return nil, gerror.New("MessagePack数据不完整")
+	}
+
+	result := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		item, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n int) (interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, gerror.New("MessagePack数据不完整")
+	}
+
+	result := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		result[fmt.Sprint(key)] = value
+	}
+	return result, nil
+}
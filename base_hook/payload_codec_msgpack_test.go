@@ -0,0 +1,249 @@
+package base_hook
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type msgpackInner struct {
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags"`
+	Score float64  `json:"score"`
+}
+
+type msgpackSample struct {
+	Bool    bool                    `json:"bool"`
+	Int     int                     `json:"int"`
+	Int8    int8                    `json:"int8"`
+	Int64   int64                   `json:"int64"`
+	Uint64  uint64                  `json:"uint64"`
+	Float32 float32                 `json:"float32"`
+	Float64 float64                 `json:"float64"`
+	String  string                  `json:"string"`
+	Binary  []byte                  `json:"binary"`
+	Inner   msgpackInner            `json:"inner"`
+	Pointer *msgpackInner           `json:"pointer"`
+	List    []msgpackInner          `json:"list"`
+	Map     map[string]msgpackInner `json:"map"`
+	Empty   map[string]int          `json:"empty"`
+	Nil     map[string]int          `json:"nil"`
+	Skipped string                  `json:"-"`
+}
+
+// msgpackRoundTrip 编码后解码到 out
+func msgpackRoundTrip(t *testing.T, in interface{}, out interface{}) []byte {
+	t.Helper()
+
+	data, err := MsgpackPayloadCodec{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("编码失败：%v", err)
+	}
+	if err = (MsgpackPayloadCodec{}).Unmarshal(data, out); err != nil {
+		t.Fatalf("解码失败：%v", err)
+	}
+	return data
+}
+
+// TestMsgpackRoundTrip 结构体的各类型字段编解码后保持不变
+func TestMsgpackRoundTrip(t *testing.T) {
+	in := msgpackSample{
+		Bool:    true,
+		Int:     -1 << 40,
+		Int8:    math.MinInt8,
+		Int64:   math.MinInt64,
+		Uint64:  math.MaxUint64,
+		Float32: 1.5,
+		Float64: math.Pi,
+		String:  "中文 string",
+		Binary:  []byte{0, 1, 0xfe, 0xff},
+		Inner:   msgpackInner{Name: "inner", Tags: []string{"a", "b"}, Score: 0.1},
+		Pointer: &msgpackInner{Name: "pointer"},
+		List:    []msgpackInner{{Name: "first"}, {Name: "second", Score: -2.5}},
+		Map:     map[string]msgpackInner{"key": {Name: "value"}},
+		Empty:   map[string]int{},
+		Skipped: "skipped",
+	}
+
+	var out msgpackSample
+	msgpackRoundTrip(t, in, &out)
+
+	in.Skipped = ""
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("编解码后不一致：\n期望 %+v\n实际 %+v", in, out)
+	}
+	if out.Empty == nil || out.Nil != nil {
+		t.Fatalf("空映射应解码为空映射，nil映射应解码为nil：empty=%v nil=%v", out.Empty, out.Nil)
+	}
+}
+
+// TestMsgpackIntegerLimits 整数的边界值使用最短的格式编码，且编解码后不丢失精度
+func TestMsgpackIntegerLimits(t *testing.T) {
+	signed := []struct {
+		value int64
+		head  byte
+	}{
+		{0, 0x00},
+		{math.MaxInt8, 0x7f},
+		{-1, 0xff},
+		{-32, 0xe0},
+		{-33, 0xd0},
+		{math.MinInt8, 0xd0},
+		{math.MaxInt8 + 1, 0xd1},
+		{math.MinInt16, 0xd1},
+		{math.MaxInt16 + 1, 0xd2},
+		{math.MinInt32, 0xd2},
+		{math.MaxInt32 + 1, 0xd3},
+		{math.MaxInt64, 0xd3},
+		{math.MinInt64, 0xd3},
+	}
+	for _, item := range signed {
+		var out int64
+		data := msgpackRoundTrip(t, item.value, &out)
+		if out != item.value {
+			t.Fatalf("%d 编解码后为 %d", item.value, out)
+		}
+		if data[0] != item.head {
+			t.Fatalf("%d 的格式为 0x%x，期望 0x%x", item.value, data[0], item.head)
+		}
+	}
+
+	unsigned := []uint64{0, math.MaxInt64, math.MaxInt64 + 1, math.MaxUint64}
+	for _, value := range unsigned {
+		var out uint64
+		msgpackRoundTrip(t, value, &out)
+		if out != value {
+			t.Fatalf("%d 编解码后为 %d", value, out)
+		}
+	}
+}
+
+// TestMsgpackNil nil、空映射、空数组及空字符串编解码后保持不变
+func TestMsgpackNil(t *testing.T) {
+	data, err := MsgpackPayloadCodec{}.Marshal(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte{0xc0}) {
+		t.Fatalf("nil 编码为 %x", data)
+	}
+
+	var m map[string]interface{}
+	msgpackRoundTrip(t, map[string]interface{}{}, &m)
+	if m == nil || len(m) != 0 {
+		t.Fatalf("空映射解码为 %#v", m)
+	}
+
+	var list []int
+	msgpackRoundTrip(t, []int{}, &list)
+	if list == nil || len(list) != 0 {
+		t.Fatalf("空数组解码为 %#v", list)
+	}
+
+	var s = "not empty"
+	msgpackRoundTrip(t, "", &s)
+	if s != "" {
+		t.Fatalf("空字符串解码为 %q", s)
+	}
+}
+
+// TestMsgpackInterface 解码到 interface{} 时与JSON一致，数值为 float64
+func TestMsgpackInterface(t *testing.T) {
+	var out interface{}
+	msgpackRoundTrip(t, map[string]interface{}{"int": 1, "list": []interface{}{"a", true, nil}}, &out)
+
+	expect := map[string]interface{}{"int": float64(1), "list": []interface{}{"a", true, nil}}
+	if !reflect.DeepEqual(out, expect) {
+		t.Fatalf("解码为 %#v", out)
+	}
+}
+
+// TestMsgpackLength 字符串、数组及映射的长度跨越各长度格式时编解码后保持不变
+func TestMsgpackLength(t *testing.T) {
+	for _, n := range []int{31, 32, math.MaxUint8 + 1, math.MaxUint16 + 1} {
+		s := string(bytes.Repeat([]byte("x"), n))
+		var out string
+		msgpackRoundTrip(t, s, &out)
+		if out != s {
+			t.Fatalf("长度为 %d 的字符串编解码后长度为 %d", n, len(out))
+		}
+	}
+
+	for _, n := range []int{15, 16, math.MaxUint16 + 1} {
+		list := make([]int, n)
+		m := make(map[string]int, n)
+		for i := range list {
+			list[i] = i
+			m[strconv.Itoa(i)] = i
+		}
+
+		var outList []int
+		msgpackRoundTrip(t, list, &outList)
+		if !reflect.DeepEqual(list, outList) {
+			t.Fatalf("长度为 %d 的数组编解码后不一致", n)
+		}
+
+		var outMap map[string]int
+		msgpackRoundTrip(t, m, &outMap)
+		if !reflect.DeepEqual(m, outMap) {
+			t.Fatalf("长度为 %d 的映射编解码后不一致", n)
+		}
+	}
+}
+
+// TestMsgpackDecodeFormats 解码其他服务发送的 uint、float32、二进制格式及非字符串的键
+func TestMsgpackDecodeFormats(t *testing.T) {
+	var out struct {
+		Uint8   uint8   `json:"u8"`
+		Uint32  uint32  `json:"u32"`
+		Float32 float32 `json:"f32"`
+		Binary  []byte  `json:"bin"`
+		Keys    map[string]string
+	}
+	data := []byte{
+		0x85,
+		0xa2, 'u', '8', 0xcc, 0xff,
+		0xa3, 'u', '3', '2', 0xce, 0xff, 0xff, 0xff, 0xff,
+		0xa3, 'f', '3', '2', 0xca, 0x3f, 0xc0, 0x00, 0x00,
+		0xa3, 'b', 'i', 'n', 0xc4, 0x03, 0x00, 0x01, 0xff,
+		0xa4, 'K', 'e', 'y', 's', 0x81, 0x01, 0xa1, 'v',
+	}
+	if err := (MsgpackPayloadCodec{}).Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Uint8 != math.MaxUint8 || out.Uint32 != math.MaxUint32 || out.Float32 != 1.5 {
+		t.Fatalf("数值解码错误：%+v", out)
+	}
+	if !bytes.Equal(out.Binary, []byte{0x00, 0x01, 0xff}) {
+		t.Fatalf("二进制解码为 %x", out.Binary)
+	}
+	if out.Keys["1"] != "v" {
+		t.Fatalf("整数键解码为 %v", out.Keys)
+	}
+}
+
+// TestMsgpackInvalid 不完整、多余的字节及不支持的格式返回错误
+func TestMsgpackInvalid(t *testing.T) {
+	invalid := map[string][]byte{
+		"空数据":     {},
+		"字符串不完整":  {0xa3, 'a'},
+		"整数不完整":   {0xd3, 0x00},
+		"数组长度过大":  {0xdd, 0xff, 0xff, 0xff, 0xff},
+		"映射缺少值":   {0x81, 0xa1, 'k'},
+		"末尾多余的字节": {0xc0, 0xc0},
+		"扩展类型":    {0xd4, 0x01, 0x00},
+	}
+	for name, data := range invalid {
+		var out interface{}
+		if err := (MsgpackPayloadCodec{}).Unmarshal(data, &out); err == nil {
+			t.Fatalf("%s：期望返回错误", name)
+		}
+	}
+
+	if _, err := (MsgpackPayloadCodec{}).Marshal(math.Inf(1)); err == nil {
+		t.Fatal("无穷大：期望返回错误")
+	}
+}
@@ -0,0 +1,260 @@
+package base_hook
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+
+	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	网络消息载荷的版本约定：
+		1、通过 RegisterSchema 按业务类型声明载荷的编码方式、当前版本、版本迁移函数及校验规则
+		2、发送时载荷按声明的编码方式编码后以base64写入 HookModel.Data，并携带 ContentType 及 SchemaVersion
+		3、接收时旧版本的载荷依次经过迁移函数升级到当前版本，高于当前版本的载荷拒绝处理，由发送方重新投递
+		4、解码后的载荷在调用订阅者之前校验，校验失败时回复nack；BaseHook 接收时按声明的载荷类型（Type）解码，
+		   未声明载荷类型时解码为通用的映射，只能按 Rules 及 Validator 校验
+		5、未声明且未携带 ContentType 的消息按原有方式处理，兼容旧版本的服务
+*/
+
+var ErrSchemaVersionTooNew = gerror.NewCode(gcode.CodeNotSupported, "Hook载荷版本高于本服务支持的版本")
+
+// Upcaster 版本迁移函数，将载荷从某一版本迁移到下一版本，data 为按消息的编码方式编码的载荷
+type Upcaster func(data []byte, codec PayloadCodec) ([]byte, error)
+
+// MapUpcaster 基于映射的版本迁移函数，适用于JSON及MessagePack编码的载荷
+func MapUpcaster(f func(payload map[string]interface{}) error) Upcaster {
+	return func(data []byte, codec PayloadCodec) ([]byte, error) {
+		payload := make(map[string]interface{})
+		if err := codec.Unmarshal(data, &payload); err != nil {
+			return nil, err
+		}
+		if err := f(payload); err != nil {
+			return nil, err
+		}
+		return codec.Marshal(payload)
+	}
+}
+
+// PayloadSchema 业务类型的载荷约定
+type PayloadSchema struct {
+	ContentType string                                               // 编码方式，默认为 application/json
+	Version     int                                                  // 当前版本，默认为1
+	Upcasters   map[int]Upcaster                                     // 版本迁移函数，key为源版本
+	Type        reflect.Type                                         // 载荷的类型，例如 reflect.TypeOf(OrderPaid{})，BaseHook 接收时解码为该类型
+	Rules       map[string]string                                    // 载荷的校验规则（gvalid），为空时按结构体的 v 标签校验
+	Validator   func(ctx context.Context, payload interface{}) error // 自定义校验函数
+}
+
+// payloadSchemaMap 已声明的载荷约定，key为业务类型，value为 PayloadSchema
+var payloadSchemaMap = gmap.NewStrAnyMap(true)
+
+// RegisterSchema 声明业务类型的载荷约定，重复声明时覆盖
+func RegisterSchema(businessType string, schema PayloadSchema) {
+	if schema.ContentType == "" {
+		schema.ContentType = ContentTypeJson
+	}
+	if schema.Version <= 0 {
+		schema.Version = 1
+	}
+	payloadSchemaMap.Set(base_enum.Hook.BusinessType.Resolve(businessType), schema)
+}
+
+// GetSchema 获取业务类型的载荷约定
+func GetSchema(businessType string) (PayloadSchema, bool) {
+	v := payloadSchemaMap.Get(base_enum.Hook.BusinessType.Resolve(businessType))
+	if v == nil {
+		return PayloadSchema{}, false
+	}
+	return v.(PayloadSchema), true
+}
+
+// getSchemaOrDefault 获取业务类型的载荷约定，未声明时为JSON编码的第1版
+func getSchemaOrDefault(businessType string) PayloadSchema {
+	if schema, ok := GetSchema(businessType); ok {
+		return schema
+	}
+	return PayloadSchema{ContentType: ContentTypeJson, Version: 1}
+}
+
+// encodePayload 按业务类型的载荷约定编码载荷，并写入消息的信封字段
+func encodePayload(model *base_model.HookModel, payload interface{}) error {
+	schema := getSchemaOrDefault(model.BusinessTypeStr)
+
+	codec, err := GetPayloadCodec(schema.ContentType)
+	if err != nil {
+		return err
+	}
+
+	data, err := codec.Marshal(payload)
+	if err != nil {
+		return gerror.Wrap(err, "Hook消息载荷编码失败")
+	}
+
+	model.ContentType = schema.ContentType
+	model.SchemaVersion = schema.Version
+	model.Data = base64.StdEncoding.EncodeToString(data)
+	return nil
+}
+
+// checkEnvelope 校验接收到的消息的编码方式及版本，未携带 ContentType 的旧版本消息不校验
+func checkEnvelope(model base_model.HookModel) error {
+	if model.ContentType == "" {
+		return nil
+	}
+
+	if _, err := GetPayloadCodec(model.ContentType); err != nil {
+		return err
+	}
+
+	if schema, ok := GetSchema(model.BusinessTypeStr); ok && model.SchemaVersion > schema.Version {
+		return gerror.WrapCodef(gcode.CodeNotSupported, ErrSchemaVersionTooNew, "%s：%d > %d", model.BusinessTypeStr, model.SchemaVersion, schema.Version)
+	}
+
+	return nil
+}
+
+// DecodePayload 将消息的载荷解码到 target（指针）：旧版本的载荷先迁移到当前版本，解码后按载荷约定校验。
+// 未携带 ContentType 的旧版本消息直接转换。
+func DecodePayload(ctx context.Context, model base_model.HookModel, target interface{}) error {
+	if model.ContentType == "" {
+		if err := gconv.Scan(model.Data, target); err != nil {
+			return gerror.Wrap(err, "Hook消息载荷转换失败")
+		}
+	} else {
+		data, err := upcastPayload(model)
+		if err != nil {
+			return err
+		}
+
+		codec, err := GetPayloadCodec(model.ContentType)
+		if err != nil {
+			return err
+		}
+		if err = codec.Unmarshal(data, target); err != nil {
+			return gerror.Wrapf(err, "Hook消息载荷解码失败：%s", model.BusinessTypeStr)
+		}
+	}
+
+	return ValidatePayload(ctx, model.BusinessTypeStr, target)
+}
+
+// decodeSchemaPayload 解码网关接收到的载荷：声明了载荷类型时解码为该类型并按 v 标签校验，未声明时保持解码后的通用值；
+// legacyData 为未携带 ContentType 的旧版本消息中的业务数据
+func decodeSchemaPayload(ctx context.Context, model base_model.HookModel, legacyData interface{}) (interface{}, error) {
+	schema, _ := GetSchema(model.BusinessTypeStr)
+
+	t, isPtr := schema.Type, false
+	if t != nil && t.Kind() == reflect.Ptr {
+		t, isPtr = t.Elem(), true
+	}
+
+	var target interface{}
+	if t != nil {
+		target = reflect.New(t).Interface()
+	} else {
+		target = new(interface{})
+	}
+
+	switch {
+	case model.ContentType != "":
+		if err := DecodePayload(ctx, model, target); err != nil {
+			return nil, err
+		}
+	case t == nil:
+		return legacyData, ValidatePayload(ctx, model.BusinessTypeStr, legacyData)
+	default:
+		if err := gconv.Scan(legacyData, target); err != nil {
+			return nil, gerror.Wrap(err, "Hook消息载荷转换失败")
+		}
+		if err := ValidatePayload(ctx, model.BusinessTypeStr, target); err != nil {
+			return nil, err
+		}
+	}
+
+	if isPtr {
+		return target, nil
+	}
+	return reflect.ValueOf(target).Elem().Interface(), nil
+}
+
+// upcastPayload 解码base64载荷，并依次迁移到当前版本
+func upcastPayload(model base_model.HookModel) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(gconv.String(model.Data))
+	if err != nil {
+		return nil, gerror.Wrap(err, "Hook消息载荷格式错误")
+	}
+
+	schema, ok := GetSchema(model.BusinessTypeStr)
+	if !ok {
+		return data, nil
+	}
+
+	if model.SchemaVersion > schema.Version {
+		return nil, gerror.WrapCodef(gcode.CodeNotSupported, ErrSchemaVersionTooNew, "%s：%d > %d", model.BusinessTypeStr, model.SchemaVersion, schema.Version)
+	}
+
+	codec, err := GetPayloadCodec(model.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	for version := max(model.SchemaVersion, 1); version < schema.Version; version++ {
+		upcaster, ok := schema.Upcasters[version]
+		if !ok {
+			return nil, gerror.NewCodef(gcode.CodeNotSupported, "Hook载荷缺少版本迁移函数：%s %d -> %d", model.BusinessTypeStr, version, version+1)
+		}
+		if data, err = upcaster(data, codec); err != nil {
+			return nil, gerror.Wrapf(err, "Hook载荷版本迁移失败：%s %d -> %d", model.BusinessTypeStr, version, version+1)
+		}
+	}
+
+	return data, nil
+}
+
+// ValidatePayload 按业务类型的载荷约定校验载荷，未声明载荷约定时不校验
+func ValidatePayload(ctx context.Context, businessType string, payload interface{}) error {
+	schema, ok := GetSchema(businessType)
+	if !ok {
+		return nil
+	}
+
+	// 解码目标为映射或接口的指针时，校验指向的值
+	if v := reflect.ValueOf(payload); v.Kind() == reflect.Ptr && !v.IsNil() && !isStructPayload(payload) {
+		payload = v.Elem().Interface()
+	}
+
+	if len(schema.Rules) > 0 {
+		if err := g.Validator().Rules(schema.Rules).Data(payload).Run(ctx); err != nil {
+			return gerror.WrapCode(gcode.CodeValidationFailed, err, "Hook消息载荷校验失败")
+		}
+	} else if isStructPayload(payload) {
+		if err := g.Validator().Data(payload).Run(ctx); err != nil {
+			return gerror.WrapCode(gcode.CodeValidationFailed, err, "Hook消息载荷校验失败")
+		}
+	}
+
+	if schema.Validator != nil {
+		if err := schema.Validator(ctx, payload); err != nil {
+			return gerror.WrapCode(gcode.CodeValidationFailed, err, "Hook消息载荷校验失败")
+		}
+	}
+
+	return nil
+}
+
+// isStructPayload 载荷是否为结构体或结构体指针
+func isStructPayload(payload interface{}) bool {
+	t := reflect.TypeOf(payload)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t != nil && t.Kind() == reflect.Struct
+}
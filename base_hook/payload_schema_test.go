@@ -0,0 +1,156 @@
+package base_hook
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type schemaOrderPaid struct {
+	OrderId string `json:"orderId" v:"required"`
+	Amount  int    `json:"amount"  v:"min:1"`
+}
+
+// registerTestSchema 测试期间声明载荷约定，结束后移除
+func registerTestSchema(t *testing.T, businessType string, schema PayloadSchema) {
+	RegisterSchema(businessType, schema)
+	t.Cleanup(func() {
+		payloadSchemaMap.Remove(businessType)
+	})
+}
+
+func TestDecodeSchemaPayload_Type(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name     string
+		schema   PayloadSchema
+		payload  interface{}
+		wantType reflect.Type
+		wantCode gcode.Code
+	}{
+		{
+			name:     "struct type",
+			schema:   PayloadSchema{Type: reflect.TypeOf(schemaOrderPaid{})},
+			payload:  schemaOrderPaid{OrderId: "o-1", Amount: 10},
+			wantType: reflect.TypeOf(schemaOrderPaid{}),
+		},
+		{
+			name:     "pointer type",
+			schema:   PayloadSchema{Type: reflect.TypeOf(&schemaOrderPaid{})},
+			payload:  schemaOrderPaid{OrderId: "o-1", Amount: 10},
+			wantType: reflect.TypeOf(&schemaOrderPaid{}),
+		},
+		{
+			name:     "v tags of type",
+			schema:   PayloadSchema{Type: reflect.TypeOf(schemaOrderPaid{})},
+			payload:  schemaOrderPaid{Amount: 10},
+			wantCode: gcode.CodeValidationFailed,
+		},
+		{
+			name:     "msgpack with type",
+			schema:   PayloadSchema{Type: reflect.TypeOf(schemaOrderPaid{}), ContentType: ContentTypeMsgpack},
+			payload:  schemaOrderPaid{OrderId: "o-1"},
+			wantCode: gcode.CodeValidationFailed,
+		},
+		{
+			name:     "rules without type",
+			schema:   PayloadSchema{Rules: map[string]string{"orderId": "required"}},
+			payload:  g.Map{"amount": 10},
+			wantCode: gcode.CodeValidationFailed,
+		},
+		{
+			name:     "no type decodes to map",
+			schema:   PayloadSchema{},
+			payload:  schemaOrderPaid{OrderId: "o-1", Amount: 10},
+			wantType: reflect.TypeOf(map[string]interface{}{}),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			registerTestSchema(t, "test.schema.type", c.schema)
+
+			model, err := newNetHookModel("test.schema.type", Option{Data: c.payload})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = checkEnvelope(model); err != nil {
+				t.Fatalf("checkEnvelope() = %v", err)
+			}
+
+			got, err := decodeSchemaPayload(ctx, model, nil)
+			if c.wantCode != nil {
+				if gerror.Code(err) != c.wantCode {
+					t.Fatalf("decodeSchemaPayload() err = %v, want code %v", err, c.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeSchemaPayload() = %v", err)
+			}
+			if reflect.TypeOf(got) != c.wantType {
+				t.Fatalf("payload type = %T, want %s", got, c.wantType)
+			}
+		})
+	}
+}
+
+func TestDecodeSchemaPayload_Legacy(t *testing.T) {
+	ctx := context.Background()
+	registerTestSchema(t, "test.schema.legacy", PayloadSchema{Type: reflect.TypeOf(schemaOrderPaid{})})
+
+	// 旧版本的服务发送的消息未携带 ContentType，业务数据为通用的映射
+	model, err := newNetHookModel("test.schema.legacy", "unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := decodeSchemaPayload(ctx, model, g.Map{"orderId": "o-1", "amount": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (schemaOrderPaid{OrderId: "o-1", Amount: 3}); got != want {
+		t.Fatalf("payload = %#v, want %#v", got, want)
+	}
+
+	if _, err = decodeSchemaPayload(ctx, model, g.Map{"amount": 3}); gerror.Code(err) != gcode.CodeValidationFailed {
+		t.Fatalf("decodeSchemaPayload() err = %v, want CodeValidationFailed", err)
+	}
+
+	// 未声明载荷约定的业务类型原样返回
+	data := g.Map{"any": 1}
+	model.BusinessTypeStr = "test.schema.none"
+	if got, err = decodeSchemaPayload(ctx, model, data); err != nil || !reflect.DeepEqual(got, data) {
+		t.Fatalf("decodeSchemaPayload() = %v, %v", got, err)
+	}
+}
+
+func TestNewNetHookModel_EncodesSchemaPayload(t *testing.T) {
+	registerTestSchema(t, "test.schema.encode", PayloadSchema{Version: 2})
+
+	model, err := newNetHookModel("test.schema.encode", Option{Data: g.Map{"orderId": "o-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.ContentType != ContentTypeJson || model.SchemaVersion != 2 {
+		t.Fatalf("envelope = %s v%d, want %s v2", model.ContentType, model.SchemaVersion, ContentTypeJson)
+	}
+	if _, ok := model.Data.(string); !ok {
+		t.Fatalf("Data = %T, want base64 string", model.Data)
+	}
+
+	// 未声明载荷约定时保持原有格式
+	option := Option{Data: g.Map{"orderId": "o-1"}}
+	model, err = newNetHookModel("test.schema.plain", option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.ContentType != "" || !reflect.DeepEqual(model.Data, option) {
+		t.Fatalf("model = %+v, want legacy Option payload", model)
+	}
+}
@@ -0,0 +1,179 @@
+package base_hook
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gtype"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+/*
+	Hook订阅的注册表：
+		1、记录每次安装的订阅：业务类型、过滤条件、订阅函数名称、安装位置（文件及行号）、安装时间
+		2、统计每个订阅者的调用次数、错误次数及耗时
+		3、HookInspect 以JSON输出所有订阅、网关注册的业务类型及各服务连接的状态，需要在路由注册时候注册
+*/
+
+// subscriberRecord 订阅记录
+type subscriberRecord struct {
+	businessType string
+	filter       interface{}
+	subscriber   string
+	file         string
+	line         int
+	installedAt  *gtime.Time
+	calls        *gtype.Int64
+	errors       *gtype.Int64
+	totalLatency *gtype.Int64 // 累计耗时，纳秒
+	maxLatency   *gtype.Int64 // 最大耗时，纳秒
+}
+
+// SubscriberInfo 订阅信息
+type SubscriberInfo struct {
+	BusinessType string      `json:"businessType" dc:"业务类型"`
+	Filter       interface{} `json:"filter"       dc:"过滤条件"`
+	Subscriber   string      `json:"subscriber"   dc:"订阅函数名称"`
+	File         string      `json:"file"         dc:"安装位置的文件"`
+	Line         int         `json:"line"         dc:"安装位置的行号"`
+	InstalledAt  *gtime.Time `json:"installedAt"  dc:"安装时间"`
+	Calls        int64       `json:"calls"        dc:"调用次数"`
+	Errors       int64       `json:"errors"       dc:"错误次数"`
+	AvgLatency   string      `json:"avgLatency"   dc:"平均耗时"`
+	MaxLatency   string      `json:"maxLatency"   dc:"最大耗时"`
+}
+
+// PeerInfo 服务连接信息
+type PeerInfo struct {
+	Url        string      `json:"url"        dc:"服务地址"`
+	State      string      `json:"state"      dc:"连接状态：connecting、connected、disconnected、closed"`
+	StateSince *gtime.Time `json:"stateSince" dc:"进入当前状态的时间"`
+	LastError  string      `json:"lastError"  dc:"最后一次连接失败的原因"`
+	Queued     int         `json:"queued"     dc:"发送队列中的消息数"`
+	Pending    int         `json:"pending"    dc:"已发送待确认的消息数"`
+}
+
+// InspectRes HookInspect 的输出
+type InspectRes struct {
+	Subscribers []SubscriberInfo `json:"subscribers" dc:"所有订阅"`
+	Gateways    []string         `json:"gateways"    dc:"网关注册的业务类型"`
+	Peers       []PeerInfo       `json:"peers"       dc:"服务连接"`
+}
+
+// subscriberRegistry 所有订阅记录，key为 *subscriberRecord
+var subscriberRegistry = sync.Map{}
+
+// registerSubscriber 登记订阅，skip 为调用栈中安装位置相对于本函数的层数
+func registerSubscriber(businessType string, filter interface{}, subscriber interface{}, skip int) *subscriberRecord {
+	record := &subscriberRecord{
+		businessType: businessType,
+		filter:       filter,
+		subscriber:   hookFuncName(subscriber),
+		installedAt:  gtime.Now(),
+		calls:        gtype.NewInt64(),
+		errors:       gtype.NewInt64(),
+		totalLatency: gtype.NewInt64(),
+		maxLatency:   gtype.NewInt64(),
+	}
+	_, record.file, record.line, _ = runtime.Caller(skip + 1)
+
+	subscriberRegistry.Store(record, struct{}{})
+	return record
+}
+
+// unregisterSubscriber 注销订阅
+func unregisterSubscriber(record *subscriberRecord) {
+	if record != nil {
+		subscriberRegistry.Delete(record)
+	}
+}
+
+// observe 记录一次调用的耗时及结果
+func (r *subscriberRecord) observe(duration time.Duration, err error) {
+	if r == nil {
+		return
+	}
+
+	r.calls.Add(1)
+	if err != nil {
+		r.errors.Add(1)
+	}
+	r.totalLatency.Add(int64(duration))
+	for {
+		max := r.maxLatency.Val()
+		if int64(duration) <= max || r.maxLatency.Cas(max, int64(duration)) {
+			break
+		}
+	}
+}
+
+// info 获取订阅信息
+func (r *subscriberRecord) info() SubscriberInfo {
+	calls := r.calls.Val()
+	avg := time.Duration(0)
+	if calls > 0 {
+		avg = time.Duration(r.totalLatency.Val() / calls)
+	}
+
+	return SubscriberInfo{
+		BusinessType: r.businessType,
+		Filter:       r.filter,
+		Subscriber:   r.subscriber,
+		File:         r.file,
+		Line:         r.line,
+		InstalledAt:  r.installedAt,
+		Calls:        calls,
+		Errors:       r.errors.Val(),
+		AvgLatency:   avg.String(),
+		MaxLatency:   time.Duration(r.maxLatency.Val()).String(),
+	}
+}
+
+// Subscribers 获取所有订阅信息，按业务类型及安装时间排序
+func Subscribers() []SubscriberInfo {
+	result := make([]SubscriberInfo, 0)
+	subscriberRegistry.Range(func(key, value interface{}) bool {
+		result = append(result, key.(*subscriberRecord).info())
+		return true
+	})
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].BusinessType != result[j].BusinessType {
+			return result[i].BusinessType < result[j].BusinessType
+		}
+		return result[i].InstalledAt.Before(result[j].InstalledAt)
+	})
+
+	return result
+}
+
+// Peers 获取所有服务连接的状态，按服务地址排序
+func Peers() []PeerInfo {
+	result := make([]PeerInfo, 0, wsArr.Size())
+	wsArr.Iterator(func(k string, v interface{}) bool {
+		result = append(result, v.(*peerConn).info())
+		return true
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Url < result[j].Url
+	})
+
+	return result
+}
+
+// HookInspect 输出所有订阅、网关注册的业务类型及各服务连接的状态，需要在路由注册时候注册，
+// 建议仅在内部管理路由中注册，并由调用方自行添加鉴权中间件
+func HookInspect(r *ghttp.Request) {
+	gateways := gateway.GatewayHookMap.Keys()
+	sort.Strings(gateways)
+
+	r.Response.WriteJsonExit(InspectRes{
+		Subscribers: Subscribers(),
+		Gateways:    gateways,
+		Peers:       Peers(),
+	})
+}
@@ -0,0 +1,145 @@
+package base_hook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/guid"
+)
+
+// subscribersOf 获取指定业务类型的订阅信息
+func subscribersOf(businessType string) []SubscriberInfo {
+	result := make([]SubscriberInfo, 0)
+	for _, item := range Subscribers() {
+		if item.BusinessType == businessType {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func TestSubscribers(t *testing.T) {
+	hook := newTestBaseHook(t, 1)
+	businessType := hook.GetBusinessType().Code()
+
+	_, file, line, _ := runtime.Caller(0)
+	hook.InstallHook("a", func(ctx context.Context, order *testOrder) error {
+		return nil
+	})
+	hook.InstallHook("b", func(ctx context.Context, order *testOrder) error {
+		return errors.New("b failed")
+	}, InstallOption{Priority: 10})
+
+	// 记录安装位置，按安装时间排序，与优先级无关
+	infos := subscribersOf(businessType)
+	if len(infos) != 2 || infos[0].Filter != "a" || infos[1].Filter != "b" {
+		t.Fatalf("Subscribers() = %+v, want a and b", infos)
+	}
+	if infos[0].File != file || infos[0].Line != line+1 || infos[1].Line != line+4 {
+		t.Fatalf("install location = %s:%d, %d, want %s:%d", infos[0].File, infos[0].Line, infos[1].Line, file, line+1)
+	}
+	if !strings.Contains(infos[0].Subscriber, "TestSubscribers") || infos[0].InstalledAt == nil || infos[0].Calls != 0 {
+		t.Fatalf("subscriber = %+v", infos[0])
+	}
+
+	// 统计调用次数、错误次数及耗时
+	for i := 0; i < 2; i++ {
+		_ = hook.Dispatch(context.Background(), callOrder)
+	}
+	infos = subscribersOf(businessType)
+	if infos[0].Calls != 2 || infos[0].Errors != 0 || infos[1].Calls != 2 || infos[1].Errors != 2 {
+		t.Fatalf("calls = %+v", infos)
+	}
+	if infos[1].AvgLatency == "" || infos[1].MaxLatency == "" {
+		t.Fatalf("latency = %s, %s", infos[1].AvgLatency, infos[1].MaxLatency)
+	}
+
+	// 卸载后从注册表中移除
+	hook.UnInstallHook("a", func(filter string, key string) bool { return filter == key })
+	if infos = subscribersOf(businessType); len(infos) != 1 || infos[0].Filter != "b" {
+		t.Fatalf("Subscribers() after UnInstallHook = %+v", infos)
+	}
+	hook.ClearAllHook()
+	if infos = subscribersOf(businessType); len(infos) != 0 {
+		t.Fatalf("Subscribers() after ClearAllHook = %+v", infos)
+	}
+}
+
+func TestSubscribers_TypedHook(t *testing.T) {
+	topic := testTopic(t)
+	hook := NewTypedHook[string, testOrder](topic)
+
+	_, file, line, _ := runtime.Caller(0)
+	hook.Subscribe("a", func(ctx context.Context, payload testOrder) error {
+		return nil
+	})
+	_ = hook.Publish(context.Background(), testOrder{Id: 1})
+
+	infos := subscribersOf(topic)
+	if len(infos) != 1 || infos[0].File != file || infos[0].Line != line+1 || infos[0].Calls != 1 {
+		t.Fatalf("Subscribers() = %+v, want a installed at %s:%d", infos, file, line+1)
+	}
+
+	hook.Unsubscribe(func(filter string) bool { return true })
+	if infos = subscribersOf(topic); len(infos) != 0 {
+		t.Fatalf("Subscribers() after Unsubscribe = %+v", infos)
+	}
+}
+
+func TestHookInspect(t *testing.T) {
+	topic := testTopic(t)
+	hook := NewTypedHook[string, testOrder](topic)
+	hook.Subscribe("a", func(ctx context.Context, payload testOrder) error {
+		return nil
+	})
+	t.Cleanup(func() {
+		hook.Unsubscribe(func(filter string) bool { return true })
+	})
+
+	s := g.Server(t.Name() + "-" + guid.S())
+	s.SetAddr("127.0.0.1:0")
+	s.SetDumpRouterMap(false)
+	s.SetAccessLogEnabled(false)
+	s.BindHandler("/hook/inspect", HookInspect)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = s.Shutdown()
+	})
+
+	res, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/hook/inspect", s.GetListenedPort()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inspect InspectRes
+	if err = json.Unmarshal(body, &inspect); err != nil {
+		t.Fatalf("body = %s: %v", body, err)
+	}
+	if !slices.Contains(inspect.Gateways, topic) || !slices.IsSorted(inspect.Gateways) {
+		t.Fatalf("gateways = %v, want sorted and containing %s", inspect.Gateways, topic)
+	}
+	if !slices.ContainsFunc(inspect.Subscribers, func(item SubscriberInfo) bool {
+		return item.BusinessType == topic && item.Filter == "a"
+	}) {
+		t.Fatalf("subscribers = %+v, want %s", inspect.Subscribers, topic)
+	}
+	if inspect.Peers == nil {
+		t.Fatalf("body = %s, want peers", body)
+	}
+}
@@ -0,0 +1,469 @@
+package base_hook
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/utility/base_funs"
+)
+
+/*
+	跨进程Hook安全机制：
+		1、握手认证：客户端在握手请求头中携带节点ID、时间戳、随机数及签名，服务端校验通过后才升级为websocket
+		2、消息签名：每条消息携带时间戳、随机数及签名，支持共享密钥HMAC-SHA256和Ed25519密钥对两种方式
+		3、防重放：时间戳超出允许的时钟偏差，或随机数在有效期内重复出现的消息将被拒绝
+		4、TLS：启用后使用wss协议连接，支持自定义CA及客户端证书（双向认证）
+		5、来源白名单：仅允许白名单内的主机连接，为空时不限制
+		6、配置校验：设置配置或传输方式初始化（Connect、ServeTCP）时校验，配置无效时返回错误，签名及验签同样返回错误而不会以空密钥签名
+*/
+
+var (
+	ErrUnauthorized     = gerror.New("Hook握手认证失败")
+	ErrInvalidSignature = gerror.New("Hook消息签名无效")
+	ErrReplayedMessage  = gerror.New("Hook消息已过期或重复")
+	ErrHostNotAllowed   = gerror.New("Hook消息来源不在白名单内")
+)
+
+// 签名方式
+const (
+	SignModeNone    = ""
+	SignModeHmac    = "hmac"
+	SignModeEd25519 = "ed25519"
+)
+
+// 握手请求头
+const (
+	headerHookNode      = "X-Hook-Node"
+	headerHookTimestamp = "X-Hook-Timestamp"
+	headerHookNonce     = "X-Hook-Nonce"
+	headerHookSignature = "X-Hook-Signature"
+)
+
+// TLSOption TLS配置
+type TLSOption struct {
+	Enabled            bool   // 是否启用TLS，启用后使用wss协议
+	CaFile             string // 用于校验服务端证书的CA证书文件，为空时使用系统CA
+	CertFile           string // 客户端证书文件，双向认证时使用
+	KeyFile            string // 客户端私钥文件，双向认证时使用
+	ServerName         string // 校验服务端证书时使用的服务名
+	InsecureSkipVerify bool   // 是否跳过服务端证书校验，仅用于测试环境
+}
+
+// SecurityOption 安全配置
+type SecurityOption struct {
+	Mode         string                       // 签名方式：空不签名，hmac共享密钥，ed25519密钥对
+	NodeId       string                       // 本节点ID，ed25519方式下对端据此查找公钥
+	Secret       string                       // hmac共享密钥
+	PrivateKey   ed25519.PrivateKey           // 本节点ed25519私钥
+	PublicKeys   map[string]ed25519.PublicKey // 受信任节点的ed25519公钥，key为节点ID
+	MaxClockSkew time.Duration                // 允许的最大时钟偏差，同时也是随机数的缓存时长
+	AllowHosts   []string                     // 允许连接的来源主机，为空时不限制
+	TLS          TLSOption                    // TLS配置
+}
+
+// DefaultSecurityOption 从配置 service.hook.security 读取安全配置
+func DefaultSecurityOption(ctx context.Context) SecurityOption {
+	prefix := "service.hook.security."
+	option := SecurityOption{
+		Mode:         g.Cfg().MustGet(ctx, prefix+"mode", SignModeNone).String(),
+		NodeId:       g.Cfg().MustGet(ctx, prefix+"nodeId", g.Cfg().MustGet(ctx, "server.address").String()).String(),
+		Secret:       g.Cfg().MustGet(ctx, prefix+"secret").String(),
+		MaxClockSkew: g.Cfg().MustGet(ctx, prefix+"maxClockSkew", "5m").Duration(),
+		AllowHosts:   base_funs.FilterEmpty(g.Cfg().MustGet(ctx, prefix+"allowHosts").Strings()),
+		PublicKeys:   make(map[string]ed25519.PublicKey),
+	}
+
+	if key := decodeEd25519Key(g.Cfg().MustGet(ctx, prefix+"privateKey").String()); len(key) == ed25519.SeedSize {
+		option.PrivateKey = ed25519.NewKeyFromSeed(key)
+	} else if len(key) == ed25519.PrivateKeySize {
+		option.PrivateKey = key
+	}
+
+	for nodeId, v := range g.Cfg().MustGet(ctx, prefix+"publicKeys").MapStrStr() {
+		if key := decodeEd25519Key(v); len(key) == ed25519.PublicKeySize {
+			option.PublicKeys[nodeId] = key
+		}
+	}
+
+	_ = g.Cfg().MustGet(ctx, prefix+"tls").Struct(&option.TLS)
+
+	return option
+}
+
+// decodeEd25519Key 解码base64格式的密钥
+func decodeEd25519Key(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+// defaultMaxClockSkew 未配置时允许的最大时钟偏差
+const defaultMaxClockSkew = 5 * time.Minute
+
+var (
+	securityMu     sync.RWMutex
+	securityOption *SecurityOption
+	nonceCache     = gcache.New()
+)
+
+// SetSecurityOption 设置安全配置，覆盖配置文件中的设置；配置无效时返回错误，不覆盖原有的设置；未设置时钟偏差时使用默认的5分钟
+func SetSecurityOption(option SecurityOption) error {
+	if err := option.Validate(); err != nil {
+		return err
+	}
+	if option.MaxClockSkew <= 0 {
+		option.MaxClockSkew = defaultMaxClockSkew
+	}
+
+	securityMu.Lock()
+	defer securityMu.Unlock()
+
+	securityOption = &option
+	return nil
+}
+
+// Validate 校验安全配置：hmac方式必须配置共享密钥，ed25519方式必须配置本节点私钥
+func (o *SecurityOption) Validate() error {
+	switch o.Mode {
+	case SignModeNone:
+	case SignModeHmac:
+		if o.Secret == "" {
+			return gerror.NewCode(gcode.CodeInvalidConfiguration, "Hook签名方式为hmac时必须配置共享密钥：service.hook.security.secret")
+		}
+	case SignModeEd25519:
+		if len(o.PrivateKey) != ed25519.PrivateKeySize {
+			return gerror.NewCode(gcode.CodeInvalidConfiguration, "Hook签名方式为ed25519时必须配置私钥：service.hook.security.privateKey")
+		}
+	default:
+		return gerror.NewCodef(gcode.CodeInvalidConfiguration, "不支持的Hook签名方式：%s", o.Mode)
+	}
+	return nil
+}
+
+// getSecurityOption 获取安全配置，首次使用时从配置文件读取；配置无效时返回错误，避免以空密钥签名或跳过校验
+func getSecurityOption(ctx context.Context) (SecurityOption, error) {
+	securityMu.RLock()
+	option := securityOption
+	securityMu.RUnlock()
+
+	if option != nil {
+		return *option, nil
+	}
+
+	securityMu.Lock()
+	defer securityMu.Unlock()
+
+	if securityOption == nil {
+		conf := DefaultSecurityOption(ctx)
+		if err := conf.Validate(); err != nil {
+			return SecurityOption{}, err
+		}
+		securityOption = &conf
+	}
+
+	return *securityOption, nil
+}
+
+// wsScheme 根据TLS配置返回ws协议
+func wsScheme(option SecurityOption) string {
+	if option.TLS.Enabled {
+		return "wss://"
+	}
+	return "ws://"
+}
+
+// makeClientTLSConfig 根据TLS配置构建客户端的 tls.Config
+func makeClientTLSConfig(ctx context.Context) (*tls.Config, error) {
+	security, err := getSecurityOption(ctx)
+	if err != nil {
+		return nil, err
+	}
+	option := security.TLS
+
+	conf := &tls.Config{
+		ServerName:         option.ServerName,
+		InsecureSkipVerify: option.InsecureSkipVerify,
+	}
+
+	if option.CaFile != "" {
+		caPem, err := os.ReadFile(option.CaFile)
+		if err != nil {
+			return nil, gerror.Wrap(err, "读取Hook TLS CA证书失败")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPem) {
+			return nil, gerror.New("解析Hook TLS CA证书失败")
+		}
+		conf.RootCAs = pool
+	}
+
+	if option.CertFile != "" && option.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(option.CertFile, option.KeyFile)
+		if err != nil {
+			return nil, gerror.Wrap(err, "读取Hook TLS客户端证书失败")
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+// isAllowedHost 来源主机是否在白名单内
+func (o *SecurityOption) isAllowedHost(host string) bool {
+	allowHosts := o.AllowHosts
+	if len(allowHosts) == 0 {
+		return true
+	}
+	return base_funs.Contains(allowHosts, host)
+}
+
+// sign 按配置的方式对内容签名
+func (o *SecurityOption) sign(content []byte) (string, error) {
+	switch o.Mode {
+	case SignModeHmac:
+		if o.Secret == "" {
+			return "", gerror.New("未配置Hook签名密钥：service.hook.security.secret")
+		}
+		mac := hmac.New(sha256.New, []byte(o.Secret))
+		mac.Write(content)
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+	case SignModeEd25519:
+		if len(o.PrivateKey) != ed25519.PrivateKeySize {
+			return "", gerror.New("未配置Hook签名私钥：service.hook.security.privateKey")
+		}
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(o.PrivateKey, content)), nil
+	}
+	return "", nil
+}
+
+// verify 按配置的方式校验签名
+func (o *SecurityOption) verify(nodeId string, content []byte, signature string) bool {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	switch o.Mode {
+	case SignModeHmac:
+		if o.Secret == "" {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(o.Secret))
+		mac.Write(content)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case SignModeEd25519:
+		publicKey, ok := o.PublicKeys[nodeId]
+		return ok && ed25519.Verify(publicKey, content, sig)
+	}
+	return true
+}
+
+// checkFreshness 校验时间戳及随机数，防止重放
+func (o *SecurityOption) checkFreshness(ctx context.Context, timestamp int64, nonce string) error {
+	skew := time.Since(time.UnixMilli(timestamp))
+	if skew < 0 {
+		skew = -skew
+	}
+	if nonce == "" || skew > o.MaxClockSkew {
+		return ErrReplayedMessage
+	}
+
+	ok, err := nonceCache.SetIfNotExist(ctx, nonce, timestamp, o.MaxClockSkew*2)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrReplayedMessage
+	}
+
+	return nil
+}
+
+// makeHandshakeHeader 构建握手认证请求头
+func makeHandshakeHeader(ctx context.Context) (http.Header, error) {
+	option, err := getSecurityOption(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	if option.Mode == SignModeNone {
+		return header, nil
+	}
+
+	timestamp := time.Now().UnixMilli()
+	nonce := guid.S()
+	signature, err := option.sign(handshakeContent(option.NodeId, timestamp, nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	header.Set(headerHookNode, option.NodeId)
+	header.Set(headerHookTimestamp, gconv.String(timestamp))
+	header.Set(headerHookNonce, nonce)
+	header.Set(headerHookSignature, signature)
+
+	return header, nil
+}
+
+// verifyHandshake 校验握手请求的来源及认证信息
+func verifyHandshake(r *ghttp.Request) error {
+	return verifyHandshakeHeader(r.Context(), r.RemoteAddr, r.Header)
+}
+
+// verifyHandshakeHeader 校验来源地址及握手认证信息，供非HTTP的传输方式使用
+func verifyHandshakeHeader(ctx context.Context, remoteAddr string, header http.Header) error {
+	option, err := getSecurityOption(ctx)
+	if err != nil {
+		return err
+	}
+
+	host := strings.Split(remoteAddr, ":")[0]
+	if !option.isAllowedHost(host) {
+		return gerror.Wrap(ErrHostNotAllowed, host)
+	}
+
+	if option.Mode == SignModeNone {
+		return nil
+	}
+
+	nodeId := header.Get(headerHookNode)
+	timestamp := gconv.Int64(header.Get(headerHookTimestamp))
+	nonce := header.Get(headerHookNonce)
+
+	if !option.verify(nodeId, handshakeContent(nodeId, timestamp, nonce), header.Get(headerHookSignature)) {
+		return ErrUnauthorized
+	}
+
+	return option.checkFreshness(ctx, timestamp, nonce)
+}
+
+func handshakeContent(nodeId string, timestamp int64, nonce string) []byte {
+	return []byte(nodeId + "\n" + gconv.String(timestamp) + "\n" + nonce)
+}
+
+// signHookModel 对消息签名，每次发送都会生成新的时间戳及随机数，以便重投的消息不会被当作重放
+func signHookModel(ctx context.Context, model *base_model.HookModel) error {
+	option, err := getSecurityOption(ctx)
+	if err != nil {
+		return err
+	}
+	if option.Mode == SignModeNone {
+		return nil
+	}
+
+	model.Node = option.NodeId
+	model.Timestamp = time.Now().UnixMilli()
+	model.Nonce = guid.S()
+
+	content, err := hookModelContent(model)
+	if err != nil {
+		return err
+	}
+
+	model.Signature, err = option.sign(content)
+	return err
+}
+
+// verifyHookModel 校验消息签名及时效
+func verifyHookModel(ctx context.Context, model *base_model.HookModel) error {
+	option, err := getSecurityOption(ctx)
+	if err != nil {
+		return err
+	}
+	if option.Mode == SignModeNone {
+		return nil
+	}
+
+	content, err := hookModelContent(model)
+	if err != nil {
+		return err
+	}
+
+	if !option.verify(model.Node, content, model.Signature) {
+		return ErrInvalidSignature
+	}
+
+	return option.checkFreshness(ctx, model.Timestamp, model.Nonce)
+}
+
+// hookModelContent 构建消息的待签名内容，Data 使用规范化的JSON，保证收发双方一致
+func hookModelContent(model *base_model.HookModel) ([]byte, error) {
+	data, err := canonicalJSON(model.Data)
+	if err != nil {
+		return nil, gerror.Wrap(err, "Hook消息序列化失败")
+	}
+
+	parts := [][]byte{
+		[]byte(model.MessageId),
+		[]byte(model.MessageTypeStr),
+		[]byte(model.BusinessTypeStr),
+		[]byte(model.Node),
+		[]byte(gconv.String(model.Timestamp)),
+		[]byte(model.Nonce),
+		[]byte(model.Error),
+		[]byte(gconv.String(model.ErrorCode)),
+		[]byte(gconv.String(model.Deadline)),
+	}
+	// 携带载荷信封时签名包含编码方式及版本，未携带时与旧版本的签名内容保持一致
+	if model.ContentType != "" {
+		parts = append(parts, []byte(model.ContentType), []byte(gconv.String(model.SchemaVersion)))
+	}
+	// 携带幂等Key时签名包含幂等Key，避免被篡改后绕过去重
+	if model.IdempotencyKey != "" {
+		parts = append(parts, []byte("idempotencyKey:"+model.IdempotencyKey))
+	}
+	// 投递方式、投递Key及链路ID影响接收方的处理，携带时一并签名，避免被篡改；未携带时与旧版本的签名内容保持一致
+	if model.DeliveryModeStr != "" {
+		parts = append(parts, []byte("deliveryMode:"+model.DeliveryModeStr))
+	}
+	if model.DeliveryKey != "" {
+		parts = append(parts, []byte("deliveryKey:"+model.DeliveryKey))
+	}
+	if model.TraceId != "" {
+		parts = append(parts, []byte("traceId:"+model.TraceId))
+	}
+
+	return bytes.Join(append(parts, data), []byte("\n")), nil
+}
+
+// canonicalJSON 将任意数据转换为规范化的JSON：对象的键按字典序排列，数字保持原样
+func canonicalJSON(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err = decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(generic)
+}
@@ -0,0 +1,160 @@
+package base_hook
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"github.com/kysion/base-library/base_model"
+)
+
+// useConfig 测试期间使用指定的配置内容，结束后恢复原有的配置及安全配置
+func useConfig(t *testing.T, content string) {
+	adapter, err := gcfg.NewAdapterContent(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prevAdapter := g.Cfg().GetAdapter()
+	securityMu.Lock()
+	prevOption := securityOption
+	securityOption = nil
+	securityMu.Unlock()
+
+	g.Cfg().SetAdapter(adapter)
+	t.Cleanup(func() {
+		g.Cfg().SetAdapter(prevAdapter)
+		securityMu.Lock()
+		securityOption = prevOption
+		securityMu.Unlock()
+	})
+}
+
+func TestSecurityOption_Validate(t *testing.T) {
+	cases := []struct {
+		name   string
+		option SecurityOption
+		valid  bool
+	}{
+		{name: "none", option: SecurityOption{Mode: SignModeNone}, valid: true},
+		{name: "hmac", option: SecurityOption{Mode: SignModeHmac, Secret: "secret"}, valid: true},
+		{name: "hmac without secret", option: SecurityOption{Mode: SignModeHmac}},
+		{name: "ed25519", option: SecurityOption{Mode: SignModeEd25519, PrivateKey: ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))}, valid: true},
+		{name: "ed25519 without key", option: SecurityOption{Mode: SignModeEd25519}},
+		{name: "unknown mode", option: SecurityOption{Mode: "rsa"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.option.Validate()
+			if c.valid && err != nil {
+				t.Fatalf("Validate() = %v, want nil", err)
+			}
+			if !c.valid && gerror.Code(err) != gcode.CodeInvalidConfiguration {
+				t.Fatalf("Validate() = %v, want CodeInvalidConfiguration", err)
+			}
+		})
+	}
+}
+
+func TestSetSecurityOption_KeepsPreviousOnInvalid(t *testing.T) {
+	useConfig(t, `{}`)
+
+	if err := SetSecurityOption(SecurityOption{Mode: SignModeHmac, Secret: "secret", NodeId: "node-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetSecurityOption(SecurityOption{Mode: SignModeHmac, NodeId: "node-b"}); err == nil {
+		t.Fatal("SetSecurityOption accepted hmac without secret")
+	}
+
+	option, err := getSecurityOption(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if option.NodeId != "node-a" {
+		t.Fatalf("NodeId = %q, want node-a", option.NodeId)
+	}
+}
+
+func TestSecurity_InvalidConfigReturnsError(t *testing.T) {
+	useConfig(t, `{"service":{"hook":{"security":{"mode":"hmac"}}}}`)
+
+	ctx := context.Background()
+	model := base_model.HookModel{MessageId: "msg-1", BusinessTypeStr: "order.paid"}
+
+	if err := signHookModel(ctx, &model); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("signHookModel() = %v, want CodeInvalidConfiguration", err)
+	}
+	if err := verifyHookModel(ctx, &model); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("verifyHookModel() = %v, want CodeInvalidConfiguration", err)
+	}
+	if _, err := makeHandshakeHeader(ctx); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("makeHandshakeHeader() = %v, want CodeInvalidConfiguration", err)
+	}
+	if err := TcpTransport().Connect(ctx, "127.0.0.1:1"); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("TcpTransport().Connect() = %v, want CodeInvalidConfiguration", err)
+	}
+	if _, err := ServeTCP(ctx, "127.0.0.1:0"); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("ServeTCP() = %v, want CodeInvalidConfiguration", err)
+	}
+
+	// 配置修正后不再返回错误
+	if err := SetSecurityOption(SecurityOption{Mode: SignModeHmac, Secret: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := signHookModel(ctx, &model); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyHookModel(ctx, &model); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSecurity_SignVerify(t *testing.T) {
+	useConfig(t, `{}`)
+
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = 1
+	privateKey := ed25519.NewKeyFromSeed(seed)
+
+	options := map[string]SecurityOption{
+		SignModeHmac: {Mode: SignModeHmac, Secret: "secret", NodeId: "node-a"},
+		SignModeEd25519: {
+			Mode:       SignModeEd25519,
+			NodeId:     "node-a",
+			PrivateKey: privateKey,
+			PublicKeys: map[string]ed25519.PublicKey{"node-a": privateKey.Public().(ed25519.PublicKey)},
+		},
+	}
+
+	for mode, option := range options {
+		t.Run(mode, func(t *testing.T) {
+			if err := SetSecurityOption(option); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			model := base_model.HookModel{MessageId: "msg-" + mode, BusinessTypeStr: "order.paid", Data: g.Map{"id": 1}}
+			if err := signHookModel(ctx, &model); err != nil {
+				t.Fatal(err)
+			}
+
+			tampered := model
+			tampered.BusinessTypeStr = "order.closed"
+			if err := verifyHookModel(ctx, &tampered); err != ErrInvalidSignature {
+				t.Fatalf("verify tampered = %v, want ErrInvalidSignature", err)
+			}
+
+			if err := verifyHookModel(ctx, &model); err != nil {
+				t.Fatalf("verify = %v", err)
+			}
+			if err := verifyHookModel(ctx, &model); err != ErrReplayedMessage {
+				t.Fatalf("verify replay = %v, want ErrReplayedMessage", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package base_hook
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+// testLegacyHookFunc 重命名后的订阅函数类型，旧类型名及旧主题通过别名映射到声明的主题
+type testLegacyHookFunc func(ctx context.Context, order *testOrder) error
+
+// 主题只能声明一次，在包级别声明，重复运行测试时不会 panic
+var testLegacyTopic = base_enum.Hook.BusinessType.Register("test.topic.legacy", "兼容映射测试", "base_hook.testLegacyHookFunc", "base_hook.testOldHookFunc", "test.topic.old")
+
+func TestBusinessType_Match(t *testing.T) {
+	cases := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{pattern: "order.created", topic: "order.created", want: true},
+		{pattern: "order.created", topic: "order.paid", want: false},
+		{pattern: "order.*", topic: "order.created", want: true},
+		{pattern: "order.*", topic: "order", want: false},
+		{pattern: "order.*", topic: "order.created.v2", want: false},
+		{pattern: "*.created", topic: "order.created", want: true},
+		{pattern: "order.#", topic: "order", want: true},
+		{pattern: "order.#", topic: "order.created.v2", want: true},
+		{pattern: "order.#", topic: "user.created", want: false},
+		{pattern: "#", topic: "order.created", want: true},
+		{pattern: "order.*.v2", topic: "order.created.v2", want: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern+"/"+c.topic, func(t *testing.T) {
+			if got := base_enum.Hook.BusinessType.Match(c.pattern, c.topic); got != c.want {
+				t.Fatalf("Match(%s, %s) = %v, want %v", c.pattern, c.topic, got, c.want)
+			}
+		})
+	}
+}
+
+func TestBusinessType_Validate(t *testing.T) {
+	cases := []struct {
+		topic     string
+		wantErr   bool
+		isPattern bool
+	}{
+		{topic: "order.created"},
+		{topic: "order.*", isPattern: true},
+		{topic: "order.#", isPattern: true},
+		{topic: "", wantErr: true},
+		{topic: "order..created", wantErr: true},
+		{topic: "order.#.created", wantErr: true, isPattern: true},
+		{topic: "order.cre*", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.topic, func(t *testing.T) {
+			if err := base_enum.Hook.BusinessType.Validate(c.topic); (err != nil) != c.wantErr {
+				t.Fatalf("Validate(%s) = %v, want error %v", c.topic, err, c.wantErr)
+			}
+			if got := base_enum.Hook.BusinessType.IsPattern(c.topic); got != c.isPattern {
+				t.Fatalf("IsPattern(%s) = %v, want %v", c.topic, got, c.isPattern)
+			}
+		})
+	}
+}
+
+func TestBusinessType_Register(t *testing.T) {
+	businessType := base_enum.Hook.BusinessType
+	topic := testLegacyTopic.Code()
+
+	// 主题、旧类型名及旧主题均解析为声明的主题
+	for _, code := range []string{topic, "base_hook.testOldHookFunc", "test.topic.old"} {
+		if item, ok := businessType.Lookup(code); !ok || item.Code() != topic || businessType.Resolve(code) != topic || businessType.New(code).Code() != topic {
+			t.Fatalf("Lookup(%s) = %v, %v, want %s", code, item, ok, topic)
+		}
+	}
+	if businessType.Resolve("test.topic.undeclared") != "test.topic.undeclared" {
+		t.Fatal("Resolve() of an undeclared topic changed the topic")
+	}
+	if !slices.ContainsFunc(businessType.Topics(), func(item base_enum.HookBusinessType) bool { return item.Code() == topic }) {
+		t.Fatalf("Topics() does not contain %s", topic)
+	}
+
+	mustPanic(t, "重复声明", func() { businessType.Register(topic, "") })
+	mustPanic(t, "已作为", func() { businessType.Register("test.topic.old", "") })
+	mustPanic(t, "与已声明的主题冲突", func() { businessType.Register("test.topic.conflict", "", topic) })
+	mustPanic(t, "已映射到", func() { businessType.Register("test.topic.conflict", "", "test.topic.old") })
+	mustPanic(t, "通配符", func() { businessType.Register("test.topic.*x", "") })
+}
+
+func TestGateway_WildcardSubscribe(t *testing.T) {
+	var received []string
+	subscribe := func(topic string) {
+		t.Helper()
+		if err := gateway.Subscribe(topic, func(model base_model.HookModel) error {
+			received = append(received, topic+"="+model.BusinessTypeStr)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			gateway.Unsubscribe(topic)
+		})
+	}
+	subscribe("test.wild.created")
+	subscribe("test.wild.*")
+	subscribe("test.wild.#")
+
+	// 同一模式只能订阅一次
+	if err := gateway.Subscribe("test.wild.*", func(model base_model.HookModel) error { return nil }); err == nil {
+		t.Fatal("Subscribe() of a subscribed pattern = nil, want error")
+	}
+
+	cases := []struct {
+		topic string
+		want  []string
+	}{
+		{topic: "test.wild.created", want: []string{"test.wild.created=test.wild.created", "test.wild.*=test.wild.created", "test.wild.#=test.wild.created"}},
+		{topic: "test.wild.created.v2", want: []string{"test.wild.#=test.wild.created.v2"}},
+		{topic: "test.wild", want: []string{"test.wild.#=test.wild"}},
+		{topic: "test.other", want: nil},
+	}
+	for _, c := range cases {
+		t.Run(c.topic, func(t *testing.T) {
+			received = nil
+			if err := gateway.broadcast(base_model.HookModel{BusinessTypeStr: c.topic}); err != nil {
+				t.Fatal(err)
+			}
+			// 通配符订阅的调用顺序不固定
+			slices.Sort(received)
+			want := slices.Clone(c.want)
+			slices.Sort(want)
+			if !slices.Equal(received, want) {
+				t.Fatalf("received = %v, want %v", received, want)
+			}
+		})
+	}
+
+	// 包含通配符的主题只能用于订阅
+	if err := deliverHookModel(context.Background(), base_model.HookModel{BusinessTypeStr: "test.wild.*"}); err == nil {
+		t.Fatal("deliverHookModel() of a pattern = nil, want error")
+	}
+}
+
+func TestGateway_LegacyTypeName(t *testing.T) {
+	hook := &BaseHook[string, testLegacyHookFunc]{}
+	hook.SetWorkerPool(1)
+	t.Cleanup(func() {
+		hook.ClearAllHook()
+		gateway.Unsubscribe(hook.GetBusinessType().Code())
+	})
+
+	var received []int64
+	hook.InstallHook("a", func(ctx context.Context, order *testOrder) error {
+		received = append(received, order.Id)
+		return nil
+	})
+
+	// 订阅函数类型名映射到声明的主题，网关按主题注册
+	if hook.GetBusinessType().Code() != testLegacyTopic.Code() || !gateway.isSubscribed(testLegacyTopic.Code()) {
+		t.Fatalf("GetBusinessType() = %s, want %s", hook.GetBusinessType().Code(), testLegacyTopic.Code())
+	}
+
+	// 旧版本按Go类型名或旧主题发送的消息，转换为主题后交给本地的订阅者
+	for _, code := range []string{testLegacyTopic.Code(), "base_hook.testOldHookFunc", "test.topic.old"} {
+		t.Run(code, func(t *testing.T) {
+			received = nil
+			model := base_model.HookModel{
+				BusinessTypeStr: code,
+				Data:            g.Map{"data": g.Map{"id": 5}},
+			}
+			if err := gateway.broadcast(model); err != nil || !slices.Equal(received, []int64{5}) {
+				t.Fatalf("broadcast(%s) = %v, received %v", code, err, received)
+			}
+		})
+	}
+}
@@ -0,0 +1,660 @@
+package base_hook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/container/gtype"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/gclient"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/gorilla/websocket"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	可靠的websocket传输：
+		1、与每个配置的服务保持长连接，定时发送ping心跳，断开后按指数退避自动重连
+		2、每条消息携带消息ID，对端处理完成后回复ack/nack
+		3、超时未确认或连接断开的消息重新投递（至少一次），超过最大投递次数后返回错误
+		4、每个服务独立的有界发送队列，队列满时快速失败
+		5、关闭时停止接收新消息，等待队列中的消息全部确认后断开连接；连接关闭时仍未结束的消息返回 ErrPeerUnavailable
+		6、未设置或设置为非正数的传输配置项使用默认值
+*/
+
+var (
+	ErrTransportClosed = gerror.New("Hook传输已关闭")
+	ErrPeerQueueFull   = gerror.New("Hook服务发送队列已满")
+	ErrPeerUnavailable = gerror.New("Hook服务不可达")
+	ErrAckTimeout      = gerror.New("Hook消息等待确认超时")
+	ErrMessageRejected = gerror.New("Hook消息被对端拒绝")
+	errPeerConnDropped = gerror.New("Hook服务连接已断开")
+)
+
+// 服务连接状态
+const (
+	peerStateConnecting = "connecting"
+	peerStateConnected  = "connected"
+	peerStateDown       = "disconnected"
+	peerStateClosed     = "closed"
+)
+
+// 传输配置未设置时使用的默认值
+const (
+	defaultQueueSize            = 1024
+	defaultAckTimeout           = 5 * time.Second
+	defaultMaxAttempts          = 3
+	defaultPingInterval         = 15 * time.Second
+	defaultHandshakeTimeout     = time.Second
+	defaultReconnectBaseBackoff = 500 * time.Millisecond
+	defaultReconnectMaxBackoff  = 30 * time.Second
+)
+
+// wsArr 服务地址与连接的映射，key为ws地址，value为 *peerConn
+var wsArr = gmap.NewStrAnyMap(true)
+
+// TransportOption websocket传输配置
+type TransportOption struct {
+	QueueSize            int           // 每个服务的发送队列长度
+	AckTimeout           time.Duration // 等待对端确认的超时时间
+	MaxAttempts          int           // 单条消息的最大投递次数
+	PingInterval         time.Duration // 心跳间隔
+	HandshakeTimeout     time.Duration // 握手超时时间
+	ReconnectBaseBackoff time.Duration // 首次重连的退避时间
+	ReconnectMaxBackoff  time.Duration // 重连退避时间上限
+}
+
+// DefaultTransportOption 从配置 service.hook.transport 读取传输配置，未配置的项使用默认值
+func DefaultTransportOption(ctx context.Context) TransportOption {
+	return TransportOption{
+		QueueSize:            g.Cfg().MustGet(ctx, "service.hook.transport.queueSize", 1024).Int(),
+		AckTimeout:           g.Cfg().MustGet(ctx, "service.hook.transport.ackTimeout", "5s").Duration(),
+		MaxAttempts:          g.Cfg().MustGet(ctx, "service.hook.transport.maxAttempts", 3).Int(),
+		PingInterval:         g.Cfg().MustGet(ctx, "service.hook.transport.pingInterval", "15s").Duration(),
+		HandshakeTimeout:     g.Cfg().MustGet(ctx, "service.hook.transport.handshakeTimeout", "1s").Duration(),
+		ReconnectBaseBackoff: g.Cfg().MustGet(ctx, "service.hook.transport.reconnectBaseBackoff", "500ms").Duration(),
+		ReconnectMaxBackoff:  g.Cfg().MustGet(ctx, "service.hook.transport.reconnectMaxBackoff", "30s").Duration(),
+	}
+}
+
+// withDefaults 未设置或设置为非正数的项使用默认值，避免确认超时为0时消息立即超时、队列长度为0时无法发送
+func (o TransportOption) withDefaults() TransportOption {
+	if o.QueueSize <= 0 {
+		o.QueueSize = defaultQueueSize
+	}
+	if o.AckTimeout <= 0 {
+		o.AckTimeout = defaultAckTimeout
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = defaultMaxAttempts
+	}
+	if o.PingInterval <= 0 {
+		o.PingInterval = defaultPingInterval
+	}
+	if o.HandshakeTimeout <= 0 {
+		o.HandshakeTimeout = defaultHandshakeTimeout
+	}
+	if o.ReconnectBaseBackoff <= 0 {
+		o.ReconnectBaseBackoff = defaultReconnectBaseBackoff
+	}
+	if o.ReconnectMaxBackoff < o.ReconnectBaseBackoff {
+		o.ReconnectMaxBackoff = max(defaultReconnectMaxBackoff, o.ReconnectBaseBackoff)
+	}
+	return o
+}
+
+// ITransport Hook消息传输接口，可通过 RegisterTransport 注册自定义实现
+type ITransport interface {
+	// Name 传输方式名称，用于配置 service.hook.routing 选择传输方式
+	Name() string
+	// Connect 与指定服务建立连接，未指定时使用发现的服务地址，已建立的连接不受影响
+	Connect(ctx context.Context, addrs ...string) error
+	// Send 发送消息给指定服务，并等待对端确认
+	Send(ctx context.Context, addr string, model base_model.HookModel) error
+	// Disconnect 服务离开后断开与该服务的连接，发送中的消息结束后退出，之后发送时重新建立连接
+	Disconnect(ctx context.Context, addr string)
+	// Shutdown 停止接收新消息，等待发送中的消息结束后断开所有连接
+	Shutdown(ctx context.Context) error
+}
+
+// IWebsocketTransport websocket传输接口
+type IWebsocketTransport interface {
+	ITransport
+	// SetOption 设置传输配置，仅对之后建立的连接生效
+	SetOption(option TransportOption)
+}
+
+// sTransport websocket传输实现
+type sTransport struct {
+	mu     sync.Mutex
+	option *TransportOption
+	closed gtype.Bool
+}
+
+var transport = sTransport{}
+
+// Transport 获取websocket传输
+func Transport() IWebsocketTransport {
+	return &transport
+}
+
+// Name 传输方式名称
+func (s *sTransport) Name() string {
+	return TransportWebsocket
+}
+
+// getOption 获取传输配置，首次使用时从配置文件读取
+func (s *sTransport) getOption(ctx context.Context) TransportOption {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.option == nil {
+		option := DefaultTransportOption(ctx).withDefaults()
+		s.option = &option
+	}
+
+	return *s.option
+}
+
+// SetOption 设置传输配置，仅对之后建立的连接生效，未设置的项使用默认值
+func (s *sTransport) SetOption(option TransportOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	option = option.withDefaults()
+	s.option = &option
+}
+
+// Connect 与指定服务建立长连接，未指定时使用发现的服务地址
+func (s *sTransport) Connect(ctx context.Context, addrs ...string) error {
+	if s.closed.Val() {
+		return ErrTransportClosed
+	}
+
+	if len(addrs) == 0 {
+		addrs = peerAddresses(ctx)
+	}
+	for _, addr := range addrs {
+		if _, err := s.peer(ctx, addr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Send 发送消息给指定服务，并等待对端确认
+func (s *sTransport) Send(ctx context.Context, addr string, model base_model.HookModel) error {
+	if s.closed.Val() {
+		return ErrTransportClosed
+	}
+
+	if model.MessageId == "" {
+		model.MessageId = guid.S()
+	}
+
+	p, err := s.peer(ctx, addr)
+	if err != nil {
+		return err
+	}
+
+	return p.send(ctx, model)
+}
+
+// Shutdown 停止接收新消息，等待发送队列清空后断开所有连接
+func (s *sTransport) Shutdown(ctx context.Context) error {
+	if !s.closed.Cas(false, true) {
+		return nil
+	}
+
+	peers := make([]*peerConn, 0)
+	wsArr.Iterator(func(k string, v interface{}) bool {
+		peers = append(peers, v.(*peerConn))
+		return true
+	})
+
+	for _, p := range peers {
+		p.close()
+	}
+
+	for _, p := range peers {
+		select {
+		case <-p.stopped:
+			wsArr.Remove(p.url)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Disconnect 服务离开后断开连接，发送队列中的消息发送完成后退出
+func (s *sTransport) Disconnect(ctx context.Context, addr string) {
+	urlStr, err := s.peerUrl(ctx, addr)
+	if err != nil {
+		return
+	}
+
+	v := wsArr.Remove(urlStr)
+	if v != nil {
+		v.(*peerConn).close()
+	}
+}
+
+// peerUrl 服务的ws地址，例如：ws://127.0.0.1:7778/ws
+func (s *sTransport) peerUrl(ctx context.Context, addr string) (string, error) {
+	security, err := getSecurityOption(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	wsPath := g.Cfg().MustGet(ctx, "service.wsPath", "/ws").String()
+	return wsScheme(security) + addr + wsPath, nil
+}
+
+// peer 获取指定服务的连接，不存在时创建并同步尝试首次连接；安全配置无效时返回错误
+func (s *sTransport) peer(ctx context.Context, addr string) (*peerConn, error) {
+	urlStr, err := s.peerUrl(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	created := false
+	v := wsArr.GetOrSetFuncLock(urlStr, func() interface{} {
+		created = true
+		return newPeerConn(urlStr, s.getOption(ctx))
+	})
+
+	p := v.(*peerConn)
+	if created {
+		p.start()
+	}
+
+	return p, nil
+}
+
+// outgoingMessage 待发送的消息
+type outgoingMessage struct {
+	model    base_model.HookModel
+	attempts int
+	sentAt   time.Time
+	done     chan error
+	once     sync.Once
+}
+
+// finish 结束消息的投递，并通知发送方
+func (m *outgoingMessage) finish(err error) {
+	m.once.Do(func() {
+		m.done <- err
+	})
+}
+
+// peerConn 与单个服务的长连接
+type peerConn struct {
+	url     string
+	option  TransportOption
+	queue   chan *outgoingMessage
+	pending *gmap.StrAnyMap // 已发送待确认的消息，key为消息ID
+	state   *gtype.String
+	since   *gtype.Int64  // 进入当前状态的时间，毫秒
+	lastErr *gtype.String // 最后一次连接失败的原因
+	ready   chan struct{}
+	closing chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newPeerConn(url string, option TransportOption) *peerConn {
+	option = option.withDefaults()
+
+	return &peerConn{
+		url:     url,
+		option:  option,
+		queue:   make(chan *outgoingMessage, option.QueueSize),
+		pending: gmap.NewStrAnyMap(true),
+		state:   gtype.NewString(peerStateConnecting),
+		since:   gtype.NewInt64(time.Now().UnixMilli()),
+		lastErr: gtype.NewString(),
+		ready:   make(chan struct{}),
+		closing: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// start 启动连接协程，并等待首次连接的结果
+func (p *peerConn) start() {
+	go p.run()
+	<-p.ready
+}
+
+// close 通知连接协程在发送队列清空后退出
+func (p *peerConn) close() {
+	p.once.Do(func() {
+		close(p.closing)
+	})
+}
+
+func (p *peerConn) isClosing() bool {
+	select {
+	case <-p.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// idle 发送队列及待确认消息均为空
+func (p *peerConn) idle() bool {
+	return len(p.queue) == 0 && p.pending.Size() == 0
+}
+
+// send 将消息放入发送队列，并等待对端确认；连接已关闭时返回 ErrPeerUnavailable
+func (p *peerConn) send(ctx context.Context, model base_model.HookModel) error {
+	if p.isClosing() {
+		return gerror.Wrap(ErrPeerUnavailable, p.url)
+	}
+
+	if p.state.Val() != peerStateConnected {
+		return gerror.Wrap(ErrPeerUnavailable, p.url)
+	}
+
+	msg := &outgoingMessage{
+		model: model,
+		done:  make(chan error, 1),
+	}
+
+	select {
+	case p.queue <- msg:
+	default:
+		return gerror.Wrap(ErrPeerQueueFull, p.url)
+	}
+
+	select {
+	case err := <-msg.done:
+		return err
+	case <-p.stopped:
+		// 连接协程退出后才放入队列的消息不会再被投递，已结束的消息返回其结果
+		msg.finish(gerror.Wrap(ErrPeerUnavailable, p.url))
+		return <-msg.done
+	case <-ctx.Done():
+		// 消息仍在队列中，后续仍会投递
+		return ctx.Err()
+	}
+}
+
+// run 连接协程：建立连接、收发消息，断开后按指数退避重连
+func (p *peerConn) run() {
+	defer close(p.stopped)
+
+	attempt := 0
+	readyOnce := sync.Once{}
+	notifyReady := func() {
+		readyOnce.Do(func() {
+			close(p.ready)
+		})
+	}
+	defer notifyReady()
+
+	for {
+		if p.isClosing() && (p.idle() || p.state.Val() != peerStateConnected) {
+			p.failAll(gerror.Wrap(ErrPeerUnavailable, p.url))
+			p.setState(peerStateClosed)
+			return
+		}
+
+		conn, err := p.dial()
+		if err != nil {
+			attempt++
+			p.lastErr.Set(err.Error())
+			p.setState(peerStateDown)
+			notifyReady()
+
+			select {
+			case <-time.After(backoffDuration(p.option.ReconnectBaseBackoff, p.option.ReconnectMaxBackoff, attempt)):
+			case <-p.closing:
+			}
+			continue
+		}
+
+		attempt = 0
+		p.setState(peerStateConnected)
+		notifyReady()
+
+		p.serve(conn)
+
+		if !p.isClosing() {
+			p.setState(peerStateDown)
+		}
+		p.requeuePending()
+	}
+}
+
+// setState 设置连接状态，并记录进入该状态的时间
+func (p *peerConn) setState(state string) {
+	if p.state.Set(state) != state {
+		p.since.Set(time.Now().UnixMilli())
+	}
+}
+
+// info 获取连接信息
+func (p *peerConn) info() PeerInfo {
+	return PeerInfo{
+		Url:        p.url,
+		State:      p.state.Val(),
+		StateSince: gtime.NewFromTimeStamp(p.since.Val()),
+		LastError:  p.lastErr.Val(),
+		Queued:     len(p.queue),
+		Pending:    p.pending.Size(),
+	}
+}
+
+// dial 建立websocket连接，并在握手请求中携带认证信息
+func (p *peerConn) dial() (*websocket.Conn, error) {
+	ctx := context.Background()
+
+	tlsConfig, err := makeClientTLSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := makeHandshakeHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := gclient.NewWebSocket()
+	client.HandshakeTimeout = p.option.HandshakeTimeout
+	client.TLSClientConfig = tlsConfig // 设置 tls 配置
+
+	conn, _, err := client.Dial(p.url, header)
+	return conn, err
+}
+
+// serve 在已建立的连接上收发消息，连接断开或关闭完成时返回
+func (p *peerConn) serve(conn *websocket.Conn) {
+	readErr := make(chan error, 1)
+
+	// 对端超过两个心跳周期无响应则视为断开
+	deadline := p.option.PingInterval * 2
+	_ = conn.SetReadDeadline(time.Now().Add(deadline))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(deadline))
+	})
+
+	go func() {
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			_ = conn.SetReadDeadline(time.Now().Add(deadline))
+			p.handleFrame(msg)
+		}
+	}()
+
+	ping := time.NewTicker(p.option.PingInterval)
+	defer ping.Stop()
+	check := time.NewTicker(checkInterval(p.option.AckTimeout))
+	defer check.Stop()
+
+	disconnect := func() {
+		_ = conn.Close()
+		<-readErr
+	}
+
+	for {
+		select {
+		case <-readErr:
+			_ = conn.Close()
+			return
+
+		case msg := <-p.queue:
+			if err := p.write(conn, msg); err != nil {
+				disconnect()
+				return
+			}
+
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(p.option.HandshakeTimeout)); err != nil {
+				disconnect()
+				return
+			}
+
+		case <-check.C:
+			p.checkTimeout()
+
+			if p.isClosing() && p.idle() {
+				_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(p.option.HandshakeTimeout))
+				disconnect()
+				return
+			}
+		}
+	}
+}
+
+// write 签名并发送消息，并记录为待确认
+func (p *peerConn) write(conn *websocket.Conn, msg *outgoingMessage) error {
+	msg.attempts++
+	msg.sentAt = time.Now()
+	p.pending.Set(msg.model.MessageId, msg)
+
+	model := msg.model
+	if err := signHookModel(context.Background(), &model); err != nil {
+		p.pending.Remove(msg.model.MessageId)
+		msg.finish(err)
+		return nil
+	}
+
+	_ = conn.SetWriteDeadline(time.Now().Add(p.option.AckTimeout))
+	return conn.WriteJSON(model)
+}
+
+// handleFrame 处理对端回复的确认消息及请求的应答
+func (p *peerConn) handleFrame(frame []byte) {
+	model := base_model.HookModel{}
+	if err := gjson.DecodeTo(frame, &model); err != nil {
+		return
+	}
+
+	// 签名无效的确认消息直接忽略，等待超时后重新投递
+	if err := verifyHookModel(context.Background(), &model); err != nil {
+		return
+	}
+
+	// 请求的应答交给等待中的调用方
+	if isReplyFrame(model) {
+		dispatchReply(model)
+		return
+	}
+
+	v := p.pending.Remove(model.MessageId)
+	if v == nil {
+		return
+	}
+	msg := v.(*outgoingMessage)
+
+	switch model.MessageType().Code() {
+	case base_enum.Hook.MessageType.Ack.Code():
+		msg.finish(nil)
+	case base_enum.Hook.MessageType.Nack.Code():
+		msg.finish(newRemoteError(model))
+	default:
+		// 非确认消息，放回待确认列表
+		p.pending.Set(model.MessageId, msg)
+	}
+}
+
+// checkTimeout 重新投递超时未确认的消息
+func (p *peerConn) checkTimeout() {
+	now := time.Now()
+	expired := make([]*outgoingMessage, 0)
+
+	p.pending.Iterator(func(k string, v interface{}) bool {
+		msg := v.(*outgoingMessage)
+		if now.Sub(msg.sentAt) >= p.option.AckTimeout {
+			expired = append(expired, msg)
+		}
+		return true
+	})
+
+	for _, msg := range expired {
+		p.pending.Remove(msg.model.MessageId)
+		p.redeliver(msg, ErrAckTimeout)
+	}
+}
+
+// requeuePending 连接断开后，将未确认的消息重新放回发送队列
+func (p *peerConn) requeuePending() {
+	for _, v := range p.pending.Map() {
+		msg := v.(*outgoingMessage)
+		p.pending.Remove(msg.model.MessageId)
+		p.redeliver(msg, errPeerConnDropped)
+	}
+}
+
+// redeliver 重新投递消息，超过最大投递次数或队列已满时返回错误
+func (p *peerConn) redeliver(msg *outgoingMessage, cause error) {
+	if msg.attempts >= p.option.MaxAttempts {
+		msg.finish(gerror.Wrapf(cause, "%s 已投递 %d 次", p.url, msg.attempts))
+		return
+	}
+
+	select {
+	case p.queue <- msg:
+	default:
+		msg.finish(gerror.Wrap(ErrPeerQueueFull, p.url))
+	}
+}
+
+// failAll 结束队列及待确认列表中的所有消息
+func (p *peerConn) failAll(err error) {
+	for {
+		select {
+		case msg := <-p.queue:
+			msg.finish(err)
+		default:
+			for _, v := range p.pending.Map() {
+				p.pending.Remove(v.(*outgoingMessage).model.MessageId)
+				v.(*outgoingMessage).finish(err)
+			}
+			return
+		}
+	}
+}
+
+// checkInterval 检查确认超时的间隔
+func checkInterval(ackTimeout time.Duration) time.Duration {
+	interval := ackTimeout / 5
+	if interval < 10*time.Millisecond {
+		return 10 * time.Millisecond
+	}
+	return interval
+}
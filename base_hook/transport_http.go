@@ -0,0 +1,448 @@
+package base_hook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/container/gtype"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	HTTP传输：适用于无法保持长连接的网络环境
+		1、每个服务独立的发送队列，攒够 batchSize 条或等待 batchInterval 后合并为一次POST请求
+		2、请求体为消息数组，响应体为对应的确认消息数组，每条消息独立确认
+		3、请求头携带与websocket握手相同的认证信息
+		4、不支持Hook请求（Call），请求/应答请使用websocket或tcp传输
+		5、接收方需要注册 HookHttpDistribution 路由，路径与 service.hook.http.path 一致
+*/
+
+var ErrHttpBatchFailed = gerror.New("Hook消息批量投递失败")
+
+// HttpTransportOption http传输配置
+type HttpTransportOption struct {
+	Path          string        // 接收消息的路径
+	QueueSize     int           // 每个服务的发送队列长度
+	BatchSize     int           // 每次请求合并的最大消息数
+	BatchInterval time.Duration // 等待合并的最长时间
+	Timeout       time.Duration // 单次请求的超时时间
+}
+
+// DefaultHttpTransportOption 从配置 service.hook.http 读取传输配置，未配置的项使用默认值
+func DefaultHttpTransportOption(ctx context.Context) HttpTransportOption {
+	return HttpTransportOption{
+		Path:          g.Cfg().MustGet(ctx, "service.hook.http.path", "/hook/http").String(),
+		QueueSize:     g.Cfg().MustGet(ctx, "service.hook.http.queueSize", 1024).Int(),
+		BatchSize:     g.Cfg().MustGet(ctx, "service.hook.http.batchSize", 100).Int(),
+		BatchInterval: g.Cfg().MustGet(ctx, "service.hook.http.batchInterval", "10ms").Duration(),
+		Timeout:       g.Cfg().MustGet(ctx, "service.hook.http.timeout", "5s").Duration(),
+	}
+}
+
+// IHttpTransport http传输接口
+type IHttpTransport interface {
+	ITransport
+	// SetOption 设置传输配置，仅对之后创建的发送队列生效
+	SetOption(option HttpTransportOption)
+}
+
+// sHttpTransport http传输实现
+type sHttpTransport struct {
+	mu       sync.Mutex
+	option   *HttpTransportOption
+	closed   gtype.Bool
+	batchers *gmap.StrAnyMap // key为服务地址，value为 *httpBatcher
+}
+
+var httpTransport = sHttpTransport{
+	batchers: gmap.NewStrAnyMap(true),
+}
+
+// HttpTransport 获取http传输
+func HttpTransport() IHttpTransport {
+	return &httpTransport
+}
+
+// Name 传输方式名称
+func (s *sHttpTransport) Name() string {
+	return TransportHttp
+}
+
+// getOption 获取传输配置，首次使用时从配置文件读取
+func (s *sHttpTransport) getOption(ctx context.Context) HttpTransportOption {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.option == nil {
+		option := DefaultHttpTransportOption(ctx)
+		s.option = &option
+	}
+
+	return *s.option
+}
+
+// SetOption 设置传输配置，仅对之后创建的发送队列生效
+func (s *sHttpTransport) SetOption(option HttpTransportOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.option = &option
+}
+
+// Connect 为指定服务创建发送队列，未指定时使用发现的服务地址
+func (s *sHttpTransport) Connect(ctx context.Context, addrs ...string) error {
+	if s.closed.Val() {
+		return ErrTransportClosed
+	}
+
+	if len(addrs) == 0 {
+		addrs = peerAddresses(ctx)
+	}
+	for _, addr := range addrs {
+		if _, err := s.batcher(ctx, addr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Send 将消息放入服务的发送队列，并等待对端确认
+func (s *sHttpTransport) Send(ctx context.Context, addr string, model base_model.HookModel) error {
+	if s.closed.Val() {
+		return ErrTransportClosed
+	}
+
+	if model.MessageId == "" {
+		model.MessageId = guid.S()
+	}
+
+	b, err := s.batcher(ctx, addr)
+	if err != nil {
+		return err
+	}
+
+	return b.send(ctx, model)
+}
+
+// Shutdown 停止接收新消息，等待发送队列中的消息全部发送后退出
+func (s *sHttpTransport) Shutdown(ctx context.Context) error {
+	if !s.closed.Cas(false, true) {
+		return nil
+	}
+
+	batchers := make([]*httpBatcher, 0)
+	s.batchers.Iterator(func(k string, v interface{}) bool {
+		batchers = append(batchers, v.(*httpBatcher))
+		return true
+	})
+
+	for _, b := range batchers {
+		b.close()
+	}
+
+	for _, b := range batchers {
+		select {
+		case <-b.stopped:
+			s.batchers.Remove(b.addr)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Disconnect 服务离开后移除该服务的发送队列，队列中的消息发送完成后退出
+func (s *sHttpTransport) Disconnect(ctx context.Context, addr string) {
+	if v := s.batchers.Remove(addr); v != nil {
+		v.(*httpBatcher).close()
+	}
+}
+
+// batcher 获取指定服务的发送队列，不存在时创建
+func (s *sHttpTransport) batcher(ctx context.Context, addr string) (*httpBatcher, error) {
+	var err error
+	v := s.batchers.GetOrSetFuncLock(addr, func() interface{} {
+		var b *httpBatcher
+		if b, err = newHttpBatcher(ctx, addr, s.getOption(ctx)); err != nil {
+			return nil
+		}
+		go b.run()
+		return b
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(*httpBatcher), nil
+}
+
+// httpBatcher 单个服务的发送队列
+type httpBatcher struct {
+	addr    string
+	url     string
+	option  HttpTransportOption
+	client  *http.Client
+	queue   chan *outgoingMessage
+	closing chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newHttpBatcher(ctx context.Context, addr string, option HttpTransportOption) (*httpBatcher, error) {
+	tlsConfig, err := makeClientTLSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	security, err := getSecurityOption(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "http://"
+	if security.TLS.Enabled {
+		scheme = "https://"
+	}
+
+	return &httpBatcher{
+		addr:   addr,
+		url:    scheme + addr + option.Path, // 例如：http://127.0.0.1:7778/hook/http
+		option: option,
+		client: &http.Client{
+			Timeout:   option.Timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+		queue:   make(chan *outgoingMessage, option.QueueSize),
+		closing: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// close 通知发送协程在发送队列清空后退出
+func (b *httpBatcher) close() {
+	b.once.Do(func() {
+		close(b.closing)
+	})
+}
+
+// send 将消息放入发送队列，并等待对端确认
+func (b *httpBatcher) send(ctx context.Context, model base_model.HookModel) error {
+	msg := &outgoingMessage{
+		model: model,
+		done:  make(chan error, 1),
+	}
+
+	select {
+	case <-b.closing:
+		return ErrTransportClosed
+	default:
+	}
+
+	select {
+	case b.queue <- msg:
+	default:
+		return gerror.Wrap(ErrPeerQueueFull, b.url)
+	}
+
+	select {
+	case err := <-msg.done:
+		return err
+	case <-ctx.Done():
+		// 消息仍在队列中，后续仍会投递
+		return ctx.Err()
+	}
+}
+
+// run 发送协程：合并队列中的消息批量发送，关闭时发送剩余的消息后退出
+func (b *httpBatcher) run() {
+	defer close(b.stopped)
+
+	for {
+		var first *outgoingMessage
+		select {
+		case first = <-b.queue:
+		case <-b.closing:
+			b.flush(b.drain(nil))
+			return
+		}
+
+		batch := []*outgoingMessage{first}
+		timer := time.NewTimer(b.option.BatchInterval)
+	collect:
+		for len(batch) < b.option.BatchSize {
+			select {
+			case msg := <-b.queue:
+				batch = append(batch, msg)
+			case <-timer.C:
+				break collect
+			case <-b.closing:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		b.flush(batch)
+	}
+}
+
+// drain 取出队列中剩余的消息
+func (b *httpBatcher) drain(batch []*outgoingMessage) []*outgoingMessage {
+	for {
+		select {
+		case msg := <-b.queue:
+			batch = append(batch, msg)
+		default:
+			return batch
+		}
+	}
+}
+
+// flush 发送一批消息，并按对端返回的确认消息结束每条消息的投递
+func (b *httpBatcher) flush(batch []*outgoingMessage) {
+	if len(batch) == 0 {
+		return
+	}
+
+	replies, err := b.post(batch)
+	if err != nil {
+		err = gerror.Wrapf(ErrHttpBatchFailed, "%s：%s", b.url, err.Error())
+		for _, msg := range batch {
+			msg.finish(err)
+		}
+		return
+	}
+
+	for _, msg := range batch {
+		reply, ok := replies[msg.model.MessageId]
+		switch {
+		case !ok:
+			msg.finish(gerror.Wrapf(ErrAckTimeout, "%s 未返回确认", b.url))
+		case reply.MessageType().Code() == base_enum.Hook.MessageType.Ack.Code():
+			msg.finish(nil)
+		default:
+			msg.finish(newRemoteError(reply))
+		}
+	}
+}
+
+// post 签名并发送一批消息，返回校验通过的确认消息，key为消息ID
+func (b *httpBatcher) post(batch []*outgoingMessage) (map[string]base_model.HookModel, error) {
+	ctx := context.Background()
+
+	models := make([]base_model.HookModel, 0, len(batch))
+	for _, msg := range batch {
+		model := msg.model
+		if err := signHookModel(ctx, &model); err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+
+	body, err := gjson.Encode(models)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := makeHandshakeHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, gerror.Newf("对端返回状态码 %d", resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	replyArr := make([]base_model.HookModel, 0)
+	if err = gjson.DecodeTo(content, &replyArr); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]base_model.HookModel, len(replyArr))
+	for _, reply := range replyArr {
+		// 签名无效的确认消息视为未确认
+		if err = verifyHookModel(ctx, &reply); err != nil {
+			continue
+		}
+		result[reply.MessageId] = reply
+	}
+
+	return result, nil
+}
+
+// HookHttpDistribution 接收http传输批量投递的消息，需要在路由注册时候注册，路径与 service.hook.http.path 一致
+func HookHttpDistribution(r *ghttp.Request) {
+	serveHookHttp(r, nil)
+}
+
+// HookHttpDistributionWith 返回使用指定处理函数接收业务消息的http服务，handler 为nil时交由网关广播
+func HookHttpDistributionWith(handler InboundHandler) ghttp.HandlerFunc {
+	return func(r *ghttp.Request) {
+		serveHookHttp(r, handler)
+	}
+}
+
+func serveHookHttp(r *ghttp.Request, handler InboundHandler) {
+	ctx := r.Context()
+
+	// 校验来源白名单及认证信息
+	if err := verifyHandshake(r); err != nil {
+		glog.Warning(ctx, err)
+		r.Response.WriteStatusExit(http.StatusUnauthorized)
+	}
+
+	dataArr := make([]base_model.HookModel, 0)
+	if err := gjson.DecodeTo(r.GetBody(), &dataArr); err != nil {
+		glog.Error(ctx, err)
+		r.Response.WriteStatusExit(http.StatusBadRequest)
+	}
+
+	replies := make([]base_model.HookModel, 0, len(dataArr))
+	for i := range dataArr {
+		data := dataArr[i]
+		data.Source = newHostInfo(r.RemoteAddr)
+
+		reply, ok := receiveHookModel(ctx, &data, handler, nil)
+		if !ok {
+			continue
+		}
+		if err := signHookModel(ctx, &reply); err != nil {
+			glog.Error(ctx, err)
+			continue
+		}
+		replies = append(replies, reply)
+	}
+
+	r.Response.WriteJsonExit(replies)
+}
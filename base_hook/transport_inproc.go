@@ -0,0 +1,186 @@
+package base_hook
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	进程内传输：通过 Broker 在同一进程内模拟多个服务，用于测试及单机部署
+		1、服务通过 Broker.Listen 以地址注册接收函数，发送方按地址投递
+		2、消息经过JSON编解码及签名校验，与网络传输的处理流程一致
+		3、支持Hook请求（Call），应答直接交给等待中的调用方
+*/
+
+// Broker 进程内的消息代理，key为服务地址
+type Broker struct {
+	handlers *gmap.StrAnyMap // key为服务地址，value为 InboundHandler
+}
+
+// DefaultBroker 默认的进程内消息代理，inproc 传输方式使用
+var DefaultBroker = NewBroker()
+
+// NewBroker 创建进程内的消息代理
+func NewBroker() *Broker {
+	return &Broker{
+		handlers: gmap.NewStrAnyMap(true),
+	}
+}
+
+// Listen 以地址注册服务的接收函数，handler 为nil时交由网关广播，重复注册时覆盖
+func (b *Broker) Listen(addr string, handler InboundHandler) {
+	b.handlers.Set(addr, handler)
+}
+
+// Unlisten 注销服务
+func (b *Broker) Unlisten(addr string) {
+	b.handlers.Remove(addr)
+}
+
+// deliver 将消息交给服务的接收函数，返回回复的确认消息
+func (b *Broker) deliver(ctx context.Context, addr string, model base_model.HookModel) (base_model.HookModel, error) {
+	v, ok := b.handlers.Search(addr)
+	if !ok {
+		return base_model.HookModel{}, gerror.Wrap(ErrPeerUnavailable, addr)
+	}
+	handler, _ := v.(InboundHandler)
+
+	data := base_model.HookModel{}
+	if err := encodeDecode(ctx, model, &data); err != nil {
+		return base_model.HookModel{}, err
+	}
+	data.Source = &base_model.HookHostInfo{Host: "inproc"}
+
+	reply, ok := receiveHookModel(ctx, &data, handler, inprocReplyWriter{})
+	if !ok {
+		return base_model.HookModel{}, nil
+	}
+
+	result := base_model.HookModel{}
+	if err := encodeDecode(ctx, reply, &result); err != nil {
+		return base_model.HookModel{}, err
+	}
+	return result, nil
+}
+
+// encodeDecode 签名后经过JSON编解码，并校验签名，模拟网络传输
+func encodeDecode(ctx context.Context, model base_model.HookModel, result *base_model.HookModel) error {
+	if err := signHookModel(ctx, &model); err != nil {
+		return err
+	}
+
+	content, err := gjson.Encode(model)
+	if err != nil {
+		return err
+	}
+
+	return gjson.DecodeTo(content, result)
+}
+
+// inprocReplyWriter 将应答直接交给等待中的调用方
+type inprocReplyWriter struct{}
+
+func (inprocReplyWriter) write(ctx context.Context, model base_model.HookModel) error {
+	reply := base_model.HookModel{}
+	if err := encodeDecode(ctx, model, &reply); err != nil {
+		return err
+	}
+	if err := verifyHookModel(ctx, &reply); err != nil {
+		return err
+	}
+
+	dispatchReply(reply)
+	return nil
+}
+
+// sInProcTransport 进程内传输实现
+type sInProcTransport struct {
+	broker *Broker
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewInProcTransport 创建使用指定消息代理的进程内传输
+func NewInProcTransport(broker *Broker) ITransport {
+	return &sInProcTransport{broker: broker}
+}
+
+// Name 传输方式名称
+func (s *sInProcTransport) Name() string {
+	return TransportInProc
+}
+
+// Connect 进程内传输无需建立连接
+func (s *sInProcTransport) Connect(ctx context.Context, addrs ...string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrTransportClosed
+	}
+	return nil
+}
+
+// Send 将消息交给服务的接收函数，并返回确认结果
+func (s *sInProcTransport) Send(ctx context.Context, addr string, model base_model.HookModel) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrTransportClosed
+	}
+	s.wg.Add(1)
+	s.mu.RUnlock()
+	defer s.wg.Done()
+
+	if model.MessageId == "" {
+		model.MessageId = guid.S()
+	}
+
+	reply, err := s.broker.deliver(ctx, addr, model)
+	if err != nil || reply.MessageId == "" {
+		return err
+	}
+
+	if err = verifyHookModel(ctx, &reply); err != nil {
+		g.Log().Warning(ctx, err)
+		return gerror.Wrap(ErrAckTimeout, addr)
+	}
+	if reply.MessageType().Code() == base_enum.Hook.MessageType.Nack.Code() {
+		return newRemoteError(reply)
+	}
+
+	return nil
+}
+
+// Disconnect 进程内传输无需断开连接
+func (s *sInProcTransport) Disconnect(ctx context.Context, addr string) {
+}
+
+// Shutdown 停止接收新消息，等待处理中的消息结束
+func (s *sInProcTransport) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
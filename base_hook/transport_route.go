@@ -0,0 +1,127 @@
+package base_hook
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	按业务类型选择传输方式：
+		1、内置 websocket（默认）、http（批量POST）、tcp（长度前缀帧）、inproc（进程内，用于测试）四种传输方式
+		2、配置 service.hook.routing.topics 指定业务类型使用的传输方式，支持通配符，精确匹配优先，其次为更具体的模式
+		3、配置 service.hook.routing.hosts 指定各传输方式的服务地址，未配置时使用服务发现的地址（见 discovery.go）
+		4、可通过 RegisterTransport 注册自定义的传输方式
+*/
+
+// 内置的传输方式
+const (
+	TransportWebsocket = "websocket"
+	TransportHttp      = "http"
+	TransportTcp       = "tcp"
+	TransportInProc    = "inproc"
+)
+
+// transportMap 已注册的传输方式，key为名称，value为 ITransport
+var transportMap = gmap.NewStrAnyMap(true)
+
+func init() {
+	RegisterTransport(&transport)
+	RegisterTransport(&httpTransport)
+	RegisterTransport(&tcpTransport)
+	RegisterTransport(NewInProcTransport(DefaultBroker))
+}
+
+// RegisterTransport 注册传输方式，同名时覆盖
+func RegisterTransport(t ITransport) {
+	transportMap.Set(t.Name(), t)
+}
+
+// GetTransport 获取已注册的传输方式
+func GetTransport(name string) (ITransport, bool) {
+	v := transportMap.Get(name)
+	if v == nil {
+		return nil, false
+	}
+	return v.(ITransport), true
+}
+
+// ShutdownTransports 关闭所有已注册的传输方式，返回第一个错误
+func ShutdownTransports(ctx context.Context) error {
+	var firstErr error
+	for _, v := range transportMap.Values() {
+		if err := v.(ITransport).Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// DisconnectTransports 断开所有已注册的传输方式与指定服务的连接
+func DisconnectTransports(ctx context.Context, addr string) {
+	for _, v := range transportMap.Values() {
+		v.(ITransport).Disconnect(ctx, addr)
+	}
+}
+
+// routeTransport 获取业务类型使用的传输方式及服务地址
+func routeTransport(ctx context.Context, businessType string) (ITransport, []string, error) {
+	name := transportName(ctx, businessType)
+
+	t, ok := GetTransport(name)
+	if !ok {
+		return nil, nil, gerror.NewCodef(gcode.CodeInvalidConfiguration, "未注册的Hook传输方式：%s", name)
+	}
+
+	hosts := g.Cfg().MustGet(ctx, "service.hook.routing.hosts."+name).Strings()
+	if len(hosts) == 0 {
+		return t, peerAddresses(ctx), nil
+	}
+
+	return t, excludeLocalPeers(ctx, hosts), nil
+}
+
+// transportName 按配置 service.hook.routing 匹配业务类型使用的传输方式名称
+func transportName(ctx context.Context, businessType string) string {
+	name := g.Cfg().MustGet(ctx, "service.hook.routing.default", TransportWebsocket).String()
+
+	topics := g.Cfg().MustGet(ctx, "service.hook.routing.topics").MapStrStr()
+	if v, ok := topics[businessType]; ok {
+		return v
+	}
+
+	bestScore, bestPattern := -1, ""
+	for pattern, v := range topics {
+		if !base_enum.Hook.BusinessType.Match(pattern, businessType) {
+			continue
+		}
+		// 具体程度相同时按模式排序，保证结果稳定
+		score := patternScore(pattern)
+		if score > bestScore || (score == bestScore && pattern < bestPattern) {
+			bestScore, bestPattern = score, pattern
+			name = v
+		}
+	}
+
+	return name
+}
+
+// patternScore 模式的具体程度：非通配的层级越多越具体，相同时不含 # 的更具体
+func patternScore(pattern string) int {
+	score := 0
+	segments := strings.Split(pattern, ".")
+	for _, segment := range segments {
+		if segment != "*" && segment != "#" {
+			score += 2
+		}
+	}
+	if segments[len(segments)-1] != "#" {
+		score++
+	}
+	return score
+}
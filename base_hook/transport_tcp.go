@@ -0,0 +1,577 @@
+package base_hook
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/container/gtype"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	TCP传输：长度前缀帧，适用于内网高吞吐的场景
+		1、每帧为4字节大端序的长度，后跟JSON编码的内容
+		2、建立连接后的首帧为握手帧，内容为与websocket握手相同的认证信息
+		3、每个服务一条长连接，连接断开后下次发送时重新建立，未确认的消息在超时或断开后重新投递
+		4、支持Hook请求（Call），应答通过同一连接返回
+		5、接收方需要通过 ServeTCP 启动监听，地址为 service.hook.tcp.address
+*/
+
+var ErrFrameTooLarge = gerror.New("Hook消息帧超过长度限制")
+
+// TcpTransportOption tcp传输配置
+type TcpTransportOption struct {
+	Address          string        // 监听地址，ServeTCP 未指定地址时使用
+	MaxFrameSize     int           // 单帧的最大字节数
+	DialTimeout      time.Duration // 建立连接的超时时间
+	HandshakeTimeout time.Duration // 等待握手帧的超时时间
+	AckTimeout       time.Duration // 等待对端确认的超时时间
+	MaxAttempts      int           // 单条消息的最大投递次数
+}
+
+// DefaultTcpTransportOption 从配置 service.hook.tcp 读取传输配置，未配置的项使用默认值
+func DefaultTcpTransportOption(ctx context.Context) TcpTransportOption {
+	return TcpTransportOption{
+		Address:          g.Cfg().MustGet(ctx, "service.hook.tcp.address", ":7878").String(),
+		MaxFrameSize:     g.Cfg().MustGet(ctx, "service.hook.tcp.maxFrameSize", 16<<20).Int(),
+		DialTimeout:      g.Cfg().MustGet(ctx, "service.hook.tcp.dialTimeout", "1s").Duration(),
+		HandshakeTimeout: g.Cfg().MustGet(ctx, "service.hook.tcp.handshakeTimeout", "1s").Duration(),
+		AckTimeout:       g.Cfg().MustGet(ctx, "service.hook.tcp.ackTimeout", "5s").Duration(),
+		MaxAttempts:      g.Cfg().MustGet(ctx, "service.hook.tcp.maxAttempts", 3).Int(),
+	}
+}
+
+// ITcpTransport tcp传输接口
+type ITcpTransport interface {
+	ITransport
+	// SetOption 设置传输配置，仅对之后建立的连接生效
+	SetOption(option TcpTransportOption)
+}
+
+// sTcpTransport tcp传输实现
+type sTcpTransport struct {
+	mu     sync.Mutex
+	option *TcpTransportOption
+	closed gtype.Bool
+	peers  *gmap.StrAnyMap // key为服务地址，value为 *tcpPeer
+}
+
+var tcpTransport = sTcpTransport{
+	peers: gmap.NewStrAnyMap(true),
+}
+
+// TcpTransport 获取tcp传输
+func TcpTransport() ITcpTransport {
+	return &tcpTransport
+}
+
+// Name 传输方式名称
+func (s *sTcpTransport) Name() string {
+	return TransportTcp
+}
+
+// getOption 获取传输配置，首次使用时从配置文件读取
+func (s *sTcpTransport) getOption(ctx context.Context) TcpTransportOption {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.option == nil {
+		option := DefaultTcpTransportOption(ctx)
+		s.option = &option
+	}
+
+	return *s.option
+}
+
+// SetOption 设置传输配置，仅对之后建立的连接生效
+func (s *sTcpTransport) SetOption(option TcpTransportOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.option = &option
+}
+
+// Connect 与指定服务建立长连接，未指定时使用发现的服务地址；连接失败不返回错误，发送时再次尝试；安全配置无效时返回错误
+func (s *sTcpTransport) Connect(ctx context.Context, addrs ...string) error {
+	if s.closed.Val() {
+		return ErrTransportClosed
+	}
+	if _, err := getSecurityOption(ctx); err != nil {
+		return err
+	}
+
+	if len(addrs) == 0 {
+		addrs = peerAddresses(ctx)
+	}
+	for _, addr := range addrs {
+		_, _ = s.peer(ctx, addr).connect()
+	}
+
+	return nil
+}
+
+// Send 发送消息给指定服务，并等待对端确认
+func (s *sTcpTransport) Send(ctx context.Context, addr string, model base_model.HookModel) error {
+	if s.closed.Val() {
+		return ErrTransportClosed
+	}
+
+	if model.MessageId == "" {
+		model.MessageId = guid.S()
+	}
+
+	return s.peer(ctx, addr).send(ctx, model)
+}
+
+// Shutdown 停止接收新消息，等待已发送的消息确认后断开所有连接
+func (s *sTcpTransport) Shutdown(ctx context.Context) error {
+	if !s.closed.Cas(false, true) {
+		return nil
+	}
+
+	peers := make([]*tcpPeer, 0)
+	s.peers.Iterator(func(k string, v interface{}) bool {
+		peers = append(peers, v.(*tcpPeer))
+		return true
+	})
+
+	for _, p := range peers {
+		for p.pending.Size() > 0 {
+			select {
+			case <-time.After(checkInterval(p.option.AckTimeout)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		p.close()
+		s.peers.Remove(p.addr)
+	}
+
+	return nil
+}
+
+// Disconnect 服务离开后移除与该服务的长连接，已发送的消息确认或超时后断开连接
+func (s *sTcpTransport) Disconnect(ctx context.Context, addr string) {
+	v := s.peers.Remove(addr)
+	if v == nil {
+		return
+	}
+
+	p := v.(*tcpPeer)
+	p.closed.Set(true)
+	go func() {
+		for p.pending.Size() > 0 {
+			time.Sleep(checkInterval(p.option.AckTimeout))
+		}
+		p.close()
+	}()
+}
+
+// peer 获取指定服务的连接，不存在时创建
+func (s *sTcpTransport) peer(ctx context.Context, addr string) *tcpPeer {
+	return s.peers.GetOrSetFuncLock(addr, func() interface{} {
+		return &tcpPeer{
+			addr:    addr,
+			option:  s.getOption(ctx),
+			pending: gmap.NewStrAnyMap(true),
+		}
+	}).(*tcpPeer)
+}
+
+// tcpPeer 与单个服务的长连接
+type tcpPeer struct {
+	addr    string
+	option  TcpTransportOption
+	mu      sync.Mutex // 保护连接的建立及写入
+	conn    net.Conn
+	closed  gtype.Bool      // 服务已离开，不再建立新的连接
+	pending *gmap.StrAnyMap // 已发送待确认的消息，key为消息ID
+}
+
+// connect 获取已建立的连接，不存在时建立连接并发送握手帧
+func (p *tcpPeer) connect() (net.Conn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil {
+		return p.conn, nil
+	}
+	if p.closed.Val() {
+		return nil, ErrTransportClosed
+	}
+
+	ctx := context.Background()
+	header, err := makeHandshakeHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	security, err := getSecurityOption(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: p.option.DialTimeout}
+	if security.TLS.Enabled {
+		var tlsConfig *tls.Config
+		if tlsConfig, err = makeClientTLSConfig(ctx); err != nil {
+			return nil, err
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", p.addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", p.addr)
+	}
+	if err != nil {
+		return nil, gerror.Wrap(ErrPeerUnavailable, err.Error())
+	}
+
+	if err = writeJsonFrame(conn, header, p.option.HandshakeTimeout); err != nil {
+		_ = conn.Close()
+		return nil, gerror.Wrap(ErrPeerUnavailable, err.Error())
+	}
+
+	p.conn = conn
+	go p.read(conn)
+
+	return conn, nil
+}
+
+// drop 断开连接，并结束所有待确认的消息，以便发送方重新投递
+func (p *tcpPeer) drop(conn net.Conn) {
+	p.mu.Lock()
+	if p.conn == conn {
+		p.conn = nil
+	}
+	p.mu.Unlock()
+
+	_ = conn.Close()
+
+	for _, v := range p.pending.Map() {
+		msg := v.(*outgoingMessage)
+		p.pending.Remove(msg.model.MessageId)
+		msg.finish(errPeerConnDropped)
+	}
+}
+
+// close 断开连接
+func (p *tcpPeer) close() {
+	p.mu.Lock()
+	conn := p.conn
+	p.mu.Unlock()
+
+	if conn != nil {
+		p.drop(conn)
+	}
+}
+
+// send 发送消息并等待对端确认，超时或连接断开时重新投递，超过最大投递次数后返回错误
+func (p *tcpPeer) send(ctx context.Context, model base_model.HookModel) error {
+	var lastErr error
+
+	for attempt := 1; attempt <= max(p.option.MaxAttempts, 1); attempt++ {
+		lastErr = p.sendOnce(ctx, model)
+		if lastErr == nil || ctx.Err() != nil {
+			return lastErr
+		}
+		if !gerror.Is(lastErr, ErrAckTimeout) && !gerror.Is(lastErr, errPeerConnDropped) {
+			return lastErr
+		}
+	}
+
+	return gerror.Wrapf(lastErr, "%s 已投递 %d 次", p.addr, max(p.option.MaxAttempts, 1))
+}
+
+// sendOnce 签名并发送一次消息，等待对端确认
+func (p *tcpPeer) sendOnce(ctx context.Context, model base_model.HookModel) error {
+	conn, err := p.connect()
+	if err != nil {
+		return err
+	}
+
+	msg := &outgoingMessage{
+		model: model,
+		done:  make(chan error, 1),
+	}
+	p.pending.Set(model.MessageId, msg)
+	defer p.pending.Remove(model.MessageId)
+
+	if err = signHookModel(ctx, &model); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	err = writeJsonFrame(conn, model, p.option.AckTimeout)
+	p.mu.Unlock()
+	if err != nil {
+		p.drop(conn)
+		return gerror.Wrap(errPeerConnDropped, err.Error())
+	}
+
+	timer := time.NewTimer(p.option.AckTimeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-msg.done:
+		return err
+	case <-timer.C:
+		return gerror.Wrap(ErrAckTimeout, p.addr)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// read 读取对端回复的确认消息及请求的应答，连接断开时退出
+func (p *tcpPeer) read(conn net.Conn) {
+	defer p.drop(conn)
+
+	for {
+		frame, err := readFrame(conn, p.option.MaxFrameSize, 0)
+		if err != nil {
+			return
+		}
+
+		model := base_model.HookModel{}
+		if err = gjson.DecodeTo(frame, &model); err != nil {
+			continue
+		}
+
+		// 签名无效的确认消息直接忽略，等待超时后重新投递
+		if err = verifyHookModel(context.Background(), &model); err != nil {
+			continue
+		}
+
+		// 请求的应答交给等待中的调用方
+		if isReplyFrame(model) {
+			dispatchReply(model)
+			continue
+		}
+
+		v := p.pending.Get(model.MessageId)
+		if v == nil {
+			continue
+		}
+
+		switch model.MessageType().Code() {
+		case base_enum.Hook.MessageType.Ack.Code():
+			v.(*outgoingMessage).finish(nil)
+		case base_enum.Hook.MessageType.Nack.Code():
+			v.(*outgoingMessage).finish(newRemoteError(model))
+		}
+	}
+}
+
+// TcpServer tcp传输的接收服务
+type TcpServer struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	option   TcpTransportOption
+	listener net.Listener
+	handler  InboundHandler
+	conns    sync.Map // key为 net.Conn
+	wg       sync.WaitGroup
+}
+
+// ServeTCP 启动tcp传输的接收服务，address 为空时使用 service.hook.tcp.address；handler 为nil时交由网关广播
+func ServeTCP(ctx context.Context, address string, handler ...InboundHandler) (*TcpServer, error) {
+	option := DefaultTcpTransportOption(ctx)
+	if address == "" {
+		address = option.Address
+	}
+
+	security, err := getSecurityOption(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	// 启用TLS时使用HTTP服务的证书
+	if security.TLS.Enabled {
+		cert, err := tls.LoadX509KeyPair(
+			g.Cfg().MustGet(ctx, "server.httpsCertPath").String(),
+			g.Cfg().MustGet(ctx, "server.httpsKeyPath").String(),
+		)
+		if err != nil {
+			_ = listener.Close()
+			return nil, gerror.Wrap(err, "读取Hook TLS服务端证书失败")
+		}
+		listener = tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{cert}})
+	}
+
+	s := &TcpServer{
+		option:   option,
+		listener: listener,
+	}
+	s.ctx, s.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	if len(handler) > 0 {
+		s.handler = handler[0]
+	}
+
+	s.wg.Add(1)
+	go s.accept()
+
+	return s, nil
+}
+
+// Addr 监听的地址
+func (s *TcpServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close 停止监听并断开所有连接
+func (s *TcpServer) Close() error {
+	s.cancel()
+	err := s.listener.Close()
+
+	s.conns.Range(func(key, value interface{}) bool {
+		_ = key.(net.Conn).Close()
+		return true
+	})
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *TcpServer) accept() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.conns.Store(conn, struct{}{})
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.conns.Delete(conn)
+			defer conn.Close()
+
+			s.serve(conn)
+		}()
+	}
+}
+
+// serve 校验握手帧后处理连接上的消息
+func (s *TcpServer) serve(conn net.Conn) {
+	ctx := s.ctx
+
+	frame, err := readFrame(conn, s.option.MaxFrameSize, s.option.HandshakeTimeout)
+	if err != nil {
+		return
+	}
+	header := http.Header{}
+	if err = gjson.DecodeTo(frame, &header); err != nil {
+		return
+	}
+	if err = verifyHandshakeHeader(ctx, conn.RemoteAddr().String(), header); err != nil {
+		glog.Warning(ctx, err)
+		return
+	}
+
+	writer := &tcpConnWriter{conn: conn, timeout: s.option.AckTimeout}
+	for {
+		frame, err = readFrame(conn, s.option.MaxFrameSize, 0)
+		if err != nil {
+			return
+		}
+
+		data := base_model.HookModel{}
+		if err = gjson.DecodeTo(frame, &data); err != nil {
+			glog.Error(ctx, err)
+			continue
+		}
+		data.Source = newHostInfo(conn.RemoteAddr().String())
+
+		// 回复确认消息，未携带消息ID的旧版本消息无需确认
+		reply, ok := receiveHookModel(ctx, &data, s.handler, writer)
+		if !ok {
+			continue
+		}
+
+		if err = writer.write(ctx, reply); err != nil {
+			glog.Error(ctx, err)
+			return
+		}
+	}
+}
+
+// tcpConnWriter 服务端的tcp连接，确认消息及应答可能由多个协程并发写入
+type tcpConnWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+	mu      sync.Mutex
+}
+
+// write 签名并发送消息
+func (c *tcpConnWriter) write(ctx context.Context, model base_model.HookModel) error {
+	if err := signHookModel(ctx, &model); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return writeJsonFrame(c.conn, model, c.timeout)
+}
+
+// writeJsonFrame 以长度前缀帧写入JSON编码的内容，timeout 大于0时设置写入超时
+func writeJsonFrame(conn net.Conn, value interface{}, timeout time.Duration) error {
+	content, err := gjson.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, 4+len(content))
+	binary.BigEndian.PutUint32(frame, uint32(len(content)))
+	copy(frame[4:], content)
+
+	if timeout > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	_, err = conn.Write(frame)
+	return err
+}
+
+// readFrame 读取一个长度前缀帧，timeout 大于0时设置读取超时
+func readFrame(conn net.Conn, maxSize int, timeout time.Duration) ([]byte, error) {
+	if timeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(head)
+	if maxSize > 0 && int(size) > maxSize {
+		return nil, gerror.Wrapf(ErrFrameTooLarge, "%d > %d", size, maxSize)
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(conn, frame); err != nil {
+		return nil, err
+	}
+
+	return frame, nil
+}
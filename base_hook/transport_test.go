@@ -0,0 +1,311 @@
+package base_hook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gorilla/websocket"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+// wsResponder 测试服务对每个收到的消息的处理：返回回复的消息，返回nil时不回复，drop 为true时断开连接
+type wsResponder func(model base_model.HookModel, attempt int) (reply *base_model.HookModel, drop bool)
+
+// wsTestServer 按 wsResponder 回复确认消息的websocket服务
+type wsTestServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	attempts map[string]int // 每条消息收到的次数，key为消息ID
+	conns    int            // 建立过的连接数
+	active   []*websocket.Conn
+}
+
+func newWsTestServer(t *testing.T, responder wsResponder) *wsTestServer {
+	s := &wsTestServer{attempts: map[string]int{}}
+	upgrader := websocket.Upgrader{}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.active = append(s.active, conn)
+		s.mu.Unlock()
+		defer conn.Close()
+
+		for {
+			model := base_model.HookModel{}
+			if err := conn.ReadJSON(&model); err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.attempts[model.MessageId]++
+			attempt := s.attempts[model.MessageId]
+			s.mu.Unlock()
+
+			reply, drop := responder(model, attempt)
+			if drop {
+				return
+			}
+			if reply != nil {
+				if err := conn.WriteJSON(reply); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// dropAll 断开所有已建立的连接并停止服务
+func (s *wsTestServer) dropAll() {
+	s.mu.Lock()
+	for _, conn := range s.active {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.Server.CloseClientConnections()
+	s.Server.Listener.Close()
+}
+
+func (s *wsTestServer) url() string {
+	return "ws" + strings.TrimPrefix(s.Server.URL, "http")
+}
+
+func (s *wsTestServer) attemptsOf(messageId string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[messageId]
+}
+
+func (s *wsTestServer) connCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+func ackOf(model base_model.HookModel) *base_model.HookModel {
+	return &base_model.HookModel{
+		MessageId:       model.MessageId,
+		MessageTypeStr:  base_enum.Hook.MessageType.Ack.Code(),
+		BusinessTypeStr: model.BusinessTypeStr,
+	}
+}
+
+func testTransportOption() TransportOption {
+	return TransportOption{
+		QueueSize:            16,
+		AckTimeout:           100 * time.Millisecond,
+		MaxAttempts:          3,
+		PingInterval:         time.Second,
+		HandshakeTimeout:     time.Second,
+		ReconnectBaseBackoff: 10 * time.Millisecond,
+		ReconnectMaxBackoff:  50 * time.Millisecond,
+	}
+}
+
+// startPeer 建立与测试服务的连接，测试结束时关闭
+func startPeer(t *testing.T, url string, option TransportOption) *peerConn {
+	useConfig(t, `{}`)
+
+	p := newPeerConn(url, option)
+	p.start()
+	t.Cleanup(func() {
+		p.close()
+		<-p.stopped
+	})
+	return p
+}
+
+func testModel(messageId string) base_model.HookModel {
+	return base_model.HookModel{MessageId: messageId, BusinessTypeStr: "order.paid"}
+}
+
+func TestTransportOption_WithDefaults(t *testing.T) {
+	option := TransportOption{}.withDefaults()
+
+	want := TransportOption{
+		QueueSize:            defaultQueueSize,
+		AckTimeout:           defaultAckTimeout,
+		MaxAttempts:          defaultMaxAttempts,
+		PingInterval:         defaultPingInterval,
+		HandshakeTimeout:     defaultHandshakeTimeout,
+		ReconnectBaseBackoff: defaultReconnectBaseBackoff,
+		ReconnectMaxBackoff:  defaultReconnectMaxBackoff,
+	}
+	if option != want {
+		t.Fatalf("withDefaults() = %+v, want %+v", option, want)
+	}
+
+	custom := testTransportOption()
+	if got := custom.withDefaults(); got != custom {
+		t.Fatalf("withDefaults() changed configured option: %+v", got)
+	}
+
+	if got := newPeerConn("ws://127.0.0.1:1/ws", TransportOption{}); cap(got.queue) != defaultQueueSize || got.option.AckTimeout != defaultAckTimeout {
+		t.Fatalf("newPeerConn did not apply defaults: queue=%d ackTimeout=%s", cap(got.queue), got.option.AckTimeout)
+	}
+}
+
+func TestPeerConn_Ack(t *testing.T) {
+	server := newWsTestServer(t, func(model base_model.HookModel, attempt int) (*base_model.HookModel, bool) {
+		return ackOf(model), false
+	})
+	p := startPeer(t, server.url(), testTransportOption())
+
+	if err := p.send(context.Background(), testModel("msg-ack")); err != nil {
+		t.Fatalf("send() = %v", err)
+	}
+	if got := server.attemptsOf("msg-ack"); got != 1 {
+		t.Fatalf("attempts = %d, want 1", got)
+	}
+	if p.pending.Size() != 0 {
+		t.Fatalf("pending = %d after ack", p.pending.Size())
+	}
+}
+
+func TestPeerConn_Nack(t *testing.T) {
+	server := newWsTestServer(t, func(model base_model.HookModel, attempt int) (*base_model.HookModel, bool) {
+		reply := ackOf(model)
+		reply.MessageTypeStr = base_enum.Hook.MessageType.Nack.Code()
+		setReplyError(reply, gerror.NewCode(gcode.CodeValidationFailed, "invalid payload"))
+		return reply, false
+	})
+	p := startPeer(t, server.url(), testTransportOption())
+
+	err := p.send(context.Background(), testModel("msg-nack"))
+	if !errors.Is(err, ErrMessageRejected) {
+		t.Fatalf("send() = %v, want ErrMessageRejected", err)
+	}
+	var remote *RemoteError
+	if !errors.As(err, &remote) || remote.Code() != gcode.CodeValidationFailed || remote.Message != "invalid payload" {
+		t.Fatalf("send() = %#v, want RemoteError with CodeValidationFailed", err)
+	}
+	// nack 不重新投递
+	if got := server.attemptsOf("msg-nack"); got != 1 {
+		t.Fatalf("attempts = %d, want 1", got)
+	}
+}
+
+func TestPeerConn_RedeliverOnAckTimeout(t *testing.T) {
+	server := newWsTestServer(t, func(model base_model.HookModel, attempt int) (*base_model.HookModel, bool) {
+		if model.MessageId == "msg-lost" || attempt < 2 {
+			return nil, false
+		}
+		return ackOf(model), false
+	})
+	option := testTransportOption()
+	p := startPeer(t, server.url(), option)
+
+	if err := p.send(context.Background(), testModel("msg-retry")); err != nil {
+		t.Fatalf("send() = %v", err)
+	}
+	if got := server.attemptsOf("msg-retry"); got != 2 {
+		t.Fatalf("attempts = %d, want 2", got)
+	}
+
+	err := p.send(context.Background(), testModel("msg-lost"))
+	if !errors.Is(err, ErrAckTimeout) {
+		t.Fatalf("send() = %v, want ErrAckTimeout", err)
+	}
+	if got := server.attemptsOf("msg-lost"); got != option.MaxAttempts {
+		t.Fatalf("attempts = %d, want %d", got, option.MaxAttempts)
+	}
+}
+
+func TestPeerConn_RedeliverAfterReconnect(t *testing.T) {
+	server := newWsTestServer(t, func(model base_model.HookModel, attempt int) (*base_model.HookModel, bool) {
+		// 首次收到时断开连接，重连后确认
+		if attempt == 1 {
+			return nil, true
+		}
+		return ackOf(model), false
+	})
+	p := startPeer(t, server.url(), testTransportOption())
+
+	if err := p.send(context.Background(), testModel("msg-reconnect")); err != nil {
+		t.Fatalf("send() = %v", err)
+	}
+	if got := server.attemptsOf("msg-reconnect"); got != 2 {
+		t.Fatalf("attempts = %d, want 2", got)
+	}
+	if got := server.connCount(); got != 2 {
+		t.Fatalf("connections = %d, want 2", got)
+	}
+	if state := p.state.Val(); state != peerStateConnected {
+		t.Fatalf("state = %s, want %s", state, peerStateConnected)
+	}
+}
+
+func TestPeerConn_CloseFailsPending(t *testing.T) {
+	server := newWsTestServer(t, func(model base_model.HookModel, attempt int) (*base_model.HookModel, bool) {
+		return nil, false
+	})
+	option := testTransportOption()
+	option.AckTimeout = time.Minute
+	p := startPeer(t, server.url(), option)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- p.send(context.Background(), testModel("msg-pending"))
+	}()
+	for server.attemptsOf("msg-pending") == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 服务离开后断开连接，等待确认的消息返回 ErrPeerUnavailable
+	server.dropAll()
+	for p.state.Val() == peerStateConnected {
+		time.Sleep(5 * time.Millisecond)
+	}
+	p.close()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrPeerUnavailable) {
+			t.Fatalf("send() = %v, want ErrPeerUnavailable", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending message was not failed after close")
+	}
+}
+
+func TestPeerConn_SendAfterStopped(t *testing.T) {
+	p := newPeerConn("ws://127.0.0.1:1/ws", testTransportOption())
+	// 模拟连接协程已退出，但发送方仍读取到连接状态为已连接
+	p.state.Set(peerStateConnected)
+	close(p.stopped)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.send(context.Background(), testModel("msg-late"))
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrPeerUnavailable) {
+			t.Fatalf("send() = %v, want ErrPeerUnavailable", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message queued after the connection stopped was never completed")
+	}
+
+	p.close()
+	if err := p.send(context.Background(), testModel("msg-closed")); !errors.Is(err, ErrPeerUnavailable) {
+		t.Fatalf("send() after close = %v, want ErrPeerUnavailable", err)
+	}
+}
@@ -0,0 +1,292 @@
+package base_hook
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/net/gtrace"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+)
+
+/*
+	类型安全的Hook：
+		1、订阅函数统一为 func(ctx, TPayload) error，调用时无需反射
+		2、网络消息的载荷通过注册的编解码器显式序列化：未指定编解码器时按业务类型的载荷约定（RegisterSchema）编码，默认使用JSON
+		3、所有订阅函数返回的错误（含panic）及跨进程投递的错误汇总后返回给发布方
+*/
+
+// HookHandler 类型安全的Hook订阅函数
+type HookHandler[TPayload any] func(ctx context.Context, payload TPayload) error
+
+// Codec 网络消息载荷的编解码器
+type Codec[TPayload any] interface {
+	Encode(payload TPayload) ([]byte, error)
+	Decode(data []byte) (TPayload, error)
+}
+
+// JsonCodec JSON编解码器
+type JsonCodec[TPayload any] struct{}
+
+func (c JsonCodec[TPayload]) Encode(payload TPayload) ([]byte, error) {
+	return json.Marshal(payload)
+}
+
+func (c JsonCodec[TPayload]) Decode(data []byte) (TPayload, error) {
+	var payload TPayload
+	err := json.Unmarshal(data, &payload)
+	return payload, err
+}
+
+// TypedOption 类型安全Hook的发布选项
+type TypedOption[TFilter any] struct {
+	Filter       func(filter TFilter) bool  // 订阅者过滤条件，为空时调用所有订阅者
+	NetMessage   bool                       // 是否同时投递给其他服务
+	DeliveryMode base_enum.HookDeliveryMode // 网络消息的投递方式，默认为failover
+	DeliveryKey  string                     // hash投递方式下用于选择服务的Key
+	// 网络消息的幂等Key，同一业务操作重复发布时使用相同的Key，接收方只处理一次；为空时按消息ID去重
+	IdempotencyKey string
+}
+
+// typedSubscriber 订阅者
+type typedSubscriber[TFilter any, TPayload any] struct {
+	filter  TFilter
+	handler HookHandler[TPayload]
+	record  *subscriberRecord
+}
+
+// TypedHook 类型安全的Hook
+type TypedHook[TFilter any, TPayload any] struct {
+	mu           sync.RWMutex
+	businessType base_enum.HookBusinessType
+	codec        Codec[TPayload] // 指定的编解码器，为nil时按业务类型的载荷约定编解码
+	subscribers  []typedSubscriber[TFilter, TPayload]
+	middlewares  []Middleware
+}
+
+// NewTypedHook 创建类型安全的Hook，businessType 为跨进程路由使用的主题，需在各服务间保持一致，可使用通配符订阅；
+// 未指定编解码器时按业务类型的载荷约定编解码，未声明时使用JSON。创建时即向网关注册，用于接收其他服务投递的消息；
+// 同一主题只能创建一个Hook，主题已被其他Hook订阅时 panic。
+func NewTypedHook[TFilter any, TPayload any](businessType string, codec ...Codec[TPayload]) *TypedHook[TFilter, TPayload] {
+	hook := &TypedHook[TFilter, TPayload]{
+		businessType: base_enum.Hook.BusinessType.New(businessType),
+	}
+	if len(codec) > 0 && codec[0] != nil {
+		hook.codec = codec[0]
+	}
+
+	if err := gateway.Subscribe(hook.businessType.Code(), hook.handleNetMessage); err != nil {
+		panic(err)
+	}
+
+	return hook
+}
+
+// GetBusinessType 获取业务类型
+func (h *TypedHook[TFilter, TPayload]) GetBusinessType() base_enum.HookBusinessType {
+	return h.businessType
+}
+
+// Subscribe 订阅Hook
+func (h *TypedHook[TFilter, TPayload]) Subscribe(filter TFilter, handler HookHandler[TPayload]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.subscribers = append(h.subscribers, typedSubscriber[TFilter, TPayload]{
+		filter:  filter,
+		handler: handler,
+		record:  registerSubscriber(h.businessType.Code(), filter, handler, 1),
+	})
+}
+
+// Use 注册当前Hook的中间件
+func (h *TypedHook[TFilter, TPayload]) Use(middleware ...Middleware) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.middlewares = append(h.middlewares, middleware...)
+}
+
+// Unsubscribe 取消满足条件的订阅，返回取消的订阅数
+func (h *TypedHook[TFilter, TPayload]) Unsubscribe(match func(filter TFilter) bool) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	kept := make([]typedSubscriber[TFilter, TPayload], 0, len(h.subscribers))
+	for _, item := range h.subscribers {
+		if !match(item.filter) {
+			kept = append(kept, item)
+		} else {
+			unregisterSubscriber(item.record)
+		}
+	}
+
+	removed := len(h.subscribers) - len(kept)
+	h.subscribers = kept
+	return removed
+}
+
+// Publish 发布Hook消息，同步调用所有匹配的订阅者；NetMessage 为 true 时同时投递给其他服务。
+// 订阅者返回的错误、panic及投递错误将汇总后返回。
+func (h *TypedHook[TFilter, TPayload]) Publish(ctx context.Context, payload TPayload, option ...TypedOption[TFilter]) error {
+	var opt TypedOption[TFilter]
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	errArr := h.invoke(ctx, payload, opt.Filter)
+
+	if opt.NetMessage {
+		if err := h.publishNet(ctx, payload, opt); err != nil {
+			errArr = append(errArr, err)
+		}
+	}
+
+	return errors.Join(errArr...)
+}
+
+// invoke 调用所有匹配的订阅者，调用过程经过全局及当前Hook的中间件
+func (h *TypedHook[TFilter, TPayload]) invoke(ctx context.Context, payload TPayload, match func(filter TFilter) bool) []error {
+	h.mu.RLock()
+	subscribers := h.subscribers
+	middlewares := h.middlewares
+	h.mu.RUnlock()
+
+	var errArr []error
+	for _, item := range subscribers {
+		if match != nil && !match(item.filter) {
+			continue
+		}
+
+		handler := item.handler
+		call := &HookCall{
+			BusinessType: h.businessType.Code(),
+			Subscriber:   hookFuncName(handler),
+			Filter:       item.filter,
+			Payload:      payload,
+			TraceId:      gtrace.GetTraceID(ctx),
+		}
+		next := chainMiddleware(func(ctx context.Context, call *HookCall) error {
+			return handler(ctx, payload)
+		}, middlewares)
+
+		start := time.Now()
+		err := callSubscriber(ctx, call, next)
+		item.record.observe(time.Since(start), err)
+		if err != nil {
+			errArr = append(errArr, err)
+		}
+	}
+
+	return errArr
+}
+
+// PublishTx 在事务内发布跨进程消息，载荷写入发件箱，事务提交后由 OutboxRelay 投递给其他服务。
+// 注意：本进程内的订阅者不会被调用，需要时请在事务提交后调用 Publish。
+func (h *TypedHook[TFilter, TPayload]) PublishTx(ctx context.Context, tx gdb.TX, payload TPayload, option ...TypedOption[TFilter]) error {
+	var opt TypedOption[TFilter]
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	model, err := h.netModel(ctx, payload, opt)
+	if err != nil {
+		return err
+	}
+
+	return writeOutbox(ctx, tx, model)
+}
+
+// publishNet 编码载荷并投递给其他服务
+func (h *TypedHook[TFilter, TPayload]) publishNet(ctx context.Context, payload TPayload, opt TypedOption[TFilter]) error {
+	model, err := h.netModel(ctx, payload, opt)
+	if err != nil {
+		return err
+	}
+
+	return deliverHookModel(ctx, model)
+}
+
+// netModel 校验并编码载荷，构建网络消息
+func (h *TypedHook[TFilter, TPayload]) netModel(ctx context.Context, payload TPayload, opt TypedOption[TFilter]) (base_model.HookModel, error) {
+	model := base_model.HookModel{
+		BusinessTypeStr: h.businessType.Code(),
+		DeliveryKey:     opt.DeliveryKey,
+		IdempotencyKey:  opt.IdempotencyKey,
+	}
+	if opt.DeliveryMode != nil {
+		model.DeliveryModeStr = opt.DeliveryMode.Code()
+	}
+
+	// 发送前按载荷约定校验，避免投递接收方必然拒绝的消息
+	if err := ValidatePayload(ctx, h.businessType.Code(), payload); err != nil {
+		return model, err
+	}
+
+	if h.codec != nil {
+		data, err := h.codec.Encode(payload)
+		if err != nil {
+			return model, gerror.Wrap(err, "Hook消息载荷编码失败")
+		}
+		model.Data = base64.StdEncoding.EncodeToString(data)
+	} else if err := encodePayload(&model, payload); err != nil {
+		return model, err
+	}
+
+	return model, nil
+}
+
+// handleNetMessage 处理其他服务投递的消息：解码并校验载荷后调用所有订阅者
+func (h *TypedHook[TFilter, TPayload]) handleNetMessage(model base_model.HookModel) error {
+	ctx := model.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	payload, err := h.decode(ctx, model)
+	if err != nil {
+		return err
+	}
+
+	return errors.Join(h.invoke(ctx, payload, nil)...)
+}
+
+// decode 解码并校验载荷：指定了编解码器或未携带 ContentType 的消息按base64解码后使用编解码器，否则按载荷约定解码
+func (h *TypedHook[TFilter, TPayload]) decode(ctx context.Context, model base_model.HookModel) (payload TPayload, err error) {
+	if h.codec == nil && model.ContentType != "" {
+		// 载荷为指针类型时先创建对象，使 ProtoMessage 等按指针实现的解码方法可用
+		var target interface{} = &payload
+		if t := reflect.TypeOf(payload); t != nil && t.Kind() == reflect.Ptr {
+			v := reflect.New(t.Elem())
+			payload = v.Interface().(TPayload)
+			target = v.Interface()
+		}
+		err = DecodePayload(ctx, model, target)
+		return payload, err
+	}
+
+	codec := h.codec
+	if codec == nil {
+		codec = JsonCodec[TPayload]{}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(gconv.String(model.Data))
+	if err != nil {
+		return payload, gerror.Wrap(err, "Hook消息载荷格式错误")
+	}
+
+	if payload, err = codec.Decode(data); err != nil {
+		return payload, gerror.Wrap(err, fmt.Sprintf("Hook消息载荷解码失败：%s", h.businessType.Code()))
+	}
+
+	return payload, ValidatePayload(ctx, model.BusinessTypeStr, payload)
+}
@@ -0,0 +1,190 @@
+package base_hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kysion/base-library/base_model"
+)
+
+// testOrder 测试使用的载荷
+type testOrder struct {
+	Id     int64 `json:"id"`
+	Amount int   `json:"amount"`
+}
+
+// topicSeq 测试主题的序号，同一主题只能创建一个Hook
+var topicSeq atomic.Int64
+
+// testTopic 生成测试使用的主题，测试结束时取消网关的订阅
+func testTopic(t *testing.T) string {
+	topic := fmt.Sprintf("test.hook.%d", topicSeq.Add(1))
+	t.Cleanup(func() {
+		gateway.Unsubscribe(topic)
+	})
+	return topic
+}
+
+// mustPanic f 必须 panic，且信息中包含 contains
+func mustPanic(t *testing.T, contains string, f func()) {
+	t.Helper()
+
+	defer func() {
+		t.Helper()
+		r := recover()
+		if r == nil {
+			t.Fatalf("no panic, want %q", contains)
+		}
+		if msg := fmt.Sprint(r); !strings.Contains(msg, contains) {
+			t.Fatalf("panic %q, want %q", msg, contains)
+		}
+	}()
+	f()
+}
+
+// textCodec 将载荷编码为 id:amount 的编解码器
+type textCodec struct{}
+
+func (textCodec) Encode(payload testOrder) ([]byte, error) {
+	return []byte(fmt.Sprintf("%d:%d", payload.Id, payload.Amount)), nil
+}
+
+func (textCodec) Decode(data []byte) (testOrder, error) {
+	var payload testOrder
+	_, err := fmt.Sscanf(string(data), "%d:%d", &payload.Id, &payload.Amount)
+	return payload, err
+}
+
+func TestTypedHook_Publish(t *testing.T) {
+	hook := NewTypedHook[string, testOrder](testTopic(t))
+	ctx := context.Background()
+
+	var (
+		called []string
+		errB   = errors.New("b failed")
+	)
+	record := func(name string) {
+		called = append(called, name)
+	}
+	hook.Subscribe("a", func(ctx context.Context, payload testOrder) error {
+		record("a")
+		if payload.Id != 1 {
+			t.Errorf("payload = %+v", payload)
+		}
+		return nil
+	})
+	hook.Subscribe("b", func(ctx context.Context, payload testOrder) error {
+		record("b")
+		return errB
+	})
+	hook.Subscribe("c", func(ctx context.Context, payload testOrder) error {
+		record("c")
+		panic("c panicked")
+	})
+
+	// 所有订阅者均被调用，错误及panic汇总后返回
+	err := hook.Publish(ctx, testOrder{Id: 1})
+	if !slices.Equal(called, []string{"a", "b", "c"}) {
+		t.Fatalf("called = %v, want [a b c]", called)
+	}
+	if !errors.Is(err, errB) {
+		t.Fatalf("Publish() = %v, want errB", err)
+	}
+	var subscriberErrs []*SubscriberError
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var subscriberErr *SubscriberError
+		if !errors.As(e, &subscriberErr) {
+			t.Fatalf("error %v is not a SubscriberError", e)
+		}
+		subscriberErrs = append(subscriberErrs, subscriberErr)
+	}
+	if len(subscriberErrs) != 2 || subscriberErrs[0].Filter != "b" || subscriberErrs[1].Filter != "c" || subscriberErrs[1].Panic != "c panicked" || subscriberErrs[1].Stack == "" {
+		t.Fatalf("errors = %+v, want b failed and c panicked", subscriberErrs)
+	}
+
+	// 按过滤条件调用
+	called = nil
+	if err = hook.Publish(ctx, testOrder{Id: 1}, TypedOption[string]{Filter: func(filter string) bool { return filter == "a" }}); err != nil || !slices.Equal(called, []string{"a"}) {
+		t.Fatalf("Publish(a) = %v, called %v", err, called)
+	}
+
+	// 取消订阅
+	if n := hook.Unsubscribe(func(filter string) bool { return filter != "a" }); n != 2 {
+		t.Fatalf("Unsubscribe() = %d, want 2", n)
+	}
+	called = nil
+	if err = hook.Publish(ctx, testOrder{Id: 1}); err != nil || !slices.Equal(called, []string{"a"}) {
+		t.Fatalf("Publish() after Unsubscribe = %v, called %v", err, called)
+	}
+}
+
+func TestNewTypedHook_DuplicateTopic(t *testing.T) {
+	topic := testTopic(t)
+	NewTypedHook[string, testOrder](topic)
+
+	// 同一主题只能创建一个Hook，已有的订阅不被覆盖
+	mustPanic(t, "已被其他Hook订阅", func() {
+		NewTypedHook[int, string](topic)
+	})
+	if err := gateway.Subscribe(topic, func(model base_model.HookModel) error { return nil }); err == nil {
+		t.Fatal("Subscribe() of a subscribed topic = nil, want error")
+	}
+
+	// 取消订阅后可重新创建
+	gateway.Unsubscribe(topic)
+	NewTypedHook[string, testOrder](topic)
+}
+
+func TestTypedHook_NetMessage(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name  string
+		codec Codec[testOrder]
+	}{
+		{name: "payload schema"},
+		{name: "json codec", codec: JsonCodec[testOrder]{}},
+		{name: "custom codec", codec: textCodec{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hook := NewTypedHook[string, testOrder](testTopic(t), c.codec)
+
+			var received []testOrder
+			hook.Subscribe("", func(ctx context.Context, payload testOrder) error {
+				received = append(received, payload)
+				return nil
+			})
+
+			model, err := hook.netModel(ctx, testOrder{Id: 7, Amount: 100}, TypedOption[string]{IdempotencyKey: "order-7"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if model.BusinessTypeStr != hook.GetBusinessType().Code() || model.IdempotencyKey != "order-7" {
+				t.Fatalf("model = %+v", model)
+			}
+			if (model.ContentType != "") != (c.codec == nil) {
+				t.Fatalf("ContentType = %q, want set only without a codec", model.ContentType)
+			}
+
+			if err = gateway.broadcast(model); err != nil {
+				t.Fatal(err)
+			}
+			if len(received) != 1 || received[0] != (testOrder{Id: 7, Amount: 100}) {
+				t.Fatalf("received = %+v", received)
+			}
+
+			// 载荷无法解码时返回错误，不调用订阅者
+			model.Data = "not base64!"
+			if err = gateway.broadcast(model); err == nil || len(received) != 1 {
+				t.Fatalf("broadcast(invalid) = %v, received %d", err, len(received))
+			}
+		})
+	}
+}
@@ -10,6 +10,18 @@ type (
 
 	// HookBusinessType Hook业务类型
 	HookBusinessType = sys_enum_hook.BusinessTypeEnum
+
+	// HookOutboxState Hook发件箱消息状态
+	HookOutboxState = sys_enum_hook.OutboxStateEnum
+
+	// HookMessageType Hook消息类型
+	HookMessageType = sys_enum_hook.MessageTypeEnum
+
+	// HookDeliveryMode Hook投递方式
+	HookDeliveryMode = sys_enum_hook.DeliveryModeEnum
+
+	// HookDispatchMode Hook调度方式
+	HookDispatchMode = sys_enum_hook.DispatchModeEnum
 )
 
 var (
@@ -27,6 +27,16 @@ var CaptchaType = captchaType{
 	// 可拓展.....
 }
 
+// captchaTypeRegistry 注册全部成员，用于按代码或描述查找，成员可组合为 enum.FlagSet
+var captchaTypeRegistry = enum.RegisterFlags[CaptchaTypeEnum]("CaptchaType",
+	CaptchaType.Register,
+	CaptchaType.Login,
+	CaptchaType.SetUserName,
+	CaptchaType.SetPassword,
+	CaptchaType.SetMobile,
+	CaptchaType.SetMail,
+)
+
 func (e *captchaType) New(code int, description string) CaptchaTypeEnum {
 	if (code & e.Register.Code()) == e.Register.Code() {
 		return e.Register
@@ -48,3 +58,23 @@ func (e *captchaType) New(code int, description string) CaptchaTypeEnum {
 	}
 	return enum.New[CaptchaTypeEnum](code, description)
 }
+
+// Parse 按代码获取注册的成员
+func (e *captchaType) Parse(code int) (CaptchaTypeEnum, bool) {
+	return captchaTypeRegistry.Parse(code)
+}
+
+// ParseDescription 按描述获取注册的成员
+func (e *captchaType) ParseDescription(description string) (CaptchaTypeEnum, bool) {
+	return captchaTypeRegistry.ParseDescription(description)
+}
+
+// All 按定义顺序返回所有成员
+func (e *captchaType) All() []CaptchaTypeEnum {
+	return captchaTypeRegistry.All()
+}
+
+// Values 按定义顺序返回所有成员的代码
+func (e *captchaType) Values() []int {
+	return captchaTypeRegistry.Values()
+}
@@ -1,8 +1,15 @@
 package sys_enum_hook
 
-import "github.com/kysion/base-library/utility/enum"
+import (
+	"fmt"
+	"strings"
+	"sync"
 
-// 业务类型：
+	"github.com/kysion/base-library/utility/enum"
+)
+
+// 业务类型：即跨进程路由使用的主题，推荐使用以 . 分隔的层级名称，如 order.created
+// 订阅时可使用通配符：* 匹配一个层级，# 匹配零个或多个层级（只能位于末尾），如 order.*、user.#
 
 type BusinessTypeEnum enum.IEnumCode[string]
 
@@ -10,19 +17,38 @@ type businessType struct {
 	Default BusinessTypeEnum
 
 	// 可拓展.....
+
+	registry *topicRegistry
+}
+
+// topicRegistry 已声明的主题及Go类型名的兼容映射
+type topicRegistry struct {
+	mu      sync.RWMutex
+	topics  map[string]BusinessTypeEnum // key为主题
+	aliases map[string]string           // key为Go类型名或旧主题，value为主题
 }
 
 var BusinessType = businessType{
 	Default: enum.New[BusinessTypeEnum]("default", "default"),
 
 	// 可拓展.....
+
+	registry: &topicRegistry{
+		topics:  make(map[string]BusinessTypeEnum),
+		aliases: make(map[string]string),
+	},
 }
 
+// New 获取业务类型：已声明的主题返回声明时的枚举；已声明兼容映射的Go类型名或旧主题返回映射后的主题
 func (e *businessType) New(code string, description ...string) BusinessTypeEnum {
 	if code == e.Default.Code() {
 		return e.Default
 	}
 
+	if topic, ok := e.Lookup(code); ok {
+		return topic
+	}
+
 	desc := ""
 	if len(description) > 0 {
 		desc = description[0]
@@ -30,3 +56,133 @@ func (e *businessType) New(code string, description ...string) BusinessTypeEnum
 
 	return enum.New[BusinessTypeEnum](code, desc)
 }
+
+// Register 声明主题，aliases 为映射到该主题的Go订阅函数类型名（reflect.TypeOf(F).String()）或旧主题。
+// 重命名Go函数类型时，将旧类型名加入 aliases 即可保持跨进程路由不变。
+// 主题格式非法、重复声明或同一别名映射到不同主题时 panic，应在包初始化时调用。
+func (e *businessType) Register(topic string, description string, aliases ...string) BusinessTypeEnum {
+	if err := e.Validate(topic); err != nil {
+		panic(err)
+	}
+
+	r := e.registry
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.topics[topic]; ok {
+		panic(fmt.Sprintf("Hook业务类型重复声明：%s", topic))
+	}
+	if mapped, ok := r.aliases[topic]; ok {
+		panic(fmt.Sprintf("Hook业务类型 %s 已作为 %s 的别名", topic, mapped))
+	}
+
+	for _, alias := range aliases {
+		if _, ok := r.topics[alias]; ok {
+			panic(fmt.Sprintf("Hook业务类型别名 %s 与已声明的主题冲突", alias))
+		}
+		if mapped, ok := r.aliases[alias]; ok && mapped != topic {
+			panic(fmt.Sprintf("Hook业务类型别名 %s 已映射到 %s", alias, mapped))
+		}
+	}
+
+	item := enum.New[BusinessTypeEnum](topic, description)
+	r.topics[topic] = item
+	for _, alias := range aliases {
+		r.aliases[alias] = topic
+	}
+
+	return item
+}
+
+// Lookup 查找已声明的主题，code 可以是主题本身、Go类型名或旧主题
+func (e *businessType) Lookup(code string) (BusinessTypeEnum, bool) {
+	r := e.registry
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if topic, ok := r.aliases[code]; ok {
+		code = topic
+	}
+	item, ok := r.topics[code]
+	return item, ok
+}
+
+// Resolve 返回 code 对应的主题，未声明时原样返回
+func (e *businessType) Resolve(code string) string {
+	if item, ok := e.Lookup(code); ok {
+		return item.Code()
+	}
+	return code
+}
+
+// Topics 返回所有已声明的主题
+func (e *businessType) Topics() []BusinessTypeEnum {
+	r := e.registry
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]BusinessTypeEnum, 0, len(r.topics))
+	for _, item := range r.topics {
+		result = append(result, item)
+	}
+	return result
+}
+
+// IsPattern 主题是否包含通配符
+func (e *businessType) IsPattern(topic string) bool {
+	for _, segment := range strings.Split(topic, ".") {
+		if segment == "*" || segment == "#" {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 校验主题格式：层级不能为空，通配符必须独占一个层级，# 只能位于末尾
+func (e *businessType) Validate(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("Hook业务类型不能为空")
+	}
+
+	segments := strings.Split(topic, ".")
+	for i, segment := range segments {
+		switch {
+		case segment == "":
+			return fmt.Errorf("Hook业务类型 %s 存在空的层级", topic)
+		case segment == "#" && i != len(segments)-1:
+			return fmt.Errorf("Hook业务类型 %s 中的 # 只能位于末尾", topic)
+		case segment != "*" && segment != "#" && strings.ContainsAny(segment, "*#"):
+			return fmt.Errorf("Hook业务类型 %s 中的通配符必须独占一个层级", topic)
+		}
+	}
+
+	return nil
+}
+
+// Match 判断主题是否匹配订阅的模式：* 匹配一个层级，# 匹配零个或多个层级
+func (e *businessType) Match(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+
+	return matchSegments(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+func matchSegments(pattern, topic []string) bool {
+	for i, segment := range pattern {
+		switch segment {
+		case "#":
+			return true
+		case "*":
+			if i >= len(topic) {
+				return false
+			}
+		default:
+			if i >= len(topic) || topic[i] != segment {
+				return false
+			}
+		}
+	}
+
+	return len(pattern) == len(topic)
+}
@@ -0,0 +1,71 @@
+package sys_enum_hook
+
+import "github.com/kysion/base-library/utility/enum"
+
+// 投递方式：failover依次尝试直到一个服务成功，fanout广播给所有服务，roundRobin轮询一个服务，hash按Key一致性哈希到一个服务
+
+type DeliveryModeEnum enum.IEnumCode[string]
+
+type deliveryMode struct {
+	Failover   DeliveryModeEnum
+	Fanout     DeliveryModeEnum
+	RoundRobin DeliveryModeEnum
+	Hash       DeliveryModeEnum
+}
+
+var DeliveryMode = deliveryMode{
+	Failover:   enum.New[DeliveryModeEnum]("failover", "故障转移，投递给第一个可达的服务"),
+	Fanout:     enum.New[DeliveryModeEnum]("fanout", "广播，投递给所有服务"),
+	RoundRobin: enum.New[DeliveryModeEnum]("roundRobin", "轮询，投递给其中一个服务"),
+	Hash:       enum.New[DeliveryModeEnum]("hash", "一致性哈希，相同Key投递给同一个服务"),
+}
+
+// deliveryModeRegistry 注册全部成员，用于按代码或描述查找
+var deliveryModeRegistry = enum.Register[DeliveryModeEnum]("HookDeliveryMode",
+	DeliveryMode.Failover,
+	DeliveryMode.Fanout,
+	DeliveryMode.RoundRobin,
+	DeliveryMode.Hash,
+)
+
+func (e *deliveryMode) New(code string, description ...string) DeliveryModeEnum {
+	if code == "" || code == e.Failover.Code() {
+		return e.Failover
+	}
+	if code == e.Fanout.Code() {
+		return e.Fanout
+	}
+	if code == e.RoundRobin.Code() {
+		return e.RoundRobin
+	}
+	if code == e.Hash.Code() {
+		return e.Hash
+	}
+
+	desc := ""
+	if len(description) > 0 {
+		desc = description[0]
+	}
+
+	return enum.New[DeliveryModeEnum](code, desc)
+}
+
+// Parse 按代码获取注册的成员
+func (e *deliveryMode) Parse(code string) (DeliveryModeEnum, bool) {
+	return deliveryModeRegistry.Parse(code)
+}
+
+// ParseDescription 按描述获取注册的成员
+func (e *deliveryMode) ParseDescription(description string) (DeliveryModeEnum, bool) {
+	return deliveryModeRegistry.ParseDescription(description)
+}
+
+// All 按定义顺序返回所有成员
+func (e *deliveryMode) All() []DeliveryModeEnum {
+	return deliveryModeRegistry.All()
+}
+
+// Values 按定义顺序返回所有成员的代码
+func (e *deliveryMode) Values() []string {
+	return deliveryModeRegistry.Values()
+}
@@ -0,0 +1,65 @@
+package sys_enum_hook
+
+import "github.com/kysion/base-library/utility/enum"
+
+// 调度方式：sync同步调用，async异步调用不等待结果，asyncWait异步并发调用并等待全部完成
+
+type DispatchModeEnum enum.IEnumCode[string]
+
+type dispatchMode struct {
+	Sync      DispatchModeEnum
+	Async     DispatchModeEnum
+	AsyncWait DispatchModeEnum
+}
+
+var DispatchMode = dispatchMode{
+	Sync:      enum.New[DispatchModeEnum]("sync", "同步调用"),
+	Async:     enum.New[DispatchModeEnum]("async", "异步调用，不等待结果"),
+	AsyncWait: enum.New[DispatchModeEnum]("asyncWait", "异步并发调用，等待全部完成"),
+}
+
+// dispatchModeRegistry 注册全部成员，用于按代码或描述查找
+var dispatchModeRegistry = enum.Register[DispatchModeEnum]("HookDispatchMode",
+	DispatchMode.Sync,
+	DispatchMode.Async,
+	DispatchMode.AsyncWait,
+)
+
+func (e *dispatchMode) New(code string, description ...string) DispatchModeEnum {
+	if code == "" || code == e.Sync.Code() {
+		return e.Sync
+	}
+	if code == e.Async.Code() {
+		return e.Async
+	}
+	if code == e.AsyncWait.Code() {
+		return e.AsyncWait
+	}
+
+	desc := ""
+	if len(description) > 0 {
+		desc = description[0]
+	}
+
+	return enum.New[DispatchModeEnum](code, desc)
+}
+
+// Parse 按代码获取注册的成员
+func (e *dispatchMode) Parse(code string) (DispatchModeEnum, bool) {
+	return dispatchModeRegistry.Parse(code)
+}
+
+// ParseDescription 按描述获取注册的成员
+func (e *dispatchMode) ParseDescription(description string) (DispatchModeEnum, bool) {
+	return dispatchModeRegistry.ParseDescription(description)
+}
+
+// All 按定义顺序返回所有成员
+func (e *dispatchMode) All() []DispatchModeEnum {
+	return dispatchModeRegistry.All()
+}
+
+// Values 按定义顺序返回所有成员的代码
+func (e *dispatchMode) Values() []string {
+	return dispatchModeRegistry.Values()
+}
@@ -2,8 +2,16 @@ package sys_enum_hook
 
 type hook struct {
 	BusinessType businessType
+	OutboxState  outboxState
+	MessageType  messageType
+	DeliveryMode deliveryMode
+	DispatchMode dispatchMode
 }
 
 var Hook = hook{
 	BusinessType: BusinessType,
+	OutboxState:  OutboxState,
+	MessageType:  MessageType,
+	DeliveryMode: DeliveryMode,
+	DispatchMode: DispatchMode,
 }
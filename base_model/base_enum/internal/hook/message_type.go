@@ -0,0 +1,83 @@
+package sys_enum_hook
+
+import "github.com/kysion/base-library/utility/enum"
+
+// 消息类型：message业务消息，ack确认，nack否认，request请求，replyChunk部分应答，reply最终应答
+
+type MessageTypeEnum enum.IEnumCode[string]
+
+type messageType struct {
+	Message    MessageTypeEnum
+	Ack        MessageTypeEnum
+	Nack       MessageTypeEnum
+	Request    MessageTypeEnum
+	ReplyChunk MessageTypeEnum
+	Reply      MessageTypeEnum
+}
+
+var MessageType = messageType{
+	Message:    enum.New[MessageTypeEnum]("message", "业务消息"),
+	Ack:        enum.New[MessageTypeEnum]("ack", "确认"),
+	Nack:       enum.New[MessageTypeEnum]("nack", "否认"),
+	Request:    enum.New[MessageTypeEnum]("request", "请求"),
+	ReplyChunk: enum.New[MessageTypeEnum]("replyChunk", "部分应答"),
+	Reply:      enum.New[MessageTypeEnum]("reply", "最终应答"),
+}
+
+// messageTypeRegistry 注册全部成员，用于按代码或描述查找
+var messageTypeRegistry = enum.Register[MessageTypeEnum]("HookMessageType",
+	MessageType.Message,
+	MessageType.Ack,
+	MessageType.Nack,
+	MessageType.Request,
+	MessageType.ReplyChunk,
+	MessageType.Reply,
+)
+
+func (e *messageType) New(code string, description ...string) MessageTypeEnum {
+	if code == "" || code == e.Message.Code() {
+		return e.Message
+	}
+	if code == e.Ack.Code() {
+		return e.Ack
+	}
+	if code == e.Nack.Code() {
+		return e.Nack
+	}
+	if code == e.Request.Code() {
+		return e.Request
+	}
+	if code == e.ReplyChunk.Code() {
+		return e.ReplyChunk
+	}
+	if code == e.Reply.Code() {
+		return e.Reply
+	}
+
+	desc := ""
+	if len(description) > 0 {
+		desc = description[0]
+	}
+
+	return enum.New[MessageTypeEnum](code, desc)
+}
+
+// Parse 按代码获取注册的成员
+func (e *messageType) Parse(code string) (MessageTypeEnum, bool) {
+	return messageTypeRegistry.Parse(code)
+}
+
+// ParseDescription 按描述获取注册的成员
+func (e *messageType) ParseDescription(description string) (MessageTypeEnum, bool) {
+	return messageTypeRegistry.ParseDescription(description)
+}
+
+// All 按定义顺序返回所有成员
+func (e *messageType) All() []MessageTypeEnum {
+	return messageTypeRegistry.All()
+}
+
+// Values 按定义顺序返回所有成员的代码
+func (e *messageType) Values() []string {
+	return messageTypeRegistry.Values()
+}
@@ -0,0 +1,59 @@
+package sys_enum_hook
+
+import "github.com/kysion/base-library/utility/enum"
+
+// 发件箱消息状态：0待投递，1已投递，-1死信
+
+type OutboxStateEnum enum.IEnumCode[int]
+
+type outboxState struct {
+	Pending   OutboxStateEnum
+	Delivered OutboxStateEnum
+	Dead      OutboxStateEnum
+}
+
+var OutboxState = outboxState{
+	Pending:   enum.New[OutboxStateEnum](0, "pending"),
+	Delivered: enum.New[OutboxStateEnum](1, "delivered"),
+	Dead:      enum.New[OutboxStateEnum](-1, "dead"),
+}
+
+// outboxStateRegistry 注册全部成员，用于按代码或描述查找
+var outboxStateRegistry = enum.Register[OutboxStateEnum]("HookOutboxState",
+	OutboxState.Pending,
+	OutboxState.Delivered,
+	OutboxState.Dead,
+)
+
+func (e *outboxState) New(code int, description string) OutboxStateEnum {
+	if code == e.Pending.Code() {
+		return e.Pending
+	}
+	if code == e.Delivered.Code() {
+		return e.Delivered
+	}
+	if code == e.Dead.Code() {
+		return e.Dead
+	}
+	return enum.New[OutboxStateEnum](code, description)
+}
+
+// Parse 按代码获取注册的成员
+func (e *outboxState) Parse(code int) (OutboxStateEnum, bool) {
+	return outboxStateRegistry.Parse(code)
+}
+
+// ParseDescription 按描述获取注册的成员
+func (e *outboxState) ParseDescription(description string) (OutboxStateEnum, bool) {
+	return outboxStateRegistry.ParseDescription(description)
+}
+
+// All 按定义顺序返回所有成员
+func (e *outboxState) All() []OutboxStateEnum {
+	return outboxStateRegistry.All()
+}
+
+// Values 按定义顺序返回所有成员的代码
+func (e *outboxState) Values() []int {
+	return outboxStateRegistry.Values()
+}
@@ -0,0 +1,14 @@
+package base_model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// HookDedupe Hook消息的去重记录，认领消息时写入，订阅者处理成功后记录处理时间，有效期内重投的消息不再重复处理
+type HookDedupe struct {
+	DedupeKey    string      `json:"dedupeKey"    orm:"dedupe_key"     description:"去重Key：业务类型:幂等Key或消息ID，广播的消息追加@节点ID"`
+	BusinessType string      `json:"businessType" orm:"business_type"  description:"业务类型"`
+	MessageId    string      `json:"messageId"    orm:"message_id"     description:"首次处理成功的消息ID"`
+	ProcessedAt  *gtime.Time `json:"processedAt"  orm:"processed_at"   description:"处理成功时间，为空时表示正在处理"`
+	ExpireAt     *gtime.Time `json:"expireAt"     orm:"expire_at"      description:"过期时间：正在处理时为认领的租约到期时间"`
+}
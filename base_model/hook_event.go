@@ -0,0 +1,20 @@
+package base_model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// HookEvent Hook事件日志记录，按接收顺序追加写入，用于节点重启后的续传及问题修复后的重放
+type HookEvent struct {
+	Seq          int64       `json:"seq"          orm:"seq"            description:"序号，单调递增"`
+	BusinessType string      `json:"businessType" orm:"business_type"  description:"业务类型"`
+	Payload      string      `json:"payload"      orm:"payload"        description:"消息内容，HookModel的JSON"`
+	CreatedAt    *gtime.Time `json:"createdAt"    orm:"created_at"     description:"接收时间"`
+}
+
+// HookEventOffset Hook事件日志的消费位置
+type HookEventOffset struct {
+	Consumer  string      `json:"consumer"  orm:"consumer"    description:"消费者名称"`
+	Seq       int64       `json:"seq"       orm:"seq"         description:"最后处理成功的事件序号"`
+	UpdatedAt *gtime.Time `json:"updatedAt" orm:"updated_at"  description:"更新时间"`
+}
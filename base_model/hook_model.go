@@ -17,9 +17,24 @@ type HookHostInfo struct {
 
 type HookModel struct {
 	Ctx             context.Context `json:"-"`
-	Source          *HookHostInfo   `json:"source"`                 // 源
-	BusinessTypeStr string          `json:"businessType" dc:"业务类型"` // 业务类型
-	Data            interface{}     `json:"data" dc:"数据"`           // 数据bytes
+	Source          *HookHostInfo   `json:"source"`                              // 源
+	MessageId       string          `json:"messageId,omitempty" dc:"消息ID"`       // 消息ID，用于确认和重投
+	MessageTypeStr  string          `json:"messageType,omitempty" dc:"消息类型"`     // 消息类型：message、ack、nack、request、replyChunk、reply，为空时表示message
+	BusinessTypeStr string          `json:"businessType" dc:"业务类型"`              // 业务类型
+	DeliveryModeStr string          `json:"deliveryMode,omitempty" dc:"投递方式"`    // 投递方式：failover、fanout、roundRobin、hash，为空时表示failover
+	DeliveryKey     string          `json:"deliveryKey,omitempty" dc:"投递Key"`    // hash投递方式下用于选择服务的Key
+	IdempotencyKey  string          `json:"idempotencyKey,omitempty" dc:"幂等Key"` // 业务层指定的幂等Key，接收方据此去重，为空时按消息ID去重
+	ContentType     string          `json:"contentType,omitempty" dc:"载荷编码方式"`   // 载荷的编码方式，如 application/json，为空时 Data 为未编码的原始数据
+	SchemaVersion   int             `json:"schemaVersion,omitempty" dc:"载荷版本"`   // 载荷的版本，接收方据此迁移旧版本的载荷
+	Data            interface{}     `json:"data" dc:"数据"`                        // 数据bytes，携带 ContentType 时为编码后的base64字符串
+	Error           string          `json:"error,omitempty" dc:"错误信息"`           // nack及应答时对端返回的错误信息
+	ErrorCode       int             `json:"errorCode,omitempty" dc:"错误码"`        // 对端返回的错误码，对应 gcode.Code
+	Deadline        int64           `json:"deadline,omitempty" dc:"截止时间"`        // 请求的截止时间戳，毫秒，对端据此设置处理的超时时间
+	Node            string          `json:"node,omitempty" dc:"发送方节点ID"`         // 发送方节点ID，用于查找验签公钥
+	Timestamp       int64           `json:"timestamp,omitempty" dc:"发送时间戳"`      // 发送时间戳，毫秒
+	Nonce           string          `json:"nonce,omitempty" dc:"随机数"`            // 随机数，用于防重放
+	Signature       string          `json:"signature,omitempty" dc:"签名"`         // 消息签名
+	TraceId         string          `json:"traceId,omitempty" dc:"链路ID"`         // 发布方的链路ID，接收方据此延续调用链路
 }
 
 // GetAddr 获取通信地址
@@ -30,3 +45,11 @@ func (m *HookHostInfo) GetAddr() string {
 func (m *HookModel) BusinessType() base_enum.HookBusinessType {
 	return base_enum.Hook.BusinessType.New(m.BusinessTypeStr)
 }
+
+func (m *HookModel) MessageType() base_enum.HookMessageType {
+	return base_enum.Hook.MessageType.New(m.MessageTypeStr)
+}
+
+func (m *HookModel) DeliveryMode() base_enum.HookDeliveryMode {
+	return base_enum.Hook.DeliveryMode.New(m.DeliveryModeStr)
+}
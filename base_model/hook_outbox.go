@@ -0,0 +1,19 @@
+package base_model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// HookOutbox Hook发件箱消息，与业务数据在同一事务内写入，由中继协程异步投递
+type HookOutbox struct {
+	Id           int64       `json:"id"           orm:"id"             description:"ID"`
+	BusinessType string      `json:"businessType" orm:"business_type"  description:"业务类型"`
+	Payload      string      `json:"payload"      orm:"payload"        description:"消息内容，HookModel的JSON"`
+	State        int         `json:"state"        orm:"state"          description:"状态：0待投递，1已投递，-1死信"`
+	Attempts     int         `json:"attempts"     orm:"attempts"       description:"已尝试投递次数"`
+	LastError    string      `json:"lastError"    orm:"last_error"     description:"最后一次投递失败的原因"`
+	NextRetryAt  *gtime.Time `json:"nextRetryAt"  orm:"next_retry_at"  description:"下次投递时间"`
+	DeliveredAt  *gtime.Time `json:"deliveredAt"  orm:"delivered_at"   description:"投递成功时间"`
+	CreatedAt    *gtime.Time `json:"createdAt"    orm:"created_at"     description:"创建时间"`
+	UpdatedAt    *gtime.Time `json:"updatedAt"    orm:"updated_at"     description:"更新时间"`
+}
@@ -0,0 +1,13 @@
+package base_model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// HookPeer 跨进程Hook的服务注册记录，各节点定时写入心跳，过期的记录视为节点已离开
+type HookPeer struct {
+	Address     string      `json:"address"     orm:"address"       description:"节点对外的服务地址"`
+	NodeId      string      `json:"nodeId"      orm:"node_id"       description:"节点ID"`
+	HeartbeatAt *gtime.Time `json:"heartbeatAt" orm:"heartbeat_at"  description:"最后一次心跳时间"`
+	ExpireAt    *gtime.Time `json:"expireAt"    orm:"expire_at"     description:"过期时间"`
+}
@@ -0,0 +1,164 @@
+package base_hook_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/kysion/base-library/base_hook"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/dbtest/sqlitetest"
+)
+
+const (
+	eventLogDDL = `CREATE TABLE hook_event_log (
+		seq           INTEGER PRIMARY KEY AUTOINCREMENT,
+		business_type VARCHAR(255) NOT NULL,
+		payload       TEXT         NOT NULL,
+		created_at    DATETIME
+	)`
+	eventOffsetDDL = `CREATE TABLE hook_event_offset (
+		consumer   VARCHAR(255) PRIMARY KEY,
+		seq        BIGINT NOT NULL DEFAULT 0,
+		updated_at DATETIME
+	)`
+)
+
+func newTestDbEventStore(t *testing.T) *base_hook.DbEventStore {
+	db := sqlitetest.Open(t, eventLogDDL, eventOffsetDDL)
+	return base_hook.NewDbEventStore(db, base_hook.EventLogOption{Table: "hook_event_log", OffsetTable: "hook_event_offset"})
+}
+
+func eventSeqs(events []*base_model.HookEvent) []int64 {
+	seqs := make([]int64, 0, len(events))
+	for _, event := range events {
+		seqs = append(seqs, event.Seq)
+	}
+	return seqs
+}
+
+func TestDbEventStore_AppendRead(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDbEventStore(t)
+
+	for i, businessType := range []string{"order.paid", "order.closed", "order.paid", "order.paid"} {
+		seq, err := store.Append(ctx, base_model.HookModel{MessageId: fmt.Sprintf("m%d", i+1), BusinessTypeStr: businessType})
+		if err != nil || seq != int64(i+1) {
+			t.Fatalf("Append() = %d, %v, want %d", seq, err, i+1)
+		}
+	}
+
+	cases := []struct {
+		name         string
+		businessType string
+		start, end   *gtime.Time
+		fromSeq      int64
+		limit        int
+		want         []int64
+	}{
+		{name: "all", limit: 10, want: []int64{1, 2, 3, 4}},
+		{name: "from seq", fromSeq: 2, limit: 10, want: []int64{3, 4}},
+		{name: "limit", limit: 2, want: []int64{1, 2}},
+		{name: "business type", businessType: "order.paid", limit: 10, want: []int64{1, 3, 4}},
+		{name: "business type from seq", businessType: "order.paid", fromSeq: 1, limit: 1, want: []int64{3}},
+		{name: "time range", start: gtime.Now().Add(-time.Minute), end: gtime.Now().Add(time.Minute), limit: 10, want: []int64{1, 2, 3, 4}},
+		{name: "before range", end: gtime.Now().Add(-time.Hour), limit: 10, want: []int64{}},
+		{name: "after range", start: gtime.Now().Add(time.Hour), limit: 10, want: []int64{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				events []*base_model.HookEvent
+				err    error
+			)
+			if c.businessType == "" && c.start == nil && c.end == nil {
+				events, err = store.Read(ctx, c.fromSeq, c.limit)
+			} else {
+				events, err = store.ReadRange(ctx, c.businessType, c.start, c.end, c.fromSeq, c.limit)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := eventSeqs(events); !slices.Equal(got, c.want) {
+				t.Fatalf("seqs = %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	// 事件内容可还原为原消息
+	events, err := store.Read(ctx, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := base_model.HookModel{}
+	if err = gjson.DecodeTo(events[0].Payload, &model); err != nil || model.MessageId != "m2" || model.BusinessTypeStr != "order.closed" {
+		t.Fatalf("HookModel() = %+v, %v, want m2 order.closed", model, err)
+	}
+}
+
+func TestDbEventStore_Offset(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDbEventStore(t)
+
+	if offset, err := store.Offset(ctx, "consumer"); err != nil || offset != 0 {
+		t.Fatalf("Offset() before commit = %d, %v, want 0", offset, err)
+	}
+
+	for _, seq := range []int64{2, 5} {
+		if err := store.CommitOffset(ctx, "consumer", seq); err != nil {
+			t.Fatal(err)
+		}
+		if offset, err := store.Offset(ctx, "consumer"); err != nil || offset != seq {
+			t.Fatalf("Offset() = %d, %v, want %d", offset, err, seq)
+		}
+	}
+
+	if offset, err := store.Offset(ctx, "other"); err != nil || offset != 0 {
+		t.Fatalf("Offset(other) = %d, %v, want 0", offset, err)
+	}
+}
+
+func TestDbEventStore_Resume(t *testing.T) {
+	useConfig(t, `{"service":{"hook":{"eventLog":{"batchSize":2}}}}`)
+	ctx := context.Background()
+
+	store := newTestDbEventStore(t)
+	base_hook.SetEventStore(store)
+	t.Cleanup(func() {
+		base_hook.SetEventStore(nil)
+	})
+
+	for _, messageId := range []string{"m1", "m2", "m3"} {
+		if _, err := store.Append(ctx, base_model.HookModel{MessageId: messageId, BusinessTypeStr: "order.paid"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var handled []string
+	handler := func(ctx context.Context, event *base_model.HookEvent, model base_model.HookModel) error {
+		handled = append(handled, model.MessageId)
+		return nil
+	}
+
+	if processed, err := base_hook.Resume(ctx, "consumer", handler); err != nil || processed != 3 {
+		t.Fatalf("Resume() = %d, %v, want 3", processed, err)
+	}
+	if offset, _ := store.Offset(ctx, "consumer"); offset != 3 {
+		t.Fatalf("offset = %d, want 3", offset)
+	}
+
+	if _, err := store.Append(ctx, base_model.HookModel{MessageId: "m4", BusinessTypeStr: "order.paid"}); err != nil {
+		t.Fatal(err)
+	}
+	if processed, err := base_hook.Resume(ctx, "consumer", handler); err != nil || processed != 1 {
+		t.Fatalf("Resume() = %d, %v, want 1", processed, err)
+	}
+	if want := []string{"m1", "m2", "m3", "m4"}; !slices.Equal(handled, want) {
+		t.Fatalf("handled = %v, want %v", handled, want)
+	}
+}
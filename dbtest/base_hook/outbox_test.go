@@ -0,0 +1,290 @@
+package base_hook_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/kysion/base-library/base_hook"
+	"github.com/kysion/base-library/base_model"
+	"github.com/kysion/base-library/base_model/base_enum"
+	"github.com/kysion/base-library/dbtest/sqlitetest"
+)
+
+// testTransportName 测试使用的传输方式，业务类型 test.outbox.# 的消息通过该传输方式投递
+const testTransportName = "outbox-test"
+
+// useConfig 测试期间使用指定的配置内容，结束后恢复原有的配置
+func useConfig(t *testing.T, content string) {
+	adapter, err := gcfg.NewAdapterContent(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prevAdapter := g.Cfg().GetAdapter()
+	g.Cfg().SetAdapter(adapter)
+	t.Cleanup(func() {
+		g.Cfg().SetAdapter(prevAdapter)
+	})
+}
+
+// testTransport 记录发送的消息，fail 返回非nil时发送失败
+type testTransport struct {
+	mu   sync.Mutex
+	sent map[string]int // 每条消息发送的次数，key为消息ID
+	fail error
+}
+
+func (t *testTransport) Name() string { return testTransportName }
+
+func (t *testTransport) Connect(ctx context.Context, addrs ...string) error { return nil }
+
+func (t *testTransport) Send(ctx context.Context, addr string, model base_model.HookModel) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sent[model.MessageId]++
+	return t.fail
+}
+
+func (t *testTransport) Disconnect(ctx context.Context, addr string) {}
+
+func (t *testTransport) Shutdown(ctx context.Context) error { return nil }
+
+func (t *testTransport) setFail(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.fail = err
+}
+
+func (t *testTransport) sentCount() (messages, total int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, n := range t.sent {
+		messages++
+		total += n
+	}
+	return messages, total
+}
+
+// useTestTransport 注册测试传输方式，并将 test.outbox.# 的消息路由到该传输方式
+func useTestTransport(t *testing.T) *testTransport {
+	useConfig(t, fmt.Sprintf(`{"service":{"hook":{"routing":{"topics":{"test.outbox.#":%q},"hosts":{%q:["peer-a"]}}}}}`,
+		testTransportName, testTransportName))
+
+	transport := &testTransport{sent: map[string]int{}}
+	base_hook.RegisterTransport(transport)
+	return transport
+}
+
+func outboxDDL(table string) string {
+	return fmt.Sprintf(`CREATE TABLE %s (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		business_type VARCHAR(255) NOT NULL,
+		payload       TEXT         NOT NULL,
+		state         SMALLINT     NOT NULL DEFAULT 0,
+		attempts      INT          NOT NULL DEFAULT 0,
+		last_error    TEXT         NOT NULL DEFAULT '',
+		next_retry_at DATETIME,
+		delivered_at  DATETIME,
+		created_at    DATETIME,
+		updated_at    DATETIME
+	)`, table)
+}
+
+// writeOutbox 在事务内写入消息，未指定业务类型时为 test.outbox.paid
+func writeOutbox(t *testing.T, db gdb.DB, count int, option ...base_hook.OutboxOption) {
+	t.Helper()
+
+	err := db.Transaction(context.Background(), func(ctx context.Context, tx gdb.TX) error {
+		for i := 0; i < count; i++ {
+			model := base_model.HookModel{BusinessTypeStr: "test.outbox.paid", Data: g.Map{"index": i}}
+			if err := base_hook.WriteOutbox(ctx, tx, model, option...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func outboxRows(t *testing.T, db gdb.DB, table string) []*base_model.HookOutbox {
+	t.Helper()
+
+	rows := make([]*base_model.HookOutbox, 0)
+	if err := db.Model(table).OrderAsc("id").Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestOutboxRelay_RelayOnce(t *testing.T) {
+	ctx := context.Background()
+	transport := useTestTransport(t)
+	db := sqlitetest.Open(t, outboxDDL("custom_outbox"))
+
+	// 中继指定的表同时作为写入的默认表
+	relay := base_hook.NewOutboxRelay(db, base_hook.OutboxOption{Table: "custom_outbox"})
+	writeOutbox(t, db, 3)
+
+	delivered, err := relay.RelayOnce(ctx)
+	if err != nil || delivered != 3 {
+		t.Fatalf("RelayOnce() = %d, %v, want 3", delivered, err)
+	}
+
+	for _, row := range outboxRows(t, db, "custom_outbox") {
+		if row.State != base_enum.Hook.OutboxState.Delivered.Code() || row.Attempts != 1 || row.DeliveredAt == nil {
+			t.Fatalf("row %d = state %d, attempts %d, deliveredAt %v, want delivered once", row.Id, row.State, row.Attempts, row.DeliveredAt)
+		}
+		// 写入时分配的消息ID即投递的消息ID
+		model := base_model.HookModel{}
+		if err = gjson.DecodeTo(row.Payload, &model); err != nil || model.MessageId == "" || transport.sent[model.MessageId] != 1 {
+			t.Fatalf("row %d messageId %q sent %d times, want 1", row.Id, model.MessageId, transport.sent[model.MessageId])
+		}
+	}
+
+	if delivered, err = relay.RelayOnce(ctx); err != nil || delivered != 0 {
+		t.Fatalf("RelayOnce() after delivered = %d, %v, want 0", delivered, err)
+	}
+	if _, total := transport.sentCount(); total != 3 {
+		t.Fatalf("sent %d times, want 3", total)
+	}
+}
+
+func TestOutboxRelay_ConcurrentClaim(t *testing.T) {
+	ctx := context.Background()
+	transport := useTestTransport(t)
+	db := sqlitetest.Open(t, outboxDDL("hook_outbox"))
+
+	option := base_hook.OutboxOption{Table: "hook_outbox", BatchSize: 50}
+	relays := []*base_hook.OutboxRelay{base_hook.NewOutboxRelay(db, option), base_hook.NewOutboxRelay(db, option)}
+	writeOutbox(t, db, 20)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+	)
+	for _, relay := range relays {
+		wg.Add(1)
+		go func(relay *base_hook.OutboxRelay) {
+			defer wg.Done()
+			n, err := relay.RelayOnce(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			delivered += n
+			mu.Unlock()
+		}(relay)
+	}
+	wg.Wait()
+
+	// 每条消息只被一个中继认领并投递
+	if messages, total := transport.sentCount(); delivered != 20 || messages != 20 || total != 20 {
+		t.Fatalf("delivered %d, sent %d messages %d times, want 20", delivered, messages, total)
+	}
+}
+
+func TestOutboxRelay_Backoff(t *testing.T) {
+	ctx := context.Background()
+	transport := useTestTransport(t)
+	transport.setFail(errors.New("peer unavailable"))
+	db := sqlitetest.Open(t, outboxDDL("hook_outbox"))
+
+	relay := base_hook.NewOutboxRelay(db, base_hook.OutboxOption{Table: "hook_outbox", BaseBackoff: time.Hour, MaxAttempts: 3})
+	writeOutbox(t, db, 1)
+
+	before := gtime.Now()
+	if delivered, err := relay.RelayOnce(ctx); err != nil || delivered != 0 {
+		t.Fatalf("RelayOnce() = %d, %v, want 0", delivered, err)
+	}
+
+	row := outboxRows(t, db, "hook_outbox")[0]
+	if row.State != base_enum.Hook.OutboxState.Pending.Code() || row.Attempts != 1 || row.LastError == "" {
+		t.Fatalf("row = state %d, attempts %d, lastError %q, want pending after 1 attempt", row.State, row.Attempts, row.LastError)
+	}
+	if row.NextRetryAt == nil || row.NextRetryAt.Before(before.Add(time.Hour-time.Second)) {
+		t.Fatalf("nextRetryAt = %v, want about 1h after %v", row.NextRetryAt, before)
+	}
+
+	// 退避期间不重新投递
+	if _, err := relay.RelayOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, total := transport.sentCount(); total != 1 {
+		t.Fatalf("sent %d times during backoff, want 1", total)
+	}
+}
+
+func TestOutboxRelay_DeadLetterRequeue(t *testing.T) {
+	ctx := context.Background()
+	transport := useTestTransport(t)
+	transport.setFail(errors.New("peer unavailable"))
+	db := sqlitetest.Open(t, outboxDDL("hook_outbox"))
+
+	relay := base_hook.NewOutboxRelay(db, base_hook.OutboxOption{
+		Table:       "hook_outbox",
+		MaxAttempts: 2,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	})
+	writeOutbox(t, db, 2)
+
+	for i := 0; i < 2; i++ {
+		if _, err := relay.RelayOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	dead, err := relay.DeadLetters(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead.Records) != 2 || dead.Records[0].Attempts != 2 {
+		t.Fatalf("dead letters = %d, want 2 after 2 attempts", len(dead.Records))
+	}
+
+	// 死信不再投递
+	if _, err = relay.RelayOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, total := transport.sentCount(); total != 4 {
+		t.Fatalf("sent %d times, want 4", total)
+	}
+
+	transport.setFail(nil)
+	requeued, err := relay.Requeue(ctx, dead.Records[0].Id)
+	if err != nil || requeued != 1 {
+		t.Fatalf("Requeue() = %d, %v, want 1", requeued, err)
+	}
+	if delivered, err := relay.RelayOnce(ctx); err != nil || delivered != 1 {
+		t.Fatalf("RelayOnce() after requeue = %d, %v, want 1", delivered, err)
+	}
+
+	rows := outboxRows(t, db, "hook_outbox")
+	states := map[int64]int{}
+	for _, row := range rows {
+		states[row.Id] = row.State
+	}
+	if states[dead.Records[0].Id] != base_enum.Hook.OutboxState.Delivered.Code() || states[dead.Records[1].Id] != base_enum.Hook.OutboxState.Dead.Code() {
+		t.Fatalf("states = %v, want requeued message delivered and the other dead", states)
+	}
+
+	// 仅死信可以重新投递
+	if requeued, err = relay.Requeue(ctx, dead.Records[0].Id); err != nil || requeued != 0 {
+		t.Fatalf("Requeue() delivered message = %d, %v, want 0", requeued, err)
+	}
+}
@@ -0,0 +1,369 @@
+package base_tree_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/kysion/base-library/dbtest/sqlitetest"
+	"github.com/kysion/base-library/utility/base_tree"
+)
+
+// 使用 SQLite 测试树结构的持久化
+
+// pathRecord 物化路径的节点记录
+type pathRecord struct {
+	Id       string
+	ParentId string
+	Path     string
+	Depth    int
+}
+
+// newPathStore 创建使用字符串ID的物化路径持久化
+func newPathStore(t *testing.T) (*base_tree.PathStore[string], *sqlitetest.Dao) {
+	db := sqlitetest.Open(t, "CREATE TABLE node (id TEXT PRIMARY KEY, parent_id TEXT NOT NULL DEFAULT '', path TEXT NOT NULL DEFAULT '', depth INTEGER NOT NULL DEFAULT 0, sort INTEGER NOT NULL DEFAULT 0)")
+	dao := sqlitetest.NewDao(db, "node")
+	return base_tree.NewPathStore[string](dao, base_tree.PathStoreOption{SortColumn: "sort"}), dao
+}
+
+// insertPath 插入节点记录并设置树结构
+func insertPath(t *testing.T, store *base_tree.PathStore[string], dao *sqlitetest.Dao, id string, parentId string) {
+	t.Helper()
+
+	ctx := context.Background()
+	if _, err := dao.Ctx(ctx).Data(g.Map{"id": id}).Insert(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(ctx, id, parentId); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pathRecords 按ID排列的全部节点记录
+func pathRecords(t *testing.T, dao *sqlitetest.Dao) map[string]pathRecord {
+	t.Helper()
+
+	var list []pathRecord
+	if err := dao.Ctx(context.Background()).Scan(&list); err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]pathRecord, len(list))
+	for _, item := range list {
+		result[item.Id] = item
+	}
+	return result
+}
+
+// checkPath 校验节点的父节点、路径及深度
+func checkPath(t *testing.T, records map[string]pathRecord, id string, parentId string, path string, depth int) {
+	t.Helper()
+
+	expect := pathRecord{Id: id, ParentId: parentId, Path: path, Depth: depth}
+	if records[id] != expect {
+		t.Fatalf("节点 %s 为 %+v，期望 %+v", id, records[id], expect)
+	}
+}
+
+// buildPathTree 构建测试使用的树：
+//
+//	a
+//	├── a_
+//	│   └── a_1
+//	└── ab
+//	    └── ab1
+//	b
+func buildPathTree(t *testing.T) (*base_tree.PathStore[string], *sqlitetest.Dao) {
+	store, dao := newPathStore(t)
+	insertPath(t, store, dao, "a", "")
+	insertPath(t, store, dao, "a_", "a")
+	insertPath(t, store, dao, "a_1", "a_")
+	insertPath(t, store, dao, "ab", "a")
+	insertPath(t, store, dao, "ab1", "ab")
+	insertPath(t, store, dao, "b", "")
+	return store, dao
+}
+
+func TestPathStoreInsert(t *testing.T) {
+	store, dao := buildPathTree(t)
+
+	records := pathRecords(t, dao)
+	checkPath(t, records, "a", "", "/a/", 0)
+	checkPath(t, records, "a_", "a", "/a/a_/", 1)
+	checkPath(t, records, "a_1", "a_", "/a/a_/a_1/", 2)
+	checkPath(t, records, "ab1", "ab", "/a/ab/ab1/", 2)
+	checkPath(t, records, "b", "", "/b/", 0)
+
+	if err := store.Insert(context.Background(), "missing", ""); err == nil {
+		t.Fatal("节点记录不存在时应返回错误")
+	}
+	if err := store.Insert(context.Background(), "b", "missing"); err == nil {
+		t.Fatal("父节点不存在时应返回错误")
+	}
+}
+
+func TestPathStoreDescendantIds(t *testing.T) {
+	store, _ := buildPathTree(t)
+	ctx := context.Background()
+
+	// a_ 中的 _ 不能作为通配符匹配 ab 的子树
+	ids, err := store.DescendantIds(ctx, "a_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"a_1"}) {
+		t.Fatalf("a_ 的后代节点为 %v", ids)
+	}
+
+	ids, err = store.DescendantIds(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids[:2])
+	sort.Strings(ids[2:])
+	if !reflect.DeepEqual(ids, []string{"a_", "ab", "a_1", "ab1"}) {
+		t.Fatalf("a 的后代节点为 %v", ids)
+	}
+
+	ids, err = store.DescendantIds(ctx, "b")
+	if err != nil || len(ids) != 0 {
+		t.Fatalf("b 的后代节点为 %v，错误为 %v", ids, err)
+	}
+}
+
+func TestPathStoreMove(t *testing.T) {
+	store, dao := buildPathTree(t)
+	ctx := context.Background()
+
+	if err := store.Move(ctx, "a_", "b"); err != nil {
+		t.Fatal(err)
+	}
+	records := pathRecords(t, dao)
+	checkPath(t, records, "a_", "b", "/b/a_/", 1)
+	checkPath(t, records, "a_1", "a_", "/b/a_/a_1/", 2)
+	// 路径前缀相似的 ab 子树不受影响
+	checkPath(t, records, "ab", "a", "/a/ab/", 1)
+	checkPath(t, records, "ab1", "ab", "/a/ab/ab1/", 2)
+
+	if err := store.Move(ctx, "b", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Move(ctx, "ab", ""); err != nil {
+		t.Fatal(err)
+	}
+	records = pathRecords(t, dao)
+	checkPath(t, records, "ab", "", "/ab/", 0)
+	checkPath(t, records, "ab1", "ab", "/ab/ab1/", 1)
+
+	if err := store.Move(ctx, "b", "a_1"); err == nil {
+		t.Fatal("移动到自身的子树中时应返回错误")
+	}
+}
+
+func TestPathStoreDelete(t *testing.T) {
+	t.Run("cascade", func(t *testing.T) {
+		store, dao := buildPathTree(t)
+
+		ids, err := store.Delete(context.Background(), "a_", base_tree.DeleteCascade)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids, []string{"a_", "a_1"}) {
+			t.Fatalf("删除的节点为 %v", ids)
+		}
+
+		records := pathRecords(t, dao)
+		if len(records) != 4 {
+			t.Fatalf("删除后剩余 %d 个节点", len(records))
+		}
+		checkPath(t, records, "ab1", "ab", "/a/ab/ab1/", 2)
+	})
+
+	t.Run("reparent", func(t *testing.T) {
+		store, dao := buildPathTree(t)
+
+		ids, err := store.Delete(context.Background(), "a_", base_tree.DeleteReparent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids, []string{"a_"}) {
+			t.Fatalf("删除的节点为 %v", ids)
+		}
+
+		records := pathRecords(t, dao)
+		if _, ok := records["a_"]; ok {
+			t.Fatal("节点 a_ 未删除")
+		}
+		checkPath(t, records, "a_1", "a", "/a/a_1/", 1)
+		checkPath(t, records, "ab", "a", "/a/ab/", 1)
+		checkPath(t, records, "ab1", "ab", "/a/ab/ab1/", 2)
+	})
+}
+
+func TestPathStoreReorder(t *testing.T) {
+	store, dao := buildPathTree(t)
+
+	if err := store.Reorder(context.Background(), []string{"ab", "a_"}); err != nil {
+		t.Fatal(err)
+	}
+	values, err := dao.Ctx(context.Background()).WhereIn("id", g.Slice{"ab", "a_"}).OrderAsc("sort").Array("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := gconv.Strings(values); !reflect.DeepEqual(ids, []string{"ab", "a_"}) {
+		t.Fatalf("排序后为 %v", ids)
+	}
+}
+
+// newClosureStore 创建维护业务表父节点列的闭包表持久化
+func newClosureStore(t *testing.T) (*base_tree.ClosureStore[int], *sqlitetest.Dao, *sqlitetest.Dao) {
+	db := sqlitetest.Open(t,
+		"CREATE TABLE node (id INTEGER PRIMARY KEY, parent_id INTEGER NOT NULL DEFAULT 0)",
+		"CREATE TABLE node_closure (ancestor_id INTEGER NOT NULL, descendant_id INTEGER NOT NULL, depth INTEGER NOT NULL, PRIMARY KEY (ancestor_id, descendant_id))",
+	)
+	nodes := sqlitetest.NewDao(db, "node")
+	closure := sqlitetest.NewDao(db, "node_closure")
+	return base_tree.NewClosureStore[int](closure, nodes, base_tree.ClosureStoreOption{ParentIdColumn: "parent_id"}), closure, nodes
+}
+
+// buildClosureTree 构建测试使用的树：1 -> 2 -> 3，1 -> 4，5
+func buildClosureTree(t *testing.T) (*base_tree.ClosureStore[int], *sqlitetest.Dao, *sqlitetest.Dao) {
+	store, closure, nodes := newClosureStore(t)
+	ctx := context.Background()
+	for _, item := range [][2]int{{1, 0}, {2, 1}, {3, 2}, {4, 1}, {5, 0}} {
+		if _, err := nodes.Ctx(ctx).Data(g.Map{"id": item[0]}).Insert(); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Insert(ctx, item[0], item[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store, closure, nodes
+}
+
+// closurePaths 闭包表的全部路径，格式为 祖先>后代:距离，按字典序排列
+func closurePaths(t *testing.T, closure *sqlitetest.Dao) []string {
+	t.Helper()
+
+	result, err := closure.Ctx(context.Background()).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, 0, len(result))
+	for _, record := range result {
+		paths = append(paths, fmt.Sprintf("%d>%d:%d", record["ancestor_id"].Int(), record["descendant_id"].Int(), record["depth"].Int()))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// parentIds 业务表中节点的父节点
+func parentIds(t *testing.T, nodes *sqlitetest.Dao) map[int]int {
+	t.Helper()
+
+	result, err := nodes.Ctx(context.Background()).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parents := make(map[int]int, len(result))
+	for _, record := range result {
+		parents[record["id"].Int()] = record["parent_id"].Int()
+	}
+	return parents
+}
+
+// checkClosure 校验闭包表的路径
+func checkClosure(t *testing.T, closure *sqlitetest.Dao, expect ...string) {
+	t.Helper()
+
+	sort.Strings(expect)
+	if paths := closurePaths(t, closure); !reflect.DeepEqual(paths, expect) {
+		t.Fatalf("闭包表为 %v，期望 %v", paths, expect)
+	}
+}
+
+func TestClosureStoreInsert(t *testing.T) {
+	store, closure, nodes := buildClosureTree(t)
+
+	checkClosure(t, closure,
+		"1>1:0", "2>2:0", "3>3:0", "4>4:0", "5>5:0",
+		"1>2:1", "2>3:1", "1>3:2", "1>4:1",
+	)
+	if parents := parentIds(t, nodes); !reflect.DeepEqual(parents, map[int]int{1: 0, 2: 1, 3: 2, 4: 1, 5: 0}) {
+		t.Fatalf("父节点为 %v", parents)
+	}
+
+	if err := store.Insert(context.Background(), 6, 99); err == nil {
+		t.Fatal("父节点不存在时应返回错误")
+	}
+}
+
+func TestClosureStoreDescendantIds(t *testing.T) {
+	store, _, _ := buildClosureTree(t)
+
+	ids, err := store.DescendantIds(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(ids[:2])
+	if !reflect.DeepEqual(ids, []int{2, 4, 3}) {
+		t.Fatalf("1 的后代节点为 %v", ids)
+	}
+}
+
+func TestClosureStoreMove(t *testing.T) {
+	store, closure, nodes := buildClosureTree(t)
+	ctx := context.Background()
+
+	if err := store.Move(ctx, 2, 5); err != nil {
+		t.Fatal(err)
+	}
+	checkClosure(t, closure,
+		"1>1:0", "2>2:0", "3>3:0", "4>4:0", "5>5:0",
+		"5>2:1", "2>3:1", "5>3:2", "1>4:1",
+	)
+	if parents := parentIds(t, nodes); parents[2] != 5 {
+		t.Fatalf("父节点为 %v", parents)
+	}
+
+	if err := store.Move(ctx, 5, 3); err == nil {
+		t.Fatal("移动到自身的子树中时应返回错误")
+	}
+}
+
+func TestClosureStoreDelete(t *testing.T) {
+	t.Run("cascade", func(t *testing.T) {
+		store, closure, nodes := buildClosureTree(t)
+
+		ids, err := store.Delete(context.Background(), 2, base_tree.DeleteCascade)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids, []int{2, 3}) {
+			t.Fatalf("删除的节点为 %v", ids)
+		}
+		checkClosure(t, closure, "1>1:0", "4>4:0", "5>5:0", "1>4:1")
+		if parents := parentIds(t, nodes); !reflect.DeepEqual(parents, map[int]int{1: 0, 4: 1, 5: 0}) {
+			t.Fatalf("父节点为 %v", parents)
+		}
+	})
+
+	t.Run("reparent", func(t *testing.T) {
+		store, closure, nodes := buildClosureTree(t)
+
+		ids, err := store.Delete(context.Background(), 2, base_tree.DeleteReparent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids, []int{2}) {
+			t.Fatalf("删除的节点为 %v", ids)
+		}
+		checkClosure(t, closure, "1>1:0", "3>3:0", "4>4:0", "5>5:0", "1>3:1", "1>4:1")
+		if parents := parentIds(t, nodes); !reflect.DeepEqual(parents, map[int]int{1: 0, 3: 1, 4: 1, 5: 0}) {
+			t.Fatalf("父节点为 %v", parents)
+		}
+	})
+}
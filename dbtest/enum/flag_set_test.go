@@ -0,0 +1,89 @@
+package enum_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/kysion/base-library/dbtest/sqlitetest"
+	"github.com/kysion/base-library/utility/enum"
+)
+
+// 使用 SQLite 测试位标志集合的查询条件
+
+type perm enum.IEnumCode[int]
+
+var (
+	read  = enum.New[perm](1, "read")
+	write = enum.New[perm](2, "write")
+	exec  = enum.New[perm](4, "exec")
+	_     = enum.RegisterFlags[perm]("DbTestPerm", read, write, exec)
+)
+
+// openTestDB 创建用户表，id 与权限：1 无，2 read，3 read|write，4 write|exec，5 read|write|exec
+func openTestDB(t *testing.T) gdb.DB {
+	t.Helper()
+
+	db := sqlitetest.Open(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, perm INTEGER NOT NULL DEFAULT 0)")
+	for id, bits := range []int{0, 1, 3, 6, 7} {
+		data := g.Map{"id": id + 1, "perm": enum.FlagSetOf[perm](bits)}
+		if _, err := db.Model("users").Ctx(context.Background()).Data(data).Insert(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestFlagSet_Where(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	rw := enum.NewFlagSet(read, write)
+
+	cases := []struct {
+		name    string
+		where   func(model *gdb.Model, column string) *gdb.Model
+		wantSQL string
+		wantIds []int
+	}{
+		{name: "has", where: rw.WhereHas, wantSQL: "`perm` & 3 = 3", wantIds: []int{3, 5}},
+		{name: "has any", where: rw.WhereHasAny, wantSQL: "`perm` & 3 <> 0", wantIds: []int{2, 3, 4, 5}},
+		{name: "has none", where: rw.WhereHasNone, wantSQL: "`perm` & 3 = 0", wantIds: []int{1}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sql, err := gdb.ToSQL(ctx, func(ctx context.Context) error {
+				_, err := c.where(db.Model("users").Ctx(ctx), "perm").All()
+				return err
+			})
+			if err != nil || !strings.Contains(sql, c.wantSQL) {
+				t.Fatalf("SQL = %s, %v, want condition %s", sql, err, c.wantSQL)
+			}
+
+			values, err := c.where(db.Model("users").Ctx(ctx), "perm").OrderAsc("id").Array("id")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]int, 0, len(values))
+			for _, value := range values {
+				ids = append(ids, value.Int())
+			}
+			if !slices.Equal(ids, c.wantIds) {
+				t.Fatalf("ids = %v, want %v", ids, c.wantIds)
+			}
+		})
+	}
+
+	// 读回的集合与写入的一致
+	var set enum.FlagSet[perm, int]
+	value, err := db.Model("users").Ctx(ctx).Where("id", 4).Value("perm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = value.Scan(&set); err != nil || set.String() != "write|exec" {
+		t.Fatalf("Scan() = %s, %v, want write|exec", set, err)
+	}
+}
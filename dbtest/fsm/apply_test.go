@@ -0,0 +1,174 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/kysion/base-library/dbtest/sqlitetest"
+	"github.com/kysion/base-library/utility/enum"
+	"github.com/kysion/base-library/utility/fsm"
+)
+
+// 使用 SQLite 测试状态迁移的持久化
+
+type orderState enum.IEnumCode[int]
+type orderEvent enum.IEnumCode[string]
+
+var (
+	created = enum.New[orderState](0, "已创建")
+	paid    = enum.New[orderState](1, "已支付")
+	closed  = enum.New[orderState](2, "已关闭")
+
+	payEvent   = enum.New[orderEvent]("pay", "支付")
+	closeEvent = enum.New[orderEvent]("close", "关闭")
+)
+
+// order 迁移的业务对象
+type order struct {
+	Id     int64
+	State  int
+	Amount int
+}
+
+// machineSeq 状态机名称的序号，状态机名称在进程内必须唯一
+var machineSeq atomic.Int64
+
+// newMachine 创建订单状态机：已创建 -支付-> 已支付，已创建、已支付 -关闭-> 已关闭，支付要求金额大于0
+func newMachine(t *testing.T, option ...fsm.Option) *fsm.Machine[orderState, orderEvent, *order, int, string] {
+	t.Helper()
+
+	name := fmt.Sprintf("%s%d", t.Name(), machineSeq.Add(1))
+	m := fsm.New[orderState, orderEvent, *order, int, string](name, option...).Initial(created)
+	m.Permit(payEvent, paid, created).Guard(func(ctx context.Context, t *fsm.Transition[orderState, orderEvent, *order]) error {
+		if t.Entity.Amount <= 0 {
+			return errors.New("金额必须大于0")
+		}
+		return nil
+	})
+	m.Permit(closeEvent, closed, created, paid)
+	return m
+}
+
+// openTestDB 在临时目录创建数据库及订单表，插入一条已创建的订单
+func openTestDB(t *testing.T) gdb.DB {
+	t.Helper()
+
+	db := sqlitetest.Open(t, "CREATE TABLE orders (id INTEGER PRIMARY KEY, state INTEGER NOT NULL, version INTEGER NOT NULL DEFAULT 0, amount INTEGER NOT NULL DEFAULT 0)")
+	if _, err := db.Model("orders").Ctx(context.Background()).Data(g.Map{"id": 1, "state": created.Code(), "amount": 10}).Insert(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// loadOrder 读取订单的状态、版本号及金额
+func loadOrder(t *testing.T, db gdb.DB) (state int, version int64, amount int) {
+	t.Helper()
+
+	record, err := db.Model("orders").Ctx(context.Background()).Where("id", 1).One()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record["state"].Int(), record["version"].Int64(), record["amount"].Int()
+}
+
+func TestApply(t *testing.T) {
+	db := openTestDB(t)
+	m := newMachine(t, fsm.Option{VersionColumn: "version"})
+	ctx := context.Background()
+
+	published := 0
+	m.Subscribe(fsm.Subscription[int, string]{}, func(ctx context.Context, e fsm.TransitionEvent[int, string]) error {
+		if e.Id != "1" {
+			t.Errorf("迁移事件的ID为 %s", e.Id)
+		}
+		published++
+		return nil
+	})
+
+	state, err := m.Apply(ctx, db.Model("orders"), 1, created, payEvent, &order{Id: 1, Amount: 10}, fsm.ApplyOption{
+		Version: 0,
+		Data:    g.Map{"amount": 20},
+	})
+	if err != nil || state.Code() != paid.Code() {
+		t.Fatalf("支付后为 %s，错误：%v", state.Description(), err)
+	}
+	if state, version, amount := loadOrder(t, db); state != paid.Code() || version != 1 || amount != 20 {
+		t.Fatalf("支付后的订单为 state=%d version=%d amount=%d", state, version, amount)
+	}
+	if published != 1 {
+		t.Fatalf("迁移事件发布了 %d 次", published)
+	}
+}
+
+func TestApplyStale(t *testing.T) {
+	db := openTestDB(t)
+	m := newMachine(t, fsm.Option{VersionColumn: "version"})
+	ctx := context.Background()
+
+	// 版本号已被修改
+	_, err := m.Apply(ctx, db.Model("orders"), 1, created, closeEvent, &order{Id: 1}, fsm.ApplyOption{Version: 1})
+	if !errors.Is(err, fsm.ErrStaleState) {
+		t.Fatalf("版本号不一致时返回 %v", err)
+	}
+
+	// 状态已被修改
+	if _, err = m.Apply(ctx, db.Model("orders"), 1, created, payEvent, &order{Id: 1, Amount: 10}); err != nil {
+		t.Fatal(err)
+	}
+	state, err := m.Apply(ctx, db.Model("orders"), 1, created, closeEvent, &order{Id: 1}, fsm.ApplyOption{Version: 1})
+	if !errors.Is(err, fsm.ErrStaleState) || state.Code() != created.Code() {
+		t.Fatalf("状态不一致时返回 %s，错误：%v", state.Description(), err)
+	}
+	if state, version, _ := loadOrder(t, db); state != paid.Code() || version != 1 {
+		t.Fatalf("乐观锁冲突后的订单为 state=%d version=%d", state, version)
+	}
+}
+
+func TestApplyRollback(t *testing.T) {
+	db := openTestDB(t)
+	m := newMachine(t)
+	ctx := context.Background()
+
+	actionErr := errors.New("库存不足")
+	m.OnTransition(func(ctx context.Context, t *fsm.Transition[orderState, orderEvent, *order]) error {
+		// 动作通过 ctx 加入同一事务，返回错误时一并回滚
+		if _, err := db.Model("orders").Ctx(ctx).Where("id", t.Entity.Id).Data(g.Map{"amount": 0}).Update(); err != nil {
+			return err
+		}
+		return actionErr
+	})
+
+	_, err := m.Apply(ctx, db.Model("orders"), 1, created, closeEvent, &order{Id: 1})
+	if !errors.Is(err, fsm.ErrActionFailed) || !errors.Is(err, actionErr) {
+		t.Fatalf("动作失败时返回 %v", err)
+	}
+	if state, _, amount := loadOrder(t, db); state != created.Code() || amount != 10 {
+		t.Fatalf("回滚后的订单为 state=%d amount=%d", state, amount)
+	}
+
+	// 守卫拒绝及无效迁移不更新状态
+	if _, err = m.Apply(ctx, db.Model("orders"), 1, created, payEvent, &order{Id: 1}); !errors.Is(err, fsm.ErrGuardRejected) {
+		t.Fatalf("守卫拒绝时返回 %v", err)
+	}
+	if _, err = m.Apply(ctx, db.Model("orders"), 1, closed, closeEvent, &order{Id: 1}); !errors.Is(err, fsm.ErrInvalidTransition) {
+		t.Fatalf("无效迁移时返回 %v", err)
+	}
+	if state, _, _ := loadOrder(t, db); state != created.Code() {
+		t.Fatalf("迁移失败后的状态为 %d", state)
+	}
+}
+
+func TestApplyPersistFailed(t *testing.T) {
+	db := openTestDB(t)
+	m := newMachine(t, fsm.Option{StateColumn: "status"})
+
+	_, err := m.Apply(context.Background(), db.Model("orders"), 1, created, closeEvent, &order{Id: 1})
+	if !errors.Is(err, fsm.ErrPersistFailed) || errors.Is(err, fsm.ErrActionFailed) {
+		t.Fatalf("更新失败时返回 %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Package sqlitetest 数据库相关测试使用的 SQLite 环境。
+//
+// SQLite 驱动 mattn/go-sqlite3 依赖cgo，为避免主模块引入仅测试使用的依赖，
+// 需要数据库的测试放在独立的 dbtest 模块中，在该目录下执行 go test ./... 运行。
+package sqlitetest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/kysion/base-library/utility/daoctl/dao_interface"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// MinVersion 测试要求的最低 SQLite 版本，物化路径的更新语句使用 3.44 起支持的 CONCAT
+const MinVersion = "3.44.0"
+
+// driver 测试使用的 SQLite 驱动，仅实现测试所需的连接、表结构查询及 Save
+type driver struct {
+	*gdb.Core
+}
+
+func init() {
+	if err := gdb.Register("sqlite", &driver{}); err != nil {
+		panic(err)
+	}
+}
+
+func (d *driver) New(core *gdb.Core, node *gdb.ConfigNode) (gdb.DB, error) {
+	return &driver{Core: core}, nil
+}
+
+func (d *driver) Open(node *gdb.ConfigNode) (*sql.DB, error) {
+	return sql.Open("sqlite3", node.Name+"?_foreign_keys=off&_busy_timeout=5000")
+}
+
+func (d *driver) GetChars() (charLeft string, charRight string) {
+	return "`", "`"
+}
+
+func (d *driver) TableFields(ctx context.Context, table string, schema ...string) (map[string]*gdb.TableField, error) {
+	result, err := d.GetAll(ctx, fmt.Sprintf("PRAGMA table_info(%s)", d.QuoteWord(table)))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]*gdb.TableField, len(result))
+	for i, record := range result {
+		name := record["name"].String()
+		fields[name] = &gdb.TableField{
+			Index: i,
+			Name:  name,
+			Type:  record["type"].String(),
+			Null:  record["notnull"].Int() == 0,
+		}
+	}
+	return fields, nil
+}
+
+// FormatUpsert Save 使用 SQLite 的 ON CONFLICT 语法，冲突时更新冲突键以外的字段
+func (d *driver) FormatUpsert(columns []string, list gdb.List, option gdb.DoInsertOption) (string, error) {
+	if len(option.OnConflict) == 0 {
+		return "", fmt.Errorf("SQLite 的 Save 必须通过 OnConflict 指定冲突键")
+	}
+
+	conflict := make(map[string]bool, len(option.OnConflict))
+	keys := make([]string, 0, len(option.OnConflict))
+	for _, column := range option.OnConflict {
+		conflict[column] = true
+		keys = append(keys, d.QuoteWord(column))
+	}
+	updates := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if !conflict[column] {
+			updates = append(updates, fmt.Sprintf("%s=excluded.%s", d.QuoteWord(column), d.QuoteWord(column)))
+		}
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(keys, ","), strings.Join(updates, ",")), nil
+}
+
+// Open 在临时目录创建数据库并执行建表语句，测试结束时关闭；SQLite 版本低于 MinVersion 时测试失败
+func Open(t testing.TB, ddl ...string) gdb.DB {
+	t.Helper()
+
+	db, err := gdb.New(gdb.ConfigNode{
+		Type: "sqlite",
+		Name: filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConnCount(1)
+	t.Cleanup(func() {
+		_ = db.Close(context.Background())
+	})
+
+	ctx := context.Background()
+	version, err := db.GetValue(ctx, "SELECT sqlite_version()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compareVersion(version.String(), MinVersion) < 0 {
+		t.Fatalf("SQLite 版本 %s 低于测试要求的 %s", version.String(), MinVersion)
+	}
+
+	for _, sql := range ddl {
+		if _, err = db.Exec(ctx, sql); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// compareVersion 比较点分隔的版本号
+func compareVersion(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// Dao 测试使用的 IDao，直接访问指定的表
+type Dao struct {
+	db    gdb.DB
+	table string
+}
+
+// NewDao 创建访问指定表的 IDao
+func NewDao(db gdb.DB, table string) *Dao {
+	return &Dao{db: db, table: table}
+}
+
+func (d *Dao) DB() gdb.DB          { return d.db }
+func (d *Dao) Table() string       { return d.table }
+func (d *Dao) Group() string       { return d.db.GetGroup() }
+func (d *Dao) IsIgnoreCache() bool { return true }
+func (d *Dao) GetExtWhereKeys() []string {
+	return nil
+}
+
+func (d *Dao) Ctx(ctx context.Context, cacheOption ...*gdb.CacheOption) *gdb.Model {
+	return d.db.Model(d.table).Safe().Ctx(ctx)
+}
+
+func (d *Dao) Transaction(ctx context.Context, f func(ctx context.Context, tx gdb.TX) error) error {
+	return d.db.Transaction(ctx, f)
+}
+
+func (d *Dao) DaoConfig(ctx context.Context, cacheOption ...*gdb.CacheOption) *dao_interface.DaoConfig {
+	return &dao_interface.DaoConfig{Dao: d, DB: d.db, Table: d.table, Group: d.Group(), Model: d.Ctx(ctx)}
+}
@@ -0,0 +1,11 @@
+-- Hook 消息去重表（PostgreSQL），表名可通过 service.hook.dedupe.table 配置
+CREATE TABLE IF NOT EXISTS hook_dedupe
+(
+    dedupe_key    VARCHAR(512) PRIMARY KEY,
+    business_type VARCHAR(255) NOT NULL,
+    message_id    VARCHAR(64),
+    processed_at  TIMESTAMP,
+    expire_at     TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_hook_dedupe_expire_at ON hook_dedupe (expire_at);
@@ -0,0 +1,18 @@
+-- Hook 事件日志表（PostgreSQL），表名可通过 service.hook.eventLog.table 配置
+CREATE TABLE IF NOT EXISTS hook_event_log
+(
+    seq           BIGSERIAL PRIMARY KEY,
+    business_type VARCHAR(255) NOT NULL,
+    payload       TEXT         NOT NULL,
+    created_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_hook_event_log_business_type_created_at ON hook_event_log (business_type, created_at);
+
+-- Hook 事件日志消费位置表，表名可通过 service.hook.eventLog.offsetTable 配置
+CREATE TABLE IF NOT EXISTS hook_event_offset
+(
+    consumer   VARCHAR(255) PRIMARY KEY,
+    seq        BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP
+);
@@ -0,0 +1,16 @@
+-- Hook 发件箱表（PostgreSQL），表名可通过 service.hook.outbox.table 配置
+CREATE TABLE IF NOT EXISTS hook_outbox
+(
+    id            BIGSERIAL PRIMARY KEY,
+    business_type VARCHAR(255) NOT NULL,
+    payload       TEXT         NOT NULL,
+    state         SMALLINT     NOT NULL DEFAULT 0,
+    attempts      INT          NOT NULL DEFAULT 0,
+    last_error    TEXT         NOT NULL DEFAULT '',
+    next_retry_at TIMESTAMP,
+    delivered_at  TIMESTAMP,
+    created_at    TIMESTAMP,
+    updated_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_hook_outbox_state_next_retry_at ON hook_outbox (state, next_retry_at);
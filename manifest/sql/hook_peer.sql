@@ -0,0 +1,10 @@
+-- Hook 服务注册表（PostgreSQL），表名可通过 service.hook.discovery.db.table 配置
+CREATE TABLE IF NOT EXISTS hook_peer
+(
+    address      VARCHAR(255) PRIMARY KEY,
+    node_id      VARCHAR(255),
+    heartbeat_at TIMESTAMP,
+    expire_at    TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_hook_peer_expire_at ON hook_peer (expire_at);
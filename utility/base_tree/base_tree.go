@@ -34,7 +34,12 @@ type Tree[T any] interface {
 	MakeSubNodeSort()
 }
 
-// ToTree 将列表转换为树结构
+// subNodeSorter 节点自身实现了子节点排序，如 TestTree
+type subNodeSorter interface {
+	MakeSubNodeSort()
+}
+
+// ToTree 将列表转换为树结构，每个节点都会遍历整个列表，时间复杂度为 O(n²)，数据量较大时请使用 Build
 // list 为待转换的列表
 // fun 为实现Tree接口的具体类型
 // 返回值为树结构列表
@@ -50,6 +55,10 @@ func ToTree[T any](list []T, fun Tree[T]) []T {
 		// 如果子树Arr不为空， 那么父树father.Children = branchArr设置上
 		if len(branchArr) > 0 {
 			fun.AssignChildren(father, branchArr)
+			// 节点实现了 MakeSubNodeSort 时对子节点排序
+			if sorter, ok := any(father).(subNodeSorter); ok {
+				sorter.MakeSubNodeSort()
+			}
 		}
 
 		// 外层递归退出条件是：father.ID = 0
@@ -0,0 +1,365 @@
+package base_tree
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+/*
+	基于索引构建树：
+		1、按ID建立索引后一次遍历挂载子节点，时间复杂度 O(n)，不依赖调用方实现的 IsRoot
+		2、ParentId 为零值（或 IsRoot 返回 true）的节点为根节点；父节点不存在的节点为孤儿节点，按 OrphanPolicy 处理
+		3、从根节点无法到达且父节点存在的节点构成循环引用（含 ParentId 等于自身ID），返回 ErrCycle
+		4、子节点按列表中的顺序排列，配置 Less 时递归排序
+*/
+
+var (
+	// ErrCycle 节点之间存在循环引用
+	ErrCycle = gerror.NewCode(gcode.CodeInvalidParameter, "树节点存在循环引用")
+	// ErrDuplicateId 存在重复的节点ID
+	ErrDuplicateId = gerror.NewCode(gcode.CodeInvalidParameter, "树节点ID重复")
+	// ErrOrphan 存在父节点不存在的节点，OrphanPolicy 为 OrphanReject 时返回
+	ErrOrphan = gerror.NewCode(gcode.CodeInvalidParameter, "树节点的父节点不存在")
+	// ErrNodeNotFound 节点不存在
+	ErrNodeNotFound = gerror.NewCode(gcode.CodeNotFound, "树节点不存在")
+)
+
+// OrphanPolicy 孤儿节点（父节点不存在）的处理方式
+type OrphanPolicy int
+
+const (
+	OrphanAsRoot OrphanPolicy = iota // 作为根节点，默认
+	OrphanDrop                       // 丢弃孤儿节点及其子树
+	OrphanReject                     // 返回 ErrOrphan
+)
+
+// Option 构建树的选项，Id、ParentId 必须设置
+type Option[T any, K comparable] struct {
+	Id          func(node T) K             // 节点ID
+	ParentId    func(node T) K             // 父节点ID
+	IsRoot      func(node T) bool          // 是否为根节点，未设置时 ParentId 为零值的节点为根节点
+	SetChildren func(node T, children []T) // 将子节点设置到节点，叶子节点设置为 nil；未设置时不修改节点，通过 Forest 访问子节点
+	SetParentId func(node T, parentId K)   // 移动、插入节点时设置节点的父节点ID，根节点为零值；未设置时不修改节点
+	Less        func(a, b T) bool          // 兄弟节点的排序，未设置时保持列表中的顺序
+	Orphan      OrphanPolicy               // 孤儿节点的处理方式
+}
+
+// Forest 构建后的树（可能有多个根节点），维护ID索引以支持遍历及查找
+type Forest[T any, K comparable] struct {
+	option   Option[T, K]
+	roots    []T
+	nodes    map[K]T
+	parent   map[K]K   // 节点ID -> 父节点ID，根节点不在其中
+	children map[K][]T // 节点ID -> 子节点
+	orphans  []K
+}
+
+// Build 将列表构建为树，返回的 Forest 中 Roots 即为树结构列表
+func Build[T any, K comparable](list []T, option Option[T, K]) (*Forest[T, K], error) {
+	if option.Id == nil || option.ParentId == nil {
+		return nil, gerror.NewCode(gcode.CodeMissingParameter, "构建树必须设置 Id 及 ParentId")
+	}
+
+	f := &Forest[T, K]{
+		option:   option,
+		nodes:    make(map[K]T, len(list)),
+		parent:   make(map[K]K, len(list)),
+		children: make(map[K][]T, len(list)),
+	}
+
+	for _, node := range list {
+		id := option.Id(node)
+		if _, ok := f.nodes[id]; ok {
+			return nil, gerror.WrapCodef(gcode.CodeInvalidParameter, ErrDuplicateId, "ID：%v", id)
+		}
+		f.nodes[id] = node
+	}
+
+	for _, node := range list {
+		id, parentId := option.Id(node), option.ParentId(node)
+		if f.isRoot(node) {
+			f.roots = append(f.roots, node)
+			continue
+		}
+		if _, ok := f.nodes[parentId]; !ok {
+			f.orphans = append(f.orphans, id)
+			switch option.Orphan {
+			case OrphanReject:
+				return nil, gerror.WrapCodef(gcode.CodeInvalidParameter, ErrOrphan, "ID：%v，父节点ID：%v", id, parentId)
+			case OrphanAsRoot:
+				f.roots = append(f.roots, node)
+			}
+			continue
+		}
+		f.parent[id] = parentId
+		f.children[parentId] = append(f.children[parentId], node)
+	}
+
+	if err := f.checkCycle(list); err != nil {
+		return nil, err
+	}
+
+	f.sortNodes(&f.roots)
+	f.assign(f.roots)
+
+	return f, nil
+}
+
+// isRoot 是否为根节点
+func (f *Forest[T, K]) isRoot(node T) bool {
+	if f.option.IsRoot != nil {
+		return f.option.IsRoot(node)
+	}
+	var zero K
+	return f.option.ParentId(node) == zero
+}
+
+// checkCycle 从根节点出发标记可到达的节点，不可到达的节点沿父节点向上查找：到达被丢弃的孤儿节点时一并丢弃，回到自身路径时为循环引用
+func (f *Forest[T, K]) checkCycle(list []T) error {
+	reached := make(map[K]bool, len(f.nodes))
+	stack := append([]T{}, f.roots...)
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		id := f.option.Id(node)
+		reached[id] = true
+		stack = append(stack, f.children[id]...)
+	}
+	if len(reached) == len(f.nodes) {
+		return nil
+	}
+
+	dropped := make(map[K]bool)
+	for _, node := range list {
+		id := f.option.Id(node)
+		if reached[id] || dropped[id] {
+			continue
+		}
+
+		path := []K{id}
+		index := map[K]int{id: 0}
+		for {
+			parentId, ok := f.parent[id]
+			if !ok || dropped[parentId] {
+				// 到达被丢弃的孤儿节点，路径上的节点均被丢弃
+				for _, item := range path {
+					dropped[item] = true
+				}
+				break
+			}
+			if i, ok := index[parentId]; ok {
+				cycle := append(path[i:], parentId)
+				return gerror.WrapCodef(gcode.CodeInvalidParameter, ErrCycle, "%s", joinIds(cycle, " -> "))
+			}
+			index[parentId] = len(path)
+			path = append(path, parentId)
+			id = parentId
+		}
+	}
+
+	for id := range dropped {
+		delete(f.nodes, id)
+		delete(f.parent, id)
+		delete(f.children, id)
+	}
+	return nil
+}
+
+// sortNodes 按 Less 排序兄弟节点
+func (f *Forest[T, K]) sortNodes(nodes *[]T) {
+	if f.option.Less == nil || len(*nodes) <= 1 {
+		return
+	}
+	sort.SliceStable(*nodes, func(i, j int) bool {
+		return f.option.Less((*nodes)[i], (*nodes)[j])
+	})
+}
+
+// assign 递归排序子节点，并通过 SetChildren 设置到节点
+func (f *Forest[T, K]) assign(nodes []T) {
+	for _, node := range nodes {
+		id := f.option.Id(node)
+		children := f.children[id]
+		f.sortNodes(&children)
+		f.assign(children)
+
+		if f.option.SetChildren != nil {
+			f.option.SetChildren(node, children)
+		}
+	}
+}
+
+// Roots 根节点，即树结构列表
+func (f *Forest[T, K]) Roots() []T {
+	return f.roots
+}
+
+// Len 树中节点的数量，不含被丢弃的孤儿节点
+func (f *Forest[T, K]) Len() int {
+	return len(f.nodes)
+}
+
+// Orphans 父节点不存在的节点ID，按列表中的顺序排列
+func (f *Forest[T, K]) Orphans() []K {
+	return f.orphans
+}
+
+// Get 按ID获取节点
+func (f *Forest[T, K]) Get(id K) (T, bool) {
+	node, ok := f.nodes[id]
+	return node, ok
+}
+
+// Children 节点的直接子节点
+func (f *Forest[T, K]) Children(id K) []T {
+	return f.children[id]
+}
+
+// Parent 节点的父节点，根节点及孤儿节点返回 false
+func (f *Forest[T, K]) Parent(id K) (T, bool) {
+	var zero T
+	parentId, ok := f.parent[id]
+	if !ok {
+		return zero, false
+	}
+	return f.Get(parentId)
+}
+
+// WalkDFS 深度优先（先序）遍历，depth 为节点的深度，根节点为 0；fn 返回 false 时停止遍历
+func (f *Forest[T, K]) WalkDFS(fn func(node T, depth int) bool) {
+	f.walkDFS(f.roots, 0, fn)
+}
+
+// walkDFS 递归遍历，返回 false 表示已停止
+func (f *Forest[T, K]) walkDFS(nodes []T, depth int, fn func(node T, depth int) bool) bool {
+	for _, node := range nodes {
+		if !fn(node, depth) {
+			return false
+		}
+		if !f.walkDFS(f.children[f.option.Id(node)], depth+1, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// WalkBFS 广度优先遍历，depth 为节点的深度，根节点为 0；fn 返回 false 时停止遍历
+func (f *Forest[T, K]) WalkBFS(fn func(node T, depth int) bool) {
+	level := f.roots
+	for depth := 0; len(level) > 0; depth++ {
+		var next []T
+		for _, node := range level {
+			if !fn(node, depth) {
+				return
+			}
+			next = append(next, f.children[f.option.Id(node)]...)
+		}
+		level = next
+	}
+}
+
+// Find 按深度优先的顺序查找第一个满足条件的节点
+func (f *Forest[T, K]) Find(predicate func(node T) bool) (result T, found bool) {
+	f.WalkDFS(func(node T, _ int) bool {
+		if predicate(node) {
+			result, found = node, true
+			return false
+		}
+		return true
+	})
+	return result, found
+}
+
+// FindAll 按深度优先的顺序查找所有满足条件的节点
+func (f *Forest[T, K]) FindAll(predicate func(node T) bool) (result []T) {
+	f.WalkDFS(func(node T, _ int) bool {
+		if predicate(node) {
+			result = append(result, node)
+		}
+		return true
+	})
+	return result
+}
+
+// PathToRoot 从节点到根节点的路径，第一个元素为节点本身，最后一个为根节点；节点不存在时返回 nil
+func (f *Forest[T, K]) PathToRoot(id K) []T {
+	node, ok := f.nodes[id]
+	if !ok {
+		return nil
+	}
+
+	path := []T{node}
+	for {
+		parentId, ok := f.parent[id]
+		if !ok {
+			return path
+		}
+		path = append(path, f.nodes[parentId])
+		id = parentId
+	}
+}
+
+// SubtreeIds 节点及其所有后代节点的ID，按广度优先的顺序排列，祖先节点在后代节点之前；节点不存在时返回 nil
+func (f *Forest[T, K]) SubtreeIds(id K) []K {
+	if _, ok := f.nodes[id]; !ok {
+		return nil
+	}
+
+	ids := []K{id}
+	for i := 0; i < len(ids); i++ {
+		children := f.children[ids[i]]
+		for _, child := range children {
+			ids = append(ids, f.option.Id(child))
+		}
+	}
+	return ids
+}
+
+// FlatNode 展开后的节点
+type FlatNode[T any, K comparable] struct {
+	Node     T   `json:"node"`
+	Id       K   `json:"id"`
+	ParentId K   `json:"parentId" dc:"父节点ID，根节点为零值"`
+	Depth    int `json:"depth" dc:"深度，根节点为0"`
+	Path     []K `json:"path"  dc:"从根节点到该节点的ID路径，含节点本身"`
+}
+
+// LevelPath 以 separator 连接的ID路径，如 1,3,5
+func (n FlatNode[T, K]) LevelPath(separator string) string {
+	return joinIds(n.Path, separator)
+}
+
+// Flatten 按深度优先的顺序将树展开为列表，附带深度及ID路径
+func (f *Forest[T, K]) Flatten() []FlatNode[T, K] {
+	result := make([]FlatNode[T, K], 0, len(f.nodes))
+	var walk func(nodes []T, depth int, path []K)
+	walk = func(nodes []T, depth int, path []K) {
+		for _, node := range nodes {
+			id := f.option.Id(node)
+			nodePath := append(append(make([]K, 0, len(path)+1), path...), id)
+			result = append(result, FlatNode[T, K]{
+				Node:     node,
+				Id:       id,
+				ParentId: f.parent[id],
+				Depth:    depth,
+				Path:     nodePath,
+			})
+			walk(f.children[id], depth+1, nodePath)
+		}
+	}
+	walk(f.roots, 0, nil)
+	return result
+}
+
+// joinIds 以 separator 连接ID
+func joinIds[K comparable](ids []K, separator string) string {
+	items := make([]string, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, gconv.String(id))
+	}
+	return strings.Join(items, separator)
+}
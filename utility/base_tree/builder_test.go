@@ -0,0 +1,242 @@
+package base_tree
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+func nodeIds(nodes []*testNode) []int {
+	ids := make([]int, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.Id)
+	}
+	return ids
+}
+
+func TestBuild(t *testing.T) {
+	cases := []struct {
+		name        string
+		spec        string
+		option      func(option *Option[*testNode, int])
+		want        string
+		wantLen     int
+		wantOrphans []int
+		wantErr     error
+	}{
+		{name: "tree", spec: testTree, want: "1(2(4,5),3),6", wantLen: 6},
+		{name: "children before parent", spec: "4:2 5:2 2:1 3:1 1:0 6:0", want: "1(2(4,5),3),6", wantLen: 6},
+		{name: "empty", spec: "", want: "", wantLen: 0},
+		{
+			name: "less",
+			spec: testTree,
+			option: func(option *Option[*testNode, int]) {
+				option.Less = func(a, b *testNode) bool { return a.Id > b.Id }
+			},
+			want:    "6,1(3,2(5,4))",
+			wantLen: 6,
+		},
+		{
+			name: "is root",
+			spec: "1:-1 2:1 3:-1",
+			option: func(option *Option[*testNode, int]) {
+				option.IsRoot = func(node *testNode) bool { return node.ParentId == -1 }
+			},
+			want:    "1(2),3",
+			wantLen: 3,
+		},
+		{name: "orphan as root", spec: "1:0 2:1 3:9 4:3", want: "1(2),3(4)", wantLen: 4, wantOrphans: []int{3}},
+		{
+			name: "orphan drop",
+			spec: "1:0 2:1 3:9 4:3 5:4",
+			option: func(option *Option[*testNode, int]) {
+				option.Orphan = OrphanDrop
+			},
+			want:        "1(2)",
+			wantLen:     2,
+			wantOrphans: []int{3},
+		},
+		{
+			name: "orphan reject",
+			spec: "1:0 2:1 3:9",
+			option: func(option *Option[*testNode, int]) {
+				option.Orphan = OrphanReject
+			},
+			wantErr: ErrOrphan,
+		},
+		{name: "duplicate id", spec: "1:0 2:1 2:1", wantErr: ErrDuplicateId},
+		{name: "self parent", spec: "1:0 2:2", wantErr: ErrCycle},
+		{name: "cycle", spec: "1:0 2:3 3:4 4:2", wantErr: ErrCycle},
+		{name: "cycle with branch", spec: "1:0 2:3 3:2 4:3", wantErr: ErrCycle},
+		{
+			name: "dropped orphan is not a cycle",
+			spec: "1:0 2:9 3:2 4:3",
+			option: func(option *Option[*testNode, int]) {
+				option.Orphan = OrphanDrop
+			},
+			want:        "1",
+			wantLen:     1,
+			wantOrphans: []int{2},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			option := testOption()
+			if c.option != nil {
+				c.option(&option)
+			}
+
+			f, err := Build(testNodes(c.spec), option)
+			if c.wantErr != nil {
+				if !errors.Is(err, c.wantErr) {
+					t.Fatalf("Build() = %v, want %v", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := shape(f.Roots()); got != c.want {
+				t.Fatalf("shape = %s, want %s", got, c.want)
+			}
+			if f.Len() != c.wantLen {
+				t.Fatalf("Len = %d, want %d", f.Len(), c.wantLen)
+			}
+			if !slices.Equal(f.Orphans(), c.wantOrphans) {
+				t.Fatalf("Orphans = %v, want %v", f.Orphans(), c.wantOrphans)
+			}
+		})
+	}
+
+	if _, err := Build(testNodes(testTree), Option[*testNode, int]{}); gerror.Code(err) != gcode.CodeMissingParameter {
+		t.Fatalf("Build() without Id = %v, want CodeMissingParameter", err)
+	}
+}
+
+func TestForest_Walk(t *testing.T) {
+	f := buildTestForest(t, testTree)
+
+	cases := []struct {
+		name       string
+		walk       func(fn func(node *testNode, depth int) bool)
+		stopAt     int
+		wantIds    []int
+		wantDepths []int
+	}{
+		{name: "dfs", walk: f.WalkDFS, wantIds: []int{1, 2, 4, 5, 3, 6}, wantDepths: []int{0, 1, 2, 2, 1, 0}},
+		{name: "dfs stop", walk: f.WalkDFS, stopAt: 4, wantIds: []int{1, 2, 4}, wantDepths: []int{0, 1, 2}},
+		{name: "bfs", walk: f.WalkBFS, wantIds: []int{1, 6, 2, 3, 4, 5}, wantDepths: []int{0, 0, 1, 1, 2, 2}},
+		{name: "bfs stop", walk: f.WalkBFS, stopAt: 3, wantIds: []int{1, 6, 2, 3}, wantDepths: []int{0, 0, 1, 1}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var ids, depths []int
+			c.walk(func(node *testNode, depth int) bool {
+				ids = append(ids, node.Id)
+				depths = append(depths, depth)
+				return node.Id != c.stopAt
+			})
+			if !slices.Equal(ids, c.wantIds) || !slices.Equal(depths, c.wantDepths) {
+				t.Fatalf("walk = %v depths %v, want %v depths %v", ids, depths, c.wantIds, c.wantDepths)
+			}
+		})
+	}
+}
+
+func TestForest_Find(t *testing.T) {
+	f := buildTestForest(t, testTree)
+	even := func(node *testNode) bool { return node.Id%2 == 0 }
+
+	if node, ok := f.Find(even); !ok || node.Id != 2 {
+		t.Fatalf("Find() = %v, %v, want 2", node, ok)
+	}
+	if got := nodeIds(f.FindAll(even)); !slices.Equal(got, []int{2, 4, 6}) {
+		t.Fatalf("FindAll() = %v, want [2 4 6]", got)
+	}
+
+	none := func(node *testNode) bool { return node.Id > 10 }
+	if node, ok := f.Find(none); ok || node != nil {
+		t.Fatalf("Find() = %v, %v, want not found", node, ok)
+	}
+	if got := f.FindAll(none); got != nil {
+		t.Fatalf("FindAll() = %v, want nil", got)
+	}
+}
+
+func TestForest_PathToRoot(t *testing.T) {
+	f := buildTestForest(t, testTree)
+
+	cases := []struct {
+		id   int
+		want []int
+	}{
+		{id: 5, want: []int{5, 2, 1}},
+		{id: 3, want: []int{3, 1}},
+		{id: 6, want: []int{6}},
+		{id: 99, want: nil},
+	}
+	for _, c := range cases {
+		path := f.PathToRoot(c.id)
+		if got := nodeIds(path); !slices.Equal(got, c.want) || (c.want == nil) != (path == nil) {
+			t.Fatalf("PathToRoot(%d) = %v, want %v", c.id, got, c.want)
+		}
+	}
+}
+
+func TestForest_SubtreeIds(t *testing.T) {
+	f := buildTestForest(t, testTree)
+
+	cases := []struct {
+		id   int
+		want []int
+	}{
+		{id: 1, want: []int{1, 2, 3, 4, 5}},
+		{id: 2, want: []int{2, 4, 5}},
+		{id: 4, want: []int{4}},
+		{id: 99, want: nil},
+	}
+	for _, c := range cases {
+		if got := f.SubtreeIds(c.id); !slices.Equal(got, c.want) || (c.want == nil) != (got == nil) {
+			t.Fatalf("SubtreeIds(%d) = %v, want %v", c.id, got, c.want)
+		}
+	}
+}
+
+func TestForest_Flatten(t *testing.T) {
+	f := buildTestForest(t, testTree)
+
+	want := []struct {
+		id, parentId, depth int
+		levelPath           string
+	}{
+		{id: 1, parentId: 0, depth: 0, levelPath: "1"},
+		{id: 2, parentId: 1, depth: 1, levelPath: "1,2"},
+		{id: 4, parentId: 2, depth: 2, levelPath: "1,2,4"},
+		{id: 5, parentId: 2, depth: 2, levelPath: "1,2,5"},
+		{id: 3, parentId: 1, depth: 1, levelPath: "1,3"},
+		{id: 6, parentId: 0, depth: 0, levelPath: "6"},
+	}
+
+	flat := f.Flatten()
+	if len(flat) != len(want) {
+		t.Fatalf("Flatten() = %d nodes, want %d", len(flat), len(want))
+	}
+	for i, w := range want {
+		got := flat[i]
+		if got.Id != w.id || got.Node.Id != w.id || got.ParentId != w.parentId || got.Depth != w.depth || got.LevelPath(",") != w.levelPath {
+			t.Fatalf("Flatten()[%d] = id %d parent %d depth %d path %s, want %+v",
+				i, got.Id, got.ParentId, got.Depth, got.LevelPath(","), w)
+		}
+	}
+
+	// 各节点的路径互不影响
+	flat[2].Path[0] = 99
+	if flat[3].LevelPath(",") != "1,2,5" {
+		t.Fatalf("path shared between nodes: %s", flat[3].LevelPath(","))
+	}
+}
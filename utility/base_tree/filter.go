@@ -0,0 +1,168 @@
+package base_tree
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/kysion/base-library/utility/format_utils"
+)
+
+/*
+	过滤树结构，用于搜索时展示匹配节点所在的层级：
+		1、保留满足条件的节点及其所有祖先节点，不满足条件且没有满足条件的后代节点的分支被剪除
+		2、Matched 标记节点是否满足条件，为 false 的节点仅作为匹配节点的祖先保留，前端可据此高亮
+		3、Keyword 在 Text 返回的文本中查找（忽略大小写），开启 Pinyin 时同时匹配文本的全拼及首字母，如 caiwu、cw 均可匹配 财务；
+		   多音字按任一读音匹配，如 yinhang、yh 均可匹配 银行
+		4、MaxDepth 限制返回的层级，超出层级的节点不返回，但其中的匹配节点会保留其在层级内的祖先；
+		   配合 Root 可实现懒加载：首次加载根节点，展开时以该节点作为 Root 加载其子节点，HasChildren 用于显示展开按钮
+		5、Match、Keyword 均未设置时所有节点均满足条件
+*/
+
+// FilterOption 过滤树结构的选项
+type FilterOption[T any, K comparable] struct {
+	Match    func(node T) bool   // 节点需满足的条件，与 Keyword 同时设置时需同时满足
+	Keyword  string              // 关键字，为空时不按关键字过滤
+	Text     func(node T) string // 用于匹配关键字的文本，如节点名称；未设置时忽略 Keyword
+	Pinyin   bool                // 关键字是否同时匹配文本的拼音及首字母
+	MaxDepth int                 // 返回的最大层级数，如1为仅返回第一层；小于等于0时不限制
+	Root     K                   // 从该节点的子节点开始过滤，零值时从根节点开始
+}
+
+// FilterNode 过滤后的节点
+type FilterNode[T any, K comparable] struct {
+	Node        T                   `json:"node"`
+	Id          K                   `json:"id"`
+	Depth       int                 `json:"depth"       dc:"相对于开始过滤的层级的深度，第一层为0"`
+	Matched     bool                `json:"matched"     dc:"是否满足条件，为 false 时仅作为匹配节点的祖先保留"`
+	HasChildren bool                `json:"hasChildren" dc:"在完整的树中是否有子节点，Children 为空时可懒加载"`
+	Children    []*FilterNode[T, K] `json:"children"    dc:"过滤后的子节点"`
+}
+
+// Filter 过滤树结构，返回满足条件的节点及其祖先节点；不会修改原有的节点
+func (f *Forest[T, K]) Filter(option FilterOption[T, K]) []*FilterNode[T, K] {
+	nodes := f.roots
+	if !f.isRootId(option.Root) {
+		if _, ok := f.nodes[option.Root]; !ok {
+			return nil
+		}
+		nodes = f.children[option.Root]
+	}
+
+	return f.filter(nodes, 0, option, option.matcher())
+}
+
+// filter 递归过滤，超出 MaxDepth 的层级仅判断是否存在匹配节点
+func (f *Forest[T, K]) filter(nodes []T, depth int, option FilterOption[T, K], match func(node T) bool) []*FilterNode[T, K] {
+	var result []*FilterNode[T, K]
+	for _, node := range nodes {
+		id := f.option.Id(node)
+		children := f.children[id]
+
+		item := &FilterNode[T, K]{
+			Node:        node,
+			Id:          id,
+			Depth:       depth,
+			Matched:     match(node),
+			HasChildren: len(children) > 0,
+		}
+
+		keep := item.Matched
+		if option.MaxDepth <= 0 || depth+1 < option.MaxDepth {
+			item.Children = f.filter(children, depth+1, option, match)
+			keep = keep || len(item.Children) > 0
+		} else if !keep {
+			keep = f.anyMatch(children, match)
+		}
+
+		if keep {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// anyMatch 节点及其后代中是否存在满足条件的节点
+func (f *Forest[T, K]) anyMatch(nodes []T, match func(node T) bool) bool {
+	for _, node := range nodes {
+		if match(node) || f.anyMatch(f.children[f.option.Id(node)], match) {
+			return true
+		}
+	}
+	return false
+}
+
+// matcher 合并 Match 及 Keyword 的条件
+func (o FilterOption[T, K]) matcher() func(node T) bool {
+	keyword := strings.ToLower(strings.TrimSpace(o.Keyword))
+	if keyword == "" || o.Text == nil {
+		if o.Match == nil {
+			return func(T) bool { return true }
+		}
+		return o.Match
+	}
+
+	return func(node T) bool {
+		if o.Match != nil && !o.Match(node) {
+			return false
+		}
+		return matchKeyword(o.Text(node), keyword, o.Pinyin)
+	}
+}
+
+// matchKeyword 文本是否包含关键字，keyword 已转为小写
+func matchKeyword(text string, keyword string, pinyin bool) bool {
+	if strings.Contains(strings.ToLower(text), keyword) {
+		return true
+	}
+	if !pinyin {
+		return false
+	}
+
+	keyword = strings.ReplaceAll(keyword, " ", "")
+	readings := format_utils.ChineseToPinyinHeteronyms(text)
+	initials := make([][]string, len(readings))
+	for i, words := range readings {
+		for _, word := range words {
+			if word != "" && !slices.Contains(initials[i], word[:1]) {
+				initials[i] = append(initials[i], word[:1])
+			}
+		}
+	}
+
+	return matchReadings(readings, keyword) || matchReadings(initials, keyword)
+}
+
+// matchReadings 按每个汉字的任一读音拼接后是否包含关键字，关键字可以从读音的中间开始、在读音的中间结束
+func matchReadings(readings [][]string, keyword string) bool {
+	// failed 记录从第 i 个汉字开始无法匹配剩余 n 个字符的组合，避免多音字较多时重复计算
+	failed := make(map[[2]int]bool)
+
+	var match func(i int, rest string) bool
+	match = func(i int, rest string) bool {
+		if rest == "" {
+			return true
+		}
+		if i >= len(readings) || failed[[2]int{i, len(rest)}] {
+			return false
+		}
+		for _, word := range readings[i] {
+			if strings.HasPrefix(word, rest) || strings.HasPrefix(rest, word) && word != "" && match(i+1, rest[len(word):]) {
+				return true
+			}
+		}
+		failed[[2]int{i, len(rest)}] = true
+		return false
+	}
+
+	for i, words := range readings {
+		for _, word := range words {
+			for start := range word {
+				piece := word[start:]
+				if strings.HasPrefix(piece, keyword) || strings.HasPrefix(keyword, piece) && match(i+1, keyword[len(piece):]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
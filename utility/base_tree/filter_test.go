@@ -0,0 +1,171 @@
+package base_tree
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// filterTree 1 财务部(2 银行对账(4 出纳),3 会计),5 人事部(6 招聘)
+const filterTree = "1:0 2:1 3:1 4:2 5:0 6:5"
+
+var filterNames = map[int]string{1: "财务部", 2: "银行对账", 3: "会计", 4: "出纳", 5: "人事部", 6: "招聘"}
+
+func filterText(node *testNode) string {
+	return filterNames[node.Id]
+}
+
+// filterShape 输出过滤后的树结构，满足条件的节点标记 *，懒加载的节点（有子节点但未返回）标记 +，如 1(2(4*)),5+
+func filterShape(nodes []*FilterNode[*testNode, int]) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		part := strconv.Itoa(node.Id)
+		if node.Matched {
+			part += "*"
+		}
+		if len(node.Children) > 0 {
+			part += "(" + filterShape(node.Children) + ")"
+		} else if node.HasChildren {
+			part += "+"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestMatchKeyword(t *testing.T) {
+	cases := []struct {
+		text    string
+		keyword string
+		pinyin  bool
+		want    bool
+	}{
+		{text: "财务部", keyword: "财务", want: true},
+		{text: "Finance", keyword: "fin", want: true},
+		{text: "财务部", keyword: "caiwu", want: false},
+		{text: "财务部", keyword: "caiwu", pinyin: true, want: true},
+		{text: "财务部", keyword: "cw", pinyin: true, want: true},
+		{text: "财务部", keyword: "wb", pinyin: true, want: true},
+		{text: "财务部", keyword: "cai wu", pinyin: true, want: true},
+		{text: "财务部", keyword: "aiwub", pinyin: true, want: true},
+		{text: "财务部", keyword: "caiwub", pinyin: true, want: true},
+		{text: "中国银行", keyword: "yinhang", pinyin: true, want: true},
+		{text: "中国银行", keyword: "yinxing", pinyin: true, want: true},
+		{text: "中国银行", keyword: "yh", pinyin: true, want: true},
+		{text: "中国银行", keyword: "zgyh", pinyin: true, want: true},
+		{text: "中国银行", keyword: "guoyin", pinyin: true, want: true},
+		{text: "财务部", keyword: "cwx", pinyin: true, want: false},
+		{text: "财务部", keyword: "yh", pinyin: true, want: false},
+		{text: "财务部", keyword: "wucai", pinyin: true, want: false},
+		{text: "中国银行", keyword: "yinhangx", pinyin: true, want: false},
+		{text: "中国银行", keyword: "zgh", pinyin: true, want: false},
+	}
+
+	for _, c := range cases {
+		if got := matchKeyword(c.text, c.keyword, c.pinyin); got != c.want {
+			t.Errorf("matchKeyword(%s, %s, pinyin=%v) = %v, want %v", c.text, c.keyword, c.pinyin, got, c.want)
+		}
+	}
+}
+
+func TestMatchReadings(t *testing.T) {
+	readings := [][]string{{"yin"}, {"xing", "hang", "heng"}}
+
+	cases := []struct {
+		keyword string
+		want    bool
+	}{
+		{keyword: "yinhang", want: true},
+		{keyword: "yinxing", want: true},
+		{keyword: "yinheng", want: true},
+		{keyword: "inha", want: true},
+		{keyword: "ang", want: true},
+		{keyword: "yin", want: true},
+		{keyword: "yinh", want: true},
+		{keyword: "yinhangyin", want: false},
+		{keyword: "hangyin", want: false},
+		{keyword: "yinhong", want: false},
+		{keyword: "yh", want: false},
+	}
+
+	for _, c := range cases {
+		if got := matchReadings(readings, c.keyword); got != c.want {
+			t.Errorf("matchReadings(%s) = %v, want %v", c.keyword, got, c.want)
+		}
+	}
+}
+
+func TestForest_Filter(t *testing.T) {
+	cases := []struct {
+		name   string
+		option FilterOption[*testNode, int]
+		want   string
+	}{
+		{name: "no condition", want: "1*(2*(4*),3*),5*(6*)"},
+		{name: "keyword keeps ancestors", option: FilterOption[*testNode, int]{Keyword: "出纳", Text: filterText}, want: "1(2(4*))"},
+		{name: "keyword without text", option: FilterOption[*testNode, int]{Keyword: "出纳"}, want: "1*(2*(4*),3*),5*(6*)"},
+		{name: "pinyin initials", option: FilterOption[*testNode, int]{Keyword: "CN", Text: filterText, Pinyin: true}, want: "1(2(4*))"},
+		{name: "pinyin heteronym", option: FilterOption[*testNode, int]{Keyword: "yinhang", Text: filterText, Pinyin: true}, want: "1(2*+)"},
+		{name: "unmatched children are pruned", option: FilterOption[*testNode, int]{Keyword: "部", Text: filterText}, want: "1*+,5*+"},
+		{
+			name: "match and keyword",
+			option: FilterOption[*testNode, int]{
+				Match:   func(node *testNode) bool { return node.Id != 4 },
+				Keyword: "出纳",
+				Text:    filterText,
+			},
+			want: "",
+		},
+		{name: "match", option: FilterOption[*testNode, int]{Match: func(node *testNode) bool { return node.Id == 3 || node.Id == 6 }}, want: "1(3*),5(6*)"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := buildTestForest(t, filterTree)
+			if got := filterShape(f.Filter(c.option)); got != c.want {
+				t.Fatalf("Filter() = %s, want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestForest_FilterMaxDepth(t *testing.T) {
+	keyword := FilterOption[*testNode, int]{Keyword: "出纳", Text: filterText}
+
+	cases := []struct {
+		name     string
+		maxDepth int
+		root     int
+		option   FilterOption[*testNode, int]
+		want     string
+	}{
+		{name: "first level", maxDepth: 1, want: "1*+,5*+"},
+		{name: "two levels", maxDepth: 2, want: "1*(2*+,3*),5*(6*)"},
+		{name: "unlimited", maxDepth: 0, want: "1*(2*(4*),3*),5*(6*)"},
+		{name: "deep match keeps ancestor in range", maxDepth: 1, option: keyword, want: "1+"},
+		{name: "deep match two levels", maxDepth: 2, option: keyword, want: "1(2+)"},
+		{name: "lazy load children", maxDepth: 1, root: 1, want: "2*+,3*"},
+		{name: "lazy load grandchildren", maxDepth: 1, root: 2, want: "4*"},
+		{name: "lazy load leaf", maxDepth: 1, root: 4, want: ""},
+		{name: "lazy load with keyword", maxDepth: 1, root: 1, option: keyword, want: "2+"},
+		{name: "root not found", maxDepth: 1, root: 99, want: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := buildTestForest(t, filterTree)
+			option := c.option
+			option.MaxDepth, option.Root = c.maxDepth, c.root
+
+			result := f.Filter(option)
+			if got := filterShape(result); got != c.want {
+				t.Fatalf("Filter() = %s, want %s", got, c.want)
+			}
+			for _, node := range result {
+				if node.Depth != 0 {
+					t.Fatalf("node %d depth = %d, want 0 relative to the filter root", node.Id, node.Depth)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package base_tree
+
+import (
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+/*
+	修改树结构：
+		1、parentId 为零值时表示根节点所在的层级
+		2、index 为在兄弟节点中的位置，小于0或超出范围时追加到末尾；移动时按移除节点后的兄弟节点计算
+		3、修改后通过 SetChildren、SetParentId 同步到节点，持久化请使用 Store
+*/
+
+// DeleteMode 删除节点时子节点的处理方式
+type DeleteMode int
+
+const (
+	DeleteCascade  DeleteMode = iota // 删除节点及其所有后代节点
+	DeleteReparent                   // 子节点挂载到被删除节点的父节点，位于被删除节点的位置
+)
+
+// Insert 在 parentId 的子节点的 index 处插入节点
+func (f *Forest[T, K]) Insert(node T, parentId K, index int) error {
+	id := f.option.Id(node)
+	if _, ok := f.nodes[id]; ok {
+		return gerror.WrapCodef(gcode.CodeInvalidParameter, ErrDuplicateId, "ID：%v", id)
+	}
+	if err := f.checkParent(parentId); err != nil {
+		return err
+	}
+
+	f.nodes[id] = node
+	f.attach(node, parentId, index)
+	return nil
+}
+
+// Move 将节点及其子树移动到 parentId 的子节点的 index 处，不能移动到自身的子树中
+func (f *Forest[T, K]) Move(id K, parentId K, index int) error {
+	node, ok := f.nodes[id]
+	if !ok {
+		return gerror.WrapCodef(gcode.CodeNotFound, ErrNodeNotFound, "ID：%v", id)
+	}
+	if err := f.checkParent(parentId); err != nil {
+		return err
+	}
+	if !f.isRootId(parentId) {
+		for _, item := range f.SubtreeIds(id) {
+			if item == parentId {
+				return gerror.WrapCodef(gcode.CodeInvalidParameter, ErrCycle, "不能将节点 %v 移动到自身的子树 %v 中", id, parentId)
+			}
+		}
+	}
+
+	f.detach(id)
+	f.attach(node, parentId, index)
+	return nil
+}
+
+// Reorder 按 ids 的顺序重新排列 parentId 的子节点，ids 必须包含且仅包含全部子节点
+func (f *Forest[T, K]) Reorder(parentId K, ids []K) error {
+	if err := f.checkParent(parentId); err != nil {
+		return err
+	}
+
+	siblings := f.siblings(parentId)
+	if len(ids) != len(siblings) {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "排序的节点数量 %d 与子节点数量 %d 不一致", len(ids), len(siblings))
+	}
+
+	index := make(map[K]T, len(siblings))
+	for _, node := range siblings {
+		index[f.option.Id(node)] = node
+	}
+	list := make([]T, 0, len(ids))
+	for _, id := range ids {
+		node, ok := index[id]
+		if !ok {
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "节点 %v 不是 %v 的子节点或重复", id, parentId)
+		}
+		delete(index, id)
+		list = append(list, node)
+	}
+
+	f.setSiblings(parentId, list)
+	return nil
+}
+
+// Delete 删除节点，返回被删除的节点ID
+func (f *Forest[T, K]) Delete(id K, mode DeleteMode) ([]K, error) {
+	if _, ok := f.nodes[id]; !ok {
+		return nil, gerror.WrapCodef(gcode.CodeNotFound, ErrNodeNotFound, "ID：%v", id)
+	}
+
+	if mode == DeleteReparent {
+		parentId := f.parent[id]
+		index := f.indexOf(id)
+		children := f.children[id]
+		f.detach(id)
+		for i, child := range children {
+			f.attach(child, parentId, index+i)
+		}
+		delete(f.nodes, id)
+		delete(f.children, id)
+		return []K{id}, nil
+	}
+
+	ids := f.SubtreeIds(id)
+	f.detach(id)
+	for _, item := range ids {
+		delete(f.nodes, item)
+		delete(f.parent, item)
+		delete(f.children, item)
+	}
+	return ids, nil
+}
+
+// isRootId 是否表示根节点所在的层级
+func (f *Forest[T, K]) isRootId(parentId K) bool {
+	var zero K
+	return parentId == zero
+}
+
+// checkParent 父节点必须存在
+func (f *Forest[T, K]) checkParent(parentId K) error {
+	if f.isRootId(parentId) {
+		return nil
+	}
+	if _, ok := f.nodes[parentId]; !ok {
+		return gerror.WrapCodef(gcode.CodeNotFound, ErrNodeNotFound, "父节点ID：%v", parentId)
+	}
+	return nil
+}
+
+// siblings parentId 的子节点，parentId 为零值时为根节点
+func (f *Forest[T, K]) siblings(parentId K) []T {
+	if f.isRootId(parentId) {
+		return f.roots
+	}
+	return f.children[parentId]
+}
+
+// setSiblings 设置 parentId 的子节点，并同步到父节点
+func (f *Forest[T, K]) setSiblings(parentId K, list []T) {
+	if f.isRootId(parentId) {
+		f.roots = list
+		return
+	}
+
+	if len(list) == 0 {
+		list = nil
+		delete(f.children, parentId)
+	} else {
+		f.children[parentId] = list
+	}
+	if f.option.SetChildren != nil {
+		f.option.SetChildren(f.nodes[parentId], list)
+	}
+}
+
+// indexOf 节点在兄弟节点中的位置
+func (f *Forest[T, K]) indexOf(id K) int {
+	for i, node := range f.siblings(f.parent[id]) {
+		if f.option.Id(node) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// detach 将节点从兄弟节点中移除
+func (f *Forest[T, K]) detach(id K) {
+	parentId := f.parent[id]
+	siblings := f.siblings(parentId)
+	list := make([]T, 0, len(siblings))
+	for _, node := range siblings {
+		if f.option.Id(node) != id {
+			list = append(list, node)
+		}
+	}
+	f.setSiblings(parentId, list)
+	delete(f.parent, id)
+}
+
+// attach 将节点插入到 parentId 的子节点的 index 处
+func (f *Forest[T, K]) attach(node T, parentId K, index int) {
+	siblings := f.siblings(parentId)
+	if index < 0 || index > len(siblings) {
+		index = len(siblings)
+	}
+	list := make([]T, 0, len(siblings)+1)
+	list = append(list, siblings[:index]...)
+	list = append(list, node)
+	list = append(list, siblings[index:]...)
+
+	id := f.option.Id(node)
+	if f.isRootId(parentId) {
+		delete(f.parent, id)
+	} else {
+		f.parent[id] = parentId
+	}
+	f.setSiblings(parentId, list)
+	if f.option.SetParentId != nil {
+		f.option.SetParentId(node, parentId)
+	}
+}
@@ -0,0 +1,252 @@
+package base_tree
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testNode 测试使用的树节点
+type testNode struct {
+	Id       int
+	ParentId int
+	Children []*testNode
+}
+
+func testOption() Option[*testNode, int] {
+	return Option[*testNode, int]{
+		Id:          func(node *testNode) int { return node.Id },
+		ParentId:    func(node *testNode) int { return node.ParentId },
+		SetChildren: func(node *testNode, children []*testNode) { node.Children = children },
+		SetParentId: func(node *testNode, parentId int) { node.ParentId = parentId },
+	}
+}
+
+// testNodes 按 id:parentId 创建节点，如 "1:0 2:1"
+func testNodes(spec string) []*testNode {
+	var list []*testNode
+	for _, item := range strings.Fields(spec) {
+		pair := strings.SplitN(item, ":", 2)
+		id, _ := strconv.Atoi(pair[0])
+		parentId, _ := strconv.Atoi(pair[1])
+		list = append(list, &testNode{Id: id, ParentId: parentId})
+	}
+	return list
+}
+
+// shape 按节点的 Children 输出树结构，如 1(2,3(4)),5
+func shape(nodes []*testNode) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		part := strconv.Itoa(node.Id)
+		if len(node.Children) > 0 {
+			part += "(" + shape(node.Children) + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+// checkForest 校验树结构，并校验节点的 ParentId 与 Forest 的索引一致
+func checkForest(t *testing.T, f *Forest[*testNode, int], want string) {
+	t.Helper()
+
+	if got := shape(f.Roots()); got != want {
+		t.Fatalf("shape = %s, want %s", got, want)
+	}
+	count := 0
+	f.WalkDFS(func(node *testNode, depth int) bool {
+		count++
+		parent, ok := f.Parent(node.Id)
+		switch {
+		case depth == 0 && (ok || node.ParentId != 0):
+			t.Fatalf("root %d has parent %d", node.Id, node.ParentId)
+		case depth > 0 && (!ok || parent.Id != node.ParentId):
+			t.Fatalf("node %d ParentId = %d, forest parent = %v", node.Id, node.ParentId, parent)
+		}
+		if got, want := shape(f.Children(node.Id)), shape(node.Children); got != want {
+			t.Fatalf("node %d forest children = %s, node children = %s", node.Id, got, want)
+		}
+		return true
+	})
+	if count != f.Len() {
+		t.Fatalf("Len = %d, walked %d nodes", f.Len(), count)
+	}
+}
+
+func buildTestForest(t *testing.T, spec string) *Forest[*testNode, int] {
+	t.Helper()
+
+	f, err := Build(testNodes(spec), testOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// testTree 1(2(4,5),3),6
+const testTree = "1:0 2:1 3:1 4:2 5:2 6:0"
+
+func TestForest_Insert(t *testing.T) {
+	cases := []struct {
+		name     string
+		id       int
+		parentId int
+		index    int
+		want     string
+		wantErr  error
+	}{
+		{name: "first child", id: 7, parentId: 2, index: 0, want: "1(2(7,4,5),3),6"},
+		{name: "middle child", id: 7, parentId: 2, index: 1, want: "1(2(4,7,5),3),6"},
+		{name: "append", id: 7, parentId: 2, index: -1, want: "1(2(4,5,7),3),6"},
+		{name: "index out of range", id: 7, parentId: 2, index: 10, want: "1(2(4,5,7),3),6"},
+		{name: "leaf", id: 7, parentId: 3, index: 0, want: "1(2(4,5),3(7)),6"},
+		{name: "root", id: 7, parentId: 0, index: 1, want: "1(2(4,5),3),7,6"},
+		{name: "duplicate id", id: 4, parentId: 3, wantErr: ErrDuplicateId},
+		{name: "parent not found", id: 7, parentId: 99, wantErr: ErrNodeNotFound},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := buildTestForest(t, testTree)
+
+			err := f.Insert(&testNode{Id: c.id, ParentId: -1}, c.parentId, c.index)
+			if c.wantErr != nil {
+				if !errors.Is(err, c.wantErr) {
+					t.Fatalf("Insert() = %v, want %v", err, c.wantErr)
+				}
+				checkForest(t, f, "1(2(4,5),3),6")
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkForest(t, f, c.want)
+			if _, ok := f.Get(c.id); !ok {
+				t.Fatalf("Get(%d) not found after insert", c.id)
+			}
+		})
+	}
+}
+
+func TestForest_Move(t *testing.T) {
+	cases := []struct {
+		name     string
+		id       int
+		parentId int
+		index    int
+		want     string
+		wantErr  error
+	}{
+		{name: "to another parent", id: 4, parentId: 3, index: 0, want: "1(2(5),3(4)),6"},
+		{name: "subtree", id: 2, parentId: 6, index: 0, want: "1(3),6(2(4,5))"},
+		{name: "within siblings", id: 4, parentId: 2, index: -1, want: "1(2(5,4),3),6"},
+		{name: "to root", id: 2, parentId: 0, index: 0, want: "2(4,5),1(3),6"},
+		{name: "root to child", id: 6, parentId: 5, index: 0, want: "1(2(4,5(6)),3)"},
+		{name: "into itself", id: 2, parentId: 2, wantErr: ErrCycle},
+		{name: "into own subtree", id: 1, parentId: 4, wantErr: ErrCycle},
+		{name: "node not found", id: 99, parentId: 1, wantErr: ErrNodeNotFound},
+		{name: "parent not found", id: 4, parentId: 99, wantErr: ErrNodeNotFound},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := buildTestForest(t, testTree)
+
+			err := f.Move(c.id, c.parentId, c.index)
+			if c.wantErr != nil {
+				if !errors.Is(err, c.wantErr) {
+					t.Fatalf("Move() = %v, want %v", err, c.wantErr)
+				}
+				checkForest(t, f, "1(2(4,5),3),6")
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkForest(t, f, c.want)
+		})
+	}
+}
+
+func TestForest_Reorder(t *testing.T) {
+	cases := []struct {
+		name     string
+		parentId int
+		ids      []int
+		want     string
+		wantErr  bool
+	}{
+		{name: "children", parentId: 2, ids: []int{5, 4}, want: "1(2(5,4),3),6"},
+		{name: "roots", parentId: 0, ids: []int{6, 1}, want: "6,1(2(4,5),3)"},
+		{name: "same order", parentId: 1, ids: []int{2, 3}, want: "1(2(4,5),3),6"},
+		{name: "missing child", parentId: 2, ids: []int{5}, wantErr: true},
+		{name: "extra child", parentId: 2, ids: []int{4, 5, 3}, wantErr: true},
+		{name: "not a child", parentId: 2, ids: []int{4, 3}, wantErr: true},
+		{name: "duplicate", parentId: 2, ids: []int{4, 4}, wantErr: true},
+		{name: "parent not found", parentId: 99, ids: []int{}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := buildTestForest(t, testTree)
+
+			err := f.Reorder(c.parentId, c.ids)
+			if c.wantErr {
+				if err == nil {
+					t.Fatal("Reorder() = nil, want error")
+				}
+				checkForest(t, f, "1(2(4,5),3),6")
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkForest(t, f, c.want)
+		})
+	}
+}
+
+func TestForest_Delete(t *testing.T) {
+	cases := []struct {
+		name    string
+		id      int
+		mode    DeleteMode
+		want    string
+		deleted []int
+	}{
+		{name: "cascade subtree", id: 2, mode: DeleteCascade, want: "1(3),6", deleted: []int{2, 4, 5}},
+		{name: "cascade leaf", id: 5, mode: DeleteCascade, want: "1(2(4),3),6", deleted: []int{5}},
+		{name: "cascade root", id: 1, mode: DeleteCascade, want: "6", deleted: []int{1, 2, 3, 4, 5}},
+		{name: "reparent", id: 2, mode: DeleteReparent, want: "1(4,5,3),6", deleted: []int{2}},
+		{name: "reparent last child", id: 5, mode: DeleteReparent, want: "1(2(4),3),6", deleted: []int{5}},
+		{name: "reparent root", id: 1, mode: DeleteReparent, want: "2(4,5),3,6", deleted: []int{1}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := buildTestForest(t, testTree)
+
+			deleted, err := f.Delete(c.id, c.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(deleted, c.deleted) {
+				t.Fatalf("deleted = %v, want %v", deleted, c.deleted)
+			}
+			for _, id := range deleted {
+				if _, ok := f.Get(id); ok {
+					t.Fatalf("Get(%d) found after delete", id)
+				}
+			}
+			checkForest(t, f, c.want)
+		})
+	}
+
+	f := buildTestForest(t, testTree)
+	if _, err := f.Delete(99, DeleteCascade); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("Delete() = %v, want ErrNodeNotFound", err)
+	}
+}
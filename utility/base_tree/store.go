@@ -0,0 +1,503 @@
+package base_tree
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/kysion/base-library/utility/daoctl"
+	"github.com/kysion/base-library/utility/daoctl/dao_interface"
+)
+
+/*
+	树结构的持久化，在数据库事务内保持父节点、路径、深度等字段一致：
+		1、PathStore：邻接表增加物化路径及深度列，路径为 /1/3/5/ 的形式（含节点自身），根节点深度为0；
+		   通过路径前缀查询所有后代节点，移动节点时使用 CONCAT、SUBSTRING 批量更新子树的路径，适用于 MySQL、PostgreSQL
+		2、ClosureStore：闭包表记录每对祖先与后代（含节点自身，深度为0），可选同时维护业务表的父节点列
+		3、节点记录由业务代码插入及更新其他字段，插入后调用 Store.Insert 设置树结构；parentId 为零值时表示根节点
+		4、在已开启的事务内调用时加入该事务
+*/
+
+// Store 树结构的持久化
+type Store[K comparable] interface {
+	// Insert 节点记录插入后调用，设置节点的父节点及树结构
+	Insert(ctx context.Context, id K, parentId K) error
+	// Move 将节点及其子树移动到 parentId 下，不能移动到自身的子树中
+	Move(ctx context.Context, id K, parentId K) error
+	// Reorder 按 ids 的顺序设置排序列，值为在 ids 中的位置
+	Reorder(ctx context.Context, ids []K) error
+	// Delete 删除节点，返回被删除的节点ID
+	Delete(ctx context.Context, id K, mode DeleteMode) ([]K, error)
+	// DescendantIds 获取节点的所有后代节点ID，不含节点自身，按深度排列
+	DescendantIds(ctx context.Context, id K) ([]K, error)
+}
+
+// PathStoreOption 物化路径的列名配置，未设置的列使用默认值
+type PathStoreOption struct {
+	IdColumn       string // 主键列，默认为 id
+	ParentIdColumn string // 父节点列，默认为 parent_id
+	PathColumn     string // 路径列，默认为 path
+	DepthColumn    string // 深度列，默认为 depth
+	SortColumn     string // 排序列，为空时不支持 Reorder
+	Separator      string // 路径的分隔符，默认为 /
+}
+
+// PathStore 邻接表及物化路径的持久化
+type PathStore[K comparable] struct {
+	dao    dao_interface.IDao
+	option PathStoreOption
+}
+
+// NewPathStore 创建物化路径的持久化，dao 为存储树节点的业务表
+func NewPathStore[K comparable](dao dao_interface.IDao, option ...PathStoreOption) *PathStore[K] {
+	var opt PathStoreOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+	opt.IdColumn = defaultColumn(opt.IdColumn, "id")
+	opt.ParentIdColumn = defaultColumn(opt.ParentIdColumn, "parent_id")
+	opt.PathColumn = defaultColumn(opt.PathColumn, "path")
+	opt.DepthColumn = defaultColumn(opt.DepthColumn, "depth")
+	opt.Separator = defaultColumn(opt.Separator, "/")
+
+	return &PathStore[K]{dao: dao, option: opt}
+}
+
+// pathNode 节点的路径信息
+type pathNode struct {
+	ParentId any
+	Path     string
+	Depth    int
+}
+
+// node 查询节点的路径信息，parentId 为零值时返回根节点的上级，即路径为分隔符、深度为-1
+func (s *PathStore[K]) node(ctx context.Context, id K) (*pathNode, error) {
+	var zero K
+	if id == zero {
+		return &pathNode{ParentId: zero, Path: s.option.Separator, Depth: -1}, nil
+	}
+
+	record, err := s.dao.Ctx(ctx).
+		Fields(s.option.ParentIdColumn, s.option.PathColumn, s.option.DepthColumn).
+		Where(s.option.IdColumn, id).
+		One()
+	if err != nil {
+		return nil, err
+	}
+	if record.IsEmpty() {
+		return nil, gerror.WrapCodef(gcode.CodeNotFound, ErrNodeNotFound, "ID：%v", id)
+	}
+	return &pathNode{
+		ParentId: record[s.option.ParentIdColumn].Val(),
+		Path:     record[s.option.PathColumn].String(),
+		Depth:    record[s.option.DepthColumn].Int(),
+	}, nil
+}
+
+// path 节点的路径
+func (s *PathStore[K]) path(parent *pathNode, id K) string {
+	return parent.Path + gconv.String(id) + s.option.Separator
+}
+
+// Insert 节点记录插入后调用，设置节点的父节点、路径及深度
+func (s *PathStore[K]) Insert(ctx context.Context, id K, parentId K) error {
+	return s.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		parent, err := s.node(ctx, parentId)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := daoctl.UpdateWithError(s.dao.Ctx(ctx).Where(s.option.IdColumn, id).Data(g.Map{
+			s.option.ParentIdColumn: parentId,
+			s.option.PathColumn:     s.path(parent, id),
+			s.option.DepthColumn:    parent.Depth + 1,
+		}))
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return gerror.WrapCodef(gcode.CodeNotFound, ErrNodeNotFound, "ID：%v", id)
+		}
+		return nil
+	})
+}
+
+// Move 将节点及其子树移动到 parentId 下，同时更新子树中所有节点的路径及深度
+func (s *PathStore[K]) Move(ctx context.Context, id K, parentId K) error {
+	return s.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		node, err := s.node(ctx, id)
+		if err != nil {
+			return err
+		}
+		parent, err := s.node(ctx, parentId)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(parent.Path, node.Path) {
+			return gerror.WrapCodef(gcode.CodeInvalidParameter, ErrCycle, "不能将节点 %v 移动到自身的子树 %v 中", id, parentId)
+		}
+
+		if err = s.replacePrefix(ctx, node.Path, s.path(parent, id), parent.Depth+1-node.Depth); err != nil {
+			return err
+		}
+		_, err = daoctl.UpdateWithError(s.dao.Ctx(ctx).Where(s.option.IdColumn, id).Data(s.option.ParentIdColumn, parentId))
+		return err
+	})
+}
+
+// replacePrefix 将路径以 oldPrefix 开头的节点替换为 newPrefix，深度增加 depthDelta
+func (s *PathStore[K]) replacePrefix(ctx context.Context, oldPrefix string, newPrefix string, depthDelta int) error {
+	var (
+		path  = s.quote(s.option.PathColumn)
+		depth = s.quote(s.option.DepthColumn)
+	)
+	_, err := daoctl.UpdateWithError(s.dao.Ctx(ctx).
+		Where(s.likePrefix(), likeValue(oldPrefix)).
+		Data(
+			fmt.Sprintf("%s = CONCAT(?, SUBSTRING(%s, ?)), %s = %s + ?", path, path, depth, depth),
+			newPrefix, utf8.RuneCountInString(oldPrefix)+1, depthDelta,
+		))
+	return err
+}
+
+// Reorder 按 ids 的顺序设置排序列
+func (s *PathStore[K]) Reorder(ctx context.Context, ids []K) error {
+	return reorder(ctx, s.dao, s.option.IdColumn, s.option.SortColumn, ids)
+}
+
+// Delete 删除节点：DeleteCascade 删除整个子树；DeleteReparent 将子节点挂载到被删除节点的父节点，并更新子树的路径及深度
+func (s *PathStore[K]) Delete(ctx context.Context, id K, mode DeleteMode) (ids []K, err error) {
+	err = s.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		node, err := s.node(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if mode == DeleteReparent {
+			ids = []K{id}
+			if _, err = daoctl.UpdateWithError(s.dao.Ctx(ctx).Where(s.option.ParentIdColumn, id).Data(s.option.ParentIdColumn, node.ParentId)); err != nil {
+				return err
+			}
+			parentPath := strings.TrimSuffix(node.Path, gconv.String(id)+s.option.Separator)
+			if err = s.replacePrefix(ctx, node.Path, parentPath, -1); err != nil {
+				return err
+			}
+			_, err = daoctl.DeleteWithError(s.dao.Ctx(ctx).Where(s.option.IdColumn, id))
+			return err
+		}
+
+		values, err := s.dao.Ctx(ctx).Where(s.likePrefix(), likeValue(node.Path)).OrderAsc(s.option.DepthColumn).Array(s.option.IdColumn)
+		if err != nil {
+			return err
+		}
+		if err = gconv.Scan(values, &ids); err != nil {
+			return err
+		}
+		_, err = daoctl.DeleteWithError(s.dao.Ctx(ctx).WhereIn(s.option.IdColumn, ids))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// DescendantIds 通过路径前缀查询所有后代节点ID
+func (s *PathStore[K]) DescendantIds(ctx context.Context, id K) (ids []K, err error) {
+	node, err := s.node(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.dao.Ctx(ctx).
+		Where(s.likePrefix(), likeValue(node.Path)).
+		WhereNot(s.option.IdColumn, id).
+		OrderAsc(s.option.DepthColumn).
+		Array(s.option.IdColumn)
+	if err != nil {
+		return nil, err
+	}
+	err = gconv.Scan(values, &ids)
+	return ids, err
+}
+
+// likePrefix 路径以指定前缀开头的查询条件，参数使用 likeValue 转义
+func (s *PathStore[K]) likePrefix() string {
+	return fmt.Sprintf("%s LIKE ? ESCAPE '%s'", s.quote(s.option.PathColumn), likeEscape)
+}
+
+// quote 转义列名
+func (s *PathStore[K]) quote(column string) string {
+	return s.dao.DB().GetCore().QuoteWord(column)
+}
+
+// LIKE 的转义字符，不使用反斜杠以兼容 MySQL 字符串字面量中反斜杠的转义
+const likeEscape = "!"
+
+var likeEscaper = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+
+// likeValue 以 prefix 开头的 LIKE 参数，转义 prefix 中的通配符，避免ID中的 _、% 匹配其他节点的路径
+func likeValue(prefix string) string {
+	return likeEscaper.Replace(prefix) + "%"
+}
+
+// ClosureStoreOption 闭包表的列名配置，未设置的列使用默认值
+type ClosureStoreOption struct {
+	AncestorColumn   string // 祖先节点列，默认为 ancestor_id
+	DescendantColumn string // 后代节点列，默认为 descendant_id
+	DepthColumn      string // 祖先到后代的距离，默认为 depth
+	IdColumn         string // 业务表的主键列，默认为 id
+	ParentIdColumn   string // 业务表的父节点列，为空时不维护
+	SortColumn       string // 业务表的排序列，为空时不支持 Reorder
+}
+
+// ClosureStore 闭包表的持久化
+type ClosureStore[K comparable] struct {
+	closure dao_interface.IDao
+	nodes   dao_interface.IDao
+	option  ClosureStoreOption
+}
+
+// NewClosureStore 创建闭包表的持久化，closure 为闭包表，nodes 为存储树节点的业务表（为 nil 时仅维护闭包表）
+func NewClosureStore[K comparable](closure dao_interface.IDao, nodes dao_interface.IDao, option ...ClosureStoreOption) *ClosureStore[K] {
+	var opt ClosureStoreOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+	opt.AncestorColumn = defaultColumn(opt.AncestorColumn, "ancestor_id")
+	opt.DescendantColumn = defaultColumn(opt.DescendantColumn, "descendant_id")
+	opt.DepthColumn = defaultColumn(opt.DepthColumn, "depth")
+	opt.IdColumn = defaultColumn(opt.IdColumn, "id")
+
+	return &ClosureStore[K]{closure: closure, nodes: nodes, option: opt}
+}
+
+// ancestors 节点的所有祖先（含自身）及距离
+func (s *ClosureStore[K]) ancestors(ctx context.Context, id K) (gdb.Result, error) {
+	result, err := s.closure.Ctx(ctx).
+		Fields(s.option.AncestorColumn, s.option.DepthColumn).
+		Where(s.option.DescendantColumn, id).
+		All()
+	if err != nil {
+		return nil, err
+	}
+	if result.IsEmpty() {
+		return nil, gerror.WrapCodef(gcode.CodeNotFound, ErrNodeNotFound, "ID：%v", id)
+	}
+	return result, nil
+}
+
+// subtree 节点的子树（含自身）及距离
+func (s *ClosureStore[K]) subtree(ctx context.Context, id K) (gdb.Result, []K, error) {
+	result, err := s.closure.Ctx(ctx).
+		Fields(s.option.DescendantColumn, s.option.DepthColumn).
+		Where(s.option.AncestorColumn, id).
+		OrderAsc(s.option.DepthColumn).
+		All()
+	if err != nil {
+		return nil, nil, err
+	}
+	if result.IsEmpty() {
+		return nil, nil, gerror.WrapCodef(gcode.CodeNotFound, ErrNodeNotFound, "ID：%v", id)
+	}
+
+	var ids []K
+	if err = gconv.Scan(result.Array(s.option.DescendantColumn), &ids); err != nil {
+		return nil, nil, err
+	}
+	return result, ids, nil
+}
+
+// link 为子树中的每个节点增加到 parentId 及其所有祖先的路径
+func (s *ClosureStore[K]) link(ctx context.Context, subtree gdb.Result, parentId K) error {
+	var zero K
+	if parentId == zero {
+		return nil
+	}
+
+	ancestors, err := s.ancestors(ctx, parentId)
+	if err != nil {
+		return err
+	}
+
+	data := make(g.List, 0, len(ancestors)*len(subtree))
+	for _, a := range ancestors {
+		for _, d := range subtree {
+			data = append(data, g.Map{
+				s.option.AncestorColumn:   a[s.option.AncestorColumn].Val(),
+				s.option.DescendantColumn: d[s.option.DescendantColumn].Val(),
+				s.option.DepthColumn:      a[s.option.DepthColumn].Int() + d[s.option.DepthColumn].Int() + 1,
+			})
+		}
+	}
+	_, err = daoctl.InsertWithError(s.closure.Ctx(ctx), data)
+	return err
+}
+
+// setParentId 维护业务表的父节点列
+func (s *ClosureStore[K]) setParentId(ctx context.Context, id K, parentId K) error {
+	if s.nodes == nil || s.option.ParentIdColumn == "" {
+		return nil
+	}
+	_, err := daoctl.UpdateWithError(s.nodes.Ctx(ctx).Where(s.option.IdColumn, id).Data(s.option.ParentIdColumn, parentId))
+	return err
+}
+
+// Insert 节点记录插入后调用，写入节点到自身及所有祖先的路径
+func (s *ClosureStore[K]) Insert(ctx context.Context, id K, parentId K) error {
+	return s.closure.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		self := gdb.Result{gdb.Record{
+			s.option.DescendantColumn: g.NewVar(id),
+			s.option.DepthColumn:      g.NewVar(0),
+		}}
+		if _, err := daoctl.InsertWithError(s.closure.Ctx(ctx), g.Map{
+			s.option.AncestorColumn:   id,
+			s.option.DescendantColumn: id,
+			s.option.DepthColumn:      0,
+		}); err != nil {
+			return err
+		}
+		if err := s.link(ctx, self, parentId); err != nil {
+			return err
+		}
+		return s.setParentId(ctx, id, parentId)
+	})
+}
+
+// Move 删除子树与原祖先之间的路径，再增加子树到新父节点及其祖先的路径
+func (s *ClosureStore[K]) Move(ctx context.Context, id K, parentId K) error {
+	return s.closure.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		subtree, ids, err := s.subtree(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, item := range ids {
+			if item == parentId {
+				return gerror.WrapCodef(gcode.CodeInvalidParameter, ErrCycle, "不能将节点 %v 移动到自身的子树 %v 中", id, parentId)
+			}
+		}
+
+		if _, err = daoctl.DeleteWithError(s.closure.Ctx(ctx).
+			WhereIn(s.option.DescendantColumn, ids).
+			WhereNotIn(s.option.AncestorColumn, ids),
+		); err != nil {
+			return err
+		}
+		if err = s.link(ctx, subtree, parentId); err != nil {
+			return err
+		}
+		return s.setParentId(ctx, id, parentId)
+	})
+}
+
+// Reorder 按 ids 的顺序设置业务表的排序列
+func (s *ClosureStore[K]) Reorder(ctx context.Context, ids []K) error {
+	if s.nodes == nil {
+		return gerror.NewCode(gcode.CodeInvalidConfiguration, "闭包表未配置业务表，不支持排序")
+	}
+	return reorder(ctx, s.nodes, s.option.IdColumn, s.option.SortColumn, ids)
+}
+
+// Delete 删除节点：DeleteCascade 删除整个子树；DeleteReparent 将子节点移动到被删除节点的父节点；配置了业务表时同时删除节点记录
+func (s *ClosureStore[K]) Delete(ctx context.Context, id K, mode DeleteMode) (ids []K, err error) {
+	err = s.closure.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		_, subtreeIds, err := s.subtree(ctx, id)
+		if err != nil {
+			return err
+		}
+		ids = subtreeIds
+
+		if mode == DeleteReparent {
+			ids = []K{id}
+			if err = s.reparentChildren(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		if _, err = daoctl.DeleteWithError(s.closure.Ctx(ctx).WhereIn(s.option.DescendantColumn, ids)); err != nil {
+			return err
+		}
+		if s.nodes != nil {
+			_, err = daoctl.DeleteWithError(s.nodes.Ctx(ctx).WhereIn(s.option.IdColumn, ids))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// reparentChildren 将节点的子节点移动到节点的父节点
+func (s *ClosureStore[K]) reparentChildren(ctx context.Context, id K) error {
+	var (
+		parentId K
+		children []K
+	)
+	value, err := s.closure.Ctx(ctx).Where(s.option.DescendantColumn, id).Where(s.option.DepthColumn, 1).Value(s.option.AncestorColumn)
+	if err != nil {
+		return err
+	}
+	if !value.IsNil() {
+		if err = value.Scan(&parentId); err != nil {
+			return err
+		}
+	}
+
+	values, err := s.closure.Ctx(ctx).Where(s.option.AncestorColumn, id).Where(s.option.DepthColumn, 1).Array(s.option.DescendantColumn)
+	if err != nil {
+		return err
+	}
+	if err = gconv.Scan(values, &children); err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err = s.Move(ctx, child, parentId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DescendantIds 查询闭包表中以节点为祖先的所有后代节点ID
+func (s *ClosureStore[K]) DescendantIds(ctx context.Context, id K) (ids []K, err error) {
+	values, err := s.closure.Ctx(ctx).
+		Where(s.option.AncestorColumn, id).
+		WhereGT(s.option.DepthColumn, 0).
+		OrderAsc(s.option.DepthColumn).
+		Array(s.option.DescendantColumn)
+	if err != nil {
+		return nil, err
+	}
+	err = gconv.Scan(values, &ids)
+	return ids, err
+}
+
+// reorder 按 ids 的顺序设置排序列
+func reorder[K comparable](ctx context.Context, dao dao_interface.IDao, idColumn string, sortColumn string, ids []K) error {
+	if sortColumn == "" {
+		return gerror.NewCode(gcode.CodeInvalidConfiguration, "未配置排序列，不支持排序")
+	}
+	return dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		for i, id := range ids {
+			if _, err := daoctl.UpdateWithError(dao.Ctx(ctx).Where(idColumn, id).Data(sortColumn, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// defaultColumn 未设置时使用默认值
+func defaultColumn(value string, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
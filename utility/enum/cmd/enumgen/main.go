@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/kysion/base-library/utility/enum/enumgen"
+)
+
+/*
+	枚举代码生成命令：
+		go run github.com/kysion/base-library/utility/enum/cmd/enumgen -i hack/enum/sample.yaml -go internal/enum -ts web/enum.ts -md docs/enum.md
+	多个定义文件以逗号分隔，包名必须一致；Go代码生成到同一目录，TypeScript常量及Markdown表格按定义文件的顺序合并输出到同一文件
+*/
+
+func main() {
+	var (
+		inputs   = flag.String("i", "", "枚举定义文件，YAML或JSON格式，多个以逗号分隔")
+		goDir    = flag.String("go", "", "Go代码输出目录")
+		tsFile   = flag.String("ts", "", "TypeScript常量输出文件")
+		markdown = flag.String("md", "", "Markdown表格输出文件")
+	)
+	flag.Parse()
+
+	if *inputs == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(strings.Split(*inputs, ","), *goDir, *tsFile, *markdown); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run 读取并校验全部定义文件后再生成：Go代码生成到同一输出目录，各定义文件的包名必须一致；TypeScript及Markdown合并输出
+func run(inputs []string, goDir, tsFile, markdown string) error {
+	merged := &enumgen.Definition{}
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		def, err := enumgen.Load(input)
+		if err != nil {
+			return err
+		}
+		if goDir != "" && merged.Package != "" && def.Package != merged.Package {
+			return gerror.Newf("定义文件 %s 的包名 %s 与其他定义文件的包名 %s 不一致，不能生成到同一目录 %s", input, def.Package, merged.Package, goDir)
+		}
+		merged.Package = def.Package
+		merged.Enums = append(merged.Enums, def.Enums...)
+	}
+
+	// 合并后校验，不同定义文件中的枚举名称及注册名称也不能重复
+	if err := merged.Validate(); err != nil {
+		return err
+	}
+
+	return enumgen.Generate(merged, enumgen.Option{GoDir: goDir, TsFile: tsFile, MarkdownFile: markdown})
+}
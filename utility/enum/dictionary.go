@@ -0,0 +1,111 @@
+package enum
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+/*
+	枚举字典：将所有注册的枚举族导出为前端下拉选项等使用的字典文档
+		1、包含每个枚举族的代码类型、是否为位标志枚举，及成员的代码、描述、按请求语言翻译的名称和附加数据
+		2、Version 为文档内容的摘要，DictionaryHandler 将其作为 ETag，客户端携带 If-None-Match 且内容未变化时返回 304
+*/
+
+// Dictionary 枚举字典文档
+type Dictionary struct {
+	Version string           `json:"version" dc:"文档内容的摘要，内容变化时改变"`
+	Enums   []DictionaryEnum `json:"enums"   dc:"枚举族，按名称排序"`
+}
+
+// DictionaryEnum 枚举族
+type DictionaryEnum struct {
+	Name     string             `json:"name"     dc:"枚举族名称，即 enum 校验规则的参数"`
+	CodeType string             `json:"codeType" dc:"代码类型，如 int、string"`
+	Flag     bool               `json:"flag"     dc:"是否为位标志枚举，代码可组合"`
+	Members  []DictionaryMember `json:"members"  dc:"成员，按注册顺序排列"`
+}
+
+// DictionaryMember 枚举成员
+type DictionaryMember struct {
+	Code        any    `json:"code"           dc:"代码"`
+	Description string `json:"description"    dc:"描述"`
+	Label       string `json:"label"          dc:"按请求语言翻译的名称"`
+	Data        any    `json:"data,omitempty" dc:"附加数据"`
+}
+
+// NewDictionary 导出指定名称的枚举族，未指定时导出全部；成员名称按 ctx 中的语言翻译，不存在的名称将被忽略
+func NewDictionary(ctx context.Context, names ...string) *Dictionary {
+	registries := Registries()
+	if len(names) > 0 {
+		registries = make([]IRegistry, 0, len(names))
+		for _, r := range Registries() {
+			if gstr.InArray(names, r.Name()) {
+				registries = append(registries, r)
+			}
+		}
+	}
+
+	dict := &Dictionary{
+		Enums: make([]DictionaryEnum, 0, len(registries)),
+	}
+	for _, r := range registries {
+		item := DictionaryEnum{
+			Name:     r.Name(),
+			CodeType: r.CodeType(),
+			Flag:     r.IsFlag(),
+			Members:  make([]DictionaryMember, 0, r.Len()),
+		}
+		for _, member := range r.Members() {
+			m := ToMapCtx(ctx, member)
+			item.Members = append(item.Members, DictionaryMember{
+				Code:        m["code"],
+				Description: member.Description(),
+				Label:       DescriptionCtx(ctx, member),
+				Data:        m["data"],
+			})
+		}
+		dict.Enums = append(dict.Enums, item)
+	}
+
+	data, _ := json.Marshal(dict.Enums)
+	sum := sha1.Sum(data)
+	dict.Version = hex.EncodeToString(sum[:8])
+
+	return dict
+}
+
+// DictionaryHandler 输出枚举字典，参数 names 为以逗号分隔的枚举族名称，为空时输出全部，需要在路由注册时候注册；
+// 响应携带 ETag，内容未变化时返回 304。成员名称按请求上下文的语言翻译，请在此前的中间件中通过 gi18n.WithLanguage 设置。
+func DictionaryHandler(r *ghttp.Request) {
+	dict := NewDictionary(r.Context(), gstr.SplitAndTrim(r.Get("names").String(), ",")...)
+
+	etag := `"` + dict.Version + `"`
+	r.Response.Header().Set("ETag", etag)
+	r.Response.Header().Set("Cache-Control", "no-cache")
+	r.Response.Header().Set("Vary", "Accept-Language")
+
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		r.Response.WriteHeader(http.StatusNotModified)
+		r.ExitAll()
+	}
+
+	r.Response.WriteJsonExit(dict)
+}
+
+// etagMatch If-None-Match 是否包含指定的 ETag，忽略弱校验前缀 W/
+func etagMatch(header string, etag string) bool {
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimPrefix(strings.TrimSpace(item), "W/")
+		if item == "*" || item == etag {
+			return true
+		}
+	}
+	return false
+}
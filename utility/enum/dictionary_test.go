@@ -0,0 +1,155 @@
+package enum
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/i18n/gi18n"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/guid"
+)
+
+// startTestServer 启动监听随机端口的服务，bind 在启动前注册路由，返回服务地址，测试结束时关闭；
+// g.Server 按名称缓存实例，名称追加随机后缀使重复运行的测试使用新的服务
+func startTestServer(t *testing.T, bind func(s *ghttp.Server)) (*ghttp.Server, string) {
+	t.Helper()
+
+	s := g.Server(t.Name() + "-" + guid.S())
+	s.SetAddr("127.0.0.1:0")
+	s.SetDumpRouterMap(false)
+	s.SetAccessLogEnabled(false)
+	bind(s)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = s.Shutdown()
+	})
+	return s, fmt.Sprintf("http://127.0.0.1:%d", s.GetListenedPort())
+}
+
+// get 发送 GET 请求，返回状态码、响应头及内容
+func get(t *testing.T, url string, header map[string]string) (int, http.Header, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, res.Header, string(body)
+}
+
+func TestNewDictionary(t *testing.T) {
+	useI18n(t)
+	ctx := context.Background()
+
+	dict := NewDictionary(ctx, "TestPerm", "TestI18nState", "NotRegistered")
+	if len(dict.Enums) != 2 || dict.Enums[0].Name != "TestI18nState" || dict.Enums[1].Name != "TestPerm" {
+		t.Fatalf("Enums = %+v, want TestI18nState and TestPerm sorted by name", dict.Enums)
+	}
+
+	perm := dict.Enums[1]
+	if perm.CodeType != "int" || !perm.Flag || len(perm.Members) != 3 {
+		t.Fatalf("TestPerm = %+v", perm)
+	}
+	if m := perm.Members[2]; m.Code != 4 || m.Description != "exec" || m.Label != "exec" || m.Data != nil {
+		t.Fatalf("TestPerm members[2] = %+v", m)
+	}
+	if m := dict.Enums[0].Members[1]; m.Description != "disabled" || m.Label != "停用" {
+		t.Fatalf("TestI18nState members[1] = %+v, want label 停用", m)
+	}
+
+	// 全部导出时按名称排序
+	var names []string
+	for _, e := range NewDictionary(ctx).Enums {
+		names = append(names, e.Name)
+	}
+	if !slices.IsSorted(names) || !slices.Contains(names, "TestColor") || !slices.Contains(names, "TestI18nState") {
+		t.Fatalf("NewDictionary() = %v, want all registries sorted by name", names)
+	}
+
+	// 内容不变时版本不变，翻译的语言不同时版本不同
+	if again := NewDictionary(ctx, "TestPerm", "TestI18nState"); again.Version != dict.Version {
+		t.Fatalf("Version = %s, want %s", again.Version, dict.Version)
+	}
+	en := NewDictionary(gi18n.WithLanguage(ctx, "en"), "TestPerm", "TestI18nState")
+	if en.Version == dict.Version || en.Enums[0].Members[1].Label != "Disabled" {
+		t.Fatalf("en = version %s label %s, want a different version and label Disabled", en.Version, en.Enums[0].Members[1].Label)
+	}
+}
+
+func TestDictionaryHandler(t *testing.T) {
+	useI18n(t)
+
+	_, url := startTestServer(t, func(s *ghttp.Server) {
+		s.Group("/", func(group *ghttp.RouterGroup) {
+			group.Middleware(func(r *ghttp.Request) {
+				if lang := r.Header.Get("Accept-Language"); lang != "" {
+					r.SetCtx(gi18n.WithLanguage(r.Context(), lang))
+				}
+				r.Middleware.Next()
+			})
+			group.GET("/enum/dictionary", DictionaryHandler)
+		})
+	})
+	url += "/enum/dictionary?names=TestColor,TestI18nState"
+
+	status, header, body := get(t, url, nil)
+	etag := header.Get("ETag")
+	if status != http.StatusOK || etag != `"`+NewDictionary(context.Background(), "TestColor", "TestI18nState").Version+`"` {
+		t.Fatalf("GET = %d, ETag %s", status, etag)
+	}
+	if header.Get("Cache-Control") != "no-cache" || header.Get("Vary") != "Accept-Language" {
+		t.Fatalf("header = %v", header)
+	}
+	if want := `"label":"停用"`; !strings.Contains(body, want) || strings.Contains(body, `"name":"TestPerm"`) {
+		t.Fatalf("body = %s, want %s and only the requested registries", body, want)
+	}
+
+	cases := []struct {
+		name       string
+		header     map[string]string
+		wantStatus int
+	}{
+		{name: "matched", header: map[string]string{"If-None-Match": etag}, wantStatus: http.StatusNotModified},
+		{name: "weak", header: map[string]string{"If-None-Match": "W/" + etag}, wantStatus: http.StatusNotModified},
+		{name: "list", header: map[string]string{"If-None-Match": `"other", ` + etag}, wantStatus: http.StatusNotModified},
+		{name: "any", header: map[string]string{"If-None-Match": "*"}, wantStatus: http.StatusNotModified},
+		{name: "changed", header: map[string]string{"If-None-Match": `"other"`}, wantStatus: http.StatusOK},
+		{name: "other language", header: map[string]string{"If-None-Match": etag, "Accept-Language": "en"}, wantStatus: http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			status, header, body := get(t, url, c.header)
+			if status != c.wantStatus {
+				t.Fatalf("GET = %d, want %d", status, c.wantStatus)
+			}
+			if status == http.StatusNotModified && (body != "" || header.Get("ETag") != etag) {
+				t.Fatalf("304 body = %q, ETag %s", body, header.Get("ETag"))
+			}
+		})
+	}
+
+	if _, header, body := get(t, url, map[string]string{"Accept-Language": "en"}); header.Get("ETag") == etag || !strings.Contains(body, `"label":"Disabled"`) {
+		t.Fatalf("en = ETag %s, body %s", header.Get("ETag"), body)
+	}
+}
@@ -1,6 +1,8 @@
 package enum
 
 import (
+	"context"
+
 	"github.com/gogf/gf/v2/util/gconv"
 )
 
@@ -57,6 +59,8 @@ type enumType[TCode NumberEnumCode | string, TData any] struct {
 	code        TCode  // 错误代码，通常是一个整数。
 	data        TData  // 该值的简短数据。
 	description string // 该代码的简短描述。
+	i18nKey     string // 描述的翻译Key，为空时按所属枚举族生成。
+	family      string // 所属的枚举族名称，注册时设置。
 }
 
 // Code 返回当前代码的数值。
@@ -74,13 +78,9 @@ func (e *enumType[TCode, TData]) Data() TData {
 	return e.data
 }
 
-// ToMap 将枚举类型转换为映射格式。
+// ToMap 将枚举类型转换为映射格式，label 为默认语言翻译后的描述。
 func (e *enumType[TCode, TData]) ToMap() map[string]any {
-	return map[string]any{
-		"code":        e.code,
-		"description": e.description,
-		"data":        e.data,
-	}
+	return e.ToMapCtx(context.Background())
 }
 
 // Has 检查是否有指定的枚举类型，如果有多个，则必须全部包含才返回 true。
@@ -366,6 +366,17 @@ func NewWithData[TCode uint | uint8 | uint16 | uint32 | uintptr | uint64 | int |
 	return result.(*IEnumCodeWithData[TCode, TData])
 }
 
+// NewData 创建一个新的带有附加数据的枚举类型实例，返回值与 New 相同，可通过 ToMap 或断言为 IEnumCodeWithData 获取附加数据。
+func NewData[R IEnumCode[TCode], TCode NumberEnumCode | string, TData any](code TCode, description string, data TData) R {
+	var result interface{}
+	result = &enumType[TCode, TData]{
+		code:        code,
+		data:        data,
+		description: description,
+	}
+	return result.(R)
+}
+
 // GetTypes 获取指定代码类型的所有枚举类型实例。
 func GetTypes[V uint | uint8 | uint16 | uint32 | uintptr | uint64 | int | int8 | int16 | int32 | int64, T IEnumCode[V]](code V, enumOjb interface{}) []IEnumCode[V] {
 	typeMaps := gconv.Map(enumOjb)
@@ -0,0 +1,420 @@
+package enumgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+/*
+	枚举代码生成器：读取YAML/JSON格式的枚举定义，生成
+		1、Go代码：枚举类型、成员结构体、注册表及 New、Parse、ParseDescription、All、Values 方法，每个枚举一个文件
+		2、TypeScript常量：供前端使用的代码及描述
+		3、Markdown表格：用于文档
+	定义示例见 hack/enum/sample.yaml，命令行工具见 utility/enum/cmd/enumgen
+*/
+
+// Definition 枚举定义文件
+type Definition struct {
+	Package string           `json:"package"` // 生成的Go代码的包名
+	Enums   []EnumDefinition `json:"enums"`   // 枚举族
+}
+
+// EnumDefinition 枚举族定义
+type EnumDefinition struct {
+	Name        string             `json:"name"`        // 枚举族名称，如 CaptchaType，生成 CaptchaTypeEnum 类型及 CaptchaType 变量
+	Registry    string             `json:"registry"`    // 注册表名称，用于 v:"enum:名称" 校验规则及字典导出，默认为 Name
+	Description string             `json:"description"` // 枚举族说明
+	CodeType    string             `json:"codeType"`    // 代码类型：int、int8…uint64、string，默认为 int
+	Flag        bool               `json:"flag"`        // 是否为位标志枚举，成员代码必须是单个位
+	Members     []MemberDefinition `json:"members"`     // 成员
+}
+
+// MemberDefinition 枚举成员定义
+type MemberDefinition struct {
+	Name        string      `json:"name"`        // 成员名称，Go标识符，如 Register
+	Code        interface{} `json:"code"`        // 代码
+	Description string      `json:"description"` // 描述
+	Comment     string      `json:"comment"`     // 说明，生成到代码注释及文档中
+	Data        interface{} `json:"data"`        // 附加数据，支持字符串、数字、布尔、数组及对象
+}
+
+// Option 生成选项，未指定的输出不生成
+type Option struct {
+	GoDir        string // Go代码输出目录
+	TsFile       string // TypeScript常量输出文件
+	MarkdownFile string // Markdown表格输出文件
+}
+
+// 支持的代码类型
+var codeTypes = map[string]bool{
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"string": true,
+}
+
+// Load 读取枚举定义文件，按扩展名识别YAML或JSON格式；数字按原文读取，避免超出 float64 精度的代码被舍入
+func Load(path string) (*Definition, error) {
+	j, err := gjson.LoadPath(path, gjson.Options{StrNumber: true})
+	if err != nil {
+		return nil, gerror.Wrapf(err, "枚举定义文件读取失败：%s", path)
+	}
+
+	def := &Definition{}
+	if err = j.Scan(def); err != nil {
+		return nil, gerror.Wrapf(err, "枚举定义文件格式错误：%s", path)
+	}
+
+	return def, def.Validate()
+}
+
+// Validate 校验枚举定义并补充默认值：名称必须是Go标识符，枚举名称、注册名称及成员代码不能重复，位标志枚举的成员代码必须是单个位
+func (d *Definition) Validate() error {
+	if !token.IsIdentifier(d.Package) {
+		return gerror.Newf("包名不是有效的Go标识符：%s", d.Package)
+	}
+
+	enumNames := make(map[string]bool)
+	registries := make(map[string]string)
+	for i := range d.Enums {
+		e := &d.Enums[i]
+		if !token.IsExported(e.Name) {
+			return gerror.Newf("枚举名称必须是导出的Go标识符：%s", e.Name)
+		}
+		if enumNames[e.Name] {
+			return gerror.Newf("枚举名称重复：%s", e.Name)
+		}
+		enumNames[e.Name] = true
+		if e.Registry == "" {
+			e.Registry = e.Name
+		}
+		if exists, ok := registries[e.Registry]; ok {
+			return gerror.Newf("枚举 %s、%s 的注册名称重复：%s", exists, e.Name, e.Registry)
+		}
+		registries[e.Registry] = e.Name
+		if e.CodeType == "" {
+			e.CodeType = "int"
+		}
+		if !codeTypes[e.CodeType] {
+			return gerror.Newf("枚举 %s 不支持的代码类型：%s", e.Name, e.CodeType)
+		}
+		if e.Flag && e.CodeType == "string" {
+			return gerror.Newf("位标志枚举 %s 的代码类型不能是 string", e.Name)
+		}
+		if len(e.Members) == 0 {
+			return gerror.Newf("枚举 %s 没有成员", e.Name)
+		}
+
+		names := make(map[string]bool)
+		codes := make(map[string]string)
+		for _, m := range e.Members {
+			if !token.IsExported(m.Name) {
+				return gerror.Newf("枚举 %s 的成员名称必须是导出的Go标识符：%s", e.Name, m.Name)
+			}
+			if names[m.Name] {
+				return gerror.Newf("枚举 %s 的成员名称重复：%s", e.Name, m.Name)
+			}
+			names[m.Name] = true
+
+			code, err := e.codeLiteral(m.Code)
+			if err != nil {
+				return err
+			}
+			if exists, ok := codes[code]; ok {
+				return gerror.Newf("枚举 %s 的成员代码重复：%s（%s、%s）", e.Name, code, exists, m.Name)
+			}
+			codes[code] = m.Name
+
+			if e.Flag {
+				if v, _ := strconv.ParseUint(code, 10, 64); v == 0 || v&(v-1) != 0 {
+					return gerror.Newf("位标志枚举 %s 的成员代码必须是单个位：%s %s", e.Name, m.Name, code)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Generate 按选项生成代码及文档
+func Generate(d *Definition, option Option) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	if option.GoDir != "" {
+		files, err := GenerateGo(d)
+		if err != nil {
+			return err
+		}
+		for name, content := range files {
+			if err = writeFile(filepath.Join(option.GoDir, name), content); err != nil {
+				return err
+			}
+		}
+	}
+
+	if option.TsFile != "" {
+		content, err := GenerateTypeScript(d)
+		if err != nil {
+			return err
+		}
+		if err = writeFile(option.TsFile, content); err != nil {
+			return err
+		}
+	}
+
+	if option.MarkdownFile != "" {
+		content, err := GenerateMarkdown(d)
+		if err != nil {
+			return err
+		}
+		if err = writeFile(option.MarkdownFile, content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GenerateGo 生成Go代码，返回文件名及内容，每个枚举一个文件，文件名为枚举名称的下划线形式
+func GenerateGo(d *Definition) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(d.Enums))
+	for _, e := range d.Enums {
+		buf := &bytes.Buffer{}
+		if err := goTemplate.Execute(buf, goTemplateData{Package: d.Package, Enum: e}); err != nil {
+			return nil, gerror.Wrapf(err, "枚举 %s 的代码生成失败", e.Name)
+		}
+
+		content, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, gerror.Wrapf(err, "枚举 %s 的代码格式化失败", e.Name)
+		}
+		files[snakeCase(e.Name)+".go"] = content
+	}
+	return files, nil
+}
+
+// GenerateTypeScript 生成TypeScript常量，每个枚举族生成一个对象常量及代码的联合类型
+func GenerateTypeScript(d *Definition) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("// Code generated by enumgen. DO NOT EDIT.\n")
+
+	for _, e := range d.Enums {
+		buf.WriteString("\n")
+		if e.Description != "" {
+			fmt.Fprintf(buf, "/** %s */\n", e.Description)
+		}
+		fmt.Fprintf(buf, "export const %s = {\n", e.Name)
+
+		codes := make([]string, 0, len(e.Members))
+		for _, m := range e.Members {
+			item := tsMember{Code: m.Code, Description: m.Description, Data: m.Data}
+			if e.CodeType != "string" {
+				item.Code = json.Number(mustCodeLiteral(e, m.Code))
+			}
+			value, err := json.Marshal(item)
+			if err != nil {
+				return nil, gerror.Wrapf(err, "枚举 %s 的成员 %s 序列化失败", e.Name, m.Name)
+			}
+
+			if m.Comment != "" {
+				fmt.Fprintf(buf, "  /** %s */\n", m.Comment)
+			}
+			fmt.Fprintf(buf, "  %s: %s,\n", m.Name, value)
+
+			code, _ := json.Marshal(item.Code)
+			codes = append(codes, string(code))
+		}
+		buf.WriteString("} as const;\n\n")
+		fmt.Fprintf(buf, "export type %sCode = %s;\n", e.Name, strings.Join(codes, " | "))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// GenerateMarkdown 生成Markdown表格
+func GenerateMarkdown(d *Definition) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("<!-- Code generated by enumgen. DO NOT EDIT. -->\n")
+
+	for _, e := range d.Enums {
+		fmt.Fprintf(buf, "\n## %s", e.Name)
+		if e.Description != "" {
+			fmt.Fprintf(buf, " %s", e.Description)
+		}
+		buf.WriteString("\n\n")
+
+		kind := "普通枚举"
+		if e.Flag {
+			kind = "位标志枚举，可组合多个成员"
+		}
+		fmt.Fprintf(buf, "注册名称：`%s`，代码类型：`%s`，%s\n\n", e.Registry, e.CodeType, kind)
+
+		buf.WriteString("| 名称 | 代码 | 描述 | 说明 | 附加数据 |\n")
+		buf.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, m := range e.Members {
+			data := ""
+			if m.Data != nil {
+				b, err := json.Marshal(m.Data)
+				if err != nil {
+					return nil, gerror.Wrapf(err, "枚举 %s 的成员 %s 序列化失败", e.Name, m.Name)
+				}
+				data = "`" + string(b) + "`"
+			}
+			fmt.Fprintf(buf, "| %s | %s | %s | %s | %s |\n",
+				m.Name, mustCodeLiteral(e, m.Code), markdownEscape(m.Description), markdownEscape(m.Comment), data,
+			)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// tsMember TypeScript常量中的成员
+type tsMember struct {
+	Code        interface{} `json:"code"`
+	Description string      `json:"description"`
+	Data        interface{} `json:"data,omitempty"`
+}
+
+// codeLiteral 将成员代码转换为Go字面量
+func (e *EnumDefinition) codeLiteral(code interface{}) (string, error) {
+	if code == nil {
+		return "", gerror.Newf("枚举 %s 存在未设置代码的成员", e.Name)
+	}
+
+	if e.CodeType == "string" {
+		return strconv.Quote(gconv.String(code)), nil
+	}
+
+	s := gconv.String(code)
+	var err error
+	if strings.HasPrefix(e.CodeType, "uint") {
+		_, err = strconv.ParseUint(s, 10, bitSize(e.CodeType))
+	} else {
+		_, err = strconv.ParseInt(s, 10, bitSize(e.CodeType))
+	}
+	if err != nil {
+		return "", gerror.Newf("枚举 %s 的代码 %v 不是有效的 %s", e.Name, code, e.CodeType)
+	}
+	return s, nil
+}
+
+// mustCodeLiteral 已校验的成员代码的字面量
+func mustCodeLiteral(e EnumDefinition, code interface{}) string {
+	s, _ := e.codeLiteral(code)
+	return s
+}
+
+// bitSize 整数类型的位数，int、uint 按64位处理
+func bitSize(codeType string) int {
+	size, err := strconv.Atoi(strings.TrimLeft(codeType, "uint"))
+	if err != nil {
+		return 64
+	}
+	return size
+}
+
+// goLiteral 将附加数据转换为Go字面量，对象按键排序
+func goLiteral(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(value)
+	case bool:
+		return strconv.FormatBool(value)
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, goLiteral(item))
+		}
+		return "[]any{" + strings.Join(items, ", ") + "}"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		items := make([]string, 0, len(value))
+		for _, k := range keys {
+			items = append(items, strconv.Quote(k)+": "+goLiteral(value[k]))
+		}
+		return "map[string]any{" + strings.Join(items, ", ") + "}"
+	default:
+		return gconv.String(value)
+	}
+}
+
+// snakeCase 将驼峰名称转换为下划线形式，如 CaptchaType 转换为 captcha_type
+func snakeCase(s string) string {
+	runes := []rune(s)
+	buf := strings.Builder{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				buf.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// lowerFirst 首字母小写，用于生成非导出的结构体名称；开头的缩写整体小写，如 HTTPChannel 转换为 httpChannel
+func lowerFirst(s string) string {
+	runes := []rune(s)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) || (i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// markdownEscape 转义表格单元格中的 |
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// writeFile 写入文件，目录不存在时创建
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return gerror.Wrapf(err, "目录创建失败：%s", filepath.Dir(path))
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return gerror.Wrapf(err, "文件写入失败：%s", path)
+	}
+	return nil
+}
+
+// goTemplateData Go代码模板的数据
+type goTemplateData struct {
+	Package string
+	Enum    EnumDefinition
+}
+
+var goTemplate = template.Must(template.New("enum").Funcs(template.FuncMap{
+	"lowerFirst": lowerFirst,
+	"goLiteral":  goLiteral,
+	"code": func(e EnumDefinition, code interface{}) string {
+		return mustCodeLiteral(e, code)
+	},
+}).Parse(TemplateGenEnumGo))
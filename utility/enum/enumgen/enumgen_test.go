@@ -0,0 +1,197 @@
+package enumgen
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "更新 testdata/golden 中的生成结果")
+
+// checkGolden 对比生成结果与 testdata/golden 中的文件，-update 时写入生成结果
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := writeFile(path, got); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v，执行 go test -update 生成", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s 与 %s 不一致，确认生成结果正确后执行 go test -update 更新：\n%s", name, path, got)
+	}
+}
+
+func loadTestDefinition(t *testing.T) *Definition {
+	t.Helper()
+
+	def, err := Load(filepath.Join("testdata", "enums.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return def
+}
+
+func TestGenerateGo(t *testing.T) {
+	files, err := GenerateGo(loadTestDefinition(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if want := "big_code.go file_permission.go http_channel.go order_state.go"; strings.Join(names, " ") != want {
+		t.Fatalf("files = %v, want %s", names, want)
+	}
+
+	for _, name := range names {
+		checkGolden(t, name, files[name])
+	}
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	content, err := GenerateTypeScript(loadTestDefinition(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "enums.ts", content)
+}
+
+func TestGenerateMarkdown(t *testing.T) {
+	content, err := GenerateMarkdown(loadTestDefinition(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "enums.md", content)
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	option := Option{
+		GoDir:        filepath.Join(dir, "enum"),
+		TsFile:       filepath.Join(dir, "web", "enum.ts"),
+		MarkdownFile: filepath.Join(dir, "docs", "enum.md"),
+	}
+	if err := Generate(loadTestDefinition(t), option); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, golden := range map[string]string{
+		filepath.Join(option.GoDir, "order_state.go"): "order_state.go",
+		option.TsFile:       "enums.ts",
+		option.MarkdownFile: "enums.md",
+	} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := os.ReadFile(filepath.Join("testdata", "golden", golden+".golden"))
+		if !bytes.Equal(content, want) {
+			t.Fatalf("%s 与 %s 的生成结果不一致", path, golden)
+		}
+	}
+}
+
+func TestDefinition_Validate(t *testing.T) {
+	member := func(name string, code any) MemberDefinition {
+		return MemberDefinition{Name: name, Code: code, Description: strings.ToLower(name)}
+	}
+
+	cases := []struct {
+		name     string
+		def      Definition
+		contains string
+	}{
+		{name: "package", def: Definition{Package: "my-enum"}, contains: "包名"},
+		{name: "unexported enum", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "state", Members: []MemberDefinition{member("A", 1)}}}}, contains: "导出"},
+		{
+			name: "duplicate enum",
+			def: Definition{Package: "p", Enums: []EnumDefinition{
+				{Name: "State", Members: []MemberDefinition{member("A", 1)}},
+				{Name: "State", Registry: "Other", Members: []MemberDefinition{member("A", 1)}},
+			}},
+			contains: "枚举名称重复",
+		},
+		{
+			name: "duplicate registry",
+			def: Definition{Package: "p", Enums: []EnumDefinition{
+				{Name: "State", Members: []MemberDefinition{member("A", 1)}},
+				{Name: "Other", Registry: "State", Members: []MemberDefinition{member("A", 1)}},
+			}},
+			contains: "注册名称重复",
+		},
+		{name: "code type", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", CodeType: "float64", Members: []MemberDefinition{member("A", 1)}}}}, contains: "代码类型"},
+		{name: "string flag", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", CodeType: "string", Flag: true, Members: []MemberDefinition{member("A", "a")}}}}, contains: "不能是 string"},
+		{name: "no members", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State"}}}, contains: "没有成员"},
+		{name: "unexported member", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", Members: []MemberDefinition{member("a", 1)}}}}, contains: "成员名称必须"},
+		{name: "duplicate member", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", Members: []MemberDefinition{member("A", 1), member("A", 2)}}}}, contains: "成员名称重复"},
+		{name: "duplicate code", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", Members: []MemberDefinition{member("A", 1), member("B", "1")}}}}, contains: "成员代码重复"},
+		{name: "missing code", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", Members: []MemberDefinition{member("A", nil)}}}}, contains: "未设置代码"},
+		{name: "overflow", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", CodeType: "int8", Members: []MemberDefinition{member("A", 128)}}}}, contains: "不是有效的 int8"},
+		{name: "negative unsigned", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", CodeType: "uint", Members: []MemberDefinition{member("A", -1)}}}}, contains: "不是有效的 uint"},
+		{name: "fraction", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", Members: []MemberDefinition{member("A", 1.5)}}}}, contains: "不是有效的 int"},
+		{name: "flag multiple bits", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", Flag: true, Members: []MemberDefinition{member("A", 3)}}}}, contains: "单个位"},
+		{name: "flag zero", def: Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", Flag: true, Members: []MemberDefinition{member("A", 0)}}}}, contains: "单个位"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.def.Validate(); err == nil || !strings.Contains(err.Error(), c.contains) {
+				t.Fatalf("Validate() = %v, want error containing %s", err, c.contains)
+			}
+		})
+	}
+
+	// 补充默认值
+	def := Definition{Package: "p", Enums: []EnumDefinition{{Name: "State", Members: []MemberDefinition{member("A", 1)}}}}
+	if err := def.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if e := def.Enums[0]; e.Registry != "State" || e.CodeType != "int" {
+		t.Fatalf("defaults = registry %s, codeType %s", e.Registry, e.CodeType)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"CaptchaType":    "captcha_type",
+		"HTTPChannel":    "http_channel",
+		"OrderIDState":   "order_id_state",
+		"State":          "state",
+		"FilePermission": "file_permission",
+	}
+	for s, want := range cases {
+		if got := snakeCase(s); got != want {
+			t.Errorf("snakeCase(%s) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestLowerFirst(t *testing.T) {
+	cases := map[string]string{
+		"CaptchaType": "captchaType",
+		"HTTPChannel": "httpChannel",
+		"ID":          "id",
+		"A":           "a",
+		"OrderID":     "orderID",
+	}
+	for s, want := range cases {
+		if got := lowerFirst(s); got != want {
+			t.Errorf("lowerFirst(%s) = %s, want %s", s, got, want)
+		}
+	}
+}
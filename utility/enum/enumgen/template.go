@@ -0,0 +1,69 @@
+package enumgen
+
+// TemplateGenEnumGo 枚举Go代码模板，与手写的枚举族（如 base_enum/internal/captcha）结构一致
+const TemplateGenEnumGo = `// Code generated by enumgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/kysion/base-library/utility/enum"
+)
+{{with .Enum}}{{$e := .}}{{$s := lowerFirst .Name}}
+{{if .Description}}// {{.Name}}Enum {{.Description}}
+{{end}}type {{.Name}}Enum enum.IEnumCode[{{.CodeType}}]
+
+type {{$s}} struct {
+{{- range .Members}}
+	{{.Name}} {{$e.Name}}Enum{{if .Comment}} // {{.Comment}}{{end}}
+{{- end}}
+}
+
+var {{.Name}} = {{$s}}{
+{{- range .Members}}
+	{{- if .Data}}
+	{{.Name}}: enum.NewData[{{$e.Name}}Enum]({{code $e .Code}}, {{printf "%q" .Description}}, {{goLiteral .Data}}),
+	{{- else}}
+	{{.Name}}: enum.New[{{$e.Name}}Enum]({{code $e .Code}}, {{printf "%q" .Description}}),
+	{{- end}}
+{{- end}}
+}
+
+// {{$s}}Registry 注册全部成员，用于按代码或描述查找{{if .Flag}}，成员可组合为 enum.FlagSet{{end}}
+var {{$s}}Registry = enum.{{if .Flag}}RegisterFlags{{else}}Register{{end}}[{{.Name}}Enum]({{printf "%q" .Registry}},
+{{- range .Members}}
+	{{$e.Name}}.{{.Name}},
+{{- end}}
+)
+
+// New 按代码获取注册的成员，未注册时按代码及描述创建
+func (e *{{$s}}) New(code {{.CodeType}}, description ...string) {{.Name}}Enum {
+	if item, ok := {{$s}}Registry.Parse(code); ok {
+		return item
+	}
+	desc := ""
+	if len(description) > 0 {
+		desc = description[0]
+	}
+	return enum.New[{{.Name}}Enum](code, desc)
+}
+
+// Parse 按代码获取注册的成员
+func (e *{{$s}}) Parse(code {{.CodeType}}) ({{.Name}}Enum, bool) {
+	return {{$s}}Registry.Parse(code)
+}
+
+// ParseDescription 按描述获取注册的成员
+func (e *{{$s}}) ParseDescription(description string) ({{.Name}}Enum, bool) {
+	return {{$s}}Registry.ParseDescription(description)
+}
+
+// All 按定义顺序返回所有成员
+func (e *{{$s}}) All() []{{.Name}}Enum {
+	return {{$s}}Registry.All()
+}
+
+// Values 按定义顺序返回所有成员的代码
+func (e *{{$s}}) Values() []{{.CodeType}} {
+	return {{$s}}Registry.Values()
+}
+{{end}}`
@@ -0,0 +1,267 @@
+package enum
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/gogf/gf/v2/util/gvalid"
+)
+
+/*
+	枚举字段：可直接用作请求参数及数据库实体的字段
+		1、JSON序列化为代码，ObjectField 序列化为 {code, description}；反序列化时接受代码或包含 code 的对象
+		2、实现 sql.Scanner 及 driver.Valuer，数据库中保存代码，超出 int64 范围的无符号代码保存为十进制字符串
+		3、实现 gf 的 UnmarshalValue，请求参数绑定及ORM查询结果转换时使用
+		4、输入的代码必须是枚举族注册的成员，否则返回错误；gconv 转换时会忽略该错误，此时保留输入的代码（见 Invalid），
+		   String 返回该输入，使 required 规则不会将其视为空值，由 enum 规则校验失败并在提示中显示该输入
+		5、注册 gvalid 规则 enum，如 v:"enum:CaptchaType"，校验代码是否为指定枚举族的成员；
+		   仅 FlagSet 及未使用枚举字段的位标志代码校验是否为成员代码的组合，Field 只能是单个成员
+*/
+
+// Field 枚举字段，零值表示未设置，序列化为 null
+type Field[R IEnumCode[TCode], TCode NumberEnumCode | string] struct {
+	item    R
+	invalid any // 转换失败的输入，供 enum 规则校验
+}
+
+// ObjectField JSON序列化为 {code, description} 的枚举字段
+type ObjectField[R IEnumCode[TCode], TCode NumberEnumCode | string] struct {
+	Field[R, TCode]
+}
+
+// enumField 与代码类型无关的枚举字段，用于 gvalid 规则
+type enumField interface {
+	code() any
+	combinable() bool
+}
+
+func init() {
+	gvalid.RegisterRule("enum", checkEnumRule)
+}
+
+// NewField 创建枚举字段
+func NewField[R IEnumCode[TCode], TCode NumberEnumCode | string](item R) Field[R, TCode] {
+	return Field[R, TCode]{item: item}
+}
+
+// NewObjectField 创建JSON序列化为 {code, description} 的枚举字段
+func NewObjectField[R IEnumCode[TCode], TCode NumberEnumCode | string](item R) ObjectField[R, TCode] {
+	return ObjectField[R, TCode]{Field: NewField[R, TCode](item)}
+}
+
+// ParseField 按代码创建枚举字段，代码必须是枚举族注册的成员
+func ParseField[R IEnumCode[TCode], TCode NumberEnumCode | string](code TCode) (Field[R, TCode], error) {
+	var f Field[R, TCode]
+	err := f.set(code)
+	return f, err
+}
+
+// Enum 获取枚举实例，未设置时返回nil
+func (f Field[R, TCode]) Enum() R {
+	return f.item
+}
+
+// Code 获取代码，未设置时返回零值
+func (f Field[R, TCode]) Code() TCode {
+	var code TCode
+	if f.IsZero() {
+		return code
+	}
+	return f.item.Code()
+}
+
+// Description 获取描述，未设置时返回空字符串
+func (f Field[R, TCode]) Description() string {
+	if f.IsZero() {
+		return ""
+	}
+	return f.item.Description()
+}
+
+// IsZero 是否未设置
+func (f Field[R, TCode]) IsZero() bool {
+	return any(f.item) == nil
+}
+
+// Invalid 转换失败的原始输入，转换成功或未设置时返回nil
+func (f Field[R, TCode]) Invalid() any {
+	return f.invalid
+}
+
+// String 返回代码的字符串形式，转换失败时返回原始输入，未设置时返回空字符串
+func (f Field[R, TCode]) String() string {
+	if f.IsZero() {
+		if f.invalid != nil {
+			return gconv.String(f.invalid)
+		}
+		return ""
+	}
+	return gconv.String(f.item.Code())
+}
+
+// code 获取代码，转换失败时返回原始输入，未设置时返回nil
+func (f Field[R, TCode]) code() any {
+	if f.IsZero() {
+		return f.invalid
+	}
+	return f.item.Code()
+}
+
+// combinable Field 只能是单个成员，不能为位标志的组合
+func (f Field[R, TCode]) combinable() bool {
+	return false
+}
+
+// MarshalJSON 序列化为代码，未设置时为 null
+func (f Field[R, TCode]) MarshalJSON() ([]byte, error) {
+	if f.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f.item.Code())
+}
+
+// MarshalJSON 序列化为 {code, description}，未设置时为 null
+func (f ObjectField[R, TCode]) MarshalJSON() ([]byte, error) {
+	if f.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(map[string]any{
+		"code":        f.item.Code(),
+		"description": f.item.Description(),
+	})
+}
+
+// UnmarshalJSON 接受代码或包含 code 的对象，null 表示未设置
+func (f *Field[R, TCode]) UnmarshalJSON(data []byte) error {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return gerror.WrapCode(gcode.CodeInvalidParameter, err, "枚举值格式错误")
+	}
+	return f.UnmarshalValue(value)
+}
+
+// UnmarshalValue 实现 gf 的类型转换接口，接受代码、包含 code 的对象或枚举实例，nil 表示未设置
+func (f *Field[R, TCode]) UnmarshalValue(value any) error {
+	f.item, f.invalid = *new(R), nil
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case Field[R, TCode]:
+		*f = v
+		return nil
+	case *Field[R, TCode]:
+		*f = *v
+		return nil
+	case ObjectField[R, TCode]:
+		*f = v.Field
+		return nil
+	case R:
+		value = v.Code()
+	case map[string]any:
+		value = v["code"]
+	}
+
+	code, ok := ConvertCode[TCode](value)
+	if !ok {
+		f.invalid = value
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "枚举代码类型错误：%v", value)
+	}
+	if err := f.set(code); err != nil {
+		f.invalid = value
+		return err
+	}
+	return nil
+}
+
+// Scan 实现 sql.Scanner，NULL 表示未设置
+func (f *Field[R, TCode]) Scan(src any) error {
+	if b, ok := src.([]byte); ok {
+		src = string(b)
+	}
+	return f.UnmarshalValue(src)
+}
+
+// Value 实现 driver.Valuer，保存代码，未设置时为 NULL
+func (f Field[R, TCode]) Value() (driver.Value, error) {
+	if f.IsZero() {
+		return nil, nil
+	}
+	return codeValue(f.item.Code()), nil
+}
+
+// codeValue 将代码转换为 driver.Value，超出 int64 范围的无符号代码转换为十进制字符串，避免溢出为负数
+func codeValue(code any) driver.Value {
+	v := reflect.ValueOf(code)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	default:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return strconv.FormatUint(u, 10)
+		}
+		return int64(u)
+	}
+}
+
+// set 按代码设置枚举实例，代码必须是枚举族注册的成员
+func (f *Field[R, TCode]) set(code TCode) error {
+	registry, ok := registryOf[R, TCode]()
+	if !ok {
+		return gerror.NewCodef(gcode.CodeInvalidConfiguration, "枚举未注册：%s", reflect.TypeOf((*R)(nil)).Elem())
+	}
+
+	item, ok := registry.Parse(code)
+	if !ok {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "枚举 %s 不存在代码：%v", registry.Name(), code)
+	}
+
+	f.item = item
+	return nil
+}
+
+// checkEnumRule gvalid 规则 enum:枚举族名称，空值不校验，需要时请同时使用 required 规则
+func checkEnumRule(ctx context.Context, in gvalid.RuleFuncInput) error {
+	name := strings.TrimSpace(strings.TrimPrefix(in.Rule, "enum:"))
+	registry, ok := Lookup(name)
+	if !ok {
+		return gerror.NewCodef(gcode.CodeInvalidConfiguration, "校验规则 %s 的枚举未注册：%s", in.Rule, name)
+	}
+
+	value, combinable := in.Value.Val(), registry.IsFlag()
+	if field, ok := value.(enumField); ok {
+		value, combinable = field.code(), combinable && field.combinable()
+	}
+	// 关联原始请求参数校验时，与 UnmarshalValue 一致接受包含 code 的对象
+	if m, ok := value.(map[string]any); ok {
+		value = m["code"]
+	}
+	if value == nil || gconv.String(value) == "" {
+		return nil
+	}
+
+	if combinable && registry.Valid(value) {
+		return nil
+	}
+	if _, ok := registry.ParseAny(value); ok {
+		return nil
+	}
+	if in.Message != "" {
+		return gerror.NewCode(gcode.CodeValidationFailed, in.Message)
+	}
+	return gerror.NewCodef(gcode.CodeValidationFailed, "The %s value `%v` is not a valid %s", in.Field, value, name)
+}
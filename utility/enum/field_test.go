@@ -0,0 +1,246 @@
+package enum
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/gogf/gf/v2/util/gvalid"
+)
+
+// 测试使用的枚举族
+type (
+	testColor IEnumCode[int]
+	testLevel IEnumCode[string]
+	testBig   IEnumCode[uint64]
+	testPerm  IEnumCode[int]
+)
+
+var (
+	testRed           = New[testColor](1, "red")
+	testBlue          = New[testColor](2, "blue")
+	testColorRegistry = Register[testColor]("TestColor", testRed, testBlue)
+
+	testLow           = New[testLevel]("low", "低")
+	testHigh          = New[testLevel]("high", "高")
+	testLevelRegistry = Register[testLevel]("TestLevel", testLow, testHigh)
+
+	testBigOne      = New[testBig](1, "one")
+	testBigMax      = New[testBig](math.MaxUint64, "max")
+	testBigRegistry = Register[testBig]("TestBig", testBigOne, testBigMax)
+
+	testRead         = New[testPerm](1, "read")
+	testWrite        = New[testPerm](2, "write")
+	testExec         = New[testPerm](4, "exec")
+	testPermRegistry = RegisterFlags[testPerm]("TestPerm", testRead, testWrite, testExec)
+)
+
+type testColorReq struct {
+	Color  Field[testColor, int]       `json:"color"  v:"required|enum:TestColor"`
+	Level  Field[testLevel, string]    `json:"level"  v:"enum:TestLevel"`
+	Object ObjectField[testColor, int] `json:"object"`
+}
+
+func TestField_MarshalJSON(t *testing.T) {
+	cases := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "number", value: NewField[testColor](testBlue), want: `2`},
+		{name: "string", value: NewField[testLevel](testLow), want: `"low"`},
+		{name: "zero", value: Field[testColor, int]{}, want: `null`},
+		{name: "object", value: NewObjectField[testColor](testRed), want: `{"code":1,"description":"red"}`},
+		{name: "zero object", value: ObjectField[testColor, int]{}, want: `null`},
+		{name: "uint64", value: NewField[testBig](testBigMax), want: `18446744073709551615`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := json.Marshal(c.value)
+			if err != nil || string(data) != c.want {
+				t.Fatalf("Marshal() = %s, %v, want %s", data, err, c.want)
+			}
+		})
+	}
+}
+
+func TestField_UnmarshalJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		want     testColor
+		wantCode gcode.Code
+	}{
+		{name: "number", data: `2`, want: testBlue},
+		{name: "number string", data: `"2"`, want: testBlue},
+		{name: "object", data: `{"code":1,"description":"ignored"}`, want: testRed},
+		{name: "null", data: `null`},
+		{name: "not a member", data: `3`, wantCode: gcode.CodeInvalidParameter},
+		{name: "fraction", data: `1.5`, wantCode: gcode.CodeInvalidParameter},
+		{name: "wrong type", data: `true`, wantCode: gcode.CodeInvalidParameter},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var f Field[testColor, int]
+			err := json.Unmarshal([]byte(c.data), &f)
+			if c.wantCode != nil {
+				if gerror.Code(err) != c.wantCode {
+					t.Fatalf("Unmarshal() = %v, want code %v", err, c.wantCode)
+				}
+				return
+			}
+			if err != nil || f.Enum() != c.want {
+				t.Fatalf("Unmarshal() = %v, %v, want %v", f.Enum(), err, c.want)
+			}
+		})
+	}
+
+	// 结构体往返
+	req := testColorReq{Color: NewField[testColor](testRed), Level: NewField[testLevel](testHigh), Object: NewObjectField[testColor](testBlue)}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded testColorReq
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Color.Enum() != testRed || decoded.Level.Code() != "high" || decoded.Object.Description() != "blue" {
+		t.Fatalf("round trip = %s -> %+v", data, decoded)
+	}
+}
+
+func TestField_ScanValue(t *testing.T) {
+	valueCases := []struct {
+		name  string
+		value driver.Valuer
+		want  driver.Value
+	}{
+		{name: "number", value: NewField[testColor](testBlue), want: int64(2)},
+		{name: "string", value: NewField[testLevel](testLow), want: "low"},
+		{name: "zero", value: Field[testColor, int]{}, want: nil},
+		{name: "uint64", value: NewField[testBig](testBigOne), want: int64(1)},
+		{name: "uint64 above MaxInt64", value: NewField[testBig](testBigMax), want: "18446744073709551615"},
+		{name: "flag set above MaxInt64", value: FlagSetOf[testBig](math.MaxUint64), want: "18446744073709551615"},
+	}
+	for _, c := range valueCases {
+		if got, err := c.value.Value(); err != nil || got != c.want {
+			t.Fatalf("%s: Value() = %#v, %v, want %#v", c.name, got, err, c.want)
+		}
+	}
+
+	var color Field[testColor, int]
+	for _, src := range []any{int64(2), []byte("2"), "2"} {
+		if err := color.Scan(src); err != nil || color.Enum() != testBlue {
+			t.Fatalf("Scan(%#v) = %v, %v, want blue", src, color.Enum(), err)
+		}
+	}
+	if err := color.Scan(nil); err != nil || !color.IsZero() {
+		t.Fatalf("Scan(nil) = %v, %v, want zero", color.Enum(), err)
+	}
+	if err := color.Scan(int64(9)); gerror.Code(err) != gcode.CodeInvalidParameter || color.Invalid() != int64(9) {
+		t.Fatalf("Scan(9) = %v, invalid %v, want CodeInvalidParameter", err, color.Invalid())
+	}
+
+	// 保存为字符串的无符号代码可以读回
+	var big Field[testBig, uint64]
+	value, _ := NewField[testBig](testBigMax).Value()
+	if err := big.Scan([]byte(value.(string))); err != nil || big.Enum() != testBigMax {
+		t.Fatalf("Scan(%v) = %v, %v, want max", value, big.Enum(), err)
+	}
+}
+
+func TestField_Validation(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		data    g.Map
+		wantErr string // 为空时校验通过，否则为错误信息中应包含的内容
+	}{
+		{name: "valid", data: g.Map{"color": 2, "level": "low"}},
+		{name: "object input", data: g.Map{"color": g.Map{"code": 1}}},
+		{name: "missing", data: g.Map{}, wantErr: "required"},
+		{name: "not a member", data: g.Map{"color": 3}, wantErr: "`3`"},
+		{name: "not a member string", data: g.Map{"color": 1, "level": "middle"}, wantErr: "`middle`"},
+		{name: "wrong type", data: g.Map{"color": "red"}, wantErr: "`red`"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var req testColorReq
+			_ = gconv.Struct(c.data, &req)
+
+			// 仅校验结构体，及与请求参数绑定时同时关联原始参数
+			for _, validator := range []*gvalid.Validator{gvalid.New().Data(&req), gvalid.New().Data(&req).Assoc(c.data)} {
+				err := validator.Run(ctx)
+				if c.wantErr == "" {
+					if err != nil {
+						t.Fatalf("Run() = %v, want nil", err)
+					}
+					continue
+				}
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("Run() = %v, want error containing %s", err, c.wantErr)
+				}
+				if c.wantErr != "required" && strings.Contains(err.Error(), "required") {
+					t.Fatalf("Run() = %v, invalid input reported as missing", err)
+				}
+			}
+		})
+	}
+}
+
+func TestField_FlagValidation(t *testing.T) {
+	ctx := context.Background()
+
+	type permReq struct {
+		Field Field[testPerm, int] `v:"enum:TestPerm"`
+		Code  int                  `v:"enum:TestPerm"`
+	}
+
+	cases := []struct {
+		name    string
+		data    g.Map
+		wantErr bool
+	}{
+		{name: "single member", data: g.Map{"Field": 2, "Code": 2}},
+		{name: "code combination", data: g.Map{"Code": 3}},
+		{name: "field combination", data: g.Map{"Field": 3}, wantErr: true},
+		{name: "unknown bit", data: g.Map{"Code": 8}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var req permReq
+			_ = gconv.Struct(c.data, &req)
+			if err := gvalid.New().Data(&req).Run(ctx); (err != nil) != c.wantErr {
+				t.Fatalf("Run() = %v, want error %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseField(t *testing.T) {
+	if f, err := ParseField[testColor](1); err != nil || f.Enum() != testRed || f.Description() != "red" || f.String() != "1" {
+		t.Fatalf("ParseField(1) = %v, %v", f.Enum(), err)
+	}
+	if _, err := ParseField[testColor](9); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("ParseField(9) = %v, want CodeInvalidParameter", err)
+	}
+
+	// 未注册的枚举族
+	type unregistered IEnumCode[int]
+	if _, err := ParseField[unregistered](1); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("ParseField(unregistered) = %v, want CodeInvalidConfiguration", err)
+	}
+}
@@ -0,0 +1,289 @@
+package enum
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"iter"
+	"reflect"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+/*
+	位标志集合：多个按位取值的枚举成员（如验证码类型1、2、4、8）组合后的代码
+		1、FlagSet 不可变，With、Without、Union 等操作返回新的集合，可安全地在多个协程间共享
+		2、按枚举族的注册顺序遍历包含的成员，字符串形式为成员描述以 | 连接，如 register|login
+		3、JSON序列化为整数，NamedFlagSet 序列化为成员描述的数组；反序列化时两种格式均可接受
+		4、实现 sql.Scanner、driver.Valuer 及 gf 的 UnmarshalValue，并提供 WhereHas 等构建 col & ? = ? 查询条件的方法
+*/
+
+// FlagSet 不可变的位标志集合
+type FlagSet[R IEnumCode[TCode], TCode NumberEnumCode] struct {
+	bits TCode
+}
+
+// NamedFlagSet JSON序列化为成员描述数组的位标志集合
+type NamedFlagSet[R IEnumCode[TCode], TCode NumberEnumCode] struct {
+	FlagSet[R, TCode]
+}
+
+// NewFlagSet 创建包含指定成员的集合
+func NewFlagSet[R IEnumCode[TCode], TCode NumberEnumCode](items ...R) FlagSet[R, TCode] {
+	var bits TCode
+	for _, item := range items {
+		bits |= item.Code()
+	}
+	return FlagSet[R, TCode]{bits: bits}
+}
+
+// FlagSetOf 按组合后的代码创建集合
+func FlagSetOf[R IEnumCode[TCode], TCode NumberEnumCode](bits TCode) FlagSet[R, TCode] {
+	return FlagSet[R, TCode]{bits: bits}
+}
+
+// ParseFlagSet 解析字符串形式的集合：成员描述或代码以 | 或 , 分隔，如 register|login、1|2；空字符串为空集合
+func ParseFlagSet[R IEnumCode[TCode], TCode NumberEnumCode](s string) (FlagSet[R, TCode], error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '|' || r == ','
+	})
+	names := make([]any, 0, len(parts))
+	for _, part := range parts {
+		names = append(names, strings.TrimSpace(part))
+	}
+
+	var f FlagSet[R, TCode]
+	err := f.setMembers(names)
+	return f, err
+}
+
+// Bits 组合后的代码
+func (f FlagSet[R, TCode]) Bits() TCode {
+	return f.bits
+}
+
+// IsEmpty 是否为空集合
+func (f FlagSet[R, TCode]) IsEmpty() bool {
+	return f.bits == 0
+}
+
+// Has 是否包含全部指定的成员
+func (f FlagSet[R, TCode]) Has(items ...R) bool {
+	bits := NewFlagSet[R, TCode](items...).bits
+	return f.bits&bits == bits
+}
+
+// HasAny 是否包含任一指定的成员
+func (f FlagSet[R, TCode]) HasAny(items ...R) bool {
+	return f.bits&NewFlagSet[R, TCode](items...).bits != 0
+}
+
+// With 返回增加指定成员后的集合
+func (f FlagSet[R, TCode]) With(items ...R) FlagSet[R, TCode] {
+	return f.Union(NewFlagSet[R, TCode](items...))
+}
+
+// Without 返回移除指定成员后的集合
+func (f FlagSet[R, TCode]) Without(items ...R) FlagSet[R, TCode] {
+	return f.Difference(NewFlagSet[R, TCode](items...))
+}
+
+// Union 并集
+func (f FlagSet[R, TCode]) Union(other FlagSet[R, TCode]) FlagSet[R, TCode] {
+	return FlagSet[R, TCode]{bits: f.bits | other.bits}
+}
+
+// Intersect 交集
+func (f FlagSet[R, TCode]) Intersect(other FlagSet[R, TCode]) FlagSet[R, TCode] {
+	return FlagSet[R, TCode]{bits: f.bits & other.bits}
+}
+
+// Difference 差集，即包含在当前集合但不包含在 other 中的成员
+func (f FlagSet[R, TCode]) Difference(other FlagSet[R, TCode]) FlagSet[R, TCode] {
+	return FlagSet[R, TCode]{bits: f.bits &^ other.bits}
+}
+
+// Equal 是否与 other 包含相同的成员
+func (f FlagSet[R, TCode]) Equal(other FlagSet[R, TCode]) bool {
+	return f.bits == other.bits
+}
+
+// All 按枚举族的注册顺序遍历包含的成员
+func (f FlagSet[R, TCode]) All() iter.Seq[R] {
+	return func(yield func(R) bool) {
+		registry, ok := registryOf[R, TCode]()
+		if !ok {
+			return
+		}
+		for _, item := range registry.members {
+			if item.Code() != 0 && f.bits&item.Code() == item.Code() && !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// Members 按枚举族的注册顺序返回包含的成员
+func (f FlagSet[R, TCode]) Members() []R {
+	result := make([]R, 0)
+	for item := range f.All() {
+		result = append(result, item)
+	}
+	return result
+}
+
+// Len 包含的成员数量
+func (f FlagSet[R, TCode]) Len() int {
+	return len(f.Members())
+}
+
+// Unknown 不属于任何已注册成员的位，不为0时表示代码中包含未知的标志
+func (f FlagSet[R, TCode]) Unknown() TCode {
+	bits := f.bits
+	for item := range f.All() {
+		bits &^= item.Code()
+	}
+	return bits
+}
+
+// Names 按枚举族的注册顺序返回包含的成员描述
+func (f FlagSet[R, TCode]) Names() []string {
+	result := make([]string, 0)
+	for item := range f.All() {
+		result = append(result, item.Description())
+	}
+	return result
+}
+
+// String 成员描述以 | 连接，如 register|login；包含未知的标志时追加其代码
+func (f FlagSet[R, TCode]) String() string {
+	names := f.Names()
+	if unknown := f.Unknown(); unknown != 0 {
+		names = append(names, gconv.String(unknown))
+	}
+	return strings.Join(names, "|")
+}
+
+// code 组合后的代码，用于 enum 校验规则
+func (f FlagSet[R, TCode]) code() any {
+	return f.bits
+}
+
+// combinable FlagSet 为成员代码的组合
+func (f FlagSet[R, TCode]) combinable() bool {
+	return true
+}
+
+// MarshalJSON 序列化为组合后的代码
+func (f FlagSet[R, TCode]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.bits)
+}
+
+// MarshalJSON 序列化为成员描述的数组
+func (f NamedFlagSet[R, TCode]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Names())
+}
+
+// UnmarshalJSON 接受组合后的代码，或成员描述、代码组成的数组
+func (f *FlagSet[R, TCode]) UnmarshalJSON(data []byte) error {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return gerror.WrapCode(gcode.CodeInvalidParameter, err, "位标志格式错误")
+	}
+	return f.UnmarshalValue(value)
+}
+
+// UnmarshalValue 实现 gf 的类型转换接口，接受组合后的代码、成员描述或代码组成的数组、字符串形式的集合
+func (f *FlagSet[R, TCode]) UnmarshalValue(value any) error {
+	switch v := value.(type) {
+	case nil:
+		f.bits = 0
+		return nil
+	case FlagSet[R, TCode]:
+		*f = v
+		return nil
+	case NamedFlagSet[R, TCode]:
+		*f = v.FlagSet
+		return nil
+	case []any:
+		return f.setMembers(v)
+	case []string:
+		return f.setMembers(gconv.Interfaces(v))
+	case string:
+		if _, ok := ConvertCode[TCode](v); !ok {
+			parsed, err := ParseFlagSet[R, TCode](v)
+			if err != nil {
+				return err
+			}
+			*f = parsed
+			return nil
+		}
+	}
+
+	bits, ok := ConvertCode[TCode](value)
+	if !ok {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "位标志代码类型错误：%v", value)
+	}
+	f.bits = bits
+	return nil
+}
+
+// setMembers 按成员描述或代码设置集合
+func (f *FlagSet[R, TCode]) setMembers(values []any) error {
+	registry, ok := registryOf[R, TCode]()
+	if !ok {
+		return gerror.NewCodef(gcode.CodeInvalidConfiguration, "枚举未注册：%s", reflect.TypeOf((*R)(nil)).Elem())
+	}
+
+	var bits TCode
+	for _, value := range values {
+		item, ok := registry.ParseDescription(gconv.String(value))
+		if !ok {
+			var code TCode
+			if code, ok = ConvertCode[TCode](value); ok {
+				item, ok = registry.Parse(code)
+			}
+		}
+		if !ok {
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "枚举 %s 不存在成员：%v", registry.Name(), value)
+		}
+		bits |= item.Code()
+	}
+
+	f.bits = bits
+	return nil
+}
+
+// Scan 实现 sql.Scanner，NULL 为空集合
+func (f *FlagSet[R, TCode]) Scan(src any) error {
+	if b, ok := src.([]byte); ok {
+		src = string(b)
+	}
+	return f.UnmarshalValue(src)
+}
+
+// Value 实现 driver.Valuer，保存组合后的代码
+func (f FlagSet[R, TCode]) Value() (driver.Value, error) {
+	return codeValue(f.bits), nil
+}
+
+// WhereHas 查询 column 包含集合中全部成员的记录：column & bits = bits
+func (f FlagSet[R, TCode]) WhereHas(model *gdb.Model, column string) *gdb.Model {
+	return model.Where(model.QuoteWord(column)+" & ? = ?", f.bits, f.bits)
+}
+
+// WhereHasAny 查询 column 包含集合中任一成员的记录：column & bits <> 0
+func (f FlagSet[R, TCode]) WhereHasAny(model *gdb.Model, column string) *gdb.Model {
+	return model.Where(model.QuoteWord(column)+" & ? <> 0", f.bits)
+}
+
+// WhereHasNone 查询 column 不包含集合中任何成员的记录：column & bits = 0
+func (f FlagSet[R, TCode]) WhereHasNone(model *gdb.Model, column string) *gdb.Model {
+	return model.Where(model.QuoteWord(column)+" & ? = 0", f.bits)
+}
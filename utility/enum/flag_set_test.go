@@ -0,0 +1,236 @@
+package enum
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+type testPermSet = FlagSet[testPerm, int]
+
+func TestFlagSet_Algebra(t *testing.T) {
+	rw := NewFlagSet(testRead, testWrite)
+	wx := NewFlagSet(testWrite, testExec)
+
+	cases := []struct {
+		name string
+		got  testPermSet
+		want int
+	}{
+		{name: "new", got: rw, want: 3},
+		{name: "with", got: rw.With(testExec), want: 7},
+		{name: "with existing", got: rw.With(testRead), want: 3},
+		{name: "without", got: rw.Without(testRead), want: 2},
+		{name: "without missing", got: rw.Without(testExec), want: 3},
+		{name: "union", got: rw.Union(wx), want: 7},
+		{name: "intersect", got: rw.Intersect(wx), want: 2},
+		{name: "difference", got: rw.Difference(wx), want: 1},
+		{name: "empty", got: NewFlagSet[testPerm](), want: 0},
+	}
+	for _, c := range cases {
+		if c.got.Bits() != c.want {
+			t.Fatalf("%s = %d, want %d", c.name, c.got.Bits(), c.want)
+		}
+	}
+
+	// 运算返回新的集合，原集合不变
+	if rw.Bits() != 3 || wx.Bits() != 6 {
+		t.Fatalf("operands changed: %d, %d", rw.Bits(), wx.Bits())
+	}
+
+	if !rw.Has(testRead, testWrite) || rw.Has(testRead, testExec) || !rw.Has() {
+		t.Fatal("Has() mismatch")
+	}
+	if !rw.HasAny(testExec, testWrite) || rw.HasAny(testExec) || rw.HasAny() {
+		t.Fatal("HasAny() mismatch")
+	}
+	if !rw.Equal(FlagSetOf[testPerm](3)) || rw.Equal(wx) {
+		t.Fatal("Equal() mismatch")
+	}
+	if !NewFlagSet[testPerm]().IsEmpty() || rw.IsEmpty() {
+		t.Fatal("IsEmpty() mismatch")
+	}
+}
+
+func TestFlagSet_Members(t *testing.T) {
+	cases := []struct {
+		name        string
+		set         testPermSet
+		wantNames   []string
+		wantUnknown int
+		wantString  string
+	}{
+		{name: "registration order", set: NewFlagSet(testExec, testRead), wantNames: []string{"read", "exec"}, wantString: "read|exec"},
+		{name: "all", set: FlagSetOf[testPerm](7), wantNames: []string{"read", "write", "exec"}, wantString: "read|write|exec"},
+		{name: "unknown bits", set: FlagSetOf[testPerm](2 | 8 | 16), wantNames: []string{"write"}, wantUnknown: 24, wantString: "write|24"},
+		{name: "only unknown", set: FlagSetOf[testPerm](8), wantNames: []string{}, wantUnknown: 8, wantString: "8"},
+		{name: "empty", set: testPermSet{}, wantNames: []string{}, wantString: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.set.Names(); !slices.Equal(got, c.wantNames) {
+				t.Fatalf("Names() = %v, want %v", got, c.wantNames)
+			}
+			if c.set.Len() != len(c.wantNames) || len(c.set.Members()) != len(c.wantNames) {
+				t.Fatalf("Len() = %d, want %d", c.set.Len(), len(c.wantNames))
+			}
+			if c.set.Unknown() != c.wantUnknown {
+				t.Fatalf("Unknown() = %d, want %d", c.set.Unknown(), c.wantUnknown)
+			}
+			if c.set.String() != c.wantString {
+				t.Fatalf("String() = %q, want %q", c.set.String(), c.wantString)
+			}
+		})
+	}
+
+	// 遍历可提前结束
+	var first []testPerm
+	for item := range FlagSetOf[testPerm](7).All() {
+		first = append(first, item)
+		break
+	}
+	if len(first) != 1 || first[0] != testRead {
+		t.Fatalf("All() break = %v, want [read]", first)
+	}
+}
+
+func TestParseFlagSet(t *testing.T) {
+	cases := []struct {
+		s    string
+		want int
+	}{
+		{s: "read|write", want: 3},
+		{s: "read, exec", want: 5},
+		{s: "1|2|4", want: 7},
+		{s: "write|4", want: 6},
+		{s: "", want: 0},
+		{s: "||", want: 0},
+	}
+	for _, c := range cases {
+		if f, err := ParseFlagSet[testPerm](c.s); err != nil || f.Bits() != c.want {
+			t.Fatalf("ParseFlagSet(%q) = %d, %v, want %d", c.s, f.Bits(), err, c.want)
+		}
+	}
+
+	for _, s := range []string{"read|delete", "3", "8"} {
+		if _, err := ParseFlagSet[testPerm](s); gerror.Code(err) != gcode.CodeInvalidParameter {
+			t.Fatalf("ParseFlagSet(%q) = %v, want CodeInvalidParameter", s, err)
+		}
+	}
+
+	type unregistered IEnumCode[int]
+	if _, err := ParseFlagSet[unregistered]("1"); gerror.Code(err) != gcode.CodeInvalidConfiguration {
+		t.Fatalf("ParseFlagSet(unregistered) = %v, want CodeInvalidConfiguration", err)
+	}
+}
+
+func TestFlagSet_JSON(t *testing.T) {
+	set := NewFlagSet(testRead, testExec)
+
+	data, err := json.Marshal(set)
+	if err != nil || string(data) != `5` {
+		t.Fatalf("Marshal() = %s, %v, want 5", data, err)
+	}
+	named := NamedFlagSet[testPerm, int]{FlagSet: set}
+	if data, err = json.Marshal(named); err != nil || string(data) != `["read","exec"]` {
+		t.Fatalf("Marshal(named) = %s, %v, want [\"read\",\"exec\"]", data, err)
+	}
+	if data, err = json.Marshal(NamedFlagSet[testPerm, int]{}); err != nil || string(data) != `[]` {
+		t.Fatalf("Marshal(empty named) = %s, %v, want []", data, err)
+	}
+
+	cases := []struct {
+		name     string
+		data     string
+		want     int
+		wantCode gcode.Code
+	}{
+		{name: "code", data: `5`, want: 5},
+		{name: "code string", data: `"5"`, want: 5},
+		{name: "names", data: `["read","exec"]`, want: 5},
+		{name: "codes", data: `[1,4]`, want: 5},
+		{name: "mixed", data: `["read",2]`, want: 3},
+		{name: "name string", data: `"read|write"`, want: 3},
+		{name: "null", data: `null`, want: 0},
+		{name: "empty array", data: `[]`, want: 0},
+		{name: "unknown name", data: `["delete"]`, wantCode: gcode.CodeInvalidParameter},
+		{name: "combined code in array", data: `[3]`, wantCode: gcode.CodeInvalidParameter},
+		{name: "fraction", data: `1.5`, wantCode: gcode.CodeInvalidParameter},
+		{name: "wrong type", data: `true`, wantCode: gcode.CodeInvalidParameter},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, target := range []interface{ Bits() int }{&testPermSet{}, &NamedFlagSet[testPerm, int]{}} {
+				err := json.Unmarshal([]byte(c.data), target)
+				if c.wantCode != nil {
+					if gerror.Code(err) != c.wantCode {
+						t.Fatalf("Unmarshal(%T) = %v, want code %v", target, err, c.wantCode)
+					}
+					continue
+				}
+				if err != nil || target.Bits() != c.want {
+					t.Fatalf("Unmarshal(%T) = %d, %v, want %d", target, target.Bits(), err, c.want)
+				}
+			}
+		})
+	}
+
+	// 两种格式均可往返
+	type permReq struct {
+		Perm  testPermSet                 `json:"perm"`
+		Named NamedFlagSet[testPerm, int] `json:"named"`
+	}
+	req := permReq{Perm: set, Named: named}
+	if data, err = json.Marshal(req); err != nil || string(data) != `{"perm":5,"named":["read","exec"]}` {
+		t.Fatalf("Marshal(req) = %s, %v", data, err)
+	}
+	var decoded permReq
+	if err = json.Unmarshal(data, &decoded); err != nil || !decoded.Perm.Equal(set) || !decoded.Named.Equal(set) {
+		t.Fatalf("round trip = %+v, %v", decoded, err)
+	}
+
+	// gf 的类型转换
+	var converted permReq
+	if err = gconv.Struct(map[string]any{"perm": "read|write", "named": []string{"exec"}}, &converted); err != nil {
+		t.Fatal(err)
+	}
+	if converted.Perm.Bits() != 3 || converted.Named.Bits() != 4 {
+		t.Fatalf("gconv.Struct() = %+v", converted)
+	}
+}
+
+func TestFlagSet_ScanValue(t *testing.T) {
+	if value, err := NewFlagSet(testRead, testWrite).Value(); err != nil || value != int64(3) {
+		t.Fatalf("Value() = %#v, %v, want 3", value, err)
+	}
+	if value, err := (testPermSet{}).Value(); err != nil || value != int64(0) {
+		t.Fatalf("Value(empty) = %#v, %v, want 0", value, err)
+	}
+
+	cases := []struct {
+		src  any
+		want int
+	}{
+		{src: int64(6), want: 6},
+		{src: []byte("5"), want: 5},
+		{src: "7", want: 7},
+		{src: nil, want: 0},
+	}
+	for _, c := range cases {
+		f := FlagSetOf[testPerm](1)
+		if err := f.Scan(c.src); err != nil || f.Bits() != c.want {
+			t.Fatalf("Scan(%#v) = %d, %v, want %d", c.src, f.Bits(), err, c.want)
+		}
+	}
+
+	var f testPermSet
+	if err := f.Scan(1.5); gerror.Code(err) != gcode.CodeInvalidParameter {
+		t.Fatalf("Scan(1.5) = %v, want CodeInvalidParameter", err)
+	}
+}
@@ -0,0 +1,141 @@
+package enum
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gogf/gf/v2/i18n/gi18n"
+)
+
+/*
+	枚举描述的国际化：描述作为程序中使用的标识（如 setPassword），展示给用户的名称通过 gi18n 按请求的语言翻译
+		1、DescriptionCtx(ctx) 按 ctx 中的语言（gi18n.WithLanguage，未设置时为 i18n 管理器的默认语言）依次查找：
+			a、NewI18n 指定的翻译Key
+			b、enum.枚举族名称.描述，如 enum.CaptchaType.setPassword，注册到枚举族的成员自动使用该Key
+		   不以描述本身作为翻译Key，避免描述与其他模块的翻译Key相同时被误翻译
+		2、ctx 的语言中均未找到时按默认语言再查找一次，仍未找到时返回描述
+		3、ToMap 增加 label 字段，为默认语言的翻译；需要按请求语言输出时使用 ToMapCtx(ctx)
+*/
+
+// IEnumI18n 支持国际化描述的枚举成员
+type IEnumI18n interface {
+	// DescriptionCtx 按 ctx 中的语言返回翻译后的描述，未配置翻译时返回 Description
+	DescriptionCtx(ctx context.Context) string
+	// ToMapCtx 与 ToMap 相同，label 字段按 ctx 中的语言翻译
+	ToMapCtx(ctx context.Context) map[string]any
+}
+
+// I18nKeyPrefix 按枚举族自动生成的翻译Key的前缀
+const I18nKeyPrefix = "enum."
+
+var (
+	i18nMu      sync.RWMutex
+	i18nManager *gi18n.Manager
+)
+
+// SetI18n 设置翻译枚举描述使用的 i18n 管理器，默认使用 gi18n.Instance()
+func SetI18n(manager *gi18n.Manager) {
+	i18nMu.Lock()
+	defer i18nMu.Unlock()
+
+	i18nManager = manager
+}
+
+// GetI18n 获取翻译枚举描述使用的 i18n 管理器
+func GetI18n() *gi18n.Manager {
+	i18nMu.RLock()
+	defer i18nMu.RUnlock()
+
+	if i18nManager == nil {
+		return gi18n.Instance()
+	}
+	return i18nManager
+}
+
+// NewI18n 创建一个使用指定翻译Key的枚举类型实例
+func NewI18n[R IEnumCode[TCode], TCode NumberEnumCode | string](code TCode, description string, i18nKey string) R {
+	var result interface{}
+	result = &enumType[TCode, interface{}]{
+		code:        code,
+		description: description,
+		i18nKey:     i18nKey,
+	}
+	return result.(R)
+}
+
+// DescriptionCtx 按 ctx 中的语言返回枚举成员翻译后的描述，成员不支持国际化时返回 Description
+func DescriptionCtx(ctx context.Context, item IEnumMember) string {
+	if v, ok := item.(IEnumI18n); ok {
+		return v.DescriptionCtx(ctx)
+	}
+	return item.Description()
+}
+
+// ToMapCtx 按 ctx 中的语言返回枚举成员的映射格式，成员不支持国际化时返回 ToMap
+func ToMapCtx(ctx context.Context, item IEnumMember) map[string]any {
+	if v, ok := item.(IEnumI18n); ok {
+		return v.ToMapCtx(ctx)
+	}
+	return item.ToMap()
+}
+
+// i18nMember 注册时记录所属枚举族的成员，用于生成翻译Key
+type i18nMember interface {
+	setFamily(name string)
+}
+
+// translate 依次按翻译Key查找，ctx 的语言中未找到时按默认语言查找，均未找到时返回空字符串
+func translate(ctx context.Context, keys ...string) string {
+	manager := GetI18n()
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if content := manager.GetContent(ctx, key); content != "" {
+			return content
+		}
+	}
+
+	if gi18n.LanguageFromCtx(ctx) == "" {
+		return ""
+	}
+	return translate(gi18n.WithLanguage(ctx, ""), keys...)
+}
+
+// i18nKeys 枚举成员的翻译Key，按查找顺序排列
+func (e *enumType[TCode, TData]) i18nKeys() []string {
+	keys := make([]string, 0, 2)
+	if e.i18nKey != "" {
+		keys = append(keys, e.i18nKey)
+	}
+	if e.family != "" {
+		keys = append(keys, I18nKeyPrefix+e.family+"."+e.description)
+	}
+	return keys
+}
+
+// setFamily 记录所属的枚举族
+func (e *enumType[TCode, TData]) setFamily(name string) {
+	e.family = name
+}
+
+// DescriptionCtx 按 ctx 中的语言返回翻译后的描述，未配置翻译时返回 Description
+func (e *enumType[TCode, TData]) DescriptionCtx(ctx context.Context) string {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if label := translate(ctx, e.i18nKeys()...); label != "" {
+		return label
+	}
+	return e.description
+}
+
+// ToMapCtx 与 ToMap 相同，label 字段按 ctx 中的语言翻译
+func (e *enumType[TCode, TData]) ToMapCtx(ctx context.Context) map[string]any {
+	return map[string]any{
+		"code":        e.code,
+		"description": e.description,
+		"label":       e.DescriptionCtx(ctx),
+		"data":        e.data,
+	}
+}
@@ -0,0 +1,92 @@
+package enum
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gogf/gf/v2/i18n/gi18n"
+)
+
+type testI18nState IEnumCode[int]
+
+var (
+	testEnabled  = NewI18n[testI18nState](1, "enabled", "test.state.enabled")
+	testDisabled = New[testI18nState](2, "disabled")
+	testArchived = NewI18n[testI18nState](3, "archived", "test.state.archived")
+	testDeleted  = New[testI18nState](4, "deleted")
+	testUnknown  = New[testI18nState](5, "common.unknown")
+	_            = Register[testI18nState]("TestI18nState", testEnabled, testDisabled, testArchived, testDeleted, testUnknown)
+)
+
+// useI18n 使用 testdata/i18n 中的翻译，默认语言为 zh-CN，测试结束时恢复
+func useI18n(t *testing.T) {
+	t.Helper()
+
+	SetI18n(gi18n.New(gi18n.Options{Path: filepath.Join("testdata", "i18n"), Language: "zh-CN"}))
+	t.Cleanup(func() {
+		SetI18n(nil)
+	})
+}
+
+func TestDescriptionCtx(t *testing.T) {
+	useI18n(t)
+
+	var (
+		zh = gi18n.WithLanguage(context.Background(), "zh-CN")
+		en = gi18n.WithLanguage(context.Background(), "en")
+		ja = gi18n.WithLanguage(context.Background(), "ja")
+	)
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		item testI18nState
+		want string
+	}{
+		{name: "i18n key", ctx: en, item: testEnabled, want: "Active"},
+		{name: "i18n key default language", ctx: zh, item: testEnabled, want: "已启用"},
+		{name: "family key", ctx: en, item: testDisabled, want: "Disabled"},
+		{name: "missing i18n key falls back to family key", ctx: zh, item: testArchived, want: "归档"},
+		{name: "missing language falls back to default language", ctx: en, item: testArchived, want: "归档"},
+		{name: "unsupported language", ctx: ja, item: testDisabled, want: "停用"},
+		{name: "no language", ctx: context.Background(), item: testDisabled, want: "停用"},
+		{name: "nil ctx", ctx: nil, item: testDisabled, want: "停用"},
+		{name: "no translation", ctx: en, item: testDeleted, want: "deleted"},
+		{name: "description is not a key", ctx: zh, item: testUnknown, want: "common.unknown"},
+		{name: "unregistered", ctx: en, item: New[testI18nState](2, "disabled"), want: "disabled"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := DescriptionCtx(c.ctx, c.item); got != c.want {
+				t.Fatalf("DescriptionCtx() = %s, want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestToMapCtx(t *testing.T) {
+	useI18n(t)
+	en := gi18n.WithLanguage(context.Background(), "en")
+
+	m := testDisabled.ToMap()
+	if m["code"] != 2 || m["description"] != "disabled" || m["label"] != "停用" {
+		t.Fatalf("ToMap() = %v, want label in the default language", m)
+	}
+	if m = ToMapCtx(en, testDisabled); m["description"] != "disabled" || m["label"] != "Disabled" {
+		t.Fatalf("ToMapCtx(en) = %v, want label Disabled", m)
+	}
+	if m = ToMapCtx(en, testDeleted); m["label"] != "deleted" {
+		t.Fatalf("ToMapCtx(en) = %v, want label deleted", m)
+	}
+
+	// 未设置 i18n 管理器时使用 gi18n.Instance()，没有翻译时为描述
+	SetI18n(nil)
+	if GetI18n() != gi18n.Instance() {
+		t.Fatal("GetI18n() is not gi18n.Instance()")
+	}
+	if got := DescriptionCtx(en, testDeleted); got != "deleted" {
+		t.Fatalf("DescriptionCtx() = %s, want deleted", got)
+	}
+}
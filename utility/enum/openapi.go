@@ -0,0 +1,255 @@
+package enum
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/net/goai"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+/*
+	OpenAPI文档的枚举信息：
+		1、Field、ObjectField、FlagSet、NamedFlagSet 类型的字段按所属枚举族生成 enum 及 x-enum-varnames（成员描述或代码）、x-enum-descriptions（翻译后的名称）
+		2、使用 v:"enum:枚举族名称" 校验规则的普通字段及请求参数同样生成枚举信息
+		3、位标志枚举的代码可以组合，不生成 enum，通过 x-enum-flags 列出各个位
+		4、枚举信息只写入输出的JSON，不修改服务共享的 goai 文档，避免与框架输出文档的请求并发读写
+*/
+
+// 字段类型
+const (
+	fieldKindCode         = iota // Field，序列化为代码
+	fieldKindObject              // ObjectField，序列化为 {code, description}
+	fieldKindFlagSet             // FlagSet，序列化为组合后的代码
+	fieldKindNamedFlagSet        // NamedFlagSet，序列化为成员描述的数组
+)
+
+// fieldType 枚举族对应的字段类型
+type fieldType struct {
+	registry IRegistry
+	kind     int
+}
+
+// openApiExtensionPrefix 枚举信息扩展字段的前缀
+const openApiExtensionPrefix = "x-enum-"
+
+// BindOpenApi 服务的OpenAPI文档输出时补充枚举信息，需要在服务启动前调用，服务未配置 openapiPath 时不处理；
+// 文档在服务启动后首次请求时生成并缓存，之后的请求直接输出缓存的内容
+func BindOpenApi(s *ghttp.Server) {
+	path := s.GetOpenApiPath()
+	if path == "" {
+		return
+	}
+
+	var (
+		once    sync.Once
+		content []byte
+		err     error
+	)
+	s.BindHookHandler(path, ghttp.HookBeforeServe, func(r *ghttp.Request) {
+		once.Do(func() {
+			content, err = OpenApiJson(s.GetOpenApi())
+		})
+		if err != nil {
+			r.Response.WriteStatusExit(500, err.Error())
+		}
+		r.Response.Header().Set("Content-Type", "application/json")
+		r.Response.Write(content)
+		r.ExitAll()
+	})
+}
+
+// OpenApiJson 输出补充了枚举信息的OpenAPI文档，不修改 oai
+func OpenApiJson(oai *goai.OpenApiV3) ([]byte, error) {
+	content, err := json.Marshal(oai)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	if err = json.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	enrichDocument(oai, doc)
+
+	return json.Marshal(doc)
+}
+
+// enrichDocument 按 oai 中的结构及校验规则，为输出的JSON文档 doc 补充枚举信息
+func enrichDocument(oai *goai.OpenApiV3, doc map[string]any) {
+	registryMu.RLock()
+	schemaNames := make(map[string]fieldType, len(fieldTypeMap))
+	for t, item := range fieldTypeMap {
+		schemaNames[openApiSchemaName(t, oai.Config.IgnorePkgPath)] = item
+	}
+	registryMu.RUnlock()
+
+	schemas := jsonObject(jsonObject(doc["components"])["schemas"])
+	for name, ref := range oai.Components.Schemas.Map() {
+		if item, ok := schemaNames[name]; ok && schemas != nil {
+			schemas[name] = item.schema()
+		} else {
+			enrichSchema(ref.Value, jsonObject(schemas[name]))
+		}
+	}
+
+	paths := jsonObject(doc["paths"])
+	for name, path := range oai.Paths {
+		node := jsonObject(paths[name])
+		for method, operation := range map[string]*goai.Operation{
+			"get": path.Get, "post": path.Post, "put": path.Put, "delete": path.Delete, "patch": path.Patch,
+			"head": path.Head, "options": path.Options, "connect": path.Connect, "trace": path.Trace,
+		} {
+			if operation == nil {
+				continue
+			}
+			parameters, _ := jsonObject(node[method])["parameters"].([]any)
+			for i, parameter := range operation.Parameters {
+				if parameter.Ref != "" || parameter.Value == nil || parameter.Value.Schema == nil || i >= len(parameters) {
+					continue
+				}
+				schema := parameter.Value.Schema
+				if schema.Ref == "" {
+					enrichSchema(schema.Value, jsonObject(jsonObject(parameters[i])["schema"]))
+				}
+			}
+		}
+	}
+}
+
+// enrichSchema 为使用 enum 校验规则的字段补充枚举信息，并处理对象的属性及数组的元素；引用其他结构的字段不处理
+func enrichSchema(schema *goai.Schema, node map[string]any) {
+	if schema == nil || node == nil {
+		return
+	}
+
+	for _, rule := range gstr.Split(schema.ValidationRules, "|") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(rule), "enum:"); ok {
+			if registry, ok := Lookup(strings.TrimSpace(name)); ok {
+				applyEnum(node, registry, registry.IsFlag())
+			}
+		}
+	}
+
+	if schema.Properties != nil {
+		properties := jsonObject(node["properties"])
+		for name, ref := range schema.Properties.Map() {
+			if ref.Ref == "" {
+				enrichSchema(ref.Value, jsonObject(properties[name]))
+			}
+		}
+	}
+	if schema.Items != nil && schema.Items.Ref == "" {
+		enrichSchema(schema.Items.Value, jsonObject(node["items"]))
+	}
+}
+
+// jsonObject 将JSON文档中的节点断言为对象，不是对象时返回 nil
+func jsonObject(value any) map[string]any {
+	m, _ := value.(map[string]any)
+	return m
+}
+
+// schema 字段类型对应的OpenAPI结构
+func (f fieldType) schema() map[string]any {
+	switch f.kind {
+	case fieldKindObject:
+		code := map[string]any{"type": openApiType(f.registry), "description": "代码"}
+		applyEnum(code, f.registry, false)
+
+		return map[string]any{
+			"type":        goai.TypeObject,
+			"description": f.registry.Name(),
+			"properties": map[string]any{
+				"code":        code,
+				"description": map[string]any{"type": goai.TypeString, "description": "描述"},
+			},
+		}
+
+	case fieldKindNamedFlagSet:
+		items := make([]any, 0, f.registry.Len())
+		for _, member := range f.registry.Members() {
+			items = append(items, member.Description())
+		}
+		return map[string]any{
+			"type":        goai.TypeArray,
+			"description": f.registry.Name() + "，成员描述的数组",
+			"items":       map[string]any{"type": goai.TypeString, "enum": items},
+		}
+
+	default:
+		// Field 只能是单个成员，仅 FlagSet 为位标志的组合
+		schema := map[string]any{"type": openApiType(f.registry)}
+		applyEnum(schema, f.registry, f.kind == fieldKindFlagSet)
+		return schema
+	}
+}
+
+// applyEnum 设置枚举信息：combinable 为 false 时为 enum、x-enum-varnames、x-enum-descriptions，为 true 时（位标志组合）为 x-enum-flags
+func applyEnum(node map[string]any, registry IRegistry, combinable bool) {
+	var (
+		ctx          = context.Background()
+		codes        = make([]any, 0, registry.Len())
+		names        = make([]string, 0, registry.Len())
+		descriptions = make([]string, 0, registry.Len())
+		flags        = make([]map[string]any, 0, registry.Len())
+		summary      = make([]string, 0, registry.Len())
+	)
+	for _, member := range registry.Members() {
+		code := member.ToMap()["code"]
+		codes = append(codes, code)
+		names = append(names, varName(member))
+		descriptions = append(descriptions, DescriptionCtx(ctx, member))
+		flags = append(flags, map[string]any{"code": code, "description": member.Description()})
+		summary = append(summary, gconv.String(code)+" "+DescriptionCtx(ctx, member))
+	}
+
+	description, _ := node["description"].(string)
+	if !strings.Contains(description, registry.Name()+"：") {
+		node["description"] = strings.TrimSpace(description + " " + registry.Name() + "：" + strings.Join(summary, "，"))
+	}
+
+	if combinable {
+		delete(node, "enum")
+		node[openApiExtensionPrefix+"flags"] = flags
+		return
+	}
+
+	node["enum"] = codes
+	node[openApiExtensionPrefix+"varnames"] = names
+	node[openApiExtensionPrefix+"descriptions"] = descriptions
+}
+
+// asciiIdentifier 可用作前端常量名称的描述
+var asciiIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// varName x-enum-varnames 中的成员名称：描述为标识符时使用描述（如 setPassword），否则使用代码（如 failover）
+func varName(member IEnumMember) string {
+	if asciiIdentifier.MatchString(member.Description()) {
+		return member.Description()
+	}
+	return gconv.String(member.ToMap()["code"])
+}
+
+// openApiType 枚举族代码对应的OpenAPI类型
+func openApiType(registry IRegistry) string {
+	if registry.CodeType() == reflect.String.String() {
+		return goai.TypeString
+	}
+	return goai.TypeInteger
+}
+
+// openApiSchemaName 与 goai 相同的结构名称，如 github.com.kysion.base-library.utility.enum.Field[...]
+func openApiSchemaName(t reflect.Type, ignorePkgPath bool) string {
+	name := strings.TrimLeft(t.String(), "*")
+	if pkgPath := t.PkgPath(); pkgPath != "" && pkgPath != "." && !ignorePkgPath {
+		name = strings.ReplaceAll(pkgPath, "/", ".") + gstr.SubStrFrom(name, ".")
+	}
+	return gstr.ReplaceByMap(name, map[string]string{" ": "", "{": "", "}": ""})
+}
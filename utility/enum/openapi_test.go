@@ -0,0 +1,195 @@
+package enum
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/net/goai"
+)
+
+type testOpenApiReq struct {
+	g.Meta `path:"/enum/test" method:"get"`
+	Level  string `json:"level" v:"required|enum:TestLevel"`
+}
+
+type testOpenApiRes struct {
+	Color  Field[testColor, int]       `json:"color"`
+	Object ObjectField[testColor, int] `json:"object"`
+	Perm   FlagSet[testPerm, int]      `json:"perm"`
+	Named  NamedFlagSet[testPerm, int] `json:"named"`
+	Code   int                         `json:"code" v:"enum:TestColor"`
+}
+
+func testOpenApiHandler(ctx context.Context, req *testOpenApiReq) (*testOpenApiRes, error) {
+	return &testOpenApiRes{}, nil
+}
+
+// testOpenApi 生成包含 testOpenApiHandler 的文档
+func testOpenApi(t *testing.T) *goai.OpenApiV3 {
+	t.Helper()
+
+	oai := goai.New()
+	if err := oai.Add(goai.AddInput{Path: "/enum/test", Method: http.MethodGet, Object: testOpenApiHandler}); err != nil {
+		t.Fatal(err)
+	}
+	return oai
+}
+
+// decodeOpenApi 解析输出的文档，返回响应结构的属性及请求参数 level 的结构
+func decodeOpenApi(t *testing.T, content []byte) (properties map[string]any, level map[string]any) {
+	t.Helper()
+
+	var doc map[string]any
+	if err := json.Unmarshal(content, &doc); err != nil {
+		t.Fatal(err)
+	}
+	schemas := jsonObject(jsonObject(doc["components"])["schemas"])
+	for name, schema := range schemas {
+		if name == openApiSchemaName(reflect.TypeOf(testOpenApiRes{}), false) {
+			properties = jsonObject(jsonObject(schema)["properties"])
+		}
+	}
+	parameters, _ := jsonObject(jsonObject(jsonObject(doc["paths"])["/enum/test"])["get"])["parameters"].([]any)
+	for _, parameter := range parameters {
+		if jsonObject(parameter)["name"] == "level" {
+			level = jsonObject(jsonObject(parameter)["schema"])
+		}
+	}
+	if properties == nil || level == nil {
+		t.Fatalf("document = %s, response schema or level parameter not found", content)
+	}
+	return properties, level
+}
+
+// resolve 返回属性的结构，引用其他结构时返回被引用的结构
+func resolve(content []byte, property any) map[string]any {
+	ref, _ := jsonObject(property)["$ref"].(string)
+	if ref == "" {
+		return jsonObject(property)
+	}
+	var doc map[string]any
+	_ = json.Unmarshal(content, &doc)
+	return jsonObject(jsonObject(jsonObject(doc["components"])["schemas"])[ref[len("#/components/schemas/"):]])
+}
+
+// stringsOf 将JSON数组转换为字符串数组
+func stringsOf(value any) []string {
+	var result []string
+	items, _ := value.([]any)
+	for _, item := range items {
+		s, _ := item.(string)
+		result = append(result, s)
+	}
+	return result
+}
+
+func TestOpenApiJson(t *testing.T) {
+	oai := testOpenApi(t)
+	before, err := json.Marshal(oai)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := OpenApiJson(oai)
+	if err != nil {
+		t.Fatal(err)
+	}
+	properties, level := decodeOpenApi(t, content)
+
+	cases := []struct {
+		name          string
+		schema        map[string]any
+		wantType      string
+		wantEnum      string
+		wantVarnames  []string
+		wantFlags     bool
+		wantItemsEnum []string
+	}{
+		{name: "field", schema: resolve(content, properties["color"]), wantType: goai.TypeInteger, wantEnum: "[1,2]", wantVarnames: []string{"red", "blue"}},
+		{name: "object field code", schema: jsonObject(jsonObject(resolve(content, properties["object"])["properties"])["code"]), wantType: goai.TypeInteger, wantEnum: "[1,2]", wantVarnames: []string{"red", "blue"}},
+		{name: "flag set", schema: resolve(content, properties["perm"]), wantType: goai.TypeInteger, wantFlags: true},
+		{name: "named flag set", schema: resolve(content, properties["named"]), wantType: goai.TypeArray, wantItemsEnum: []string{"read", "write", "exec"}},
+		{name: "enum rule", schema: jsonObject(properties["code"]), wantType: goai.TypeInteger, wantEnum: "[1,2]", wantVarnames: []string{"red", "blue"}},
+		{name: "enum rule parameter", schema: level, wantType: goai.TypeString, wantEnum: `["low","high"]`, wantVarnames: []string{"low", "high"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.schema == nil || c.schema["type"] != c.wantType {
+				t.Fatalf("schema = %v, want type %s", c.schema, c.wantType)
+			}
+			if enum, _ := json.Marshal(c.schema["enum"]); c.wantEnum != "" && string(enum) != c.wantEnum {
+				t.Fatalf("enum = %s, want %s", enum, c.wantEnum)
+			}
+			if got := stringsOf(c.schema["x-enum-varnames"]); !slices.Equal(got, c.wantVarnames) {
+				t.Fatalf("x-enum-varnames = %v, want %v", got, c.wantVarnames)
+			}
+			if _, ok := c.schema["x-enum-flags"]; ok != c.wantFlags {
+				t.Fatalf("x-enum-flags = %v, want %v", c.schema["x-enum-flags"], c.wantFlags)
+			}
+			if c.wantFlags {
+				if _, ok := c.schema["enum"]; ok {
+					t.Fatalf("flag set schema has enum: %v", c.schema["enum"])
+				}
+			}
+			if c.wantItemsEnum != nil {
+				if got := stringsOf(jsonObject(c.schema["items"])["enum"]); !slices.Equal(got, c.wantItemsEnum) {
+					t.Fatalf("items enum = %v, want %v", got, c.wantItemsEnum)
+				}
+			}
+		})
+	}
+
+	// 不修改原文档，多次输出的结果相同
+	after, err := json.Marshal(oai)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Fatalf("OpenApiJson modified the document:\nbefore %s\nafter  %s", before, after)
+	}
+	if again, _ := OpenApiJson(oai); string(again) != string(content) {
+		t.Fatal("OpenApiJson output changed between calls")
+	}
+}
+
+func TestBindOpenApi(t *testing.T) {
+	s, url := startTestServer(t, func(s *ghttp.Server) {
+		s.SetOpenApiPath("/api.json")
+		s.Group("/", func(group *ghttp.RouterGroup) {
+			group.Bind(testOpenApiHandler)
+		})
+		BindOpenApi(s)
+	})
+
+	status, header, body := get(t, url+"/api.json", nil)
+	if status != http.StatusOK || header.Get("Content-Type") != "application/json" {
+		t.Fatalf("GET = %d, Content-Type %s", status, header.Get("Content-Type"))
+	}
+	properties, level := decodeOpenApi(t, []byte(body))
+	if got := stringsOf(level["x-enum-varnames"]); !slices.Equal(got, []string{"low", "high"}) {
+		t.Fatalf("level x-enum-varnames = %v", got)
+	}
+	if got := stringsOf(jsonObject(properties["code"])["x-enum-varnames"]); !slices.Equal(got, []string{"red", "blue"}) {
+		t.Fatalf("code x-enum-varnames = %v", got)
+	}
+
+	// 服务共享的文档不包含枚举信息，再次请求输出相同的内容
+	shared, err := json.Marshal(s.GetOpenApi())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(shared), openApiExtensionPrefix) {
+		t.Fatalf("shared document was modified: %s", shared)
+	}
+	if _, _, again := get(t, url+"/api.json", nil); again != body {
+		t.Fatal("second request returned different content")
+	}
+}
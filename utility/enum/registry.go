@@ -0,0 +1,301 @@
+package enum
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+/*
+	枚举注册表：
+		1、每个枚举族（如 CaptchaTypeEnum）在初始化时通过 Register 注册一次全部成员，成员的代码重复时 panic
+		2、通过 Parse、ParseDescription 将请求中的代码或描述转换为注册的枚举实例
+		3、所有枚举族按名称登记到全局注册表，可通过 Lookup、Registries 按名称查找，用于参数校验及字典导出
+*/
+
+// IRegistry 与代码类型无关的枚举族，用于按名称查找的场景
+type IRegistry interface {
+	// Name 枚举族名称，如 CaptchaType
+	Name() string
+	// Len 成员数量
+	Len() int
+	// ParseAny 将任意类型的代码（如请求参数、数据库字段）转换为枚举实例，代码类型不匹配或未注册时返回false
+	ParseAny(code any) (any, bool)
+	// Members 按注册顺序返回所有成员
+	Members() []IEnumMember
+	// IsFlag 是否为位标志枚举，成员可组合为 FlagSet
+	IsFlag() bool
+	// Valid 代码是否有效：位标志枚举为成员代码的组合，否则为已注册的成员
+	Valid(code any) bool
+	// CodeType 代码类型，如 int、string
+	CodeType() string
+}
+
+// IEnumMember 与代码类型无关的枚举成员
+type IEnumMember interface {
+	ToMap() map[string]any
+	Description() string
+}
+
+// Registry 枚举族的成员注册表，注册后只读，可并发使用
+type Registry[R IEnumCode[TCode], TCode NumberEnumCode | string] struct {
+	name    string
+	members []R
+	byCode  map[TCode]R
+	byDesc  map[string]R
+	flag    bool
+}
+
+var (
+	registryMu      sync.RWMutex
+	registryMap     = make(map[string]IRegistry)       // key为枚举族名称
+	typeRegistryMap = make(map[reflect.Type]IRegistry) // key为枚举族的成员类型，如 CaptchaTypeEnum
+	fieldTypeMap    = make(map[reflect.Type]fieldType) // key为枚举族对应的字段类型，如 Field[CaptchaTypeEnum, int]，用于OpenAPI文档
+)
+
+// Register 注册枚举族的全部成员，成员的代码重复或枚举族名称重复时 panic；描述重复时 ParseDescription 返回先注册的成员。
+// 通常在包级变量初始化时调用：var captchaTypeRegistry = enum.Register("CaptchaType", CaptchaType.Register, ...)
+func Register[R IEnumCode[TCode], TCode NumberEnumCode | string](name string, members ...R) *Registry[R, TCode] {
+	r := &Registry[R, TCode]{
+		name:    name,
+		members: make([]R, 0, len(members)),
+		byCode:  make(map[TCode]R, len(members)),
+		byDesc:  make(map[string]R, len(members)),
+	}
+
+	for _, item := range members {
+		if exists, ok := r.byCode[item.Code()]; ok {
+			panic(fmt.Sprintf("枚举 %s 的代码重复：%v（%s、%s）", name, item.Code(), exists.Description(), item.Description()))
+		}
+		if member, ok := any(item).(i18nMember); ok {
+			member.setFamily(name)
+		}
+		r.members = append(r.members, item)
+		r.byCode[item.Code()] = item
+		if _, ok := r.byDesc[item.Description()]; !ok {
+			r.byDesc[item.Description()] = item
+		}
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registryMap[name]; ok {
+		panic(fmt.Sprintf("枚举 %s 重复注册", name))
+	}
+	registryMap[name] = r
+	typeRegistryMap[reflect.TypeOf((*R)(nil)).Elem()] = r
+	fieldTypeMap[reflect.TypeOf(Field[R, TCode]{})] = fieldType{registry: r, kind: fieldKindCode}
+	fieldTypeMap[reflect.TypeOf(ObjectField[R, TCode]{})] = fieldType{registry: r, kind: fieldKindObject}
+
+	return r
+}
+
+// RegisterFlags 注册位标志枚举族的全部成员，每个成员的代码必须是不同的单个位（1、2、4…），否则 panic
+func RegisterFlags[R IEnumCode[TCode], TCode NumberEnumCode](name string, members ...R) *Registry[R, TCode] {
+	for _, item := range members {
+		if code := item.Code(); code == 0 || code&(code-1) != 0 {
+			panic(fmt.Sprintf("位标志枚举 %s 的成员代码必须是单个位：%v（%s）", name, code, item.Description()))
+		}
+	}
+
+	r := Register[R, TCode](name, members...)
+	r.flag = true
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	fieldTypeMap[reflect.TypeOf(FlagSet[R, TCode]{})] = fieldType{registry: r, kind: fieldKindFlagSet}
+	fieldTypeMap[reflect.TypeOf(NamedFlagSet[R, TCode]{})] = fieldType{registry: r, kind: fieldKindNamedFlagSet}
+
+	return r
+}
+
+// registryOf 按成员类型查找已注册的枚举族
+func registryOf[R IEnumCode[TCode], TCode NumberEnumCode | string]() (*Registry[R, TCode], bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	r, ok := typeRegistryMap[reflect.TypeOf((*R)(nil)).Elem()].(*Registry[R, TCode])
+	return r, ok
+}
+
+// Lookup 按名称查找已注册的枚举族
+func Lookup(name string) (IRegistry, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	r, ok := registryMap[name]
+	return r, ok
+}
+
+// Registries 按名称排序返回所有已注册的枚举族
+func Registries() []IRegistry {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	result := make([]IRegistry, 0, len(registryMap))
+	for _, r := range registryMap {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name() < result[j].Name()
+	})
+
+	return result
+}
+
+// Name 枚举族名称
+func (r *Registry[R, TCode]) Name() string {
+	return r.name
+}
+
+// Len 成员数量
+func (r *Registry[R, TCode]) Len() int {
+	return len(r.members)
+}
+
+// Parse 按代码查找成员
+func (r *Registry[R, TCode]) Parse(code TCode) (R, bool) {
+	item, ok := r.byCode[code]
+	return item, ok
+}
+
+// ParseDescription 按描述查找成员
+func (r *Registry[R, TCode]) ParseDescription(description string) (R, bool) {
+	item, ok := r.byDesc[description]
+	return item, ok
+}
+
+// Contains 代码是否为已注册的成员
+func (r *Registry[R, TCode]) Contains(code TCode) bool {
+	_, ok := r.byCode[code]
+	return ok
+}
+
+// All 按注册顺序返回所有成员
+func (r *Registry[R, TCode]) All() []R {
+	return append([]R(nil), r.members...)
+}
+
+// Values 按注册顺序返回所有成员的代码
+func (r *Registry[R, TCode]) Values() []TCode {
+	result := make([]TCode, 0, len(r.members))
+	for _, item := range r.members {
+		result = append(result, item.Code())
+	}
+	return result
+}
+
+// IsFlag 是否为位标志枚举
+func (r *Registry[R, TCode]) IsFlag() bool {
+	return r.flag
+}
+
+// CodeType 代码类型，如 int、string
+func (r *Registry[R, TCode]) CodeType() string {
+	return reflect.TypeOf((*TCode)(nil)).Elem().Kind().String()
+}
+
+// Valid 代码是否有效：位标志枚举为成员代码的组合，否则为已注册的成员
+func (r *Registry[R, TCode]) Valid(code any) bool {
+	c, ok := ConvertCode[TCode](code)
+	if !ok {
+		return false
+	}
+	if !r.flag {
+		return r.Contains(c)
+	}
+
+	// 位标志枚举的代码类型均为整数，按无符号整数比较各个位
+	var mask uint64
+	for _, item := range r.members {
+		mask |= flagBits(item.Code())
+	}
+	return flagBits(c)&^mask == 0
+}
+
+// flagBits 将整数代码转换为 uint64 的位
+func flagBits(code any) uint64 {
+	v := reflect.ValueOf(code)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	}
+	return 0
+}
+
+// ParseAny 将任意类型的代码转换为枚举实例
+func (r *Registry[R, TCode]) ParseAny(code any) (any, bool) {
+	c, ok := ConvertCode[TCode](code)
+	if !ok {
+		return nil, false
+	}
+	return r.Parse(c)
+}
+
+// Members 按注册顺序返回所有成员
+func (r *Registry[R, TCode]) Members() []IEnumMember {
+	result := make([]IEnumMember, 0, len(r.members))
+	for _, item := range r.members {
+		result = append(result, item)
+	}
+	return result
+}
+
+// ConvertCode 将任意类型的值严格转换为代码类型：数值类型的代码只接受整数或整数字符串，且不能超出代码类型的范围
+func ConvertCode[TCode NumberEnumCode | string](value any) (TCode, bool) {
+	var code TCode
+	if value == nil {
+		return code, false
+	}
+	if v, ok := value.(TCode); ok {
+		return v, true
+	}
+
+	target := reflect.ValueOf(&code).Elem()
+	switch target.Kind() {
+	case reflect.String:
+		switch value.(type) {
+		case string, []byte, fmt.Stringer:
+			target.SetString(gconv.String(value))
+			return code, true
+		}
+		return code, false
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(numberString(value), 10, 64)
+		if err != nil || target.OverflowInt(i) {
+			return code, false
+		}
+		target.SetInt(i)
+		return code, true
+
+	default:
+		u, err := strconv.ParseUint(numberString(value), 10, 64)
+		if err != nil || target.OverflowUint(u) {
+			return code, false
+		}
+		target.SetUint(u)
+		return code, true
+	}
+}
+
+// numberString 将数值转换为字符串，浮点数仅在为整数时保留（JSON解码的数字为 float64）
+func numberString(value any) string {
+	switch v := value.(type) {
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return ""
+	}
+	return gconv.String(value)
+}
@@ -0,0 +1,251 @@
+package enum
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// 描述重复的枚举族，注册表为全局的，在包级别注册使测试可以重复运行
+type testDupDesc IEnumCode[int]
+
+var (
+	testDupDescFirst    = New[testDupDesc](1, "same")
+	testDupDescRegistry = Register[testDupDesc]("TestDupDesc", testDupDescFirst, New[testDupDesc](2, "same"))
+)
+
+// mustPanic fn 必须 panic，且信息中包含 contains
+func mustPanic(t *testing.T, contains string, fn func()) {
+	t.Helper()
+
+	defer func() {
+		t.Helper()
+		r := recover()
+		if r == nil {
+			t.Fatalf("no panic, want %q", contains)
+		}
+		if msg := fmt.Sprint(r); !strings.Contains(msg, contains) {
+			t.Fatalf("panic %q, want %q", msg, contains)
+		}
+	}()
+	fn()
+}
+
+func TestRegister_Panics(t *testing.T) {
+	type dupCode IEnumCode[int]
+	type dupName IEnumCode[int]
+	type badFlag IEnumCode[int]
+
+	cases := []struct {
+		name     string
+		contains string
+		register func()
+	}{
+		{
+			name:     "duplicate code",
+			contains: "代码重复",
+			register: func() {
+				Register[dupCode]("TestDupCode", New[dupCode](1, "a"), New[dupCode](1, "b"))
+			},
+		},
+		{
+			name:     "duplicate name",
+			contains: "重复注册",
+			register: func() {
+				Register[dupName]("TestColor", New[dupName](1, "a"))
+			},
+		},
+		{
+			name:     "flag with multiple bits",
+			contains: "单个位",
+			register: func() {
+				RegisterFlags[badFlag]("TestBadFlag", New[badFlag](1, "a"), New[badFlag](3, "b"))
+			},
+		},
+		{
+			name:     "flag zero",
+			contains: "单个位",
+			register: func() {
+				RegisterFlags[badFlag]("TestBadFlag", New[badFlag](0, "none"))
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mustPanic(t, c.contains, c.register)
+		})
+	}
+
+	// panic 的注册不会登记到全局注册表
+	for _, name := range []string{"TestDupCode", "TestBadFlag"} {
+		if _, ok := Lookup(name); ok {
+			t.Fatalf("Lookup(%s) found a registry that panicked", name)
+		}
+	}
+	if r, _ := Lookup("TestColor"); r != testColorRegistry {
+		t.Fatal("duplicate name replaced the registered TestColor")
+	}
+}
+
+func TestRegistry_Parse(t *testing.T) {
+	if item, ok := testColorRegistry.Parse(2); !ok || item != testBlue {
+		t.Fatalf("Parse(2) = %v, %v, want blue", item, ok)
+	}
+	if _, ok := testColorRegistry.Parse(3); ok {
+		t.Fatal("Parse(3) found an unregistered code")
+	}
+	if item, ok := testLevelRegistry.Parse("high"); !ok || item != testHigh {
+		t.Fatalf("Parse(high) = %v, %v, want high", item, ok)
+	}
+
+	if item, ok := testColorRegistry.ParseDescription("red"); !ok || item != testRed {
+		t.Fatalf("ParseDescription(red) = %v, %v, want red", item, ok)
+	}
+	if item, ok := testLevelRegistry.ParseDescription("低"); !ok || item != testLow {
+		t.Fatalf("ParseDescription(低) = %v, %v, want low", item, ok)
+	}
+	for _, description := range []string{"Red", "", "green"} {
+		if _, ok := testColorRegistry.ParseDescription(description); ok {
+			t.Fatalf("ParseDescription(%q) found a member", description)
+		}
+	}
+
+	// 描述重复时返回先注册的成员
+	if item, ok := testDupDescRegistry.ParseDescription("same"); !ok || item != testDupDescFirst {
+		t.Fatalf("ParseDescription(same) = %v, want the first member", item)
+	}
+	if testDupDescRegistry.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", testDupDescRegistry.Len())
+	}
+}
+
+func TestRegistry_Members(t *testing.T) {
+	if got := testColorRegistry.Values(); !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("Values = %v, want [1 2]", got)
+	}
+	if got := testColorRegistry.All(); len(got) != 2 || got[0] != testRed || got[1] != testBlue {
+		t.Fatalf("All = %v, want [red blue]", got)
+	}
+	// All 返回副本，修改不影响注册表
+	testColorRegistry.All()[0] = testBlue
+	if testColorRegistry.All()[0] != testRed {
+		t.Fatal("All returned the internal slice")
+	}
+
+	members := testLevelRegistry.Members()
+	if len(members) != 2 || members[0].Description() != "低" || members[1].Description() != "高" {
+		t.Fatalf("Members = %v, want [低 高]", members)
+	}
+
+	cases := []struct {
+		registry IRegistry
+		name     string
+		codeType string
+		flag     bool
+	}{
+		{registry: testColorRegistry, name: "TestColor", codeType: "int"},
+		{registry: testLevelRegistry, name: "TestLevel", codeType: "string"},
+		{registry: testBigRegistry, name: "TestBig", codeType: "uint64"},
+		{registry: testPermRegistry, name: "TestPerm", codeType: "int", flag: true},
+	}
+	for _, c := range cases {
+		if c.registry.Name() != c.name || c.registry.CodeType() != c.codeType || c.registry.IsFlag() != c.flag {
+			t.Fatalf("%s = name %s, codeType %s, flag %v", c.name, c.registry.Name(), c.registry.CodeType(), c.registry.IsFlag())
+		}
+	}
+}
+
+func TestRegistry_ParseAny(t *testing.T) {
+	cases := []struct {
+		registry IRegistry
+		code     any
+		want     any
+	}{
+		{registry: testColorRegistry, code: 2, want: testBlue},
+		{registry: testColorRegistry, code: "2", want: testBlue},
+		{registry: testColorRegistry, code: float64(2), want: testBlue},
+		{registry: testColorRegistry, code: json.Number("1"), want: testRed},
+		{registry: testColorRegistry, code: []byte("1"), want: testRed},
+		{registry: testColorRegistry, code: uint8(1), want: testRed},
+		{registry: testColorRegistry, code: 1.5},
+		{registry: testColorRegistry, code: true},
+		{registry: testColorRegistry, code: "red"},
+		{registry: testColorRegistry, code: 3},
+		{registry: testColorRegistry, code: nil},
+		{registry: testLevelRegistry, code: "low", want: testLow},
+		{registry: testLevelRegistry, code: 1},
+		{registry: testBigRegistry, code: "18446744073709551615", want: testBigMax},
+		{registry: testBigRegistry, code: -1},
+	}
+
+	for _, c := range cases {
+		item, ok := c.registry.ParseAny(c.code)
+		if ok != (c.want != nil) || (ok && item != c.want) {
+			t.Fatalf("%s.ParseAny(%#v) = %v, %v, want %v", c.registry.Name(), c.code, item, ok, c.want)
+		}
+	}
+}
+
+func TestRegistry_Valid(t *testing.T) {
+	cases := []struct {
+		registry IRegistry
+		code     any
+		want     bool
+	}{
+		{registry: testColorRegistry, code: 2, want: true},
+		{registry: testColorRegistry, code: 3},
+		{registry: testColorRegistry, code: "x"},
+		{registry: testPermRegistry, code: 0, want: true},
+		{registry: testPermRegistry, code: 4, want: true},
+		{registry: testPermRegistry, code: 7, want: true},
+		{registry: testPermRegistry, code: "3", want: true},
+		{registry: testPermRegistry, code: 8},
+		{registry: testPermRegistry, code: 9},
+		{registry: testPermRegistry, code: -1},
+	}
+
+	for _, c := range cases {
+		if got := c.registry.Valid(c.code); got != c.want {
+			t.Fatalf("%s.Valid(%#v) = %v, want %v", c.registry.Name(), c.code, got, c.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if r, ok := Lookup("TestLevel"); !ok || r != testLevelRegistry {
+		t.Fatalf("Lookup(TestLevel) = %v, %v", r, ok)
+	}
+	if _, ok := Lookup("NotRegistered"); ok {
+		t.Fatal("Lookup(NotRegistered) found a registry")
+	}
+
+	var names []string
+	for _, r := range Registries() {
+		names = append(names, r.Name())
+	}
+	if !sort.StringsAreSorted(names) || !slices.Contains(names, "TestColor") || !slices.Contains(names, "TestPerm") {
+		t.Fatalf("Registries = %v, want sorted names including test registries", names)
+	}
+}
+
+func TestConvertCode(t *testing.T) {
+	if v, ok := ConvertCode[int8](127); !ok || v != 127 {
+		t.Fatalf("ConvertCode[int8](127) = %v, %v", v, ok)
+	}
+	if _, ok := ConvertCode[int8](128); ok {
+		t.Fatal("ConvertCode[int8](128) did not detect overflow")
+	}
+	if _, ok := ConvertCode[uint](-1); ok {
+		t.Fatal("ConvertCode[uint](-1) accepted a negative code")
+	}
+	if _, ok := ConvertCode[int](float64(1e20)); ok {
+		t.Fatal("ConvertCode[int](1e20) did not detect overflow")
+	}
+	if v, ok := ConvertCode[string](json.Number("12")); !ok || v != "12" {
+		t.Fatalf("ConvertCode[string](json.Number) = %q, %v", v, ok)
+	}
+}
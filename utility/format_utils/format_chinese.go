@@ -3,6 +3,7 @@ package format_utils
 import (
 	"fmt"
 	"github.com/mozillazg/go-pinyin"
+	"slices"
 )
 
 // ChineseToPinyin 将中文转为拼音返回
@@ -31,11 +32,29 @@ func ChineseToPinyin(chineseString string) string {
 		}
 	}
 
-	fmt.Println(joinedPinyin)
-
 	return joinedPinyin
 }
 
+// ChineseToPinyinHeteronyms 将中文转为拼音返回，每个汉字包含其所有不重复的读音，如 银行 ----》 [[yin] [xing hang heng]]
+func ChineseToPinyinHeteronyms(chineseString string) [][]string {
+	pinyinConverter := pinyin.NewArgs()
+	pinyinConverter.Style = pinyin.Normal
+	pinyinConverter.Heteronym = true
+
+	pinyinList := pinyin.Convert(chineseString, &pinyinConverter)
+	for i, v := range pinyinList {
+		words := make([]string, 0, len(v))
+		for _, word := range v {
+			if !slices.Contains(words, word) {
+				words = append(words, word)
+			}
+		}
+		pinyinList[i] = words
+	}
+
+	return pinyinList
+}
+
 func test() {
 	// 中文字符串
 	chineseString := "你好，世界"
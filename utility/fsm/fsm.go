@@ -0,0 +1,443 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/kysion/base-library/base_hook"
+	"github.com/kysion/base-library/utility/enum"
+)
+
+/*
+	基于枚举的状态机：
+		1、状态及事件均为枚举成员（enum.IEnumCode），通过 Permit 声明 事件 + 源状态 → 目标状态 的迁移，同一源状态的同一事件重复声明时 panic
+		2、迁移可附加守卫（Guard）及动作（Action），守卫返回错误时拒绝迁移，动作返回错误时迁移失败
+		3、无效迁移、守卫拒绝、动作失败、持久化失败及乐观锁冲突均返回 TransitionError，可通过 errors.Is 判断 ErrInvalidTransition 等错误
+		4、每次成功的迁移均通过 base_hook 发布 TransitionEvent，业务类型为 fsm.状态机名称
+		5、Apply 在数据库事务内执行守卫、动作及状态列的条件更新，见 persist.go
+		6、DOT 导出 Graphviz 格式的状态图
+*/
+
+var (
+	ErrInvalidTransition = gerror.NewCode(gcode.CodeInvalidOperation, "当前状态不允许该事件")
+	ErrGuardRejected     = gerror.NewCode(gcode.CodeBusinessValidationFailed, "不满足状态迁移的条件")
+	ErrActionFailed      = gerror.NewCode(gcode.CodeOperationFailed, "状态迁移的动作执行失败")
+	ErrStaleState        = gerror.NewCode(gcode.CodeInvalidOperation, "状态已被修改，请刷新后重试")
+	ErrPersistFailed     = gerror.NewCode(gcode.CodeDbOperationError, "状态迁移的持久化失败")
+)
+
+// TransitionError 状态迁移失败的错误
+type TransitionError struct {
+	Machine string // 状态机名称
+	From    string // 源状态的描述
+	Event   string // 事件的描述
+	To      string // 目标状态的描述，无效迁移时为空
+	Kind    error  // 错误类型：ErrInvalidTransition、ErrGuardRejected、ErrActionFailed、ErrPersistFailed、ErrStaleState
+	Err     error  // 守卫、动作或数据库返回的错误
+}
+
+func (e *TransitionError) Error() string {
+	msg := fmt.Sprintf("状态机 %s 在状态 %s 处理事件 %s 失败：%s", e.Machine, e.From, e.Event, gerror.Current(e.Kind).Error())
+	if e.Err != nil {
+		msg += "，" + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap 使 errors.Is 可以同时判断错误类型及守卫、动作、数据库返回的错误
+func (e *TransitionError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Code 返回错误类型的错误码，使 gerror.Code 可以识别
+func (e *TransitionError) Code() gcode.Code {
+	return gerror.Code(e.Kind)
+}
+
+// Transition 正在执行的状态迁移，传递给守卫及动作
+type Transition[S any, E any, T any] struct {
+	Machine string // 状态机名称
+	From    S      // 源状态
+	To      S      // 目标状态
+	Event   E      // 事件
+	Entity  T      // 迁移的业务对象
+}
+
+// Guard 守卫，返回错误时拒绝迁移，错误信息作为拒绝的原因
+type Guard[S any, E any, T any] func(ctx context.Context, t *Transition[S, E, T]) error
+
+// Action 动作，在迁移时执行，返回错误时迁移失败；通过 Apply 迁移时在同一数据库事务内执行
+type Action[S any, E any, T any] func(ctx context.Context, t *Transition[S, E, T]) error
+
+// TransitionEvent 迁移完成后发布的Hook消息
+type TransitionEvent[SC enum.NumberEnumCode | string, EC enum.NumberEnumCode | string] struct {
+	Machine    string      `json:"machine"`
+	Id         string      `json:"id,omitempty"` // 业务对象的ID，通过 Apply 迁移时设置
+	From       SC          `json:"from"`
+	To         SC          `json:"to"`
+	Event      EC          `json:"event"`
+	OccurredAt *gtime.Time `json:"occurredAt"`
+}
+
+// Subscription 迁移事件的订阅条件，为空的条件不限制
+type Subscription[SC enum.NumberEnumCode | string, EC enum.NumberEnumCode | string] struct {
+	To     []SC // 目标状态
+	Events []EC // 事件
+}
+
+// Rule 声明的状态迁移
+type Rule[S enum.IEnumCode[SC], E enum.IEnumCode[EC], T any, SC enum.NumberEnumCode | string, EC enum.NumberEnumCode | string] struct {
+	From    S
+	To      S
+	Event   E
+	guards  []Guard[S, E, T]
+	actions []Action[S, E, T]
+}
+
+// Option 状态机选项
+type Option struct {
+	NetMessage    bool   // 迁移事件是否同时投递给其他服务，通过 Apply 迁移时在事务内写入发件箱
+	IdColumn      string // Apply 使用的主键列，默认为 id
+	StateColumn   string // Apply 使用的状态列，默认为 state
+	VersionColumn string // Apply 使用的版本列，为空时仅以状态列作为乐观锁条件
+}
+
+// Machine 状态机，声明完成后可并发使用
+type Machine[S enum.IEnumCode[SC], E enum.IEnumCode[EC], T any, SC enum.NumberEnumCode | string, EC enum.NumberEnumCode | string] struct {
+	mu      sync.RWMutex
+	name    string
+	option  Option
+	initial S
+	rules   []*Rule[S, E, T, SC, EC]
+	byState map[SC]map[EC]*Rule[S, E, T, SC, EC]
+	actions []Action[S, E, T] // 所有迁移完成后执行的动作
+	hook    *base_hook.TypedHook[Subscription[SC, EC], TransitionEvent[SC, EC]]
+}
+
+// New 创建状态机，name 用于错误信息、Hook业务类型（fsm.name）及状态图名称，在进程内必须唯一，重复创建同名状态机时 panic；
+// 通常在包级变量初始化时调用：var orderMachine = fsm.New[OrderStateEnum, OrderEventEnum, *entity.Order]("Order")
+func New[S enum.IEnumCode[SC], E enum.IEnumCode[EC], T any, SC enum.NumberEnumCode | string, EC enum.NumberEnumCode | string](name string, option ...Option) *Machine[S, E, T, SC, EC] {
+	m := &Machine[S, E, T, SC, EC]{
+		name:    name,
+		byState: make(map[SC]map[EC]*Rule[S, E, T, SC, EC]),
+		hook:    base_hook.NewTypedHook[Subscription[SC, EC], TransitionEvent[SC, EC]]("fsm." + name),
+	}
+	if len(option) > 0 {
+		m.option = option[0]
+	}
+	if m.option.IdColumn == "" {
+		m.option.IdColumn = "id"
+	}
+	if m.option.StateColumn == "" {
+		m.option.StateColumn = "state"
+	}
+	return m
+}
+
+// Name 状态机名称
+func (m *Machine[S, E, T, SC, EC]) Name() string {
+	return m.name
+}
+
+// Initial 设置初始状态，用于状态图
+func (m *Machine[S, E, T, SC, EC]) Initial(state S) *Machine[S, E, T, SC, EC] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.initial = state
+	return m
+}
+
+// Permit 声明事件 event 使状态从 from 迁移到 to，可同时声明多个源状态；同一源状态的同一事件重复声明时 panic
+func (m *Machine[S, E, T, SC, EC]) Permit(event E, to S, from ...S) *Rules[S, E, T, SC, EC] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rules := &Rules[S, E, T, SC, EC]{}
+	for _, state := range from {
+		events, ok := m.byState[state.Code()]
+		if !ok {
+			events = make(map[EC]*Rule[S, E, T, SC, EC])
+			m.byState[state.Code()] = events
+		}
+		if exists, ok := events[event.Code()]; ok {
+			panic(fmt.Sprintf("状态机 %s 的迁移重复声明：%s 在状态 %s 已迁移到 %s",
+				m.name, event.Description(), state.Description(), exists.To.Description(),
+			))
+		}
+
+		rule := &Rule[S, E, T, SC, EC]{From: state, To: to, Event: event}
+		events[event.Code()] = rule
+		m.rules = append(m.rules, rule)
+		rules.items = append(rules.items, rule)
+	}
+	return rules
+}
+
+// OnTransition 注册所有迁移均执行的动作，在各迁移自身的动作之后执行
+func (m *Machine[S, E, T, SC, EC]) OnTransition(action ...Action[S, E, T]) *Machine[S, E, T, SC, EC] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.actions = append(m.actions, action...)
+	return m
+}
+
+// Rules Permit 声明的一组迁移，用于附加守卫及动作
+type Rules[S enum.IEnumCode[SC], E enum.IEnumCode[EC], T any, SC enum.NumberEnumCode | string, EC enum.NumberEnumCode | string] struct {
+	items []*Rule[S, E, T, SC, EC]
+}
+
+// Guard 附加守卫，按声明顺序执行，任一守卫返回错误时拒绝迁移
+func (r *Rules[S, E, T, SC, EC]) Guard(guard ...Guard[S, E, T]) *Rules[S, E, T, SC, EC] {
+	for _, item := range r.items {
+		item.guards = append(item.guards, guard...)
+	}
+	return r
+}
+
+// Action 附加动作，按声明顺序执行
+func (r *Rules[S, E, T, SC, EC]) Action(action ...Action[S, E, T]) *Rules[S, E, T, SC, EC] {
+	for _, item := range r.items {
+		item.actions = append(item.actions, action...)
+	}
+	return r
+}
+
+// Rule 获取源状态下事件对应的迁移
+func (m *Machine[S, E, T, SC, EC]) Rule(from S, event E) (*Rule[S, E, T, SC, EC], bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rule, ok := m.byState[from.Code()][event.Code()]
+	return rule, ok
+}
+
+// Can 源状态下是否声明了事件对应的迁移，不执行守卫
+func (m *Machine[S, E, T, SC, EC]) Can(from S, event E) bool {
+	_, ok := m.Rule(from, event)
+	return ok
+}
+
+// Events 源状态下声明的所有事件，按声明顺序排列
+func (m *Machine[S, E, T, SC, EC]) Events(from S) []E {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]E, 0)
+	for _, rule := range m.rules {
+		if rule.From.Code() == from.Code() {
+			result = append(result, rule.Event)
+		}
+	}
+	return result
+}
+
+// Rules 所有声明的迁移，按声明顺序排列
+func (m *Machine[S, E, T, SC, EC]) Rules() []*Rule[S, E, T, SC, EC] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]*Rule[S, E, T, SC, EC](nil), m.rules...)
+}
+
+// Fire 在内存中执行迁移：依次执行守卫及动作，成功后发布迁移事件并返回目标状态；需要持久化时使用 Apply
+func (m *Machine[S, E, T, SC, EC]) Fire(ctx context.Context, from S, event E, entity T) (S, error) {
+	t, rule, err := m.begin(ctx, from, event, entity)
+	if err != nil {
+		return from, err
+	}
+	if err = m.execute(ctx, t, rule); err != nil {
+		return from, err
+	}
+
+	m.publish(ctx, t, "")
+	return t.To, nil
+}
+
+// begin 查找迁移并执行守卫
+func (m *Machine[S, E, T, SC, EC]) begin(ctx context.Context, from S, event E, entity T) (*Transition[S, E, T], *Rule[S, E, T, SC, EC], error) {
+	rule, ok := m.Rule(from, event)
+	if !ok {
+		return nil, nil, m.newError(from, event, nil, ErrInvalidTransition, nil)
+	}
+
+	t := &Transition[S, E, T]{
+		Machine: m.name,
+		From:    from,
+		To:      rule.To,
+		Event:   event,
+		Entity:  entity,
+	}
+	for _, guard := range rule.guards {
+		if err := guard(ctx, t); err != nil {
+			return nil, nil, m.newError(from, event, &rule.To, ErrGuardRejected, err)
+		}
+	}
+
+	return t, rule, nil
+}
+
+// execute 执行迁移自身的动作及所有迁移均执行的动作
+func (m *Machine[S, E, T, SC, EC]) execute(ctx context.Context, t *Transition[S, E, T], rule *Rule[S, E, T, SC, EC]) error {
+	m.mu.RLock()
+	actions := append(append([]Action[S, E, T](nil), rule.actions...), m.actions...)
+	m.mu.RUnlock()
+
+	for _, action := range actions {
+		if err := action(ctx, t); err != nil {
+			return m.newError(t.From, t.Event, &t.To, ErrActionFailed, err)
+		}
+	}
+	return nil
+}
+
+// newError 创建迁移失败的错误
+func (m *Machine[S, E, T, SC, EC]) newError(from S, event E, to *S, kind error, err error) *TransitionError {
+	e := &TransitionError{
+		Machine: m.name,
+		From:    from.Description(),
+		Event:   event.Description(),
+		Kind:    kind,
+		Err:     err,
+	}
+	if to != nil {
+		e.To = (*to).Description()
+	}
+	return e
+}
+
+// Hook 迁移事件的Hook，可直接订阅或发布
+func (m *Machine[S, E, T, SC, EC]) Hook() *base_hook.TypedHook[Subscription[SC, EC], TransitionEvent[SC, EC]] {
+	return m.hook
+}
+
+// Subscribe 订阅迁移事件，filter 为空时订阅所有迁移
+func (m *Machine[S, E, T, SC, EC]) Subscribe(filter Subscription[SC, EC], handler base_hook.HookHandler[TransitionEvent[SC, EC]]) {
+	m.hook.Subscribe(filter, handler)
+}
+
+// event 构建迁移事件
+func (m *Machine[S, E, T, SC, EC]) event(t *Transition[S, E, T], id any) TransitionEvent[SC, EC] {
+	return TransitionEvent[SC, EC]{
+		Machine:    m.name,
+		Id:         gconv.String(id),
+		From:       t.From.Code(),
+		To:         t.To.Code(),
+		Event:      t.Event.Code(),
+		OccurredAt: gtime.Now(),
+	}
+}
+
+// typedOption 按订阅条件匹配迁移事件的发布选项
+func (m *Machine[S, E, T, SC, EC]) typedOption(event TransitionEvent[SC, EC], netMessage bool) base_hook.TypedOption[Subscription[SC, EC]] {
+	return base_hook.TypedOption[Subscription[SC, EC]]{
+		NetMessage: netMessage,
+		Filter: func(filter Subscription[SC, EC]) bool {
+			return (len(filter.To) == 0 || contains(filter.To, event.To)) &&
+				(len(filter.Events) == 0 || contains(filter.Events, event.Event))
+		},
+	}
+}
+
+// publish 发布迁移事件，迁移已完成，订阅者的错误仅记录日志
+func (m *Machine[S, E, T, SC, EC]) publish(ctx context.Context, t *Transition[S, E, T], id any) {
+	event := m.event(t, id)
+	if err := m.hook.Publish(ctx, event, m.typedOption(event, m.option.NetMessage)); err != nil {
+		g.Log().Warningf(ctx, "状态机 %s 的迁移事件处理失败：%v", m.name, err)
+	}
+}
+
+// DOT 导出 Graphviz 格式的状态图，节点为状态的代码，标签为状态的描述，边的标签为事件的描述，终止状态以双圆圈表示
+func (m *Machine[S, E, T, SC, EC]) DOT() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var (
+		buf    strings.Builder
+		states = make(map[SC]S)
+		order  = make([]SC, 0)
+	)
+	addState := func(state S) {
+		if _, ok := states[state.Code()]; !ok {
+			states[state.Code()] = state
+			order = append(order, state.Code())
+		}
+	}
+	if any(m.initial) != nil {
+		addState(m.initial)
+	}
+	for _, rule := range m.rules {
+		addState(rule.From)
+		addState(rule.To)
+	}
+
+	fmt.Fprintf(&buf, "digraph %s {\n", dotQuote(m.name))
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=circle];\n")
+	if any(m.initial) != nil {
+		buf.WriteString("  __start [shape=point];\n")
+		fmt.Fprintf(&buf, "  __start -> %s;\n", dotQuote(gconv.String(m.initial.Code())))
+	}
+	for _, code := range order {
+		shape := ""
+		if len(m.byState[code]) == 0 {
+			shape = ", shape=doublecircle"
+		}
+		fmt.Fprintf(&buf, "  %s [label=%s%s];\n", dotQuote(gconv.String(code)), dotQuote(states[code].Description()), shape)
+	}
+
+	// 相同源状态及目标状态的多个事件合并为一条边
+	type edge struct{ from, to string }
+	labels := make(map[edge][]string)
+	edges := make([]edge, 0)
+	for _, rule := range m.rules {
+		e := edge{gconv.String(rule.From.Code()), gconv.String(rule.To.Code())}
+		if _, ok := labels[e]; !ok {
+			edges = append(edges, e)
+		}
+		label := rule.Event.Description()
+		if len(rule.guards) > 0 {
+			label += " [guard]"
+		}
+		labels[e] = append(labels[e], dotEscape(label))
+	}
+	for _, e := range edges {
+		sort.Strings(labels[e])
+		fmt.Fprintf(&buf, "  %s -> %s [label=\"%s\"];\n", dotQuote(e.from), dotQuote(e.to), strings.Join(labels[e], `\n`))
+	}
+	buf.WriteString("}\n")
+
+	return buf.String()
+}
+
+// dotQuote 转义并加引号
+func dotQuote(s string) string {
+	return `"` + dotEscape(s) + `"`
+}
+
+// dotEscape 转义双引号及反斜杠
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// contains 切片是否包含指定的值
+func contains[V comparable](items []V, value V) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
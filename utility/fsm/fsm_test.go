@@ -0,0 +1,172 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kysion/base-library/utility/enum"
+)
+
+type orderState enum.IEnumCode[int]
+type orderEvent enum.IEnumCode[string]
+
+var (
+	created = enum.New[orderState](0, "已创建")
+	paid    = enum.New[orderState](1, "已支付")
+	closed  = enum.New[orderState](2, "已关闭")
+
+	payEvent   = enum.New[orderEvent]("pay", "支付")
+	closeEvent = enum.New[orderEvent]("close", "关闭")
+)
+
+// order 迁移的业务对象
+type order struct {
+	Id     int64
+	State  int
+	Amount int
+}
+
+// machineSeq 状态机名称的序号，状态机名称在进程内必须唯一
+var machineSeq atomic.Int64
+
+// newMachine 创建订单状态机：已创建 -支付-> 已支付，已创建、已支付 -关闭-> 已关闭，支付要求金额大于0
+func newMachine(t *testing.T, option ...Option) *Machine[orderState, orderEvent, *order, int, string] {
+	t.Helper()
+
+	name := fmt.Sprintf("%s%d", t.Name(), machineSeq.Add(1))
+	m := New[orderState, orderEvent, *order, int, string](name, option...).Initial(created)
+	m.Permit(payEvent, paid, created).Guard(func(ctx context.Context, t *Transition[orderState, orderEvent, *order]) error {
+		if t.Entity.Amount <= 0 {
+			return errors.New("金额必须大于0")
+		}
+		return nil
+	})
+	m.Permit(closeEvent, closed, created, paid)
+	return m
+}
+
+func TestFire(t *testing.T) {
+	m := newMachine(t)
+
+	var actions []string
+	m.Permit(payEvent, paid, closed).Action(func(ctx context.Context, t *Transition[orderState, orderEvent, *order]) error {
+		actions = append(actions, "rule")
+		return nil
+	})
+	m.OnTransition(func(ctx context.Context, t *Transition[orderState, orderEvent, *order]) error {
+		actions = append(actions, "all")
+		return nil
+	})
+
+	var events []string
+	m.Subscribe(Subscription[int, string]{To: []int{paid.Code()}}, func(ctx context.Context, e TransitionEvent[int, string]) error {
+		events = append(events, fmt.Sprintf("%d-%s->%d", e.From, e.Event, e.To))
+		return nil
+	})
+
+	ctx := context.Background()
+	state, err := m.Fire(ctx, created, payEvent, &order{Amount: 1})
+	if err != nil || state.Code() != paid.Code() {
+		t.Fatalf("支付后为 %s，错误：%v", state.Description(), err)
+	}
+	if state, err = m.Fire(ctx, closed, payEvent, &order{Amount: 1}); err != nil || state.Code() != paid.Code() {
+		t.Fatalf("已关闭支付后为 %s，错误：%v", state.Description(), err)
+	}
+	if state, err = m.Fire(ctx, paid, closeEvent, &order{}); err != nil || state.Code() != closed.Code() {
+		t.Fatalf("关闭后为 %s，错误：%v", state.Description(), err)
+	}
+
+	if strings.Join(actions, ",") != "all,rule,all,all" {
+		t.Fatalf("动作的执行顺序为 %v", actions)
+	}
+	if strings.Join(events, ",") != "0-pay->1,2-pay->1" {
+		t.Fatalf("订阅者收到的迁移事件为 %v", events)
+	}
+}
+
+func TestFireInvalid(t *testing.T) {
+	m := newMachine(t)
+	ctx := context.Background()
+
+	state, err := m.Fire(ctx, closed, closeEvent, &order{})
+	if !errors.Is(err, ErrInvalidTransition) || state.Code() != closed.Code() {
+		t.Fatalf("无效迁移返回 %s，错误：%v", state.Description(), err)
+	}
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) || transitionErr.From != "已关闭" || transitionErr.Event != "关闭" || transitionErr.To != "" {
+		t.Fatalf("无效迁移的错误为 %#v", transitionErr)
+	}
+}
+
+func TestFireGuard(t *testing.T) {
+	m := newMachine(t)
+	ctx := context.Background()
+
+	executed := false
+	m.OnTransition(func(ctx context.Context, t *Transition[orderState, orderEvent, *order]) error {
+		executed = true
+		return nil
+	})
+
+	state, err := m.Fire(ctx, created, payEvent, &order{})
+	if !errors.Is(err, ErrGuardRejected) || state.Code() != created.Code() {
+		t.Fatalf("守卫拒绝时返回 %s，错误：%v", state.Description(), err)
+	}
+	if !strings.Contains(err.Error(), "金额必须大于0") {
+		t.Fatalf("错误信息不包含守卫拒绝的原因：%v", err)
+	}
+	if executed {
+		t.Fatal("守卫拒绝后不应执行动作")
+	}
+}
+
+func TestFireActionFailed(t *testing.T) {
+	m := newMachine(t)
+	ctx := context.Background()
+
+	actionErr := errors.New("库存不足")
+	m.OnTransition(func(ctx context.Context, t *Transition[orderState, orderEvent, *order]) error {
+		return actionErr
+	})
+
+	state, err := m.Fire(ctx, created, closeEvent, &order{})
+	if !errors.Is(err, ErrActionFailed) || !errors.Is(err, actionErr) || state.Code() != created.Code() {
+		t.Fatalf("动作失败时返回 %s，错误：%v", state.Description(), err)
+	}
+}
+
+func TestPermitDuplicate(t *testing.T) {
+	m := newMachine(t)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("同一源状态的同一事件重复声明时应 panic")
+		}
+	}()
+	m.Permit(payEvent, closed, created)
+}
+
+func TestDOT(t *testing.T) {
+	m := newMachine(t)
+	m.Permit(payEvent, closed, paid)
+
+	dot := m.DOT()
+	expect := []string{
+		`__start -> "0";`,
+		`"0" [label="已创建"];`,
+		`"1" [label="已支付"];`,
+		`"2" [label="已关闭", shape=doublecircle];`,
+		`"0" -> "1" [label="支付 [guard]"];`,
+		`"0" -> "2" [label="关闭"];`,
+		`"1" -> "2" [label="关闭\n支付"];`,
+	}
+	for _, line := range expect {
+		if !strings.Contains(dot, line) {
+			t.Fatalf("状态图缺少 %s：\n%s", line, dot)
+		}
+	}
+}
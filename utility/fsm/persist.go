@@ -0,0 +1,94 @@
+package fsm
+
+import (
+	"context"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/kysion/base-library/utility/daoctl"
+)
+
+/*
+	状态迁移的持久化：
+		1、在数据库事务内依次执行守卫、动作，并以源状态（及版本号）作为条件更新状态列，
+		   UPDATE table SET state = 目标状态[, version = version + 1] WHERE id = ? AND state = 源状态[ AND version = ?]
+		2、更新的行数为0时说明状态已被其他请求修改，返回 ErrStaleState 并回滚事务；更新失败时返回 ErrPersistFailed
+		3、动作通过 ctx 访问数据库时自动加入同一事务，动作返回错误时事务回滚
+		4、NetMessage 为 true 时迁移事件在事务内写入发件箱，与状态同时提交；本进程内的订阅者在事务提交后调用
+*/
+
+// ApplyOption 持久化迁移的选项
+type ApplyOption struct {
+	Version int64 // 当前版本号，配置了 VersionColumn 时作为乐观锁条件，更新后加1
+	Data    g.Map // 同时更新的其他字段
+}
+
+// Apply 执行迁移并持久化：model 为业务表的模型（如 dao.Order.Ctx(ctx)），id 为业务对象的主键，from 为读取到的当前状态；
+// 成功时返回目标状态，失败时事务回滚并返回 TransitionError。
+func (m *Machine[S, E, T, SC, EC]) Apply(ctx context.Context, model *gdb.Model, id any, from S, event E, entity T, option ...ApplyOption) (S, error) {
+	var opt ApplyOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	var t *Transition[S, E, T]
+	err := model.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		var (
+			rule *Rule[S, E, T, SC, EC]
+			err  error
+		)
+		if t, rule, err = m.begin(ctx, from, event, entity); err != nil {
+			return err
+		}
+		if err = m.execute(ctx, t, rule); err != nil {
+			return err
+		}
+
+		if err = m.update(ctx, model.Clone().TX(tx), id, t, opt); err != nil {
+			return err
+		}
+
+		if m.option.NetMessage {
+			published := m.event(t, id)
+			return m.hook.PublishTx(ctx, tx, published, m.typedOption(published, true))
+		}
+		return nil
+	})
+	if err != nil {
+		return from, err
+	}
+
+	// 事务已提交，通知本进程内的订阅者，网络消息由发件箱中继投递
+	published := m.event(t, id)
+	if err = m.hook.Publish(ctx, published, m.typedOption(published, false)); err != nil {
+		g.Log().Warningf(ctx, "状态机 %s 的迁移事件处理失败：%v", m.name, err)
+	}
+
+	return t.To, nil
+}
+
+// update 以源状态及版本号为条件更新状态列，更新失败时返回 ErrPersistFailed，未更新任何行时返回 ErrStaleState
+func (m *Machine[S, E, T, SC, EC]) update(ctx context.Context, model *gdb.Model, id any, t *Transition[S, E, T], opt ApplyOption) error {
+	data := g.Map{}
+	for k, v := range opt.Data {
+		data[k] = v
+	}
+	data[m.option.StateColumn] = t.To.Code()
+
+	model = model.Ctx(ctx).
+		Where(m.option.IdColumn, id).
+		Where(m.option.StateColumn, t.From.Code())
+	if m.option.VersionColumn != "" {
+		data[m.option.VersionColumn] = &gdb.Counter{Field: m.option.VersionColumn, Value: 1}
+		model = model.Where(m.option.VersionColumn, opt.Version)
+	}
+
+	rowsAffected, err := daoctl.UpdateWithError(model.Data(data))
+	if err != nil {
+		return m.newError(t.From, t.Event, &t.To, ErrPersistFailed, err)
+	}
+	if rowsAffected == 0 {
+		return m.newError(t.From, t.Event, &t.To, ErrStaleState, nil)
+	}
+	return nil
+}